@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"nsvpn/pkg/fakenode"
+)
+
+func main() {
+	addr := flag.String("addr", ":50051", "address to listen on")
+	load := flag.Float64("load", 0.3, "load score returned by GetLoad")
+	health := flag.String("health", "ok", "status returned by GetHealth")
+	latency := flag.Duration("latency", 0, "delay before every response")
+	failureRate := flag.Float64("failure-rate", 0, "fraction of requests failing with Unavailable")
+	keyID := flag.String("auth-key-id", "", "HMAC key id, auth is disabled when empty")
+	secret := flag.String("auth-secret", "", "HMAC key secret")
+	flag.Parse()
+
+	node := fakenode.New(fakenode.Config{
+		Latency:     *latency,
+		FailureRate: *failureRate,
+		Load:        *load,
+		Health:      *health,
+	})
+	if *keyID != "" {
+		node.EnableAuth(*keyID, *secret)
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := node.NewServer()
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+
+		stopped := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			s.Stop()
+		}
+	}()
+
+	log.Printf("fake node listening on %s", lis.Addr())
+	if err = s.Serve(lis); err != nil {
+		log.Fatal(err)
+	}
+}
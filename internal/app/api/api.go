@@ -2,84 +2,255 @@ package api
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"log/slog"
+	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"nsvpn/internal/app/events"
 	"nsvpn/internal/app/models"
 	pbClient "nsvpn/pkg/client/v1"
 	"nsvpn/pkg/logger"
+	"nsvpn/pkg/nodeauth"
 	pbServer "nsvpn/pkg/server/v1"
 )
 
+const (
+	idleTimeout      = 10 * time.Minute
+	evictInterval    = time.Minute
+	keepaliveTime    = 30 * time.Second
+	keepaliveTimeout = 10 * time.Second
+)
+
+// ServerConnection не меняется после создания: при смене учётных данных в пул кладётся новое соединение,
+// а старое закрывается, когда его отпустит последний вызов
 type ServerConnection struct {
-	conn *grpc.ClientConn
-	mu   sync.Mutex
-	serv *models.Server
+	conn     *grpc.ClientConn
+	server   pbServer.ServerServiceClient
+	client   pbClient.ClientServiceClient
+	certHash string
+
+	refs      atomic.Int64
+	retired   atomic.Bool
+	lastUsed  atomic.Int64 // unix nano
+	closeOnce sync.Once
+	log       *logger.Logger
+	address   string
 }
 
-type API struct {
-	log     *logger.Logger
-	servers map[string]*ServerConnection
-	server  pbServer.ServerServiceClient
-	client  pbClient.ClientServiceClient
+func (data *ServerConnection) Release() {
+	if data.refs.Add(-1) == 0 && data.retired.Load() {
+		data.close()
+	}
+}
+
+func (data *ServerConnection) acquire() {
+	data.refs.Add(1)
+	data.lastUsed.Store(time.Now().UnixNano())
 }
 
-func NewAPI(log *logger.Logger) *API {
-	return &API{
-		log:     log,
-		servers: make(map[string]*ServerConnection),
+func (data *ServerConnection) retire() {
+	data.retired.Store(true)
+	if data.refs.Load() == 0 {
+		data.close()
 	}
 }
 
-func (a *API) EnsureConnection(serv *models.Server) (context.Context, error) {
-	data, exists := a.servers[fmt.Sprintf("%s:%d", serv.IP, serv.Port)]
-	if !exists {
-		data = &ServerConnection{
-			serv: serv,
+func (data *ServerConnection) close() {
+	data.closeOnce.Do(func() {
+		if err := data.conn.Close(); err != nil {
+			data.log.Error("Failed to close gRPC connection", err, slog.String("address", data.address))
 		}
-		a.servers[fmt.Sprintf("%s:%d", serv.IP, serv.Port)] = data
+	})
+}
+
+type API struct {
+	log      *logger.Logger
+	bus      *events.Bus
+	mu       sync.RWMutex
+	servers  map[string]*ServerConnection
+	breakers map[string]*breaker
+	watchers map[string]context.CancelFunc
+	dialer   func(ctx context.Context, address string) (net.Conn, error)
+	done     chan struct{}
+}
+
+type Option func(a *API)
+
+func WithDialer(dialer func(ctx context.Context, address string) (net.Conn, error)) Option {
+	return func(a *API) {
+		a.dialer = dialer
+	}
+}
+
+func NewAPI(log *logger.Logger, bus *events.Bus, opts ...Option) *API {
+	a := &API{
+		log:      log,
+		bus:      bus,
+		servers:  make(map[string]*ServerConnection),
+		breakers: make(map[string]*breaker),
+		watchers: make(map[string]context.CancelFunc),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
 
-	data.mu.Lock()
-	defer data.mu.Unlock()
+	go a.evictIdle()
+	return a
+}
 
-	authKey := sha256.Sum256([]byte(fmt.Sprintf("%s%s", serv.Country.PublicKey, serv.Country.PrivateKey)))
-	ctx := metadata.AppendToOutgoingContext(
-		context.Background(),
-		"X-AUTH-KEY", hex.EncodeToString(authKey[:]),
-	)
+func (a *API) EnsureConnection(serv *models.Server) (context.Context, *ServerConnection, error) {
+	address := fmt.Sprintf("%s:%d", serv.IP, serv.Port)
 
-	if data.conn != nil {
-		a.log.Debug("Checking existing gRPC connection state")
-		if data.conn.GetState() != connectivity.Shutdown {
-			a.log.Debug("gRPC connection is already active")
-			return ctx, nil
+	ctx := context.Background()
+	if serv.AuthKeyID == "" {
+		// статический ключ не меняется и открывает доступ к ноде, поэтому без TLS он не отправляется
+		if serv.CACert == "" {
+			a.log.Warn("No auth key for server and no TLS, sending request without credentials", slog.String("address", address))
+		} else {
+			a.log.Warn("No auth key for server, using legacy auth header", slog.String("address", address))
+			ctx = metadata.AppendToOutgoingContext(ctx, nodeauth.HeaderLegacyKey, nodeauth.LegacyKey(serv.Country.PublicKey, serv.Country.PrivateKey))
 		}
+	}
 
-		a.log.Warn("gRPC connection is shutdown, attempting to close the connection")
-		if err := data.conn.Close(); err != nil {
-			a.log.Error("Failed to close existing gRPC connection", err)
-			return nil, err
-		}
+	certHash := getConnectionHash(serv)
+	if data := a.acquireConnection(address, certHash); data != nil {
+		return ctx, data, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// пока ждали блокировку, соединение могли создать параллельно
+	data, exists := a.servers[address]
+	if exists && data.usable(certHash) {
+		data.acquire()
+		return ctx, data, nil
+	}
+
+	newData, err := a.dial(serv, address, certHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if exists {
+		a.log.Warn("gRPC connection is shutdown or credentials changed, replacing the connection", slog.String("address", address))
+		data.retire()
+	}
+
+	newData.acquire()
+	a.servers[address] = newData
+	return ctx, newData, nil
+}
+
+func (a *API) Close() {
+	close(a.done)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for address, cancel := range a.watchers {
+		cancel()
+		delete(a.watchers, address)
+	}
+
+	for address, data := range a.servers {
+		data.retire()
+		delete(a.servers, address)
+	}
+}
+
+func (a *API) acquireConnection(address, certHash string) *ServerConnection {
+	// счётчик увеличивается под a.mu, поэтому соединение не может быть выведено из пула между поиском и захватом
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	data, exists := a.servers[address]
+	if !exists || !data.usable(certHash) {
+		return nil
+	}
+	data.acquire()
+	return data
+}
+
+func (data *ServerConnection) usable(certHash string) bool {
+	return data.certHash == certHash && data.conn.GetState() != connectivity.Shutdown
+}
+
+func (a *API) dial(serv *models.Server, address, certHash string) (*ServerConnection, error) {
+	creds, err := a.getTransportCredentials(serv)
+	if err != nil {
+		a.log.Error("Failed to load server certificates", err, slog.String("address", address))
+		return nil, err
+	}
+
+	a.log.Debug("Creating new gRPC connection", slog.String("address", address))
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                keepaliveTime,
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+	}
+	if a.dialer != nil {
+		opts = append(opts, grpc.WithContextDialer(a.dialer))
+	}
+	if serv.AuthKeyID != "" {
+		opts = append(opts,
+			grpc.WithUnaryInterceptor(nodeauth.UnaryClientInterceptor(serv.AuthKeyID, serv.AuthSecret)),
+			grpc.WithStreamInterceptor(nodeauth.StreamClientInterceptor(serv.AuthKeyID, serv.AuthSecret)),
+		)
 	}
 
-	a.log.Debug("Creating new gRPC connection")
-	var err error
-	data.conn, err = grpc.NewClient(fmt.Sprintf("%s:%d", serv.IP, serv.Port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// grpc.NewClient не устанавливает соединение сразу, поэтому его можно вызывать под блокировкой пула
+	conn, err := grpc.NewClient(address, opts...)
 	if err != nil {
-		a.log.Error("Failed to connect API server", err)
+		a.log.Error("Failed to connect API server", err, slog.String("address", address))
 		return nil, err
 	}
-	a.server = pbServer.NewServerServiceClient(data.conn)
-	a.client = pbClient.NewClientServiceClient(data.conn)
 
-	a.log.Debug("Successfully established gRPC connection to API server", slog.String("address", fmt.Sprintf("%s:%d", serv.IP, serv.Port)))
-	return ctx, nil
+	a.log.Debug("Successfully established gRPC connection to API server", slog.String("address", address))
+	return &ServerConnection{
+		conn:     conn,
+		server:   pbServer.NewServerServiceClient(conn),
+		client:   pbClient.NewClientServiceClient(conn),
+		certHash: certHash,
+		log:      a.log,
+		address:  address,
+	}, nil
+}
+
+func (a *API) evictIdle() {
+	ticker := time.NewTicker(evictInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+		}
+
+		a.mu.Lock()
+		for address, data := range a.servers {
+			if _, watched := a.watchers[address]; watched {
+				continue
+			}
+
+			idle := time.Since(time.Unix(0, data.lastUsed.Load()))
+			if data.refs.Load() == 0 && idle > idleTimeout {
+				data.retire()
+				delete(a.servers, address)
+				a.log.Debug("Evicted idle gRPC connection", slog.String("address", address))
+			}
+		}
+		a.mu.Unlock()
+	}
 }
@@ -0,0 +1,458 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"nsvpn/internal/app/events"
+	"nsvpn/internal/app/models"
+	pbClient "nsvpn/pkg/client/v1"
+	"nsvpn/pkg/fakenode"
+	"nsvpn/pkg/logger"
+	"nsvpn/pkg/nodeauth"
+	pbServer "nsvpn/pkg/server/v1"
+)
+
+const (
+	testKeyID      = "test-key"
+	testSecret     = "test-secret"
+	testAddress    = "10.0.0.1:50051"
+	testServerName = "node.test"
+)
+
+func newTestAPI(t *testing.T, node *fakenode.Node, opts ...grpc.ServerOption) (*API, *events.Bus) {
+	t.Helper()
+
+	network := fakenode.NewNetwork()
+	network.Serve(testAddress, node, opts...)
+
+	log := logger.NewDiscard()
+	bus := events.NewBus(log)
+	a := NewAPI(log, bus, WithDialer(network.Dial))
+	t.Cleanup(func() {
+		a.Close()
+		network.Stop()
+	})
+	return a, bus
+}
+
+// newTestTLS выпускает самоподписанный сертификат ноды и возвращает его PEM для CACert сервера
+func newTestTLS(t *testing.T) (grpc.ServerOption, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: testServerName},
+		DNSNames:              []string{testServerName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	creds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	return grpc.Creds(creds), string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func newTestNode() (*fakenode.Node, *models.Server) {
+	node := fakenode.New(fakenode.Config{Load: 0.4})
+	node.EnableAuth(testKeyID, testSecret)
+	return node, &models.Server{ID: 1, IP: "10.0.0.1", Insecure: true, Port: 50051, AuthKeyID: testKeyID, AuthSecret: testSecret}
+}
+
+func TestClientLifecycle(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := a.AddRequest(serv, models.ProtocolTrojan, "uuid-1", "secret", "nsvpn-1-de-trojan", expiresAt); err != nil {
+		t.Fatalf("AddRequest: %v", err)
+	}
+
+	found, err := a.IsFoundRequest(serv, models.ProtocolTrojan, "uuid-1")
+	if err != nil || !found {
+		t.Fatalf("IsFoundRequest = %v, %v; want true, nil", found, err)
+	}
+	if found, _ = a.IsFoundRequest(serv, models.ProtocolVLESS, "uuid-1"); found {
+		t.Fatal("client found under a different protocol")
+	}
+
+	newExpiresAt := expiresAt.Add(24 * time.Hour)
+	if err = a.UpdateRequest(serv, models.ProtocolTrojan, "uuid-1", &newExpiresAt); err != nil {
+		t.Fatalf("UpdateRequest: %v", err)
+	}
+
+	clients, err := a.ListClientsRequest(serv)
+	if err != nil {
+		t.Fatalf("ListClientsRequest: %v", err)
+	}
+	if len(clients) != 1 {
+		t.Fatalf("got %d clients, want 1", len(clients))
+	}
+	if c := clients[0]; c.Email != "nsvpn-1-de-trojan" || c.Password != "secret" || !c.ExpiresAt.Equal(newExpiresAt) {
+		t.Fatalf("unexpected client %+v", c)
+	}
+
+	if err = a.DeleteRequest(serv, models.ProtocolTrojan, "uuid-1"); err != nil {
+		t.Fatalf("DeleteRequest: %v", err)
+	}
+	if found, _ = a.IsFoundRequest(serv, models.ProtocolTrojan, "uuid-1"); found {
+		t.Fatal("client still exists after delete")
+	}
+}
+
+func TestIdempotentCallIsRetried(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	node.FailNext(2)
+	load, err := a.GetLoadRequest(serv)
+	if err != nil {
+		t.Fatalf("GetLoadRequest: %v", err)
+	}
+	if load != 0.4 {
+		t.Fatalf("load = %v, want 0.4", load)
+	}
+	if calls := node.Calls(pbServer.ServerService_GetLoad_FullMethodName); calls != 3 {
+		t.Fatalf("GetLoad called %d times, want 3", calls)
+	}
+}
+
+func TestMutatingCallIsNotRetried(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	node.FailNext(1)
+	err := a.AddRequest(serv, models.ProtocolVLESS, "uuid-1", "", "nsvpn-1-de", time.Now())
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("AddRequest error = %v, want Unavailable", err)
+	}
+	if calls := node.Calls(pbClient.ClientService_CreateClient_FullMethodName); calls != 1 {
+		t.Fatalf("CreateClient called %d times, want 1", calls)
+	}
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	node.SetFailureRate(1)
+	for i := 0; i < breakerThreshold; i++ {
+		if err := a.DeleteRequest(serv, models.ProtocolVLESS, "uuid"); err == nil {
+			t.Fatal("DeleteRequest succeeded on a failing node")
+		}
+	}
+
+	if a.IsHealthy(serv) {
+		t.Fatal("node is still healthy after consecutive failures")
+	}
+	calls := node.Calls(pbClient.ClientService_DeleteClient_FullMethodName)
+	if err := a.DeleteRequest(serv, models.ProtocolVLESS, "uuid"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("DeleteRequest error = %v, want ErrCircuitOpen", err)
+	}
+	if node.Calls(pbClient.ClientService_DeleteClient_FullMethodName) != calls {
+		t.Fatal("request reached the node while the circuit was open")
+	}
+}
+
+func TestBatchReportsFailedClients(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	node.AddClient(&pbClient.Client{Uuid: "uuid-2", Protocol: pbClient.Protocol_PROTOCOL_VLESS})
+	clients := []*models.NodeClient{
+		{UUID: "uuid-1", Email: "nsvpn-1-de", Protocol: models.ProtocolVLESS},
+		{UUID: "uuid-2", Email: "nsvpn-2-de", Protocol: models.ProtocolVLESS},
+		{UUID: "uuid-3", Email: "nsvpn-3-de-shadowsocks", Protocol: models.ProtocolShadowsocks},
+	}
+
+	failed, err := a.BatchAddRequest(serv, clients)
+	if err != nil {
+		t.Fatalf("BatchAddRequest: %v", err)
+	}
+	if len(failed) != 1 || failed[0].UUID != "uuid-2" || failed[0].Protocol != models.ProtocolVLESS {
+		t.Fatalf("unexpected failed results %+v", failed)
+	}
+	if got := len(node.Clients()); got != 3 {
+		t.Fatalf("node has %d clients, want 3", got)
+	}
+
+	if failed, err = a.BatchDeleteRequest(serv, clients); err != nil || len(failed) != 0 {
+		t.Fatalf("BatchDeleteRequest = %+v, %v", failed, err)
+	}
+	if got := len(node.Clients()); got != 0 {
+		t.Fatalf("node has %d clients after delete, want 0", got)
+	}
+}
+
+func TestWrongSecretIsRejected(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	serv.AuthSecret = "wrong"
+	if _, err := a.GetHealthRequest(serv); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("GetHealthRequest error = %v, want Unauthenticated", err)
+	}
+}
+
+func TestServerWithoutCACertIsRejected(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	serv.Insecure = false
+	if _, err := a.GetHealthRequest(serv); !errors.Is(err, ErrNoCACert) {
+		t.Fatalf("GetHealthRequest error = %v, want ErrNoCACert", err)
+	}
+	if calls := node.Calls(pbServer.ServerService_GetHealth_FullMethodName); calls != 0 {
+		t.Fatalf("node received %d requests over an insecure connection", calls)
+	}
+}
+
+func TestConnectionSetupErrorsDoNotOpenBreaker(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	serv.Insecure = false
+	for i := 0; i < breakerThreshold+1; i++ {
+		if _, err := a.GetHealthRequest(serv); !errors.Is(err, ErrNoCACert) {
+			t.Fatalf("GetHealthRequest error = %v, want ErrNoCACert", err)
+		}
+	}
+	if !a.IsHealthy(serv) {
+		t.Fatal("node marked unhealthy because of a local configuration error")
+	}
+
+	// после исправления настроек запросы идут сразу, без ожидания остывания
+	serv.Insecure = true
+	if _, err := a.GetHealthRequest(serv); err != nil {
+		t.Fatalf("GetHealthRequest after fixing config: %v", err)
+	}
+}
+
+func TestRotateAuthKey(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	if err := a.RotateAuthKeyRequest(serv, "next-key", "next-secret", time.Minute); err != nil {
+		t.Fatalf("RotateAuthKeyRequest: %v", err)
+	}
+
+	serv.AuthKeyID, serv.AuthSecret = "next-key", "next-secret"
+	if _, err := a.GetHealthRequest(serv); err != nil {
+		t.Fatalf("GetHealthRequest with rotated key: %v", err)
+	}
+	if _, ok := node.Keyring().Lookup(testKeyID); !ok {
+		t.Fatal("old key was dropped before the grace period ended")
+	}
+}
+
+func TestWatchPublishesEvents(t *testing.T) {
+	node, serv := newTestNode()
+	a, bus := newTestAPI(t, node)
+
+	ch, unsubscribe := bus.Subscribe(events.LoadThreshold)
+	defer unsubscribe()
+
+	a.Watch(serv, func(uint) (*models.Server, error) { return serv, nil })
+	defer a.Unwatch(serv)
+
+	waitEvent(t, node, ch, serv.ID)
+}
+
+func TestWatchReconnectsWithRotatedKey(t *testing.T) {
+	node, serv := newTestNode()
+	a, bus := newTestAPI(t, node)
+
+	ch, unsubscribe := bus.Subscribe(events.LoadThreshold)
+	defer unsubscribe()
+
+	var (
+		mu     sync.Mutex
+		stored = *serv
+	)
+	a.Watch(serv, func(uint) (*models.Server, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		current := stored
+		return &current, nil
+	})
+	defer a.Unwatch(serv)
+	waitEvent(t, node, ch, serv.ID)
+
+	// ключ ротируется, пока поток событий открыт, старый ключ сразу перестаёт действовать
+	if err := a.RotateAuthKeyRequest(serv, "next-key", "next-secret", time.Millisecond); err != nil {
+		t.Fatalf("RotateAuthKeyRequest: %v", err)
+	}
+	mu.Lock()
+	stored.AuthKeyID, stored.AuthSecret = "next-key", "next-secret"
+	rotated := stored
+	mu.Unlock()
+
+	_, current, err := a.EnsureConnection(&rotated)
+	if err != nil {
+		t.Fatalf("EnsureConnection with rotated key: %v", err)
+	}
+	current.Release()
+
+	time.Sleep(10 * time.Millisecond)
+	node.DropWatchers()
+	waitEvent(t, node, ch, serv.ID)
+
+	_, after, err := a.EnsureConnection(&rotated)
+	if err != nil {
+		t.Fatalf("EnsureConnection after reconnect: %v", err)
+	}
+	defer after.Release()
+	if after != current {
+		t.Fatal("watcher replaced the connection with stale credentials")
+	}
+}
+
+func waitEvent(t *testing.T, node *fakenode.Node, ch <-chan events.Event, serverID uint) {
+	t.Helper()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		node.Emit(&pbServer.Event{Type: pbServer.EventType_EVENT_TYPE_LOAD_THRESHOLD, LoadScore: 0.95})
+
+		select {
+		case event := <-ch:
+			if event.ServerID != serverID || event.Load != 0.95 {
+				t.Fatalf("unexpected event %+v", event)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("event was not published to the bus")
+		}
+	}
+}
+
+func TestReplacedConnectionStaysOpenUntilReleased(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	_, old, err := a.EnsureConnection(serv)
+	if err != nil {
+		t.Fatalf("EnsureConnection: %v", err)
+	}
+	if err = a.RotateAuthKeyRequest(serv, "next-key", "next-secret", time.Minute); err != nil {
+		t.Fatalf("RotateAuthKeyRequest: %v", err)
+	}
+
+	rotated := *serv
+	rotated.AuthKeyID, rotated.AuthSecret = "next-key", "next-secret"
+	_, current, err := a.EnsureConnection(&rotated)
+	if err != nil {
+		t.Fatalf("EnsureConnection with rotated key: %v", err)
+	}
+	defer current.Release()
+	if current == old {
+		t.Fatal("connection was not replaced after credentials changed")
+	}
+
+	if _, err = old.server.GetHealth(context.Background(), &pbServer.ServerRequest{}); err != nil {
+		t.Fatalf("replaced connection closed while in use: %v", err)
+	}
+	old.Release()
+	if state := old.conn.GetState(); state != connectivity.Shutdown {
+		t.Fatalf("replaced connection is %s after release, want shutdown", state)
+	}
+}
+
+func TestConcurrentCallsWithDifferentCredentials(t *testing.T) {
+	node, serv := newTestNode()
+	a, _ := newTestAPI(t, node)
+
+	if err := a.RotateAuthKeyRequest(serv, "next-key", "next-secret", time.Minute); err != nil {
+		t.Fatalf("RotateAuthKeyRequest: %v", err)
+	}
+	rotated := *serv
+	rotated.AuthKeyID, rotated.AuthSecret = "next-key", "next-secret"
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := range 40 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			target := serv
+			if i%2 == 0 {
+				target = &rotated
+			}
+			if _, err := a.GetHealthRequest(target); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("GetHealthRequest: %v", err)
+	}
+}
+
+func TestLegacyNodeBootstrap(t *testing.T) {
+	country := models.Country{PublicKey: "pub", PrivateKey: "priv"}
+	node := fakenode.New(fakenode.Config{})
+	node.EnableLegacyAuth(nodeauth.LegacyKey(country.PublicKey, country.PrivateKey))
+	creds, caCert := newTestTLS(t)
+	a, _ := newTestAPI(t, node, creds)
+
+	serv := &models.Server{ID: 1, IP: "10.0.0.1", Port: 50051, ServerName: testServerName, CACert: caCert, Country: country}
+	if _, err := a.GetHealthRequest(serv); err != nil {
+		t.Fatalf("legacy node rejected legacy key before bootstrap: %v", err)
+	}
+	if err := a.RotateAuthKeyRequest(serv, "first-key", "first-secret", time.Minute); err != nil {
+		t.Fatalf("RotateAuthKeyRequest with legacy key: %v", err)
+	}
+
+	if _, err := a.GetHealthRequest(serv); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("legacy key accepted after bootstrap: %v", err)
+	}
+	serv.AuthKeyID, serv.AuthSecret = "first-key", "first-secret"
+	if _, err := a.GetHealthRequest(serv); err != nil {
+		t.Fatalf("GetHealthRequest with bootstrapped key: %v", err)
+	}
+}
+
+func TestLegacyKeyIsNotSentWithoutTLS(t *testing.T) {
+	country := models.Country{PublicKey: "pub", PrivateKey: "priv"}
+	node := fakenode.New(fakenode.Config{})
+	node.EnableLegacyAuth(nodeauth.LegacyKey(country.PublicKey, country.PrivateKey))
+	a, _ := newTestAPI(t, node)
+
+	serv := &models.Server{ID: 1, IP: "10.0.0.1", Insecure: true, Port: 50051, Country: country}
+	if _, err := a.GetHealthRequest(serv); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("GetHealthRequest error = %v, want Unauthenticated without the legacy key", err)
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"nsvpn/internal/app/models"
+	pbClient "nsvpn/pkg/client/v1"
+)
+
+const batchSize = 500
+
+func (a *API) BatchAddRequest(serv *models.Server, clients []*models.NodeClient) ([]*models.NodeClientResult, error) {
+	return a.batch(serv, clients, false, func(ctx context.Context, data *ServerConnection, chunk []*models.NodeClient) (*pbClient.BatchClientsResponse, error) {
+		req := &pbClient.BatchCreateClientsRequest{Clients: make([]*pbClient.CreateClientRequest, 0, len(chunk))}
+		for _, client := range chunk {
+			req.Clients = append(req.Clients, &pbClient.CreateClientRequest{
+				Uuid:      client.UUID,
+				Email:     client.Email,
+				ExpiresAt: timestamppb.New(client.ExpiresAt),
+				Protocol:  toProtocol(client.Protocol),
+				Password:  client.Password,
+			})
+		}
+		return data.client.BatchCreateClients(ctx, req)
+	})
+}
+
+func (a *API) BatchUpdateRequest(serv *models.Server, clients []*models.NodeClient) ([]*models.NodeClientResult, error) {
+	return a.batch(serv, clients, true, func(ctx context.Context, data *ServerConnection, chunk []*models.NodeClient) (*pbClient.BatchClientsResponse, error) {
+		req := &pbClient.BatchUpdateClientsRequest{Clients: make([]*pbClient.UpdateClientRequest, 0, len(chunk))}
+		for _, client := range chunk {
+			req.Clients = append(req.Clients, &pbClient.UpdateClientRequest{
+				Uuid:      client.UUID,
+				ExpiresAt: timestamppb.New(client.ExpiresAt),
+				Protocol:  toProtocol(client.Protocol),
+			})
+		}
+		return data.client.BatchUpdateClients(ctx, req)
+	})
+}
+
+func (a *API) BatchDeleteRequest(serv *models.Server, clients []*models.NodeClient) ([]*models.NodeClientResult, error) {
+	return a.batch(serv, clients, false, func(ctx context.Context, data *ServerConnection, chunk []*models.NodeClient) (*pbClient.BatchClientsResponse, error) {
+		req := &pbClient.BatchDeleteClientsRequest{Clients: make([]*pbClient.DeleteClientRequest, 0, len(chunk))}
+		for _, client := range chunk {
+			req.Clients = append(req.Clients, &pbClient.DeleteClientRequest{
+				Uuid:     client.UUID,
+				Protocol: toProtocol(client.Protocol),
+			})
+		}
+		return data.client.BatchDeleteClients(ctx, req)
+	})
+}
+
+func (a *API) batch(serv *models.Server, clients []*models.NodeClient, idempotent bool,
+	fn func(ctx context.Context, data *ServerConnection, chunk []*models.NodeClient) (*pbClient.BatchClientsResponse, error)) ([]*models.NodeClientResult, error) {
+	var failed []*models.NodeClientResult
+	for start := 0; start < len(clients); start += batchSize {
+		chunk := clients[start:min(start+batchSize, len(clients))]
+
+		err := a.call(serv, idempotent, func(ctx context.Context, data *ServerConnection) error {
+			resp, err := fn(ctx, data, chunk)
+			if err != nil {
+				return err
+			}
+
+			for _, result := range resp.GetResults() {
+				if result.GetSuccess() {
+					continue
+				}
+				failed = append(failed, &models.NodeClientResult{
+					UUID:     result.GetUuid(),
+					Protocol: fromProtocol(result.GetProtocol()),
+					Error:    result.GetError(),
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return failed, err
+		}
+	}
+
+	return failed, nil
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+var ErrCircuitOpen = errors.New("node is unavailable, circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breaker struct {
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < breakerCooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+// release возвращает пробный запрос, который так и не дошёл до ноды
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+func (b *breaker) failure() (opened bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= breakerThreshold {
+		opened = b.state != breakerOpen
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+	return opened
+}
+
+func (b *breaker) healthy() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state == breakerClosed || (b.state == breakerOpen && time.Since(b.openedAt) >= breakerCooldown)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := &breaker{}
+	for i := 1; i < breakerThreshold; i++ {
+		if b.failure() {
+			t.Fatalf("opened after %d failures", i)
+		}
+		if !b.allow() {
+			t.Fatalf("request rejected after %d failures", i)
+		}
+	}
+
+	if !b.failure() {
+		t.Fatal("not opened after threshold")
+	}
+	if b.allow() || b.healthy() {
+		t.Fatal("open breaker lets requests through")
+	}
+	if b.failure() {
+		t.Fatal("already open breaker reported as opened again")
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := &breaker{state: breakerOpen, openedAt: time.Now().Add(-breakerCooldown)}
+	if !b.healthy() {
+		t.Fatal("breaker is unhealthy after cooldown")
+	}
+	if !b.allow() {
+		t.Fatal("probe request rejected after cooldown")
+	}
+	if b.allow() {
+		t.Fatal("second request allowed while probing")
+	}
+
+	if !b.failure() {
+		t.Fatal("failed probe did not reopen breaker")
+	}
+	if b.allow() {
+		t.Fatal("request allowed right after failed probe")
+	}
+
+	b.openedAt = time.Now().Add(-breakerCooldown)
+	b.allow()
+	b.release()
+	if !b.allow() {
+		t.Fatal("released probe did not let the next request probe")
+	}
+	b.success()
+	if !b.allow() || !b.healthy() || b.failures != 0 {
+		t.Fatalf("successful probe did not close breaker: %+v", b)
+	}
+}
+
+func TestIsNodeFailure(t *testing.T) {
+	tests := map[error]bool{
+		context.DeadlineExceeded:                            true,
+		fmt.Errorf("call: %w", context.DeadlineExceeded):    true,
+		status.Error(codes.Unavailable, "down"):             true,
+		status.Error(codes.ResourceExhausted, "busy"):       true,
+		status.Error(codes.NotFound, "no client"):           false,
+		status.Error(codes.InvalidArgument, "bad request"):  false,
+		status.Error(codes.Unauthenticated, "wrong secret"): false,
+		errors.New("record not found"):                      false,
+	}
+	for err, want := range tests {
+		if got := isNodeFailure(err); got != want {
+			t.Errorf("isNodeFailure(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+
+	"nsvpn/internal/app/models"
+)
+
+const (
+	callTimeout    = 3 * time.Second
+	retryAttempts  = 3
+	retryBaseDelay = 200 * time.Millisecond
+)
+
+func (a *API) IsHealthy(serv *models.Server) bool {
+	return a.getBreaker(fmt.Sprintf("%s:%d", serv.IP, serv.Port)).healthy()
+}
+
+func (a *API) getBreaker(address string) *breaker {
+	a.mu.RLock()
+	b, exists := a.breakers[address]
+	a.mu.RUnlock()
+	if exists {
+		return b
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if b, exists = a.breakers[address]; exists {
+		return b
+	}
+
+	b = &breaker{}
+	a.breakers[address] = b
+	return b
+}
+
+func (a *API) call(serv *models.Server, idempotent bool, fn func(ctx context.Context, data *ServerConnection) error) error {
+	address := fmt.Sprintf("%s:%d", serv.IP, serv.Port)
+	b := a.getBreaker(address)
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+
+	attempts := 1
+	if idempotent {
+		attempts = retryAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryBaseDelay << (attempt - 1))
+		}
+
+		var (
+			ctx  context.Context
+			data *ServerConnection
+		)
+		ctx, data, err = a.EnsureConnection(serv)
+		if err != nil {
+			a.log.Error("Failed to ensure connection", err, slog.String("address", address))
+			if isNodeFailure(err) {
+				break
+			}
+			// ошибка настройки соединения на нашей стороне, нода тут ни при чём
+			b.release()
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, callTimeout)
+		err = fn(ctx, data)
+		cancel()
+		data.Release()
+
+		if err == nil || !isNodeFailure(err) {
+			b.success()
+			return err
+		}
+		a.log.Warn("Node request failed", slog.String("address", address), slog.Int("attempt", attempt+1), slog.Any("error", err))
+	}
+
+	if b.failure() {
+		a.log.Warn("Node marked as unhealthy", slog.String("address", address))
+	}
+	return err
+}
+
+func isNodeFailure(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || isNodeFailure(err)
+}
+
+func IsNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound || (err != nil && err.Error() == "record not found")
+}
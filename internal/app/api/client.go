@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"time"
 
@@ -8,74 +10,119 @@ import (
 	pbClient "nsvpn/pkg/client/v1"
 )
 
-func (a *API) IsFoundRequest(serv *models.Server, uuid string) (bool, error) {
-	ctx, err := a.EnsureConnection(serv)
-	if err != nil {
-		a.log.Error("Failed to ensure connection before adding client", err)
-		return false, err
+func toProtocol(protocol string) pbClient.Protocol {
+	switch protocol {
+	case models.ProtocolTrojan:
+		return pbClient.Protocol_PROTOCOL_TROJAN
+	case models.ProtocolShadowsocks:
+		return pbClient.Protocol_PROTOCOL_SHADOWSOCKS
+	default:
+		return pbClient.Protocol_PROTOCOL_VLESS
 	}
+}
 
-	exists, err := a.client.ClientExists(ctx, &pbClient.ClientExistsRequest{Uuid: uuid})
-	if err != nil {
-		return false, err
+func fromProtocol(protocol pbClient.Protocol) string {
+	switch protocol {
+	case pbClient.Protocol_PROTOCOL_TROJAN:
+		return models.ProtocolTrojan
+	case pbClient.Protocol_PROTOCOL_SHADOWSOCKS:
+		return models.ProtocolShadowsocks
+	default:
+		return models.ProtocolVLESS
 	}
-	return exists.GetExists(), nil
 }
 
-func (a *API) AddRequest(serv *models.Server, uuid, email string, expiresAt time.Time) error {
-	ctx, err := a.EnsureConnection(serv)
-	if err != nil {
-		a.log.Error("Failed to ensure connection before adding client", err)
-		return err
-	}
+func (a *API) IsFoundRequest(serv *models.Server, protocol, uuid string) (bool, error) {
+	var exists bool
+	err := a.call(serv, true, func(ctx context.Context, data *ServerConnection) error {
+		resp, err := data.client.ClientExists(ctx, &pbClient.ClientExistsRequest{Uuid: uuid, Protocol: toProtocol(protocol)})
+		if err != nil {
+			return err
+		}
+
+		exists = resp.GetExists()
+		return nil
+	})
+	return exists, err
+}
+
+func (a *API) ListClientsRequest(serv *models.Server) ([]*models.NodeClient, error) {
+	var clients []*models.NodeClient
+	err := a.call(serv, true, func(ctx context.Context, data *ServerConnection) error {
+		resp, err := data.client.ListClients(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
 
+		clients = make([]*models.NodeClient, 0, len(resp.GetClients()))
+		for _, client := range resp.GetClients() {
+			clients = append(clients, &models.NodeClient{
+				UUID:      client.GetUuid(),
+				Email:     client.GetEmail(),
+				Protocol:  fromProtocol(client.GetProtocol()),
+				Password:  client.GetPassword(),
+				ExpiresAt: client.GetExpiresAt().AsTime(),
+			})
+		}
+		return nil
+	})
+	return clients, err
+}
+
+func (a *API) ListClientsTrafficRequest(serv *models.Server) (map[string]uint64, error) {
+	var traffic map[string]uint64
+	err := a.call(serv, true, func(ctx context.Context, data *ServerConnection) error {
+		resp, err := data.client.ListClientsTraffic(ctx, &emptypb.Empty{})
+		if err != nil {
+			return err
+		}
+
+		traffic = make(map[string]uint64, len(resp.GetClientTraffics()))
+		for _, client := range resp.GetClientTraffics() {
+			traffic[client.GetUuid()] += client.GetTraffic().GetUplink() + client.GetTraffic().GetDownlink()
+		}
+		return nil
+	})
+	return traffic, err
+}
+
+func (a *API) AddRequest(serv *models.Server, protocol, uuid, password, email string, expiresAt time.Time) error {
 	req := pbClient.CreateClientRequest{
 		Uuid:      uuid,
 		Email:     email,
 		ExpiresAt: timestamppb.New(expiresAt),
+		Protocol:  toProtocol(protocol),
+		Password:  password,
 	}
 
-	_, err = a.client.CreateClient(ctx, &req)
-	if err != nil {
+	return a.call(serv, false, func(ctx context.Context, data *ServerConnection) error {
+		_, err := data.client.CreateClient(ctx, &req)
 		return err
-	}
-	return nil
+	})
 }
 
-func (a *API) UpdateRequest(serv *models.Server, uuid string, expiresAt *time.Time) error {
-	ctx, err := a.EnsureConnection(serv)
-	if err != nil {
-		a.log.Error("Failed to ensure connection before adding client", err)
-		return err
-	}
-
+func (a *API) UpdateRequest(serv *models.Server, protocol, uuid string, expiresAt *time.Time) error {
 	req := pbClient.UpdateClientRequest{
-		Uuid: uuid,
+		Uuid:     uuid,
+		Protocol: toProtocol(protocol),
 	}
 	req.ExpiresAt = timestamppb.New(time.Unix(0, 0))
 	if expiresAt != nil {
 		req.ExpiresAt = timestamppb.New(*expiresAt)
 	}
 
-	_, err = a.client.UpdateClient(ctx, &req)
-	if err != nil {
+	return a.call(serv, true, func(ctx context.Context, data *ServerConnection) error {
+		_, err := data.client.UpdateClient(ctx, &req)
 		return err
-	}
-	return nil
+	})
 }
 
-func (a *API) DeleteRequest(serv *models.Server, uuid string) error {
-	ctx, err := a.EnsureConnection(serv)
-	if err != nil {
-		a.log.Error("Failed to ensure connection before adding client", err)
+func (a *API) DeleteRequest(serv *models.Server, protocol, uuid string) error {
+	return a.call(serv, false, func(ctx context.Context, data *ServerConnection) error {
+		_, err := data.client.DeleteClient(ctx, &pbClient.DeleteClientRequest{
+			Uuid:     uuid,
+			Protocol: toProtocol(protocol),
+		})
 		return err
-	}
-
-	_, err = a.client.DeleteClient(ctx, &pbClient.DeleteClientRequest{
-		Uuid: uuid,
 	})
-	if err != nil {
-		return err
-	}
-	return nil
 }
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"log/slog"
+	"strconv"
+
+	"nsvpn/internal/app/models"
+)
+
+var (
+	ErrInvalidCACert = errors.New("failed to parse CA certificate")
+	ErrNoCACert      = errors.New("no CA certificate for server and insecure connection is not allowed")
+)
+
+func (a *API) getTransportCredentials(serv *models.Server) (credentials.TransportCredentials, error) {
+	if serv.CACert == "" {
+		if !serv.Insecure {
+			return nil, ErrNoCACert
+		}
+		a.log.Warn("No CA certificate for server, using insecure connection", slog.String("ip", serv.IP))
+		return insecure.NewCredentials(), nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(serv.CACert)) {
+		return nil, ErrInvalidCACert
+	}
+
+	cfg := &tls.Config{
+		RootCAs:    pool,
+		ServerName: serv.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = serv.IP
+	}
+
+	if serv.ClientCert != "" && serv.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(serv.ClientCert), []byte(serv.ClientKey))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(cfg), nil
+}
+
+func getConnectionHash(serv *models.Server) string {
+	hash := sha256.Sum256([]byte(strconv.FormatBool(serv.Insecure) + serv.ServerName + serv.CACert + serv.ClientCert + serv.ClientKey + serv.AuthKeyID + serv.AuthSecret))
+	return hex.EncodeToString(hash[:])
+}
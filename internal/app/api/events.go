@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"log/slog"
+	"time"
+
+	"nsvpn/internal/app/events"
+	"nsvpn/internal/app/models"
+	pbServer "nsvpn/pkg/server/v1"
+)
+
+const (
+	watchMinBackoff = time.Second
+	watchMaxBackoff = time.Minute
+)
+
+func toEventType(eventType pbServer.EventType) string {
+	switch eventType {
+	case pbServer.EventType_EVENT_TYPE_CLIENT_CONNECTED:
+		return events.ClientConnected
+	case pbServer.EventType_EVENT_TYPE_CLIENT_DISCONNECTED:
+		return events.ClientDisconnected
+	case pbServer.EventType_EVENT_TYPE_QUOTA_EXCEEDED:
+		return events.QuotaExceeded
+	case pbServer.EventType_EVENT_TYPE_LOAD_THRESHOLD:
+		return events.LoadThreshold
+	case pbServer.EventType_EVENT_TYPE_CONFIG_RELOADED:
+		return events.ConfigReloaded
+	default:
+		return ""
+	}
+}
+
+func (a *API) Watch(serv *models.Server, lookup func(id uint) (*models.Server, error)) {
+	address := fmt.Sprintf("%s:%d", serv.IP, serv.Port)
+
+	a.mu.Lock()
+	if _, exists := a.watchers[address]; exists {
+		a.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.watchers[address] = cancel
+	a.mu.Unlock()
+
+	go a.watch(ctx, serv, address, lookup)
+}
+
+func (a *API) Unwatch(serv *models.Server) {
+	address := fmt.Sprintf("%s:%d", serv.IP, serv.Port)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if cancel, exists := a.watchers[address]; exists {
+		cancel()
+		delete(a.watchers, address)
+	}
+}
+
+func (a *API) watch(ctx context.Context, serv *models.Server, address string, lookup func(id uint) (*models.Server, error)) {
+	backoff := watchMinBackoff
+	for {
+		start := time.Now()
+		err := a.consumeEvents(ctx, serv)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(start) > watchMaxBackoff {
+			backoff = watchMinBackoff
+		}
+		a.log.Warn("Node event stream closed, reconnecting", slog.String("address", address), slog.Any("error", err), slog.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+
+		// ключ или сертификаты могли смениться, пока поток был открыт, а со старыми данными
+		// поток и обычные запросы закрывали бы соединения друг друга
+		fresh, err := lookup(serv.ID)
+		if err != nil {
+			a.log.Warn("Failed to refresh watched server, reconnecting with previous credentials", slog.String("address", address), slog.Any("error", err))
+			continue
+		}
+		if fresh == nil {
+			a.log.Info("Watched server was deleted, stopping event stream", slog.String("address", address))
+			a.mu.Lock()
+			delete(a.watchers, address)
+			a.mu.Unlock()
+			return
+		}
+		serv = fresh
+	}
+}
+
+func (a *API) consumeEvents(ctx context.Context, serv *models.Server) error {
+	connCtx, data, err := a.EnsureConnection(serv)
+	if err != nil {
+		return err
+	}
+	defer data.Release()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if md, ok := metadata.FromOutgoingContext(connCtx); ok {
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	stream, err := data.server.WatchEvents(ctx, &pbServer.WatchEventsRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		eventType := toEventType(event.GetType())
+		if eventType == "" {
+			continue
+		}
+
+		a.bus.Publish(events.Event{
+			ServerID:  serv.ID,
+			Type:      eventType,
+			UUID:      event.GetUuid(),
+			Email:     event.GetEmail(),
+			Load:      event.GetLoadScore(),
+			Message:   event.GetMessage(),
+			CreatedAt: event.GetCreatedAt().AsTime(),
+		})
+	}
+}
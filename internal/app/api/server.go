@@ -1,20 +1,63 @@
 package api
 
 import (
+	"context"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+
 	"nsvpn/internal/app/models"
 	pbServer "nsvpn/pkg/server/v1"
 )
 
 func (a *API) GetLoadRequest(serv *models.Server) (float64, error) {
-	ctx, err := a.EnsureConnection(serv)
-	if err != nil {
-		a.log.Error("Failed to ensure connection before adding client", err)
-		return 0, err
-	}
-
-	load, err := a.server.GetLoad(ctx, &pbServer.ServerRequest{})
-	if err != nil {
-		return 0, err
-	}
-	return load.GetLoadScore(), nil
+	var load float64
+	err := a.call(serv, true, func(ctx context.Context, data *ServerConnection) error {
+		resp, err := data.server.GetLoad(ctx, &pbServer.ServerRequest{})
+		if err != nil {
+			return err
+		}
+
+		load = resp.GetLoadScore()
+		return nil
+	})
+	return load, err
+}
+
+func (a *API) GetHealthRequest(serv *models.Server) (string, error) {
+	var health string
+	err := a.call(serv, true, func(ctx context.Context, data *ServerConnection) error {
+		resp, err := data.server.GetHealth(ctx, &pbServer.ServerRequest{})
+		if err != nil {
+			return err
+		}
+
+		health = resp.GetStatus()
+		return nil
+	})
+	return health, err
+}
+
+func (a *API) RotateAuthKeyRequest(serv *models.Server, keyID, secret string, gracePeriod time.Duration) error {
+	return a.call(serv, false, func(ctx context.Context, data *ServerConnection) error {
+		_, err := data.server.RotateAuthKey(ctx, &pbServer.RotateAuthKeyRequest{
+			KeyId:       keyID,
+			Secret:      secret,
+			GracePeriod: durationpb.New(gracePeriod),
+		})
+		return err
+	})
+}
+
+func (a *API) UpdateRealityConfigRequest(serv *models.Server, privateKey string, shortIDs []string, oldPrivateKey string, oldShortIDs []string, oldExpireAt time.Time) error {
+	return a.call(serv, true, func(ctx context.Context, data *ServerConnection) error {
+		_, err := data.server.UpdateRealityConfig(ctx, &pbServer.UpdateRealityConfigRequest{
+			PrivateKey:          privateKey,
+			ShortIds:            shortIDs,
+			OldShortIds:         oldShortIDs,
+			OldShortIdsExpireAt: timestamppb.New(oldExpireAt),
+			OldPrivateKey:       oldPrivateKey,
+		})
+		return err
+	})
 }
@@ -0,0 +1,112 @@
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/pkg/logger"
+	"strings"
+	"sync"
+)
+
+const (
+	maxDataLength = 64 // ограничение Telegram на callback_data
+	signatureSize = 6
+
+	argsSeparator      = ":"
+	signatureSeparator = "."
+)
+
+var (
+	ErrInvalidData      = errors.New("invalid callback data")
+	ErrInvalidSignature = errors.New("invalid callback signature")
+	ErrDataTooLong      = errors.New("callback data is too long")
+	ErrUnknownAction    = errors.New("unknown callback action")
+)
+
+type HandlerFunc func(c telebot.Context, args []string) error
+
+type Router struct {
+	log    *logger.Logger
+	secret []byte
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+}
+
+func NewRouter(log *logger.Logger, secret string) *Router {
+	return &Router{
+		log:      log,
+		secret:   []byte(secret),
+		handlers: make(map[string]HandlerFunc),
+	}
+}
+
+func (r *Router) Handle(action string, handler HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[action] = handler
+}
+
+func (r *Router) Encode(action string, args ...string) (string, error) {
+	if action == "" || strings.ContainsAny(action, argsSeparator+signatureSeparator+"\f") {
+		return "", ErrInvalidData
+	}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, argsSeparator+signatureSeparator) {
+			return "", ErrInvalidData
+		}
+	}
+
+	payload := strings.Join(append([]string{action}, args...), argsSeparator)
+	data := payload + signatureSeparator + r.sign(payload)
+	if len(data) > maxDataLength {
+		return "", ErrDataTooLong
+	}
+	return data, nil
+}
+
+func (r *Router) Decode(data string) (action string, args []string, err error) {
+	payload, signature, ok := strings.Cut(data, signatureSeparator)
+	if !ok || payload == "" {
+		return "", nil, ErrInvalidData
+	}
+	if !hmac.Equal([]byte(signature), []byte(r.sign(payload))) {
+		return "", nil, ErrInvalidSignature
+	}
+
+	parts := strings.Split(payload, argsSeparator)
+	return parts[0], parts[1:], nil
+}
+
+func (r *Router) Dispatch(c telebot.Context) error {
+	cb := c.Callback()
+	if cb == nil {
+		return nil
+	}
+
+	action, args, err := r.Decode(cb.Data)
+	if err == nil {
+		r.mu.RLock()
+		handler, ok := r.handlers[action]
+		r.mu.RUnlock()
+		if ok {
+			return handler(c, args)
+		}
+		err = ErrUnknownAction
+	}
+
+	r.log.Warn("Rejected callback", slog.String("error", err.Error()), slog.Int64("user_id", c.Sender().ID), slog.String("data", cb.Data))
+	return c.Respond(&telebot.CallbackResponse{Text: i18n.T(i18n.FromContext(c), "callback.expired")})
+}
+
+func (r *Router) sign(payload string) string {
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureSize])
+}
@@ -0,0 +1,79 @@
+package callback
+
+import (
+	"errors"
+	"gopkg.in/telebot.v4"
+	"strings"
+	"testing"
+
+	"nsvpn/pkg/logger"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	r := NewRouter(logger.NewDiscard(), "secret")
+
+	data, err := r.Encode("sub_plan", "12")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	action, args, err := r.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if action != "sub_plan" || len(args) != 1 || args[0] != "12" {
+		t.Fatalf("Decode = %q, %v", action, args)
+	}
+}
+
+func TestDecodeRejectsTampering(t *testing.T) {
+	r := NewRouter(logger.NewDiscard(), "secret")
+
+	data, _ := r.Encode("transport", "1")
+	tampered := strings.Replace(data, "transport:1", "transport:2", 1)
+	if _, _, err := r.Decode(tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Decode(tampered) error = %v, want ErrInvalidSignature", err)
+	}
+
+	other := NewRouter(logger.NewDiscard(), "other")
+	if _, _, err := other.Decode(data); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Decode with another secret error = %v, want ErrInvalidSignature", err)
+	}
+	if _, _, err := r.Decode("\fget_key|"); !errors.Is(err, ErrInvalidData) {
+		t.Fatalf("Decode(legacy) error = %v, want ErrInvalidData", err)
+	}
+}
+
+func TestEncodeValidatesInput(t *testing.T) {
+	r := NewRouter(logger.NewDiscard(), "secret")
+
+	if _, err := r.Encode("country", "a:b"); !errors.Is(err, ErrInvalidData) {
+		t.Fatalf("Encode with separator error = %v, want ErrInvalidData", err)
+	}
+	if _, err := r.Encode("country", strings.Repeat("x", maxDataLength)); !errors.Is(err, ErrDataTooLong) {
+		t.Fatalf("Encode long error = %v, want ErrDataTooLong", err)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	bot, err := telebot.NewBot(telebot.Settings{Offline: true, Synchronous: true})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+
+	r := NewRouter(logger.NewDiscard(), "secret")
+	var got []string
+	r.Handle("country", func(_ telebot.Context, args []string) error {
+		got = args
+		return nil
+	})
+
+	data, _ := r.Encode("country", "DE")
+	c := bot.NewContext(telebot.Update{Callback: &telebot.Callback{Sender: &telebot.User{ID: 1}, Data: data}})
+	if err = r.Dispatch(c); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(got) != 1 || got[0] != "DE" {
+		t.Fatalf("handler args = %v, want [DE]", got)
+	}
+}
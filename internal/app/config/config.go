@@ -2,13 +2,15 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/caarlos0/env"
 	"github.com/joho/godotenv"
 )
 
 type Configuration struct {
-	TelegramAPI string `env:"TELEGRAM_API,required"`
+	TelegramAPI    string `env:"TELEGRAM_API,required"`
+	CallbackSecret string `env:"CALLBACK_SECRET"` // по умолчанию токен бота
 
 	YoukassaURL string `env:"YOUKASSA_URL,required"`
 	YoukassaAPI string `env:"YOUKASSA_API,required"`
@@ -21,8 +23,15 @@ type Configuration struct {
 	LoggerLevel string `env:"LOGGER_LEVEL" envDefault:"info"`
 	GinMode     string `env:"GIN_MODE" envDefault:"release"`
 	PortAPI     int    `env:"PORT_API" envDefault:"8890"`
-	DB          DB
-	Redis       Redis
+
+	RealityOverlap time.Duration `env:"REALITY_OVERLAP" envDefault:"24h"`
+
+	DB       DB
+	Redis    Redis
+	NodeAuth NodeAuth
+	State    State
+	Referral Referral
+	Trial    Trial
 }
 
 type DB struct {
@@ -41,6 +50,26 @@ type Redis struct {
 	DB       int    `env:"REDIS_DB,required"`
 }
 
+type NodeAuth struct {
+	RotationInterval time.Duration `env:"NODE_AUTH_ROTATION_INTERVAL" envDefault:"720h"`
+	GracePeriod      time.Duration `env:"NODE_AUTH_GRACE_PERIOD" envDefault:"1h"`
+}
+
+type State struct {
+	Backend string        `env:"STATE_BACKEND" envDefault:"memory"` // memory или redis
+	TTL     time.Duration `env:"STATE_TTL" envDefault:"24h"`
+}
+
+type Referral struct {
+	MinWithdrawal float64 `env:"REFERRAL_MIN_WITHDRAWAL" envDefault:"500"` // минимальная сумма вывода, руб.
+}
+
+type Trial struct {
+	Duration       time.Duration `env:"TRIAL_DURATION" envDefault:"72h"`    // 0 — пробный период отключён
+	Countries      []string      `env:"TRIAL_COUNTRIES" envSeparator:","`   // коды стран, пусто — все страны
+	TrafficLimitMB int64         `env:"TRIAL_TRAFFIC_MB" envDefault:"5120"` // 0 — без ограничения
+}
+
 func NewConfig(files ...string) (*Configuration, error) {
 	err := godotenv.Load(files...)
 	if err != nil {
@@ -60,6 +89,22 @@ func NewConfig(files ...string) (*Configuration, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = env.Parse(&cfg.NodeAuth)
+	if err != nil {
+		return nil, err
+	}
+	err = env.Parse(&cfg.State)
+	if err != nil {
+		return nil, err
+	}
+	err = env.Parse(&cfg.Referral)
+	if err != nil {
+		return nil, err
+	}
+	err = env.Parse(&cfg.Trial)
+	if err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
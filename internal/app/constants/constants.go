@@ -5,15 +5,44 @@ import (
 )
 
 var (
-	ErrInsufficientFunds  = errors.New("insufficient funds on the user's balance")
-	ErrUserNotFound       = errors.New("user not found")
-	ErrEmptyFields        = errors.New("one of the fields is empty")
-	ErrPaymentTimeExpired = errors.New("payment time expired")
-	ErrProcessServers     = errors.New("failed to process servers")
-	ErrCancelPayment      = errors.New("payment is cancelled")
+	ErrInsufficientFunds   = errors.New("insufficient funds on the user's balance")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrEmptyFields         = errors.New("one of the fields is empty")
+	ErrPaymentTimeExpired  = errors.New("payment time expired")
+	ErrProcessServers      = errors.New("failed to process servers")
+	ErrCancelPayment       = errors.New("payment is cancelled")
+	ErrNoAvailableServers  = errors.New("no available servers in country")
+	ErrCountryNotFound     = errors.New("country not found")
+	ErrLegacyNodeAuth      = errors.New("server uses legacy auth derived from reality keys")
+	ErrRealityOverlap      = errors.New("previous reality keys are still being served")
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrInvalidReferralRule = errors.New("invalid referral rule")
+	ErrWithdrawalTooSmall  = errors.New("withdrawal amount is below the minimum")
+	ErrWithdrawalProcessed = errors.New("withdrawal is already processed")
+	ErrInsecureNodeChannel = errors.New("node connection is not encrypted")
+	ErrAuthKeyChanged      = errors.New("pending auth key was changed concurrently")
+	ErrTrialDisabled       = errors.New("trial is disabled")
+	ErrTrialUsed           = errors.New("trial is already used")
+	ErrTrialNotEligible    = errors.New("user is not eligible for trial")
+	ErrVoucherNotFound     = errors.New("voucher not found")
+	ErrVoucherRedeemed     = errors.New("voucher is already redeemed")
+
+	ErrInvalidPromocode       = errors.New("invalid promocode")
+	ErrPromocodeNotFound      = errors.New("promocode not found")
+	ErrPromocodeExpired       = errors.New("promocode is expired")
+	ErrPromocodeLimit         = errors.New("promocode activations limit reached")
+	ErrPromocodeOnlyNew       = errors.New("promocode is only for new users")
+	ErrPromocodeUsed          = errors.New("promocode is already used by user")
+	ErrPromocodeNotApplicable = errors.New("promocode is not applicable to this payment")
+	ErrPromocodeWrongType     = errors.New("promocode type does not match the activation method")
+	ErrPromocodeNotReserved   = errors.New("promocode reservation not found")
+	ErrCampaignNotFound       = errors.New("promocode campaign not found")
+	ErrInvalidCampaign        = errors.New("invalid promocode campaign")
+	ErrInvalidCodeFormat      = errors.New("invalid promocode format")
 )
 
+// ключи сообщений в каталогах i18n
 const (
-	UserHasNoRights = "❌ У вас нет прав на выполнение данной команды"
-	UserError       = "❌ Упс! Что-то сломалось. Повторите попытку чуть позже или обратитесь в службу поддержки"
+	UserHasNoRights = "error.no_rights"
+	UserError       = "error.internal"
 )
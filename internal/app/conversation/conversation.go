@@ -0,0 +1,404 @@
+package conversation
+
+import (
+	"errors"
+	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/pkg/logger"
+	"sync"
+	"time"
+)
+
+const (
+	Done = ""
+
+	defaultTimeout = 5 * time.Minute
+	lockShards     = 64
+)
+
+var ErrUnknownFlow = errors.New("unknown conversation flow")
+
+type Session struct {
+	Flow      string            `json:"flow"`
+	Step      string            `json:"step"`
+	Data      map[string]string `json:"data"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	Lang      string            `json:"lang"`             // язык пользователя для сообщений вне контекста, например по таймауту
+	Parent    *Session          `json:"parent,omitempty"` // прерванный сценарий, продолжится после завершения текущего
+}
+
+type Step struct {
+	Prompt  func(c telebot.Context, s *Session) error
+	Handle  func(c telebot.Context, s *Session) (next string, err error)
+	Timeout time.Duration // по умолчанию Flow.Timeout
+}
+
+type Flow struct {
+	Name      string
+	Start     string
+	Timeout   time.Duration
+	Steps     map[string]*Step
+	OnTimeout func(to telebot.Recipient, s *Session) error
+	OnCancel  func(c telebot.Context, s *Session) error
+}
+
+type Manager struct {
+	log   *logger.Logger
+	store Store
+
+	mu    sync.RWMutex
+	flows map[string]*Flow
+	locks [lockShards]sync.Mutex
+}
+
+func NewManager(log *logger.Logger, store Store) *Manager {
+	return &Manager{
+		log:   log,
+		store: store,
+		flows: make(map[string]*Flow),
+	}
+}
+
+func (m *Manager) Register(flow *Flow) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.flows[flow.Name] = flow
+}
+
+func (m *Manager) Start(c telebot.Context, name string, data map[string]string) error {
+	flow, ok := m.getFlow(name)
+	if !ok {
+		return ErrUnknownFlow
+	}
+
+	s, err := m.begin(getChatID(c), i18n.FromContext(c), flow, data)
+	if err != nil {
+		return err
+	}
+	return m.prompt(c, flow, s)
+}
+
+func (m *Manager) begin(chatID int64, lang string, flow *Flow, data map[string]string) (*Session, error) {
+	lock := m.lock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	current, err := m.store.Get(chatID)
+	if err != nil {
+		m.log.Error("Failed to get conversation", err, slog.Int64("chat_id", chatID))
+		return nil, err
+	}
+
+	if data == nil {
+		data = make(map[string]string)
+	}
+	s := &Session{Flow: flow.Name, Step: flow.Start, Data: data, Lang: lang}
+	if current != nil {
+		s.Parent = current
+		if current.Flow == flow.Name {
+			s.Parent = current.Parent
+		}
+	}
+	s.ExpiresAt = time.Now().Add(m.timeout(flow, s.Step))
+
+	if err = m.store.Set(chatID, s); err != nil {
+		m.log.Error("Failed to save conversation", err, slog.Int64("chat_id", chatID), slog.String("flow", flow.Name))
+		return nil, err
+	}
+
+	m.log.Debug("Conversation started", slog.Int64("chat_id", chatID), slog.String("flow", flow.Name), slog.String("step", s.Step))
+	return s, nil
+}
+
+func (m *Manager) Active(c telebot.Context) (*Session, error) {
+	return m.store.Get(getChatID(c))
+}
+
+func (m *Manager) Handle(c telebot.Context) (bool, error) {
+	handled, flow, s, err := m.advance(c)
+	if err != nil || flow == nil {
+		return handled, err
+	}
+	return handled, m.prompt(c, flow, s)
+}
+
+func (m *Manager) advance(c telebot.Context) (handled bool, entered *Flow, s *Session, err error) {
+	chatID := getChatID(c)
+	s, flow, err := m.current(chatID)
+	if err != nil || s == nil {
+		return false, nil, nil, err
+	}
+
+	// шаг выполняется без блокировки: обработчик может сам запустить вложенный сценарий или отменить текущий
+	snapshot := *s
+	next, stepErr := flow.Steps[s.Step].Handle(c, s)
+
+	lock := m.lock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	chain, err := m.store.Get(chatID)
+	if err != nil {
+		m.log.Error("Failed to get conversation", err, slog.Int64("chat_id", chatID))
+		return true, nil, nil, errors.Join(stepErr, err)
+	}
+
+	// переход выполняется и при ошибке шага: шаг сам решает, оставаться ли на месте
+	var updated *Session
+	switch _, ok := flow.Steps[next]; {
+	case next == Done:
+		m.log.Debug("Conversation finished", slog.Int64("chat_id", chatID), slog.String("flow", s.Flow))
+	case !ok:
+		m.log.Warn("Conversation step not found", slog.Int64("chat_id", chatID), slog.String("flow", s.Flow), slog.String("step", next))
+	default:
+		updated = s
+		updated.Step = next
+		updated.ExpiresAt = time.Now().Add(m.timeout(flow, next))
+	}
+
+	root, found := replaceSession(chain, &snapshot, updated)
+	if !found {
+		// сессию успели отменить или продвинуть параллельно, результат шага уже не актуален
+		m.log.Warn("Conversation changed while step was handled", slog.Int64("chat_id", chatID), slog.String("flow", snapshot.Flow), slog.String("step", snapshot.Step))
+		return true, nil, nil, stepErr
+	}
+
+	if root == nil {
+		err = m.store.Delete(chatID)
+	} else {
+		err = m.store.Set(chatID, root)
+	}
+	if err != nil {
+		m.log.Error("Failed to save conversation", err, slog.Int64("chat_id", chatID), slog.String("flow", snapshot.Flow))
+		return true, nil, nil, errors.Join(stepErr, err)
+	}
+
+	// если шаг запустил вложенный сценарий, его вопрос уже отправлен
+	if updated != nil && root == updated && next != snapshot.Step && stepErr == nil {
+		return true, flow, updated, nil
+	}
+	return true, nil, nil, stepErr
+}
+
+func (m *Manager) current(chatID int64) (*Session, *Flow, error) {
+	lock := m.lock(chatID)
+	lock.Lock()
+
+	s, err := m.store.Get(chatID)
+	if err != nil {
+		lock.Unlock()
+		m.log.Error("Failed to get conversation", err, slog.Int64("chat_id", chatID))
+		return nil, nil, err
+	}
+	if s == nil {
+		lock.Unlock()
+		return nil, nil, nil
+	}
+
+	flow, ok := m.getFlow(s.Flow)
+	if !ok || flow.Steps[s.Step] == nil || flow.Steps[s.Step].Handle == nil {
+		defer lock.Unlock()
+		m.log.Warn("Dropping conversation with unknown step", slog.Int64("chat_id", chatID), slog.String("flow", s.Flow), slog.String("step", s.Step))
+		return nil, nil, m.finish(chatID, s)
+	}
+
+	if time.Now().After(s.ExpiresAt) {
+		m.finishExpired(chatID, s)
+		lock.Unlock()
+		m.timedOut(chatID, flow, s)
+		return nil, nil, nil
+	}
+
+	lock.Unlock()
+	return s, flow, nil
+}
+
+func (m *Manager) OnText(fallback telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		handled, err := m.Handle(c)
+		if handled || err != nil {
+			return err
+		}
+		return fallback(c)
+	}
+}
+
+func (m *Manager) Stop(c telebot.Context) (bool, error) {
+	chatID := getChatID(c)
+	s, err := m.remove(chatID)
+	if err != nil || s == nil {
+		return false, err
+	}
+
+	for current := s; current != nil; current = current.Parent {
+		flow, ok := m.getFlow(current.Flow)
+		if !ok || flow.OnCancel == nil {
+			continue
+		}
+		if err = flow.OnCancel(c, current); err != nil {
+			m.log.Error("Failed to cancel conversation", err, slog.Int64("chat_id", chatID), slog.String("flow", current.Flow))
+		}
+	}
+	return true, nil
+}
+
+func (m *Manager) remove(chatID int64) (*Session, error) {
+	lock := m.lock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s, err := m.store.Get(chatID)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	return s, m.store.Delete(chatID)
+}
+
+func (m *Manager) CancelHandler(c telebot.Context) error {
+	stopped, err := m.Stop(c)
+	if err != nil {
+		return err
+	}
+	if !stopped {
+		return c.Send(i18n.T(i18n.FromContext(c), "conversation.nothing_to_cancel"))
+	}
+	return c.Send(i18n.T(i18n.FromContext(c), "conversation.cancelled"))
+}
+
+func (m *Manager) ExpireSessions() {
+	now := time.Now()
+	chatIDs, err := m.store.Expired(now)
+	if err != nil {
+		m.log.Error("Failed to get expired conversations", err)
+		return
+	}
+
+	for _, chatID := range chatIDs {
+		m.expireChat(chatID, now)
+	}
+}
+
+func (m *Manager) expireChat(chatID int64, now time.Time) {
+	lock := m.lock(chatID)
+	lock.Lock()
+
+	s, err := m.store.Get(chatID)
+	if err != nil {
+		lock.Unlock()
+		m.log.Error("Failed to get conversation", err, slog.Int64("chat_id", chatID))
+		return
+	}
+	if s == nil {
+		defer lock.Unlock()
+		if err = m.store.Delete(chatID); err != nil {
+			m.log.Error("Failed to delete conversation", err, slog.Int64("chat_id", chatID))
+		}
+		return
+	}
+	if now.Before(s.ExpiresAt) {
+		defer lock.Unlock()
+		if err = m.store.Set(chatID, s); err != nil {
+			m.log.Error("Failed to save conversation", err, slog.Int64("chat_id", chatID))
+		}
+		return
+	}
+
+	m.finishExpired(chatID, s)
+	lock.Unlock()
+
+	flow, _ := m.getFlow(s.Flow)
+	m.timedOut(chatID, flow, s)
+}
+
+func (m *Manager) finishExpired(chatID int64, s *Session) {
+	m.log.Debug("Conversation timed out", slog.Int64("chat_id", chatID), slog.String("flow", s.Flow), slog.String("step", s.Step))
+	if err := m.finish(chatID, s); err != nil {
+		m.log.Error("Failed to finish conversation", err, slog.Int64("chat_id", chatID))
+	}
+}
+
+// вызывается уже без блокировки, сессия к этому моменту снята
+func (m *Manager) timedOut(chatID int64, flow *Flow, s *Session) {
+	if flow == nil || flow.OnTimeout == nil {
+		return
+	}
+	if err := flow.OnTimeout(telebot.ChatID(chatID), s); err != nil {
+		m.log.Error("Failed to handle conversation timeout", err, slog.Int64("chat_id", chatID), slog.String("flow", s.Flow))
+	}
+}
+
+func (m *Manager) finish(chatID int64, s *Session) error {
+	if s.Parent == nil {
+		return m.store.Delete(chatID)
+	}
+	return m.store.Set(chatID, s.Parent)
+}
+
+// replaceSession заменяет в цепочке сессию target на updated, nil убирает её из цепочки
+func replaceSession(chain, target, updated *Session) (*Session, bool) {
+	if chain == nil {
+		return nil, false
+	}
+	if sameSession(chain, target) {
+		if updated == nil {
+			return chain.Parent, true
+		}
+		updated.Parent = chain.Parent
+		return updated, true
+	}
+
+	parent, ok := replaceSession(chain.Parent, target, updated)
+	if !ok {
+		return chain, false
+	}
+	replaced := *chain
+	replaced.Parent = parent
+	return &replaced, true
+}
+
+func sameSession(a, b *Session) bool {
+	return a.Flow == b.Flow && a.Step == b.Step && a.ExpiresAt.Equal(b.ExpiresAt)
+}
+
+func (m *Manager) prompt(c telebot.Context, flow *Flow, s *Session) error {
+	step := flow.Steps[s.Step]
+	if step == nil || step.Prompt == nil {
+		return nil
+	}
+	return step.Prompt(c, s)
+}
+
+func (m *Manager) timeout(flow *Flow, step string) time.Duration {
+	if s := flow.Steps[step]; s != nil && s.Timeout > 0 {
+		return s.Timeout
+	}
+	if flow.Timeout > 0 {
+		return flow.Timeout
+	}
+	return defaultTimeout
+}
+
+func (m *Manager) getFlow(name string) (*Flow, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	flow, ok := m.flows[name]
+	return flow, ok
+}
+
+func (m *Manager) lock(chatID int64) *sync.Mutex {
+	shard := chatID % lockShards
+	if shard < 0 {
+		shard = -shard
+	}
+	return &m.locks[shard]
+}
+
+func getChatID(c telebot.Context) int64 {
+	if chat := c.Chat(); chat != nil {
+		return chat.ID
+	}
+	return c.Sender().ID
+}
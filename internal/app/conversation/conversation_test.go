@@ -0,0 +1,243 @@
+package conversation
+
+import (
+	"gopkg.in/telebot.v4"
+	"testing"
+	"time"
+
+	"nsvpn/pkg/logger"
+)
+
+const testChatID = 42
+
+func newTestContext(t *testing.T, bot *telebot.Bot, text string) telebot.Context {
+	t.Helper()
+
+	chat := &telebot.Chat{ID: testChatID}
+	return bot.NewContext(telebot.Update{Message: &telebot.Message{
+		Chat:   chat,
+		Sender: &telebot.User{ID: testChatID},
+		Text:   text,
+	}})
+}
+
+func newTestManager(t *testing.T) (*Manager, *telebot.Bot) {
+	t.Helper()
+
+	bot, err := telebot.NewBot(telebot.Settings{Offline: true, Synchronous: true})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	return NewManager(logger.NewDiscard(), NewMemoryStore()), bot
+}
+
+func TestFlowTransitions(t *testing.T) {
+	m, bot := newTestManager(t)
+
+	var prompts []string
+	m.Register(&Flow{
+		Name:  "form",
+		Start: "name",
+		Steps: map[string]*Step{
+			"name": {
+				Prompt: func(_ telebot.Context, _ *Session) error { prompts = append(prompts, "name"); return nil },
+				Handle: func(c telebot.Context, s *Session) (string, error) {
+					if c.Text() == "" {
+						return s.Step, nil
+					}
+					s.Data["name"] = c.Text()
+					return "age", nil
+				},
+			},
+			"age": {
+				Prompt: func(_ telebot.Context, _ *Session) error { prompts = append(prompts, "age"); return nil },
+				Handle: func(c telebot.Context, s *Session) (string, error) {
+					s.Data["age"] = c.Text()
+					return Done, nil
+				},
+			},
+		},
+	})
+
+	if err := m.Start(newTestContext(t, bot, ""), "form", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	for _, text := range []string{"", "alice"} {
+		if handled, err := m.Handle(newTestContext(t, bot, text)); !handled || err != nil {
+			t.Fatalf("Handle(%q) = %v, %v", text, handled, err)
+		}
+	}
+
+	s, _ := m.Active(newTestContext(t, bot, ""))
+	if s == nil || s.Step != "age" || s.Data["name"] != "alice" {
+		t.Fatalf("unexpected session %+v", s)
+	}
+	if len(prompts) != 2 || prompts[1] != "age" {
+		t.Fatalf("prompts = %v, want [name age]", prompts)
+	}
+
+	if handled, _ := m.Handle(newTestContext(t, bot, "30")); !handled {
+		t.Fatal("last step was not handled")
+	}
+	if s, _ = m.Active(newTestContext(t, bot, "")); s != nil {
+		t.Fatalf("session still active after Done: %+v", s)
+	}
+	if handled, _ := m.Handle(newTestContext(t, bot, "text")); handled {
+		t.Fatal("text handled without an active session")
+	}
+}
+
+func TestNestedFlowRestoresParent(t *testing.T) {
+	m, bot := newTestManager(t)
+
+	done := func(_ telebot.Context, _ *Session) (string, error) { return Done, nil }
+	m.Register(&Flow{Name: "outer", Start: "wait", Steps: map[string]*Step{"wait": {Handle: done}}})
+	m.Register(&Flow{Name: "inner", Start: "ask", Steps: map[string]*Step{"ask": {Handle: done}}})
+
+	c := newTestContext(t, bot, "")
+	if err := m.Start(c, "outer", map[string]string{"id": "1"}); err != nil {
+		t.Fatalf("Start outer: %v", err)
+	}
+	if err := m.Start(c, "inner", nil); err != nil {
+		t.Fatalf("Start inner: %v", err)
+	}
+	if _, err := m.Handle(c); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	s, _ := m.Active(c)
+	if s == nil || s.Flow != "outer" || s.Data["id"] != "1" {
+		t.Fatalf("parent was not restored: %+v", s)
+	}
+}
+
+func TestExpireSessions(t *testing.T) {
+	m, bot := newTestManager(t)
+
+	var timedOut telebot.Recipient
+	m.Register(&Flow{
+		Name:    "amount",
+		Start:   "amount",
+		Timeout: time.Millisecond,
+		Steps: map[string]*Step{
+			"amount": {Handle: func(_ telebot.Context, _ *Session) (string, error) { return Done, nil }},
+		},
+		OnTimeout: func(to telebot.Recipient, _ *Session) error { timedOut = to; return nil },
+	})
+
+	c := newTestContext(t, bot, "")
+	if err := m.Start(c, "amount", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	m.ExpireSessions()
+
+	if timedOut == nil || timedOut.Recipient() != "42" {
+		t.Fatalf("OnTimeout recipient = %v, want chat 42", timedOut)
+	}
+	if s, _ := m.Active(c); s != nil {
+		t.Fatalf("session still active after timeout: %+v", s)
+	}
+}
+
+func TestStopCancelsWholeChain(t *testing.T) {
+	m, bot := newTestManager(t)
+
+	var cancelled []string
+	onCancel := func(_ telebot.Context, s *Session) error { cancelled = append(cancelled, s.Flow); return nil }
+	step := map[string]*Step{"step": {Handle: func(_ telebot.Context, s *Session) (string, error) { return s.Step, nil }}}
+	m.Register(&Flow{Name: "outer", Start: "step", Steps: step, OnCancel: onCancel})
+	m.Register(&Flow{Name: "inner", Start: "step", Steps: step, OnCancel: onCancel})
+
+	c := newTestContext(t, bot, "")
+	if stopped, _ := m.Stop(c); stopped {
+		t.Fatal("Stop reported success without an active session")
+	}
+
+	_ = m.Start(c, "outer", nil)
+	_ = m.Start(c, "inner", nil)
+	if stopped, err := m.Stop(c); !stopped || err != nil {
+		t.Fatalf("Stop = %v, %v", stopped, err)
+	}
+	if len(cancelled) != 2 || cancelled[0] != "inner" || cancelled[1] != "outer" {
+		t.Fatalf("cancelled = %v, want [inner outer]", cancelled)
+	}
+	if s, _ := m.Active(c); s != nil {
+		t.Fatalf("session still active after Stop: %+v", s)
+	}
+}
+
+func TestStepCanStartNestedFlow(t *testing.T) {
+	m, bot := newTestManager(t)
+
+	var prompted []string
+	m.Register(&Flow{Name: "outer", Start: "ask", Steps: map[string]*Step{
+		"ask": {Handle: func(c telebot.Context, s *Session) (string, error) {
+			s.Data["answer"] = c.Text()
+			return "confirm", m.Start(c, "inner", nil)
+		}},
+		"confirm": {
+			Prompt: func(_ telebot.Context, _ *Session) error { prompted = append(prompted, "confirm"); return nil },
+			Handle: func(_ telebot.Context, _ *Session) (string, error) { return Done, nil },
+		},
+	}})
+	m.Register(&Flow{Name: "inner", Start: "code", Steps: map[string]*Step{
+		"code": {
+			Prompt: func(_ telebot.Context, _ *Session) error { prompted = append(prompted, "code"); return nil },
+			Handle: func(_ telebot.Context, _ *Session) (string, error) { return Done, nil },
+		},
+	}})
+
+	c := newTestContext(t, bot, "yes")
+	if err := m.Start(c, "outer", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	handled := make(chan error, 1)
+	go func() {
+		_, err := m.Handle(c)
+		handled <- err
+	}()
+	select {
+	case err := <-handled:
+		if err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Handle deadlocked while the step started a nested flow")
+	}
+
+	s, _ := m.Active(c)
+	if s == nil || s.Flow != "inner" || s.Parent == nil {
+		t.Fatalf("nested flow is not active: %+v", s)
+	}
+	if s.Parent.Step != "confirm" || s.Parent.Data["answer"] != "yes" {
+		t.Fatalf("parent transition lost: %+v", s.Parent)
+	}
+	if len(prompted) != 1 || prompted[0] != "code" {
+		t.Fatalf("prompted = %v, want [code]", prompted)
+	}
+}
+
+func TestStepCanStopConversation(t *testing.T) {
+	m, bot := newTestManager(t)
+
+	m.Register(&Flow{Name: "form", Start: "ask", Steps: map[string]*Step{
+		"ask": {Handle: func(c telebot.Context, s *Session) (string, error) {
+			_, err := m.Stop(c)
+			return "next", err
+		}},
+		"next": {Handle: func(_ telebot.Context, _ *Session) (string, error) { return Done, nil }},
+	}})
+
+	c := newTestContext(t, bot, "")
+	if err := m.Start(c, "form", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if handled, err := m.Handle(c); !handled || err != nil {
+		t.Fatalf("Handle = %v, %v", handled, err)
+	}
+	if s, _ := m.Active(c); s != nil {
+		t.Fatalf("stopped session was saved back: %+v", s)
+	}
+}
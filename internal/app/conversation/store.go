@@ -0,0 +1,146 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	KeyPrefix    = "conversation:"
+	deadlinesKey = KeyPrefix + "deadlines"
+	sessionGrace = time.Hour
+)
+
+type Store interface {
+	Get(chatID int64) (*Session, error)
+	Set(chatID int64, s *Session) error
+	Delete(chatID int64) error
+	Expired(now time.Time) ([]int64, error)
+}
+
+type redisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) Store {
+	return &redisStore{client: client}
+}
+
+func (rs *redisStore) Get(chatID int64) (*Session, error) {
+	data, err := rs.client.Get(context.Background(), sessionKey(chatID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var s Session
+	if err = json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (rs *redisStore) Set(chatID int64, s *Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = rs.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(chatID), data, time.Until(s.ExpiresAt)+sessionGrace)
+		pipe.ZAdd(ctx, deadlinesKey, redis.Z{Score: float64(s.ExpiresAt.Unix()), Member: chatID})
+		return nil
+	})
+	return err
+}
+
+func (rs *redisStore) Delete(chatID int64) error {
+	ctx := context.Background()
+	_, err := rs.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(chatID))
+		pipe.ZRem(ctx, deadlinesKey, chatID)
+		return nil
+	})
+	return err
+}
+
+func (rs *redisStore) Expired(now time.Time) ([]int64, error) {
+	members, err := rs.client.ZRangeByScore(context.Background(), deadlinesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	chatIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		chatID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs, nil
+}
+
+type memoryStore struct {
+	mu       sync.Mutex
+	sessions map[int64]Session
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{sessions: make(map[int64]Session)}
+}
+
+func (ms *memoryStore) Get(chatID int64) (*Session, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	s, ok := ms.sessions[chatID]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (ms *memoryStore) Set(chatID int64, s *Session) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.sessions[chatID] = *s
+	return nil
+}
+
+func (ms *memoryStore) Delete(chatID int64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.sessions, chatID)
+	return nil
+}
+
+func (ms *memoryStore) Expired(now time.Time) ([]int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var chatIDs []int64
+	for chatID, s := range ms.sessions {
+		if !now.Before(s.ExpiresAt) {
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	return chatIDs, nil
+}
+
+func sessionKey(chatID int64) string {
+	return KeyPrefix + strconv.FormatInt(chatID, 10)
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"log/slog"
+	"nsvpn/pkg/logger"
+	"sync"
+	"time"
+)
+
+const (
+	ClientConnected    = "client_connected"
+	ClientDisconnected = "client_disconnected"
+	QuotaExceeded      = "quota_exceeded"
+	LoadThreshold      = "load_threshold"
+	ConfigReloaded     = "config_reloaded"
+)
+
+const subscriberBuffer = 256
+
+type Event struct {
+	ServerID  uint
+	Type      string
+	UUID      string
+	Email     string
+	Load      float64
+	Message   string
+	CreatedAt time.Time
+}
+
+type subscriber struct {
+	ch    chan Event
+	types map[string]bool
+}
+
+type Bus struct {
+	log  *logger.Logger
+	mu   sync.RWMutex
+	subs map[int]*subscriber
+	next int
+}
+
+func NewBus(log *logger.Logger) *Bus {
+	return &Bus{
+		log:  log,
+		subs: make(map[int]*subscriber),
+	}
+}
+
+func (b *Bus) Subscribe(types ...string) (<-chan Event, func()) {
+	sub := &subscriber{
+		ch:    make(chan Event, subscriberBuffer),
+		types: make(map[string]bool, len(types)),
+	}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs {
+		if len(sub.types) > 0 && !sub.types[event.Type] {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+			b.log.Warn("Event subscriber is full, dropping event", slog.String("type", event.Type), slog.Uint64("server_id", uint64(event.ServerID)))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/config"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/services"
+	"nsvpn/pkg/logger"
+	"strings"
+)
+
+type Admin struct {
+	log *logger.Logger
+	bot *telebot.Bot
+	cfg *config.Configuration
+	us  *services.Users
+	rs  *services.Reconciler
+	rls *services.Reality
+}
+
+func NewAdmin(log *logger.Logger, bot *telebot.Bot, cfg *config.Configuration, us *services.Users, rs *services.Reconciler, rls *services.Reality) *Admin {
+	return &Admin{
+		log: log,
+		bot: bot,
+		cfg: cfg,
+		us:  us,
+		rs:  rs,
+		rls: rls,
+	}
+}
+
+func (a *Admin) RegisterHandlers() {
+	a.bot.Handle("/reconcile", a.ReconcileHandler)
+	a.bot.Handle("/rotate_reality", a.RotateRealityHandler)
+}
+
+func (a *Admin) ReconcileHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	if isAdmin, err := a.us.IsAdmin(c.Sender().ID); err != nil || !isAdmin {
+		return c.Send(tr(c, constants.UserHasNoRights), btns)
+	}
+
+	dryRun := strings.TrimSpace(c.Message().Payload) != "apply"
+	report, err := a.rs.Run(dryRun)
+	if err != nil {
+		a.log.Error("Failed to reconcile nodes", err, slog.Bool("dry_run", dryRun))
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	return c.Send(report.Format(i18n.FromContext(c)), btns)
+}
+
+func (a *Admin) RotateRealityHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	if isAdmin, err := a.us.IsAdmin(c.Sender().ID); err != nil || !isAdmin {
+		return c.Send(tr(c, constants.UserHasNoRights), btns)
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(c.Message().Payload))
+	if code == "" {
+		return c.Send(tr(c, "admin.reality_usage"), btns)
+	}
+
+	queued, err := a.rls.Rotate(code, a.cfg.RealityOverlap)
+	if errors.Is(err, constants.ErrRealityOverlap) {
+		return c.Send(tr(c, "admin.reality_overlap", code), btns)
+	}
+	if err != nil {
+		a.log.Error("Failed to rotate reality keys", err, slog.String("code", code))
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	return c.Send(tr(c, "admin.reality_rotated", code, a.cfg.RealityOverlap, queued), btns)
+}
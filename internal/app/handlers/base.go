@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"gopkg.in/telebot.v4"
 	"log/slog"
 	"nsvpn/internal/app/constants"
@@ -12,18 +11,24 @@ import (
 type Base struct {
 	log *logger.Logger
 	us  *services.Users
+	gh  *Gifts
 }
 
-func NewBase(log *logger.Logger, us *services.Users) *Base {
+func NewBase(log *logger.Logger, us *services.Users, gh *Gifts) *Base {
 	return &Base{
 		log: log,
 		us:  us,
+		gh:  gh,
 	}
 }
 
 func (b *Base) StartHandler(c telebot.Context) error {
+	if handled, err := b.gh.StartHandler(c); handled {
+		return err
+	}
+
 	btns := getReplyButtons(c)
-	return c.Send(fmt.Sprintf("👋 Добро пожаловать, %s!", c.Sender().FirstName), btns)
+	return c.Send(tr(c, "base.welcome", c.Sender().FirstName), btns)
 }
 
 func (b *Base) AcceptOfferHandler(c telebot.Context) error {
@@ -32,18 +37,18 @@ func (b *Base) AcceptOfferHandler(c telebot.Context) error {
 	err := b.us.UpdateIsSign(c.Sender().ID, true)
 	if err != nil {
 		b.log.Error("Failed to update sign", err, slog.Int64("userId", c.Sender().ID))
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	return c.Send(fmt.Sprintf("👋 Добро пожаловать, %s!", c.Sender().FirstName), btns)
+	return c.Send(tr(c, "base.welcome", c.Sender().FirstName), btns)
 }
 
 func (b *Base) OnTextHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
-	return c.Send("🤔 Неизвестная команда. Используйте /help для получения списка команд", btns)
+	return c.Send(tr(c, "base.unknown_command"), btns)
 }
 
 func (b *Base) InfoHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
-	return c.Send("💡 Информация", btns)
+	return c.Send(tr(c, "base.info"), btns)
 }
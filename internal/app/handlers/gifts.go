@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/callback"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/conversation"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/services"
+	"nsvpn/internal/app/state"
+	"nsvpn/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const flowGift = "gift"
+
+type Gifts struct {
+	log                  *logger.Logger
+	bot                  *telebot.Bot
+	cb                   *callback.Router
+	ss                   *services.Subscriptions
+	ps                   *services.Payments
+	us                   *services.Users
+	ph                   *Payments
+	conv                 *conversation.Manager
+	clientButtonsWithSub *services.LocalizedButtons
+}
+
+func NewGifts(log *logger.Logger, bot *telebot.Bot, cb *callback.Router, ss *services.Subscriptions, ps *services.Payments,
+	us *services.Users, ph *Payments, conv *conversation.Manager, clientButtonsWithSub *services.LocalizedButtons) *Gifts {
+	return &Gifts{
+		log:                  log,
+		bot:                  bot,
+		cb:                   cb,
+		ss:                   ss,
+		ps:                   ps,
+		us:                   us,
+		ph:                   ph,
+		conv:                 conv,
+		clientButtonsWithSub: clientButtonsWithSub,
+	}
+}
+
+func (g *Gifts) RegisterHandlers() {
+	g.bot.Handle("/gift", g.ChoosePlanHandler)
+	g.bot.Handle("/gifts", g.ListHandler)
+	g.bot.Handle("/redeem", g.RedeemHandler)
+	g.cb.Handle("gift_plan", g.PlanHandler)
+
+	g.conv.Register(&conversation.Flow{
+		Name:    flowGift,
+		Start:   "payment",
+		Timeout: 10 * time.Minute,
+		Steps: map[string]*conversation.Step{
+			"payment": {Handle: g.handlePurchase},
+		},
+		OnTimeout: func(to telebot.Recipient, s *conversation.Session) error {
+			g.ph.ResetState(to.Recipient())
+			_, err := g.bot.Send(to, i18n.T(s.Lang, "subscriptions.payment_timeout"))
+			return err
+		},
+		OnCancel: func(c telebot.Context, _ *conversation.Session) error {
+			g.ph.ResetState(strconv.FormatInt(c.Sender().ID, 10))
+			return nil
+		},
+	})
+}
+
+func (g *Gifts) ChoosePlanHandler(c telebot.Context) error {
+	if c.Callback() != nil {
+		defer func() { _ = c.Respond() }()
+	}
+
+	btns := getReplyButtons(c)
+	plans, err := g.ss.Plans.GetAll()
+	if err != nil || len(plans) == 0 {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	buttons, layout := g.ss.Plans.ProcessButtons(plans)
+	for i := range buttons {
+		buttons[i].Value = "gift_" + buttons[i].Value
+		buttons[i].Action = "gift_plan"
+	}
+	planBtns, err := services.NewCallbackButtons(g.cb, buttons, layout)
+	if err != nil {
+		g.log.Error("Failed to create gift plan buttons", err)
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	return c.Send(tr(c, "gifts.choose_plan"), planBtns.AddBtns())
+}
+
+func (g *Gifts) PlanHandler(c telebot.Context, args []string) error {
+	defer func() { _ = c.Respond() }()
+
+	btns := getReplyButtons(c)
+	if len(args) != 1 {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	plan, err := g.ss.Plans.GetByID(uint(id))
+	if err != nil || plan == nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	voucher, err := g.pay(c.Sender().ID, plan)
+	switch {
+	case errors.Is(err, constants.ErrInsufficientFunds):
+		return g.startPurchase(c, plan)
+	case err != nil:
+		g.log.Error("Gift payment error", err, slog.Int64("user_id", c.Sender().ID))
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	return g.sendVoucher(c, voucher, plan)
+}
+
+func (g *Gifts) ListHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	vouchers, err := g.ss.Vouchers.GetByBuyerID(c.Sender().ID)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	if len(vouchers) == 0 {
+		return c.Send(tr(c, "gifts.list_empty"), btns)
+	}
+
+	msg := tr(c, "gifts.list_title")
+	for i, voucher := range vouchers {
+		status := tr(c, "gifts.status_active")
+		if voucher.Status == models.VoucherStatusRedeemed && voucher.RedeemedAt != nil {
+			status = tr(c, "gifts.status_redeemed", voucher.RedeemedAt.Format("2006-01-02 15:04:05"))
+		}
+		msg += tr(c, "gifts.list_item", i+1, voucher.Code, voucher.DurationDays, status)
+	}
+	return c.Send(msg, &telebot.SendOptions{ReplyMarkup: btns, ParseMode: telebot.ModeMarkdown})
+}
+
+func (g *Gifts) RedeemHandler(c telebot.Context) error {
+	code := strings.TrimSpace(c.Message().Payload)
+	if code == "" {
+		return c.Send(tr(c, "gifts.enter_code"), getReplyButtons(c))
+	}
+	return g.Redeem(c, code)
+}
+
+func (g *Gifts) StartHandler(c telebot.Context) (bool, error) {
+	code, ok := g.ss.Vouchers.CodeFromStart(c.Message().Payload)
+	if !ok {
+		return false, nil
+	}
+	return true, g.Redeem(c, code)
+}
+
+func (g *Gifts) Redeem(c telebot.Context, code string) error {
+	btns := getReplyButtons(c)
+	voucher, sub, err := g.ss.Vouchers.Redeem(code, c.Sender().ID)
+	switch {
+	case errors.Is(err, constants.ErrVoucherNotFound), errors.Is(err, constants.ErrEmptyFields):
+		return c.Send(tr(c, "gifts.not_found"), btns)
+	case errors.Is(err, constants.ErrVoucherRedeemed):
+		return c.Send(tr(c, "gifts.already_redeemed"), btns)
+	case err != nil:
+		g.log.Error("Failed to redeem voucher", err, slog.Int64("user_id", c.Sender().ID))
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	if voucher.BuyerID != c.Sender().ID {
+		msg := i18n.T(g.us.Language(voucher.BuyerID), "gifts.buyer_notice", voucher.Code, c.Sender().FirstName)
+		if _, err = g.bot.Send(&telebot.User{ID: voucher.BuyerID}, msg); err != nil {
+			g.log.Error("Failed to notify gift buyer", err, slog.Int64("buyer_id", voucher.BuyerID))
+		}
+	}
+
+	lang := i18n.FromContext(c)
+	return c.Send(tr(c, "gifts.redeemed", voucher.DurationDays, sub.EndDate.Format("2006-01-02 15:04:05")), g.clientButtonsWithSub.Get(lang).AddBtns())
+}
+
+func (g *Gifts) pay(userID int64, plan *models.SubscriptionPlan) (*models.Voucher, error) {
+	amount := plan.SubscriptionPrice.Price
+	if err := g.us.DecrementBalance(userID, amount); err != nil {
+		return nil, err
+	}
+
+	payload := uuid.New().String()
+	err := g.ps.Add(&models.Payment{
+		UserID:      userID,
+		Amount:      amount,
+		Type:        "expense",
+		Payload:     payload,
+		Note:        "Подарочная подписка на " + plan.Name,
+		IsCompleted: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	voucher, err := g.ss.Vouchers.Create(userID, plan, payload)
+	if err != nil {
+		if compErr := g.us.IncrementBalance(userID, amount); compErr != nil {
+			g.log.Error("Balance compensation failed", compErr)
+		}
+		return nil, err
+	}
+	return voucher, nil
+}
+
+func (g *Gifts) startPurchase(c telebot.Context, plan *models.SubscriptionPlan) error {
+	btns := getReplyButtons(c)
+	paymentID := uuid.New().String()
+	note := "Подарочная подписка на " + plan.Name
+
+	g.ph.ResetState(strconv.FormatInt(c.Sender().ID, 10))
+	g.ph.PaymentsState.Set(strconv.FormatInt(c.Sender().ID, 10), state.PaymentsState{
+		Amount:            plan.SubscriptionPrice.Price,
+		Payload:           paymentID,
+		Note:              note,
+		PlanID:            plan.ID,
+		IsBuySubscription: true,
+	})
+
+	err := g.conv.Start(c, flowGift, map[string]string{
+		"payment_id": paymentID,
+		"plan_id":    strconv.FormatUint(uint64(plan.ID), 10),
+	})
+	if err != nil {
+		g.log.Error("Failed to start gift purchase", err)
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	return g.ph.ChooseCurrencyHandler(c)
+}
+
+func (g *Gifts) handlePurchase(c telebot.Context, sess *conversation.Session) (string, error) {
+	btns := getReplyButtons(c)
+	userID := c.Sender().ID
+
+	payment, err := g.ps.Get(userID, sess.Data["payment_id"])
+	if err != nil {
+		g.log.Error("Payment status check failed", err)
+		return sess.Step, c.Send(tr(c, constants.UserError), btns)
+	}
+	if payment == nil || !payment.IsCompleted {
+		return sess.Step, c.Send(tr(c, "subscriptions.waiting_payment"), btns)
+	}
+
+	planID, _ := strconv.ParseUint(sess.Data["plan_id"], 10, 64)
+	plan, err := g.ss.Plans.GetByID(uint(planID))
+	if err != nil || plan == nil {
+		return conversation.Done, c.Send(tr(c, constants.UserError), btns)
+	}
+
+	voucher, err := g.pay(userID, plan)
+	if err != nil {
+		g.log.Error("Gift payment error", err, slog.Int64("user_id", userID))
+		return conversation.Done, c.Send(tr(c, constants.UserError), btns)
+	}
+
+	return conversation.Done, g.sendVoucher(c, voucher, plan)
+}
+
+func (g *Gifts) sendVoucher(c telebot.Context, voucher *models.Voucher, plan *models.SubscriptionPlan) error {
+	msg := tr(c, "gifts.purchased", plan.Name, voucher.Code, g.ss.Vouchers.Link(g.bot.Me.Username, voucher.Code), voucher.Code)
+	return c.Send(msg, &telebot.SendOptions{ReplyMarkup: getReplyButtons(c), ParseMode: telebot.ModeMarkdown})
+}
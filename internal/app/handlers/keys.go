@@ -1,58 +1,67 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/google/uuid"
 	"gopkg.in/telebot.v4"
-	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/callback"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/services"
 	"nsvpn/internal/app/state"
 	"nsvpn/pkg/logger"
 	"strconv"
-	"strings"
-	"sync"
 	"time"
 )
 
 type Keys struct {
-	log   *logger.Logger
-	bot   *telebot.Bot
-	ks    *services.Keys
-	servs *services.Servers
-	ss    *services.Subscriptions
-	api   *api.API
+	log *logger.Logger
+	bot *telebot.Bot
+	cb  *callback.Router
+	ks  *services.Keys
+	ps  *services.Placement
+	ss  *services.Subscriptions
+	cs  *services.Country
 
-	GetBtns    *services.Buttons
-	updateBtns *services.Buttons
+	GetBtns    *services.LocalizedButtons
+	updateBtns *services.LocalizedButtons
+	keyBtns    *services.LocalizedButtons
 
 	KeysState state.Storage[state.KeysState]
 }
 
-func NewKeys(log *logger.Logger, bot *telebot.Bot, ks *services.Keys, servs *services.Servers, ss *services.Subscriptions, api *api.API) *Keys {
+func NewKeys(log *logger.Logger, bot *telebot.Bot, cb *callback.Router, ks *services.Keys, ps *services.Placement, ss *services.Subscriptions, cs *services.Country, sb *state.Backend) *Keys {
 	return &Keys{
-		log:   log,
-		bot:   bot,
-		ks:    ks,
-		servs: servs,
-		ss:    ss,
-		api:   api,
-		GetBtns: services.NewButtons([]models.ButtonOption{{
+		log: log,
+		bot: bot,
+		cb:  cb,
+		ks:  ks,
+		ps:  ps,
+		ss:  ss,
+		cs:  cs,
+		GetBtns: services.NewLocalizedButtons([]models.ButtonOption{{
 			Value:   "get_key",
 			Display: "📥 Получить ключ",
 		}}, []int{1}, "inline"),
-		updateBtns: services.NewButtons([]models.ButtonOption{{
+		updateBtns: services.NewLocalizedButtons([]models.ButtonOption{{
 			Value:   "update_key",
 			Display: "🔄 Обновить ключ",
 		}}, []int{1}, "inline"),
-		KeysState: state.NewMemoryStorage[state.KeysState](),
+		keyBtns: services.NewLocalizedButtons([]models.ButtonOption{
+			{Value: "update_key", Display: "🔄 Обновить ключ"},
+			{Value: "choose_transport", Display: "🔀 Сменить протокол"},
+		}, []int{1, 1}, "inline"),
+		KeysState: state.New[state.KeysState](sb, "keys"),
 	}
 }
 
 func (k *Keys) RegisterHandlers() {
 	k.bot.Handle(k.GetBtns.GetBtn("get_key"), k.GetKeyHandler)
 	k.bot.Handle(k.updateBtns.GetBtn("update_key"), k.UpdateKeyHandler)
+	k.bot.Handle(k.keyBtns.GetBtn("choose_transport"), k.ChooseTransportHandler)
+	k.cb.Handle("transport", k.TransportHandler)
 }
 
 func (k *Keys) GetKeyHandler(c telebot.Context) error {
@@ -65,62 +74,67 @@ func (k *Keys) GetKeyHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
 	sub := getSubscription(c, k.ss)
 	if sub == nil || !sub.IsActive || (sub.EndDate.Before(time.Now()) && (!sub.EndDate.IsZero() || sub.ID == 0)) {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	ks, exists := k.KeysState.Get(strconv.FormatInt(c.Sender().ID, 10))
+	ks, exists := k.getKeysState(c.Sender().ID)
 	if !exists {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	if !k.ss.Trials.CountryAllowed(sub, ks.Country.Code) {
+		return c.Send(tr(c, "trials.country_unavailable"), btns)
 	}
 
 	key, err := k.getOrCreateKey(c.Sender().ID, ks.Country.ID)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	servers, err := k.servs.GetAllByCountryID(ks.Country.ID)
+	server, err := k.ps.Assign(c.Sender().ID, ks.Country.ID)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	email := fmt.Sprintf("nsvpn-%d-%s", c.Sender().ID, strings.ToLower(ks.Country.Code))
-	err = k.processServers(servers, func(server *models.Server) error {
-		found, err := k.api.IsFoundRequest(server, key.UUID)
-		if err != nil {
-			k.log.Error("Failed check if request", err)
-			return err
-		}
-		if found {
-			return nil
-		}
+	transports, err := k.cs.Transports.GetAllByCountryID(ks.Country.ID)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
 
-		if err := k.api.AddRequest(server, key.UUID, email, sub.EndDate); err != nil {
-			k.log.Error("Failed add request", err)
-			return err
-		}
+	transport := transports[0]
+	if ks.Transport != nil {
+		transport = ks.Transport
+	}
 
-		return nil
-	})
-	if err != nil {
-		return c.Send(constants.UserError, btns)
+	protocols := k.cs.Transports.GetProtocols(transports)
+	email := k.ks.GetEmail(c.Sender().ID, ks.Country.Code, models.ProtocolVLESS)
+	if err = k.ps.Provision(server, key, ks.Country.Code, sub.EndDate, protocols); err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	k.KeysState.Update(strconv.FormatInt(c.Sender().ID, 10), func(ks state.KeysState) state.KeysState {
 		ks.UUID = key.UUID
 		ks.Email = email
 		ks.EndDate = sub.EndDate
-		ks.Servers = servers
+		ks.Transport = transport
+		ks.Transports = protocols
+		ks.Host = k.ps.GetHost(server, ks.Country)
 		return ks
 	})
 
-	keyMessage := k.ks.GetVlessKey(key.UUID, ks.Country, email)
-	return c.Send(fmt.Sprintf("🔑 Ваш ключ для сервера %s %s:\n```%s```", ks.Country.Emoji, ks.Country.Code, keyMessage), &telebot.SendOptions{
-		ReplyMarkup: k.updateBtns.AddBtns(),
+	keyBtns := k.updateBtns
+	if len(transports) > 1 {
+		keyBtns = k.keyBtns
+	}
+
+	keyMessage := k.ks.GetKey(key, k.ps.GetHost(server, ks.Country), ks.Country, transport, email)
+	k.sendQRCode(c, keyMessage)
+	return c.Send(tr(c, "keys.key", ks.Country.Emoji, ks.Country.Code, transport.Name, keyMessage), &telebot.SendOptions{
+		ReplyMarkup: keyBtns.Get(i18n.FromContext(c)).AddBtns(),
 		ParseMode:   telebot.ModeMarkdown,
 	})
 }
 
-func (k *Keys) UpdateKeyHandler(c telebot.Context) error {
+func (k *Keys) ChooseTransportHandler(c telebot.Context) error {
 	defer func(c telebot.Context) {
 		if err := c.Respond(); err != nil {
 			k.log.Error("Failed to send message", err)
@@ -128,36 +142,90 @@ func (k *Keys) UpdateKeyHandler(c telebot.Context) error {
 	}(c)
 
 	btns := getReplyButtons(c)
-	if err := validateSubscription(c, k.ss); err != nil {
-		return err
+	ks, exists := k.KeysState.Get(strconv.FormatInt(c.Sender().ID, 10))
+	if !exists {
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
+	transports, err := k.cs.Transports.GetAllByCountryID(ks.Country.ID)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	buttons, layout := k.cs.Transports.ProcessButtons(transports)
+	transportBtns, err := services.NewCallbackButtons(k.cb, buttons, layout)
+	if err != nil {
+		k.log.Error("Failed to create transport buttons", err)
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	return c.Send(tr(c, "keys.choose_transport"), transportBtns.AddBtns())
+}
+
+func (k *Keys) TransportHandler(c telebot.Context, args []string) error {
+	btns := getReplyButtons(c)
 	ks, exists := k.KeysState.Get(strconv.FormatInt(c.Sender().ID, 10))
 	if !exists {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
-	newUUID := uuid.New().String()
 
-	err := k.ks.Update(ks.Country.ID, c.Sender().ID, &models.Key{UUID: newUUID})
+	if len(args) != 1 {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 64)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	err = k.processServers(ks.Servers, func(server *models.Server) error {
-		if err := k.api.DeleteRequest(server, ks.UUID); err != nil && err.Error() != "record not found" {
-			k.log.Error("Failed delete request", err)
-			return err
+	transport, err := k.cs.Transports.Get(uint(id))
+	if err != nil || transport == nil || transport.CountryID != ks.Country.ID {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	k.KeysState.Update(strconv.FormatInt(c.Sender().ID, 10), func(ks state.KeysState) state.KeysState {
+		ks.Transport = transport
+		return ks
+	})
+
+	if c.Message() != nil {
+		if err := k.bot.Delete(c.Message()); err != nil {
+			k.log.Error("Failed to delete message", err)
 		}
+	}
 
-		if err := k.api.AddRequest(server, newUUID, ks.Email, ks.EndDate); err != nil {
-			k.log.Error("Failed add request", err)
-			return err
+	return k.GetKeyHandler(c)
+}
+
+func (k *Keys) UpdateKeyHandler(c telebot.Context) error {
+	defer func(c telebot.Context) {
+		if err := c.Respond(); err != nil {
+			k.log.Error("Failed to send message", err)
 		}
+	}(c)
 
-		return nil
-	})
+	btns := getReplyButtons(c)
+	if err := validateSubscription(c, k.ss); err != nil {
+		return err
+	}
+
+	ks, exists := k.getKeysState(c.Sender().ID)
+	if !exists {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	key, err := k.ks.Get(ks.Country.ID, c.Sender().ID)
+	if err != nil || key == nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	protocols := ks.Transports
+	if len(protocols) == 0 {
+		protocols = []*models.CountryTransport{k.cs.Transports.Default(ks.Country.ID)}
+	}
+
+	newKey, server, err := k.ps.RotateKey(key, ks.Country, ks.EndDate, protocols)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	if c.Message() != nil {
@@ -167,9 +235,31 @@ func (k *Keys) UpdateKeyHandler(c telebot.Context) error {
 		}
 	}
 
+	transport := ks.Transport
+	if transport == nil {
+		transport = k.cs.Transports.Default(ks.Country.ID)
+	}
+
 	k.KeysState.Delete(strconv.FormatInt(c.Sender().ID, 10))
-	keyMessage := k.ks.GetVlessKey(newUUID, ks.Country, ks.Email)
-	return c.Send(fmt.Sprintf("🔑 Ваш новый ключ для сервера %s %s:\n```%s```", ks.Country.Emoji, ks.Country.Code, keyMessage), telebot.ModeMarkdown)
+	// ротация могла перенести пользователя на другой сервер, поэтому адрес берётся от него, а не из состояния
+	keyMessage := k.ks.GetKey(newKey, k.ps.GetHost(server, ks.Country), ks.Country, transport, ks.Email)
+	k.sendQRCode(c, keyMessage)
+	return c.Send(tr(c, "keys.new_key", ks.Country.Emoji, ks.Country.Code, transport.Name, keyMessage), telebot.ModeMarkdown)
+}
+
+func (k *Keys) getKeysState(userID int64) (state.KeysState, bool) {
+	ks, exists := k.KeysState.Get(strconv.FormatInt(userID, 10))
+	if !exists || ks.Country == nil {
+		return ks, false
+	}
+
+	// в состоянии лежит снимок страны, а ключи Reality могли смениться после выбора сервера
+	country, err := k.cs.Get(ks.Country.Code)
+	if err != nil || country == nil {
+		return ks, false
+	}
+	ks.Country = country
+	return ks, true
 }
 
 func (k *Keys) getOrCreateKey(userID int64, countryID uint) (*models.Key, error) {
@@ -179,6 +269,7 @@ func (k *Keys) getOrCreateKey(userID int64, countryID uint) (*models.Key, error)
 		return nil, err
 	}
 	if key != nil {
+		// пустые секреты старых ключей заполняет Provision, заодно пересоздавая клиентов на ноде
 		return key, nil
 	}
 
@@ -191,6 +282,9 @@ func (k *Keys) getOrCreateKey(userID int64, countryID uint) (*models.Key, error)
 		TrafficUsed:  0,
 		IsActive:     true,
 	}
+	if err := k.ks.GenerateSecrets(newKey); err != nil {
+		return nil, err
+	}
 
 	if err := k.ks.Add(newKey); err != nil {
 		k.log.Error("Failed add key", err)
@@ -200,25 +294,17 @@ func (k *Keys) getOrCreateKey(userID int64, countryID uint) (*models.Key, error)
 	return newKey, nil
 }
 
-func (k *Keys) processServers(servers []*models.Server, process func(server *models.Server) error) error {
-	var wg sync.WaitGroup
-	var errs []error
-	for _, server := range servers {
-		wg.Add(1)
-		go func(server *models.Server) {
-			defer wg.Done()
-			if err := process(server); err != nil {
-				errs = append(errs, err)
-			}
-		}(server)
-	}
-	wg.Wait()
-
-	if len(errs) > 0 {
-		for _, err := range errs {
-			k.log.Error("Failed process servers", err)
-		}
-		return constants.ErrProcessServers
+func (k *Keys) sendQRCode(c telebot.Context, keyMessage string) {
+	png, err := k.ks.GetQRCode(keyMessage)
+	if err != nil {
+		return
+	}
+
+	photo := &telebot.Photo{
+		File:    telebot.FromReader(bytes.NewReader(png)),
+		Caption: fmt.Sprintf("```%s```", keyMessage),
+	}
+	if err := c.Send(photo, telebot.ModeMarkdown); err != nil {
+		k.log.Error("Failed to send qr code", err)
 	}
-	return nil
 }
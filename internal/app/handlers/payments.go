@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"fmt"
-	"github.com/google/uuid"
 	"gopkg.in/telebot.v4"
+	"log/slog"
 	"math"
 	"nsvpn/internal/app/config"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/conversation"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/services"
 	"nsvpn/internal/app/state"
@@ -15,6 +17,11 @@ import (
 	"time"
 )
 
+const (
+	flowTopUp     = "top_up"
+	flowPromocode = "promocode"
+)
+
 type Payments struct {
 	log    *logger.Logger
 	bot    *telebot.Bot
@@ -22,9 +29,11 @@ type Payments struct {
 	pcodes *services.Promocodes
 	ps     *services.Payments
 	us     *services.Users
+	rfs    *services.Referrals
 	ph     *Promocodes
+	conv   *conversation.Manager
 
-	chooseBtns    *services.Buttons
+	chooseBtns    *services.LocalizedButtons
 	historyPgBtns *services.Buttons
 
 	PaymentsState   state.Storage[state.PaymentsState]
@@ -32,7 +41,7 @@ type Payments struct {
 }
 
 func NewPayments(log *logger.Logger, bot *telebot.Bot, cfg *config.Configuration,
-	pcodes *services.Promocodes, ps *services.Payments, us *services.Users, ph *Promocodes) *Payments {
+	pcodes *services.Promocodes, ps *services.Payments, us *services.Users, rfs *services.Referrals, ph *Promocodes, conv *conversation.Manager, sb *state.Backend) *Payments {
 	return &Payments{
 		log:    log,
 		bot:    bot,
@@ -40,9 +49,11 @@ func NewPayments(log *logger.Logger, bot *telebot.Bot, cfg *config.Configuration
 		pcodes: pcodes,
 		ps:     ps,
 		us:     us,
+		rfs:    rfs,
 		ph:     ph,
+		conv:   conv,
 
-		chooseBtns: services.NewButtons([]models.ButtonOption{
+		chooseBtns: services.NewLocalizedButtons([]models.ButtonOption{
 			{Value: "pay_bankcard", Display: "💳 Банковская карта/СБП"},
 			{Value: "pay_stars", Display: "⭐ Telegram Stars"},
 			{Value: "pay_cryptocurrency", Display: "💎 Криптовалюта"},
@@ -54,20 +65,16 @@ func NewPayments(log *logger.Logger, bot *telebot.Bot, cfg *config.Configuration
 			{Value: "pagination_last", Display: "⏩"},
 		}, []int{4}, "inline"),
 
-		PaymentsState:   state.NewMemoryStorage[state.PaymentsState](),
-		paginationState: state.NewMemoryStorage[state.PaginationState](),
+		PaymentsState:   state.New[state.PaymentsState](sb, "payments"),
+		paginationState: state.New[state.PaginationState](sb, "pagination"),
 	}
 }
 
 func (p *Payments) RegisterRoutes() {
-	paymentHandlers := map[string]func(c telebot.Context) error{
-		"pay_bankcard":       p.BankcardPaymentHandler,
-		"pay_stars":          p.TelegramPaymentHandler,
-		"pay_cryptocurrency": p.CryptoPaymentHandler,
-	}
-	for value, handler := range paymentHandlers {
-		p.bot.Handle(p.chooseBtns.GetBtn(value), p.CreatePaymentHandler(handler))
+	for value := range p.methodHandlers() {
+		p.bot.Handle(p.chooseBtns.GetBtn(value), p.CreatePaymentHandler(value))
 	}
+	p.bot.Handle(p.ph.skipBtn.GetBtn("skip_promocode"), p.SkipPromocodeHandler)
 
 	p.bot.Handle(telebot.OnCheckout, p.TelegramPreCheckoutHandler)
 	p.bot.Handle(telebot.OnPayment, p.SuccessfulPaymentHandler)
@@ -81,85 +88,155 @@ func (p *Payments) RegisterRoutes() {
 	for value, handler := range paginationHandlers {
 		p.bot.Handle(p.historyPgBtns.GetBtn(value), p.PaginationHandler(handler))
 	}
+
+	p.conv.Register(&conversation.Flow{
+		Name:    flowTopUp,
+		Start:   "amount",
+		Timeout: 5 * time.Minute,
+		Steps: map[string]*conversation.Step{
+			"amount": {Prompt: p.promptAmount, Handle: p.handleAmount},
+		},
+		OnTimeout: p.timeoutHandler("payments.amount_timeout"),
+		OnCancel:  p.cancelHandler,
+	})
+	p.conv.Register(&conversation.Flow{
+		Name:    flowPromocode,
+		Start:   "code",
+		Timeout: 5 * time.Minute,
+		Steps: map[string]*conversation.Step{
+			"code": {Prompt: p.ph.RequestPromocodeHandler, Handle: p.handlePromocode},
+		},
+		OnTimeout: p.timeoutHandler("payments.promocode_timeout"),
+		OnCancel:  p.cancelHandler,
+	})
+}
+
+func (p *Payments) methodHandlers() map[string]func(c telebot.Context) error {
+	return map[string]func(c telebot.Context) error{
+		"pay_bankcard":       p.BankcardPaymentHandler,
+		"pay_stars":          p.TelegramPaymentHandler,
+		"pay_cryptocurrency": p.CryptoPaymentHandler,
+	}
 }
 
-func (p *Payments) CreatePaymentHandler(handler func(c telebot.Context) error) func(c telebot.Context) error {
+func (p *Payments) CreatePaymentHandler(method string) func(c telebot.Context) error {
 	return func(c telebot.Context) error {
-		btns := getReplyButtons(c)
-		err := p.ph.RequestPromocodeHandler(c, p.PaymentsState)
-		if err != nil {
-			p.log.Error("Failed to request promocode handler", err)
+		if err := c.Respond(); err != nil {
+			p.log.Error("Failed to send message", err)
 		}
 
-		ps, exists := p.PaymentsState.Get(strconv.FormatInt(c.Sender().ID, 10))
-		if !exists {
-			return c.Send(constants.UserError, btns)
+		if _, exists := p.PaymentsState.Get(strconv.FormatInt(c.Sender().ID, 10)); !exists {
+			return c.Send(tr(c, constants.UserError), getReplyButtons(c))
 		}
 
-		payment := &models.Payment{
-			UserID:      c.Sender().ID,
-			Amount:      ps.Amount,
-			Type:        "income",
-			Payload:     ps.Payload,
-			Note:        ps.Note,
-			IsCompleted: false,
-		}
+		return p.conv.Start(c, flowPromocode, map[string]string{"method": method})
+	}
+}
 
-		err = p.ps.Add(payment)
-		if err != nil {
-			p.log.Error("Failed add payment", err)
-			return c.Send(constants.UserError, btns)
+func (p *Payments) SkipPromocodeHandler(c telebot.Context) error {
+	handled, err := p.conv.Handle(c)
+	if !handled {
+		if err := c.Respond(); err != nil {
+			p.log.Error("Failed to send message", err)
 		}
+	}
+	return err
+}
 
-		return handler(c)
+func (p *Payments) handlePromocode(c telebot.Context, s *conversation.Session) (string, error) {
+	if c.Callback() != nil {
+		if err := c.Respond(); err != nil {
+			p.log.Error("Failed to send message", err)
+		}
+	} else if err := p.ph.GetPromocodeHandler(c, c.Text(), s.Data["method"], p.PaymentsState); err != nil {
+		p.log.Error("Failed to apply promocode", err)
 	}
+
+	return conversation.Done, p.createPayment(c, s.Data["method"])
 }
 
-func (p *Payments) RequestAmount(c telebot.Context) error {
+func (p *Payments) createPayment(c telebot.Context, method string) error {
 	btns := getReplyButtons(c)
-	if err := c.Send("💳 Введите сумму в RUB:", btns); err != nil {
-		p.log.Error("Failed to send message", err)
-		p.PaymentsState.Delete(strconv.FormatInt(c.Sender().ID, 10))
-		return nil
+	handler, ok := p.methodHandlers()[method]
+	if !ok {
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	err := c.Respond()
-	if err != nil {
-		p.log.Error("Failed to send message", err)
+	ps, exists := p.PaymentsState.Get(strconv.FormatInt(c.Sender().ID, 10))
+	if !exists {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	payment := &models.Payment{
+		UserID:      c.Sender().ID,
+		Amount:      ps.Amount,
+		Type:        "income",
+		Payload:     ps.Payload,
+		Note:        ps.Note,
+		IsCompleted: false,
 	}
 
-	resultChan := make(chan float64)
-	defer close(resultChan)
+	if err := p.ps.Add(payment); err != nil {
+		p.log.Error("Failed add payment", err)
+		return c.Send(tr(c, constants.UserError), btns)
+	}
 
-	p.bot.Handle(telebot.OnText, func(c telebot.Context) error {
-		amount, err := strconv.ParseFloat(c.Text(), 64)
-		if err != nil || amount < 1 {
-			return c.Send("❌ Некорректная сумма, попробуйте ещё раз", btns)
+	return handler(c)
+}
+
+func (p *Payments) RequestAmount(c telebot.Context) error {
+	if c.Callback() != nil {
+		if err := c.Respond(); err != nil {
+			p.log.Error("Failed to send message", err)
 		}
-		resultChan <- amount
+	}
 
-		p.bot.Handle(telebot.OnText, func(c telebot.Context) error {
-			return c.Send("🤔 Неизвестная команда. Используйте /help для получения списка команд", btns)
-		})
-		return nil
+	return p.conv.Start(c, flowTopUp, nil)
+}
+
+func (p *Payments) promptAmount(c telebot.Context, _ *conversation.Session) error {
+	if err := c.Send(tr(c, "payments.enter_amount"), getReplyButtons(c)); err != nil {
+		p.log.Error("Failed to send message", err)
+		p.ResetState(strconv.FormatInt(c.Sender().ID, 10))
+	}
+	return nil
+}
+
+func (p *Payments) handleAmount(c telebot.Context, s *conversation.Session) (string, error) {
+	amount, err := strconv.ParseFloat(c.Text(), 64)
+	if err != nil || amount < 1 {
+		return s.Step, c.Send(tr(c, "payments.invalid_amount"), getReplyButtons(c))
+	}
+
+	p.PaymentsState.Update(strconv.FormatInt(c.Sender().ID, 10), func(ps state.PaymentsState) state.PaymentsState {
+		ps.Amount = amount
+		return ps
 	})
 
-	select {
-	case amount := <-resultChan:
-		p.PaymentsState.Update(strconv.FormatInt(c.Sender().ID, 10), func(ps state.PaymentsState) state.PaymentsState {
-			ps.Amount = amount
-			return ps
-		})
+	return conversation.Done, p.ChooseCurrencyHandler(c)
+}
+
+func (p *Payments) timeoutHandler(key string) func(to telebot.Recipient, s *conversation.Session) error {
+	return func(to telebot.Recipient, s *conversation.Session) error {
+		p.ResetState(to.Recipient())
+		_, err := p.bot.Send(to, i18n.T(s.Lang, key))
+		return err
+	}
+}
 
-		return p.ChooseCurrencyHandler(c)
-	case <-time.After(5 * time.Minute):
-		p.bot.Handle(telebot.OnText, func(c telebot.Context) error {
-			return c.Send("🤔 Неизвестная команда. Используйте /help для получения списка команд", btns)
-		})
+func (p *Payments) cancelHandler(c telebot.Context, _ *conversation.Session) error {
+	p.ResetState(strconv.FormatInt(c.Sender().ID, 10))
+	return nil
+}
 
-		p.PaymentsState.Delete(strconv.FormatInt(c.Sender().ID, 10))
-		return c.Send("⌛ Время ввода суммы истекло", btns)
+func (p *Payments) ResetState(key string) {
+	if ps, exists := p.PaymentsState.Get(key); exists && ps.ReservationID != 0 {
+		userID, _ := strconv.ParseInt(key, 10, 64)
+		if err := p.pcodes.Release(ps.ReservationID, userID); err != nil {
+			p.log.Error("Failed to release promocode", err, slog.Uint64("reservation_id", uint64(ps.ReservationID)))
+		}
 	}
+	p.PaymentsState.Delete(key)
 }
 
 func (p *Payments) ChooseCurrencyHandler(c telebot.Context) error {
@@ -167,28 +244,30 @@ func (p *Payments) ChooseCurrencyHandler(c telebot.Context) error {
 	user := getUser(c, p.us)
 	if user == nil {
 		p.log.Error("Failed to get user", nil)
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	ps, exists := p.PaymentsState.Get(strconv.FormatInt(c.Sender().ID, 10))
 	if !exists {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	var msg string
 	if user.Balance > 0 && ps.IsBuySubscription {
 		ps.Amount -= user.Balance
-		msg = fmt.Sprintf("💰 Ваш текущий баланс: %.f RUB\n", user.Balance)
+		msg = tr(c, "payments.balance", user.Balance)
 	}
-	msg += fmt.Sprintf("💵 Сумма к оплате: %.f RUB\n📦 Номер платежа: %s\n\nВыберите удобный для Вас способ оплаты:", ps.Amount, ps.Payload)
+	msg += tr(c, "payments.choose_method", ps.Amount, ps.Payload)
 
-	return c.Send(msg, p.chooseBtns.AddBtns())
+	return c.Send(msg, p.chooseBtns.Get(i18n.FromContext(c)).AddBtns())
 }
 
 func (p *Payments) BankcardPaymentHandler(c telebot.Context) error {
 	defer func(c telebot.Context) {
-		err := c.Respond()
-		if err != nil {
+		if c.Callback() == nil {
+			return
+		}
+		if err := c.Respond(); err != nil {
 			p.log.Error("Failed to send message", err)
 		}
 	}(c)
@@ -196,18 +275,18 @@ func (p *Payments) BankcardPaymentHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
 	ps, exists := p.PaymentsState.Get(strconv.FormatInt(c.Sender().ID, 10))
 	if !exists {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	response, err := p.ps.CreateBankcardPayment(ps.Amount, "kneshkreba@mail.ru", ps.Description, ps.Payload)
+	response, err := p.ps.CreateBankcardPayment(ps.Total(), "kneshkreba@mail.ru", ps.Description, ps.Payload)
 	if err != nil {
 		p.log.Error("Failed to create bankcard payment", err)
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	paymentBtns := services.NewButtons([]models.ButtonOption{
-		{Value: "proceed_payment", Display: "Перейти к оплате", URL: response.Confirmation.ConfirmationURL},
-		{Value: "tech_support", Display: "Техподдержка", URL: "https://t.me/nsvpn_support_bot"},
+		{Value: "proceed_payment", Display: tr(c, "button.proceed_payment"), URL: response.Confirmation.ConfirmationURL},
+		{Value: "tech_support", Display: tr(c, "button.tech_support"), URL: "https://t.me/nsvpn_support_bot"},
 	}, []int{1, 1, 1}, "inline")
 
 	go func(id string) {
@@ -223,13 +302,15 @@ func (p *Payments) BankcardPaymentHandler(c telebot.Context) error {
 		}
 	}(response.ID)
 
-	return c.Send(p.ps.CreatePaymentMessage(ps.Amount, time.Now().Add(10*time.Minute), "Банковская карта/СБП", ps.Payload), paymentBtns.AddBtns())
+	return c.Send(p.ps.CreatePaymentMessage(i18n.FromContext(c), ps.Total(), time.Now().Add(10*time.Minute), tr(c, "payments.method_bankcard"), ps.Payload), paymentBtns.AddBtns())
 }
 
 func (p *Payments) CryptoPaymentHandler(c telebot.Context) error {
 	defer func(c telebot.Context) {
-		err := c.Respond()
-		if err != nil {
+		if c.Callback() == nil {
+			return
+		}
+		if err := c.Respond(); err != nil {
 			p.log.Error("Failed to send message", err)
 		}
 	}(c)
@@ -237,18 +318,18 @@ func (p *Payments) CryptoPaymentHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
 	ps, exists := p.PaymentsState.Get(strconv.FormatInt(c.Sender().ID, 10))
 	if !exists {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	response, err := p.ps.CreateCryptoPayment(ps.Amount, ps.Description, ps.Payload)
+	response, err := p.ps.CreateCryptoPayment(ps.Total(), ps.Description, ps.Payload)
 	if err != nil {
 		p.log.Error("Failed to create crypto payment", err)
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	paymentBtns := services.NewButtons([]models.ButtonOption{
-		{Value: "proceed_payment", Display: "Перейти к оплате", URL: response.Result.URL},
-		{Value: "tech_support", Display: "Техподдержка", URL: "https://t.me/nsvpn_support_bot"},
+		{Value: "proceed_payment", Display: tr(c, "button.proceed_payment"), URL: response.Result.URL},
+		{Value: "tech_support", Display: tr(c, "button.tech_support"), URL: "https://t.me/nsvpn_support_bot"},
 	}, []int{1, 1, 1}, "inline")
 
 	go func() {
@@ -264,13 +345,15 @@ func (p *Payments) CryptoPaymentHandler(c telebot.Context) error {
 		}
 	}()
 
-	return c.Send(p.ps.CreatePaymentMessage(ps.Amount, time.Now().Add(10*time.Minute), "Криптовалюта", ps.Payload), paymentBtns.AddBtns())
+	return c.Send(p.ps.CreatePaymentMessage(i18n.FromContext(c), ps.Total(), time.Now().Add(10*time.Minute), tr(c, "payments.method_crypto"), ps.Payload), paymentBtns.AddBtns())
 }
 
 func (p *Payments) TelegramPaymentHandler(c telebot.Context) error {
 	defer func(c telebot.Context) {
-		err := c.Respond()
-		if err != nil {
+		if c.Callback() == nil {
+			return
+		}
+		if err := c.Respond(); err != nil {
 			p.log.Error("Failed to send message", err)
 		}
 	}(c)
@@ -278,11 +361,12 @@ func (p *Payments) TelegramPaymentHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
 	ps, exists := p.PaymentsState.Get(strconv.FormatInt(c.Sender().ID, 10))
 	if !exists {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	msg := p.ps.CreatePaymentMessage(ps.Amount, time.Now().Add(10*time.Minute), "Telegram Stars", ps.Payload)
-	invoice := p.ps.CreateInvoice(math.Round(ps.Amount*0.6), "Пополнение баланса", msg, ps.Payload)
+	lang := i18n.FromContext(c)
+	msg := p.ps.CreatePaymentMessage(lang, ps.Total(), time.Now().Add(10*time.Minute), tr(c, "payments.method_stars"), ps.Payload)
+	invoice := p.ps.CreateInvoice(lang, math.Round(ps.Total()*0.6), tr(c, "payments.invoice_title"), msg, ps.Payload)
 	return c.Send(&invoice)
 }
 
@@ -307,7 +391,7 @@ func (p *Payments) TelegramPreCheckoutHandler(c telebot.Context) error {
 			p.log.Error("Failed update isCompleted", err)
 		}
 
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	p.log.Info("PreCheckout accepted, waiting for payment confirmation")
@@ -318,7 +402,7 @@ func (p *Payments) SuccessfulPaymentHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
 	ps, exists := p.PaymentsState.Get(strconv.FormatInt(c.Sender().ID, 10))
 	if !exists {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	err := p.ps.UpdateIsCompleted(c.Sender().ID, ps.Payload, true)
@@ -331,45 +415,33 @@ func (p *Payments) SuccessfulPaymentHandler(c telebot.Context) error {
 		p.log.Error("Failed update isCompleted", err)
 	}
 
-	user, err := p.us.Get(c.Sender().ID)
+	earning, err := p.rfs.Reward(c.Sender().ID, ps.Payload, ps.Total())
 	if err != nil {
-		p.log.Error("Failed update isCompleted", err)
-	} else if user.PartnerID != 0 && ps.Amount*0.15 >= 1 {
-		err = p.ps.Add(&models.Payment{
-			UserID:      user.PartnerID,
-			Amount:      ps.Amount * 0.15,
-			Type:        "income",
-			Payload:     uuid.New().String(),
-			Note:        "15% от пополнения баланса рефералом",
-			IsCompleted: true,
-		})
-		if err != nil {
-			p.log.Error("Failed add payment", err)
-		}
-
-		err = p.us.IncrementBalance(user.PartnerID, math.Round(ps.Amount*0.15))
-		if err != nil {
-			p.log.Error("Failed increment balance", err)
+		p.log.Error("Failed to reward partner", err, slog.Int64("user_id", c.Sender().ID))
+	} else if earning != nil {
+		msg := i18n.T(p.us.Language(earning.PartnerID), "referrals.reward", earning.Amount)
+		if _, err = p.bot.Send(&telebot.User{ID: earning.PartnerID}, msg); err != nil {
+			p.log.Error("Failed to notify partner", err, slog.Int64("partner_id", earning.PartnerID))
 		}
 	}
 
-	if ps.Promocode.ID != 0 {
-		err = p.pcodes.Activations.Add(&models.PromocodeActivations{
-			PromocodeID: ps.Promocode.ID,
-			UserID:      c.Sender().ID,
-		})
-		if err != nil {
-			p.log.Error("Failed to activate promocode", err)
-		}
-
-		err = p.pcodes.IncrementActivationsByID(ps.Promocode.ID)
-		if err != nil {
-			p.log.Error("Failed to increment activations promocode", err)
+	if ps.ReservationID != 0 {
+		if err = p.pcodes.Confirm(ps.ReservationID, c.Sender().ID, ps.Total(), ps.Discount); err != nil {
+			p.log.Error("Failed to confirm promocode", err, slog.Uint64("reservation_id", uint64(ps.ReservationID)), slog.Int64("user_id", c.Sender().ID))
 		}
 	}
 
 	p.PaymentsState.Delete(strconv.FormatInt(c.Sender().ID, 10))
-	return c.Send("✅ Платёж успешно завершен!", btns)
+	if err = c.Send(tr(c, "payments.success"), btns); err != nil {
+		p.log.Error("Failed to send message", err)
+	}
+
+	// продолжаем ожидающую покупку подписки или подарка, если она есть
+	if s, err := p.conv.Active(c); err == nil && s != nil && (s.Flow == flowPurchase || s.Flow == flowGift) {
+		_, err = p.conv.Handle(c)
+		return err
+	}
+	return nil
 }
 
 func (p *Payments) PaginationHandler(action string) func(c telebot.Context) error {
@@ -411,10 +483,10 @@ func (p *Payments) HistoryPaymentsHandler(c telebot.Context, currentPage int, is
 
 	totalCount, err := p.ps.GetPaymentsCount(c.Sender().ID)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 	if totalCount == 0 {
-		return c.Send("🧾 У вас пока нету оплаченных платежей", btns)
+		return c.Send(tr(c, "payments.history_empty"), btns)
 	}
 
 	totalPages := totalCount / pageSize
@@ -422,10 +494,10 @@ func (p *Payments) HistoryPaymentsHandler(c telebot.Context, currentPage int, is
 		totalPages++
 	}
 
-	p.paginationState.Set(strconv.FormatInt(c.Sender().ID, 10), state.PaginationState{
+	p.paginationState.SetWithTTL(strconv.FormatInt(c.Sender().ID, 10), state.PaginationState{
 		CurrentPage: currentPage,
 		TotalPages:  int(totalPages),
-	})
+	}, time.Hour)
 
 	if currentPage < 1 || currentPage > int(totalPages) {
 		return nil
@@ -434,10 +506,10 @@ func (p *Payments) HistoryPaymentsHandler(c telebot.Context, currentPage int, is
 	offset := (currentPage - 1) * pageSize
 	payments, err := p.ps.GetAll(c.Sender().ID, offset, pageSize)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	msg := fmt.Sprintf("🧾 История платежей (страница %d из %d):\n", currentPage, totalPages)
+	msg := tr(c, "payments.history_title", currentPage, totalPages)
 	for i, payment := range payments {
 		amount := fmt.Sprintf("+%.f", payment.Amount)
 		if payment.Type == "expense" {
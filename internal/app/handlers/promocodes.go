@@ -1,16 +1,30 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"gopkg.in/telebot.v4"
+	"log/slog"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/conversation"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/services"
 	"nsvpn/internal/app/state"
 	"nsvpn/pkg/logger"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const campaignUsage = "Команды кампаний:\n" +
+	"/campaign add <название> <percent|fixed|balance|days> <значение> [uses=N] [per_user=N] [plans=1,2] [providers=stars,bankcard] [days=N] [new]\n" +
+	"/campaign gen <id> <количество> [prefix=X] [length=N] [alphabet=ABC...]\n" +
+	"/campaign export <id>\n" +
+	"/campaign stats <id>\n" +
+	"/campaign on|off <id>"
+
 type Promocodes struct {
 	log    *logger.Logger
 	bot    *telebot.Bot
@@ -18,7 +32,7 @@ type Promocodes struct {
 	pcodes *services.Promocodes
 	us     *services.Users
 
-	skipBtn *services.Buttons
+	skipBtn *services.LocalizedButtons
 }
 
 func NewPromocodes(log *logger.Logger, bot *telebot.Bot, ps *services.Payments, pcodes *services.Promocodes, us *services.Users) *Promocodes {
@@ -29,7 +43,7 @@ func NewPromocodes(log *logger.Logger, bot *telebot.Bot, ps *services.Payments,
 		pcodes: pcodes,
 		us:     us,
 
-		skipBtn: services.NewButtons([]models.ButtonOption{
+		skipBtn: services.NewLocalizedButtons([]models.ButtonOption{
 			{
 				Value:   "skip_promocode",
 				Display: "Пропустить",
@@ -38,84 +52,333 @@ func NewPromocodes(log *logger.Logger, bot *telebot.Bot, ps *services.Payments,
 	}
 }
 
-func (p *Promocodes) RequestPromocodeHandler(c telebot.Context, ps state.Storage[state.PaymentsState]) error {
+func (p *Promocodes) RegisterHandlers() {
+	p.bot.Handle("/promo", p.RedeemHandler)
+	p.bot.Handle("/campaigns", p.CampaignsHandler)
+	p.bot.Handle("/campaign", p.CampaignHandler)
+}
+
+func (p *Promocodes) RequestPromocodeHandler(c telebot.Context, _ *conversation.Session) error {
+	if err := c.Send(tr(c, "promocodes.enter"), p.skipBtn.Get(i18n.FromContext(c)).AddBtns()); err != nil {
+		return c.Send(tr(c, constants.UserError), getReplyButtons(c))
+	}
+	return nil
+}
+
+func (p *Promocodes) GetPromocodeHandler(c telebot.Context, code, method string, ps state.Storage[state.PaymentsState]) error {
 	btns := getReplyButtons(c)
-	err := c.Send("💳 Введите промокод:", p.skipBtn.AddBtns())
-	if err != nil {
-		return c.Send(constants.UserError, btns)
+	key := strconv.FormatInt(c.Sender().ID, 10)
+	current, exists := ps.Get(key)
+	if !exists {
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	err = c.Respond()
-	if err != nil {
-		p.log.Error("Failed to send message", err)
+	if current.ReservationID != 0 {
+		if err := p.pcodes.Release(current.ReservationID, c.Sender().ID); err != nil {
+			p.log.Error("Failed to release promocode", err, slog.Uint64("reservation_id", uint64(current.ReservationID)))
+		}
 	}
 
-	resultChan := make(chan string)
-	p.bot.Handle(p.skipBtn.GetBtn("skip_promocode"), func(c telebot.Context) error {
-		defer func(c telebot.Context) {
-			err := c.Respond()
-			if err != nil {
-				p.log.Error("Failed to send message", err)
-			}
-		}(c)
+	reservation, discount, err := p.pcodes.ApplyDiscount(code, c.Sender().ID, current.Amount, current.PlanID, strings.TrimPrefix(method, "pay_"))
+	if errors.Is(err, constants.ErrPromocodeWrongType) {
+		return c.Send(tr(c, "promocodes.use_command"), btns)
+	}
+	if err != nil {
+		return p.sendError(c, err)
+	}
 
-		resultChan <- ""
-		return nil
+	ps.Update(key, func(ps state.PaymentsState) state.PaymentsState {
+		ps.Discount = discount
+		ps.Promocode = &reservation.Activation.Promocode
+		ps.ReservationID = reservation.ID
+		return ps
 	})
 
-	p.bot.Handle(telebot.OnText, func(c telebot.Context) error {
-		resultChan <- c.Text()
+	return c.Send(tr(c, "promocodes.applied", discount), btns)
+}
 
-		p.bot.Handle(telebot.OnText, func(c telebot.Context) error {
-			return c.Send("🤔 Неизвестная команда. Используйте /help для получения списка команд", btns)
-		})
-		return nil
-	})
+func (p *Promocodes) RedeemHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	code := strings.TrimSpace(c.Message().Payload)
+	if code == "" {
+		return c.Send(tr(c, "promocodes.enter_command"), btns)
+	}
+
+	promocode, err := p.pcodes.Redeem(code, c.Sender().ID)
+	if errors.Is(err, constants.ErrPromocodeWrongType) {
+		return c.Send(tr(c, "promocodes.use_payment"), btns)
+	}
+	if err != nil {
+		return p.sendError(c, err)
+	}
+
+	if promocode.Type == models.PromocodeTypeDays {
+		return c.Send(tr(c, "promocodes.days_added", int(promocode.Value)), btns)
+	}
+	return c.Send(tr(c, "promocodes.balance_added", promocode.Value), btns)
+}
 
-	promocode := <-resultChan
-	if promocode == "" {
-		return nil
+func (p *Promocodes) sendError(c telebot.Context, err error) error {
+	btns := getReplyButtons(c)
+	switch {
+	case errors.Is(err, constants.ErrPromocodeNotFound), errors.Is(err, constants.ErrEmptyFields):
+		return c.Send(tr(c, "promocodes.not_found"), btns)
+	case errors.Is(err, constants.ErrPromocodeLimit):
+		return c.Send(tr(c, "promocodes.limit"), btns)
+	case errors.Is(err, constants.ErrPromocodeOnlyNew):
+		return c.Send(tr(c, "promocodes.only_new"), btns)
+	case errors.Is(err, constants.ErrPromocodeExpired):
+		return c.Send(tr(c, "promocodes.expired"), btns)
+	case errors.Is(err, constants.ErrPromocodeUsed):
+		return c.Send(tr(c, "promocodes.already_used"), btns)
+	case errors.Is(err, constants.ErrPromocodeNotApplicable):
+		return c.Send(tr(c, "promocodes.not_applicable"), btns)
 	}
 
-	return p.GetPromocodeHandler(c, promocode, ps)
+	p.log.Error("Failed to apply promocode", err, slog.Int64("user_id", c.Sender().ID))
+	return c.Send(tr(c, constants.UserError), btns)
 }
 
-func (p *Promocodes) GetPromocodeHandler(c telebot.Context, code string, ps state.Storage[state.PaymentsState]) error {
+func (p *Promocodes) CampaignsHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
-	promocode, err := p.pcodes.Get(code)
+	if isAdmin, err := p.us.IsAdmin(c.Sender().ID); err != nil || !isAdmin {
+		return c.Send(tr(c, constants.UserHasNoRights), btns)
+	}
+
+	campaigns, err := p.pcodes.Campaigns.GetAll()
 	if err != nil {
-		return c.Send(constants.UserHasNoRights, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	count, err := p.ps.GetPaymentsCount(c.Sender().ID)
+	msg := "🎯 Кампании промокодов:\n"
+	if len(campaigns) == 0 {
+		msg += "пока нет\n"
+	}
+	for _, campaign := range campaigns {
+		status := "✅"
+		if !campaign.IsActive {
+			status = "⛔️"
+		}
+		msg += fmt.Sprintf("%s #%d %s — %s\n", status, campaign.ID, campaign.Name, describeCampaign(campaign))
+	}
+	return c.Send(msg+"\n"+campaignUsage, btns)
+}
+
+func (p *Promocodes) CampaignHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	if isAdmin, err := p.us.IsAdmin(c.Sender().ID); err != nil || !isAdmin {
+		return c.Send(tr(c, constants.UserHasNoRights), btns)
+	}
+
+	args := strings.Fields(c.Message().Payload)
+	if len(args) == 0 {
+		return c.Send(campaignUsage, btns)
+	}
+	if args[0] == "add" {
+		return p.addCampaign(c, args[1:])
+	}
+	if len(args) < 2 {
+		return c.Send(campaignUsage, btns)
+	}
+
+	id, err := strconv.ParseUint(args[1], 10, 64)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send("Некорректный id кампании", btns)
+	}
+
+	switch args[0] {
+	case "gen":
+		err = p.generateCampaign(c, uint(id), args[2:])
+	case "export":
+		err = p.exportCampaign(c, uint(id))
+	case "stats":
+		err = p.campaignStats(c, uint(id))
+	case "on":
+		if err = p.pcodes.Campaigns.UpdateIsActive(uint(id), true); err == nil {
+			err = c.Send(fmt.Sprintf("✅ Кампания #%d включена", id), btns)
+		}
+	case "off":
+		if err = p.pcodes.Campaigns.UpdateIsActive(uint(id), false); err == nil {
+			err = c.Send(fmt.Sprintf("⛔️ Кампания #%d отключена, её коды больше не принимаются", id), btns)
+		}
+	default:
+		return c.Send(campaignUsage, btns)
 	}
 
 	switch {
-	case promocode == nil || !promocode.IsActive:
-		return c.Send("❌ Промокод не найден", btns)
-	case promocode.TotalActivations != 0 && promocode.CurrentActivations >= promocode.TotalActivations:
-		return c.Send("❌ Количество активаций промокода превышено", btns)
-	case promocode.OnlyNewUsers && count > 0:
-		return c.Send("❌ Промокод доступен только для новых пользователей", btns)
-	case promocode.EndAt != nil && promocode.EndAt.Before(time.Now()):
-		return c.Send("❌ Срок действия промокода истёк", btns)
+	case errors.Is(err, constants.ErrCampaignNotFound):
+		return c.Send(fmt.Sprintf("Кампания #%d не найдена или отключена", id), btns)
+	case errors.Is(err, constants.ErrInvalidCodeFormat):
+		return c.Send("Некорректные параметры генерации: до 10000 кодов за раз, длина не меньше 4 и вместе с префиксом не больше 32, "+
+			"символы — латиница, цифры, - и _, алфавит без повторов и достаточно большой для нужного числа кодов", btns)
+	case err != nil:
+		p.log.Error("Failed to process campaign command", err, slog.String("command", args[0]), slog.Uint64("campaign_id", id))
+		return c.Send(tr(c, constants.UserError), btns)
 	}
+	return nil
+}
 
-	activation, err := p.pcodes.Activations.Get(promocode.ID, c.Sender().ID)
+func (p *Promocodes) addCampaign(c telebot.Context, args []string) error {
+	btns := getReplyButtons(c)
+	campaign, err := parseCampaign(args, time.Now())
+	if err == nil {
+		err = p.pcodes.Campaigns.Create(campaign)
+	}
+	if errors.Is(err, constants.ErrInvalidCampaign) || errors.Is(err, constants.ErrEmptyFields) {
+		return c.Send(campaignUsage, btns)
+	}
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		p.log.Error("Failed to add promocode campaign", err)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
-	if activation != nil && activation.ID != 0 {
-		return c.Send("❌ Промокод уже был применён", btns)
+
+	return c.Send(fmt.Sprintf("✅ Кампания #%d «%s» создана: %s\nСгенерировать коды: /campaign gen %d <количество>",
+		campaign.ID, campaign.Name, describeCampaign(campaign), campaign.ID), btns)
+}
+
+func (p *Promocodes) generateCampaign(c telebot.Context, id uint, args []string) error {
+	if len(args) == 0 {
+		return constants.ErrInvalidCodeFormat
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil {
+		return constants.ErrInvalidCodeFormat
 	}
 
-	ps.Update(strconv.FormatInt(c.Sender().ID, 10), func(ps state.PaymentsState) state.PaymentsState {
-		ps.Amount -= ps.Amount * float64(promocode.Discount) / 100
-		ps.Promocode = promocode
-		return ps
+	var prefix, alphabet string
+	var length int
+	for _, arg := range args[1:] {
+		key, value, _ := strings.Cut(arg, "=")
+		switch key {
+		case "prefix":
+			prefix = value
+		case "alphabet":
+			alphabet = value
+		case "length":
+			if length, err = strconv.Atoi(value); err != nil {
+				return constants.ErrInvalidCodeFormat
+			}
+		default:
+			return constants.ErrInvalidCodeFormat
+		}
+	}
+
+	generated, err := p.pcodes.Campaigns.Generate(id, count, prefix, alphabet, length)
+	if err != nil {
+		return err
+	}
+	return c.Send(fmt.Sprintf("✅ Сгенерировано кодов: %d из %d\nВыгрузить: /campaign export %d", generated, count, id), getReplyButtons(c))
+}
+
+func (p *Promocodes) exportCampaign(c telebot.Context, id uint) error {
+	var buf bytes.Buffer
+	count, err := p.pcodes.Campaigns.Export(id, &buf)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(&telebot.Document{
+		File:     telebot.FromReader(&buf),
+		FileName: fmt.Sprintf("campaign_%d.csv", id),
+		MIME:     "text/csv",
+		Caption:  fmt.Sprintf("Кодов в выгрузке: %d", count),
 	})
+}
 
-	return c.Send("✅ Промокод применён", btns)
+func (p *Promocodes) campaignStats(c telebot.Context, id uint) error {
+	campaign, err := p.pcodes.Campaigns.Get(id)
+	if err != nil {
+		return err
+	}
+	stats, err := p.pcodes.Campaigns.Stats(id)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(fmt.Sprintf("📊 Кампания #%d «%s»\n%s\n\n🎟 Кодов: %d, использовано: %d\n✅ Активаций: %d\n👥 Пользователей: %d\n💰 Выручка: %.2f₽\n🎁 Скидки и бонусы: %.2f₽",
+		campaign.ID, campaign.Name, describeCampaign(campaign), stats.Codes, stats.UsedCodes, stats.Redemptions, stats.Users, stats.Revenue, stats.Discount), getReplyButtons(c))
+}
+
+func parseCampaign(args []string, now time.Time) (*models.PromocodeCampaign, error) {
+	if len(args) < 3 {
+		return nil, constants.ErrInvalidCampaign
+	}
+
+	value, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		return nil, constants.ErrInvalidCampaign
+	}
+
+	campaign := &models.PromocodeCampaign{
+		Name:         args[0],
+		Type:         args[1],
+		PerUserLimit: 1,
+		IsActive:     true,
+	}
+	if campaign.Type == models.PromocodeTypePercent {
+		campaign.Discount = int(value)
+	} else {
+		campaign.Value = value
+	}
+
+	for _, arg := range args[3:] {
+		if arg == "new" {
+			campaign.OnlyNewUsers = true
+			continue
+		}
+
+		key, option, _ := strings.Cut(arg, "=")
+		switch key {
+		case "plans":
+			campaign.PlanIDs = option
+		case "providers":
+			campaign.Providers = option
+		case "uses", "per_user", "days":
+			n, err := strconv.ParseUint(option, 10, 32)
+			if err != nil {
+				return nil, constants.ErrInvalidCampaign
+			}
+			switch key {
+			case "uses":
+				campaign.CodeActivations = uint(n)
+			case "per_user":
+				campaign.PerUserLimit = uint(n)
+			case "days":
+				endAt := now.AddDate(0, 0, int(n))
+				campaign.EndAt = &endAt
+			}
+		default:
+			return nil, constants.ErrInvalidCampaign
+		}
+	}
+	return campaign, nil
+}
+
+func describeCampaign(campaign *models.PromocodeCampaign) string {
+	var msg string
+	switch campaign.Type {
+	case models.PromocodeTypePercent:
+		msg = fmt.Sprintf("скидка %d%%", campaign.Discount)
+	case models.PromocodeTypeFixed:
+		msg = fmt.Sprintf("скидка %.f₽", campaign.Value)
+	case models.PromocodeTypeBalance:
+		msg = fmt.Sprintf("%.f₽ на баланс", campaign.Value)
+	case models.PromocodeTypeDays:
+		msg = fmt.Sprintf("%.f дн. подписки", campaign.Value)
+	}
+
+	msg += fmt.Sprintf(", активаций на код: %d", campaign.CodeActivations)
+	if campaign.OnlyNewUsers {
+		msg += ", только новым"
+	}
+	if campaign.PlanIDs != "" {
+		msg += ", тарифы: " + campaign.PlanIDs
+	}
+	if campaign.Providers != "" {
+		msg += ", оплата: " + campaign.Providers
+	}
+	if campaign.EndAt != nil {
+		msg += ", до " + campaign.EndAt.Format("2006-01-02")
+	}
+	return msg
 }
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/callback"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/conversation"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/services"
+	"nsvpn/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const flowWithdrawal = "withdrawal"
+
+type Referrals struct {
+	log  *logger.Logger
+	bot  *telebot.Bot
+	cb   *callback.Router
+	rs   *services.Referrals
+	us   *services.Users
+	conv *conversation.Manager
+
+	referralBtns *services.LocalizedButtons
+}
+
+func NewReferrals(log *logger.Logger, bot *telebot.Bot, cb *callback.Router, rs *services.Referrals, us *services.Users, conv *conversation.Manager) *Referrals {
+	return &Referrals{
+		log:  log,
+		bot:  bot,
+		cb:   cb,
+		rs:   rs,
+		us:   us,
+		conv: conv,
+
+		referralBtns: services.NewLocalizedButtons([]models.ButtonOption{
+			{Value: "withdraw", Display: "💸 Вывести"},
+			{Value: "referral_history", Display: "🧾 Начисления"},
+		}, []int{2}, "inline"),
+	}
+}
+
+func (r *Referrals) RegisterHandlers() {
+	r.bot.Handle(r.referralBtns.GetBtn("withdraw"), r.WithdrawHandler)
+	r.bot.Handle(r.referralBtns.GetBtn("referral_history"), r.HistoryHandler)
+	r.bot.Handle("/withdrawals", r.PendingWithdrawalsHandler)
+	r.bot.Handle("/referral_rules", r.RulesHandler)
+	r.bot.Handle("/referral_rule", r.RuleHandler)
+
+	r.cb.Handle("wd_approve", r.processWithdrawal(true))
+	r.cb.Handle("wd_reject", r.processWithdrawal(false))
+
+	r.conv.Register(&conversation.Flow{
+		Name:    flowWithdrawal,
+		Start:   "amount",
+		Timeout: 5 * time.Minute,
+		Steps: map[string]*conversation.Step{
+			"amount":  {Prompt: r.promptAmount, Handle: r.handleAmount},
+			"details": {Prompt: r.promptDetails, Handle: r.handleDetails},
+		},
+		OnTimeout: func(to telebot.Recipient, s *conversation.Session) error {
+			_, err := r.bot.Send(to, i18n.T(s.Lang, "referrals.withdrawal_timeout"))
+			return err
+		},
+	})
+}
+
+func (r *Referrals) Link(userID int64) string {
+	return r.rs.Link(r.bot.Me.Username, userID)
+}
+
+func (r *Referrals) ReferralsHandler(c telebot.Context) error {
+	if c.Callback() != nil {
+		defer func() { _ = c.Respond() }()
+	}
+
+	btns := getReplyButtons(c)
+	userID := c.Sender().ID
+
+	partners, err := r.us.CountPartners(userID)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	stats, err := r.rs.GetStats(userID)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	available, err := r.rs.Withdrawals.Available(userID)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	rules, err := r.rs.GetRules()
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	lang := i18n.FromContext(c)
+	msg := tr(c, "referrals.info", r.Link(userID), partners, stats.Total, available)
+	for _, rule := range rules {
+		msg += tr(c, "referrals.tier", rule.MinReferrals, describeRule(lang, rule))
+	}
+
+	return c.Send(msg, &telebot.SendOptions{
+		ReplyMarkup: r.referralBtns.Get(lang).AddBtns(),
+		ParseMode:   telebot.ModeMarkdown,
+	})
+}
+
+func (r *Referrals) HistoryHandler(c telebot.Context) error {
+	defer func() { _ = c.Respond() }()
+
+	btns := getReplyButtons(c)
+	earnings, err := r.rs.GetEarnings(c.Sender().ID, 20)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	if len(earnings) == 0 {
+		return c.Send(tr(c, "referrals.history_empty"), btns)
+	}
+
+	msg := tr(c, "referrals.history_title")
+	for i, earning := range earnings {
+		msg += tr(c, "referrals.history_item", i+1, earning.Amount, earning.CreatedAt.Format("2006-01-02 15:04:05"), earning.PaymentAmount)
+	}
+	return c.Send(msg, btns)
+}
+
+func (r *Referrals) WithdrawHandler(c telebot.Context) error {
+	defer func() { _ = c.Respond() }()
+
+	available, err := r.rs.Withdrawals.Available(c.Sender().ID)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), getReplyButtons(c))
+	}
+	if available < r.rs.Withdrawals.MinAmount() {
+		return c.Send(tr(c, "referrals.withdrawal_unavailable", r.rs.Withdrawals.MinAmount(), available), getReplyButtons(c))
+	}
+
+	return r.conv.Start(c, flowWithdrawal, nil)
+}
+
+func (r *Referrals) promptAmount(c telebot.Context, _ *conversation.Session) error {
+	available, err := r.rs.Withdrawals.Available(c.Sender().ID)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), getReplyButtons(c))
+	}
+	return c.Send(tr(c, "referrals.enter_amount", r.rs.Withdrawals.MinAmount(), available), getReplyButtons(c))
+}
+
+func (r *Referrals) handleAmount(c telebot.Context, s *conversation.Session) (string, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(c.Text()), 64)
+	if err != nil || amount < r.rs.Withdrawals.MinAmount() {
+		return s.Step, c.Send(tr(c, "referrals.withdrawal_too_small", r.rs.Withdrawals.MinAmount()), getReplyButtons(c))
+	}
+
+	available, err := r.rs.Withdrawals.Available(c.Sender().ID)
+	if err != nil {
+		return conversation.Done, c.Send(tr(c, constants.UserError), getReplyButtons(c))
+	}
+	if amount > available {
+		return s.Step, c.Send(tr(c, "referrals.withdrawal_insufficient", available), getReplyButtons(c))
+	}
+
+	s.Data["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
+	return "details", nil
+}
+
+func (r *Referrals) promptDetails(c telebot.Context, _ *conversation.Session) error {
+	return c.Send(tr(c, "referrals.enter_details"), getReplyButtons(c))
+}
+
+func (r *Referrals) handleDetails(c telebot.Context, s *conversation.Session) (string, error) {
+	btns := getReplyButtons(c)
+	details := strings.TrimSpace(c.Text())
+	if details == "" || len(details) > 255 {
+		return s.Step, c.Send(tr(c, "referrals.enter_details"), btns)
+	}
+
+	amount, err := strconv.ParseFloat(s.Data["amount"], 64)
+	if err != nil {
+		return conversation.Done, c.Send(tr(c, constants.UserError), btns)
+	}
+
+	withdrawal, err := r.rs.Withdrawals.Request(c.Sender().ID, amount, details)
+	switch {
+	case errors.Is(err, constants.ErrWithdrawalTooSmall):
+		return conversation.Done, c.Send(tr(c, "referrals.withdrawal_too_small", r.rs.Withdrawals.MinAmount()), btns)
+	case errors.Is(err, constants.ErrInsufficientFunds):
+		available, _ := r.rs.Withdrawals.Available(c.Sender().ID)
+		return conversation.Done, c.Send(tr(c, "referrals.withdrawal_insufficient", available), btns)
+	case err != nil:
+		r.log.Error("Failed to request withdrawal", err, slog.Int64("user_id", c.Sender().ID), slog.Float64("amount", amount))
+		return conversation.Done, c.Send(tr(c, constants.UserError), btns)
+	}
+
+	r.notifyAdmins(withdrawal)
+	return conversation.Done, c.Send(tr(c, "referrals.withdrawal_created", withdrawal.ID), btns)
+}
+
+func (r *Referrals) notifyAdmins(withdrawal *models.ReferralWithdrawal) {
+	admins, err := r.us.GetAdmins()
+	if err != nil {
+		r.log.Error("Failed to get admins", err)
+		return
+	}
+
+	for _, admin := range admins {
+		if err = r.sendWithdrawal(&telebot.User{ID: admin.ID}, withdrawal); err != nil {
+			r.log.Error("Failed to notify admin about withdrawal", err, slog.Int64("admin_id", admin.ID))
+		}
+	}
+}
+
+func (r *Referrals) sendWithdrawal(to telebot.Recipient, withdrawal *models.ReferralWithdrawal) error {
+	id := strconv.FormatUint(uint64(withdrawal.ID), 10)
+	btns, err := services.NewCallbackButtons(r.cb, []models.ButtonOption{
+		{Value: "wd_approve", Display: "✅ Выплачено", Action: "wd_approve", Args: []string{id}},
+		{Value: "wd_reject", Display: "❌ Отклонить", Action: "wd_reject", Args: []string{id}},
+	}, []int{2})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.bot.Send(to, formatWithdrawal(withdrawal), btns.AddBtns())
+	return err
+}
+
+func (r *Referrals) processWithdrawal(approve bool) callback.HandlerFunc {
+	return func(c telebot.Context, args []string) error {
+		defer func() { _ = c.Respond() }()
+
+		if isAdmin, err := r.us.IsAdmin(c.Sender().ID); err != nil || !isAdmin {
+			return c.Send(tr(c, constants.UserHasNoRights), getReplyButtons(c))
+		}
+		if len(args) != 1 {
+			return c.Send(tr(c, constants.UserError), getReplyButtons(c))
+		}
+		id, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return c.Send(tr(c, constants.UserError), getReplyButtons(c))
+		}
+
+		process, key, result := r.rs.Withdrawals.Reject, "referrals.withdrawal_rejected", "❌ Отклонено"
+		if approve {
+			process, key, result = r.rs.Withdrawals.Approve, "referrals.withdrawal_approved", "✅ Выплачено"
+		}
+
+		withdrawal, err := process(uint(id), c.Sender().ID)
+		if errors.Is(err, constants.ErrWithdrawalProcessed) {
+			return c.Send(fmt.Sprintf("Заявка #%d уже обработана", id))
+		}
+		if err != nil {
+			r.log.Error("Failed to process withdrawal", err, slog.Uint64("id", id), slog.Bool("approve", approve))
+			return c.Send(tr(c, constants.UserError))
+		}
+
+		lang := r.us.Language(withdrawal.UserID)
+		if _, err = r.bot.Send(&telebot.User{ID: withdrawal.UserID}, i18n.T(lang, key, withdrawal.ID, withdrawal.Amount)); err != nil {
+			r.log.Error("Failed to notify user about withdrawal", err, slog.Int64("user_id", withdrawal.UserID))
+		}
+
+		return c.Edit(fmt.Sprintf("%s\n\n%s (%s)", formatWithdrawal(withdrawal), result, c.Sender().Username))
+	}
+}
+
+func (r *Referrals) PendingWithdrawalsHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	if isAdmin, err := r.us.IsAdmin(c.Sender().ID); err != nil || !isAdmin {
+		return c.Send(tr(c, constants.UserHasNoRights), btns)
+	}
+
+	withdrawals, err := r.rs.Withdrawals.GetPending()
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	if len(withdrawals) == 0 {
+		return c.Send("Нет заявок на вывод", btns)
+	}
+
+	for _, withdrawal := range withdrawals {
+		if err = r.sendWithdrawal(c.Recipient(), withdrawal); err != nil {
+			r.log.Error("Failed to send withdrawal", err, slog.Uint64("id", uint64(withdrawal.ID)))
+		}
+	}
+	return nil
+}
+
+func (r *Referrals) RulesHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	if isAdmin, err := r.us.IsAdmin(c.Sender().ID); err != nil || !isAdmin {
+		return c.Send(tr(c, constants.UserHasNoRights), btns)
+	}
+
+	rules, err := r.rs.GetRules()
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	msg := "🤝 Правила реферальной программы:\n"
+	for _, rule := range rules {
+		if rule.ID == 0 {
+			msg += "• по умолчанию: "
+		} else {
+			msg += fmt.Sprintf("• #%d от %d реф.: ", rule.ID, rule.MinReferrals)
+		}
+		msg += describeRule(i18n.RU, rule) + "\n"
+	}
+	msg += "\nДобавить: /referral_rule add <рефералов> <percent|fixed> <значение> [first]\nУдалить: /referral_rule del <id>"
+	return c.Send(msg, btns)
+}
+
+func (r *Referrals) RuleHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	if isAdmin, err := r.us.IsAdmin(c.Sender().ID); err != nil || !isAdmin {
+		return c.Send(tr(c, constants.UserHasNoRights), btns)
+	}
+
+	args := strings.Fields(c.Message().Payload)
+	if len(args) == 2 && args[0] == "del" {
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return c.Send("Некорректный id правила", btns)
+		}
+		if err = r.rs.DeleteRule(uint(id)); err != nil {
+			r.log.Error("Failed to delete referral rule", err, slog.Uint64("id", id))
+			return c.Send(tr(c, constants.UserError), btns)
+		}
+		return c.Send(fmt.Sprintf("✅ Правило #%d удалено", id), btns)
+	}
+
+	rule, err := parseRule(args)
+	if err == nil {
+		err = r.rs.AddRule(rule)
+	}
+	if errors.Is(err, constants.ErrInvalidReferralRule) {
+		return c.Send("Формат: /referral_rule add <рефералов> <percent|fixed> <значение> [first]", btns)
+	}
+	if err != nil {
+		r.log.Error("Failed to add referral rule", err)
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	return c.Send(fmt.Sprintf("✅ Правило #%d добавлено: от %d реф. — %s", rule.ID, rule.MinReferrals, describeRule(i18n.RU, rule)), btns)
+}
+
+func parseRule(args []string) (*models.ReferralRule, error) {
+	if len(args) < 4 || len(args) > 5 || args[0] != "add" {
+		return nil, constants.ErrInvalidReferralRule
+	}
+
+	minReferrals, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, constants.ErrInvalidReferralRule
+	}
+	value, err := strconv.ParseFloat(args[3], 64)
+	if err != nil {
+		return nil, constants.ErrInvalidReferralRule
+	}
+
+	rule := &models.ReferralRule{
+		MinReferrals: minReferrals,
+		Type:         args[2],
+		Value:        value,
+	}
+	if len(args) == 5 {
+		if args[4] != "first" {
+			return nil, constants.ErrInvalidReferralRule
+		}
+		rule.FirstPaymentOnly = true
+	}
+	return rule, nil
+}
+
+func describeRule(lang string, rule *models.ReferralRule) string {
+	key := "referrals.rule_" + rule.Type
+	if rule.FirstPaymentOnly {
+		key += "_first"
+	}
+	return i18n.T(lang, key, rule.Value)
+}
+
+func formatWithdrawal(withdrawal *models.ReferralWithdrawal) string {
+	return fmt.Sprintf("💸 Заявка на вывод #%d\n👤 Пользователь: %d\n💰 Сумма: %.f₽\n💳 Реквизиты: %s\n🕒 Создана: %s",
+		withdrawal.ID, withdrawal.UserID, withdrawal.Amount, withdrawal.Details, withdrawal.CreatedAt.Format("2006-01-02 15:04:05"))
+}
@@ -2,28 +2,35 @@ package handlers
 
 import (
 	"gopkg.in/telebot.v4"
+	"nsvpn/internal/app/callback"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/services"
 	"nsvpn/internal/app/state"
 	"nsvpn/pkg/logger"
 	"strconv"
+	"sync"
 )
 
 type Servers struct {
 	log           *logger.Logger
 	bot           *telebot.Bot
+	cb            *callback.Router
 	kh            *Keys
 	ss            *services.Servers
 	subs          *services.Subscriptions
 	cs            *services.Country
+	mu            sync.RWMutex
 	hashCountries string
 	countriesBtns *services.Buttons
 }
 
-func NewServers(log *logger.Logger, bot *telebot.Bot, ss *services.Servers, subs *services.Subscriptions, kh *Keys, cs *services.Country) *Servers {
+func NewServers(log *logger.Logger, bot *telebot.Bot, cb *callback.Router, ss *services.Servers, subs *services.Subscriptions, kh *Keys, cs *services.Country) *Servers {
 	return &Servers{
 		log:  log,
 		bot:  bot,
+		cb:   cb,
 		kh:   kh,
 		ss:   ss,
 		subs: subs,
@@ -32,24 +39,39 @@ func NewServers(log *logger.Logger, bot *telebot.Bot, ss *services.Servers, subs
 }
 
 func (s *Servers) RegisterHandlers() {
+	s.cb.Handle("country", s.CountryHandler)
+	s.ReloadCountries()
+}
+
+// ReloadCountries пересобирает кнопки стран, вызывается после каждого опроса серверов
+func (s *Servers) ReloadCountries() {
 	countries, err := s.cs.GetAll()
 	if err != nil {
 		s.log.Error("Failed to get countries from db", err)
 		return
 	}
 
-	if hash, err := s.cs.GetHash(countries); err != nil || s.hashCountries == hash {
+	countries = s.getAvailableCountries(countries)
+
+	hash, err := s.cs.GetHash(countries)
+	if err != nil {
 		return
-	} else {
-		s.hashCountries = hash
 	}
 
-	buttons, layout := s.cs.ProcessButtons(countries)
-	s.countriesBtns = services.NewButtons(buttons, layout, "inline")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hashCountries == hash {
+		return
+	}
 
-	for _, btn := range s.countriesBtns.GetBtns() {
-		s.bot.Handle(btn, s.CountryHandler)
+	buttons, layout := s.cs.ProcessButtons(countries)
+	countriesBtns, err := services.NewCallbackButtons(s.cb, buttons, layout)
+	if err != nil {
+		s.log.Error("Failed to create countries buttons", err)
+		return
 	}
+	s.hashCountries = hash
+	s.countriesBtns = countriesBtns
 }
 
 func (s *Servers) ListCountriesHandler(c telebot.Context) error {
@@ -57,30 +79,61 @@ func (s *Servers) ListCountriesHandler(c telebot.Context) error {
 		return err
 	}
 
-	s.RegisterHandlers()
-	return c.Send("✈️ Список доступных стран", s.countriesBtns.AddBtns())
+	s.mu.RLock()
+	countriesBtns := s.countriesBtns
+	s.mu.RUnlock()
+
+	if countriesBtns == nil {
+		return c.Send(tr(c, constants.UserError), getReplyButtons(c))
+	}
+	return c.Send(tr(c, "servers.list"), countriesBtns.AddBtns())
 }
 
-func (s *Servers) CountryHandler(c telebot.Context) error {
+func (s *Servers) CountryHandler(c telebot.Context, args []string) error {
 	btns := getReplyButtons(c)
 	if err := validateSubscription(c, s.subs); err != nil {
 		return err
 	}
 
-	country, err := s.cs.Get(c.Callback().Unique)
-	if err != nil {
-		return c.Send(constants.UserError, btns)
+	if len(args) != 1 {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	country, err := s.cs.Get(args[0])
+	if err != nil || country == nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	if !s.subs.Trials.CountryAllowed(getSubscription(c, s.subs), country.Code) {
+		return c.Send(tr(c, "trials.country_unavailable"), btns)
 	}
 
 	servers, err := s.ss.GetAllByCountryID(country.ID)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	s.kh.KeysState.Set(strconv.FormatInt(c.Sender().ID, 10), state.KeysState{
 		Country: country,
 	})
 
-	msg := s.ss.BuildMessage(country, s.ss.CalculateServerLoad(servers))
-	return c.Edit(msg, s.kh.GetBtns.AddBtns())
+	msg := s.ss.BuildMessage(i18n.FromContext(c), country, s.ss.CalculateServerLoad(servers))
+	return c.Edit(msg, s.kh.GetBtns.Get(i18n.FromContext(c)).AddBtns())
+}
+
+func (s *Servers) getAvailableCountries(countries []*models.Country) []*models.Country {
+	available := make([]*models.Country, 0, len(countries))
+	for _, country := range countries {
+		servers, err := s.ss.GetAllByCountryID(country.ID)
+		if err != nil {
+			s.log.Error("Failed to get servers from db", err)
+			available = append(available, country)
+			continue
+		}
+
+		if s.ss.Statuses.IsCountryDown(servers) {
+			continue
+		}
+		available = append(available, country)
+	}
+	return available
 }
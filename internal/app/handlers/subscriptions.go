@@ -1,45 +1,69 @@
 package handlers
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"gopkg.in/telebot.v4"
+	"nsvpn/internal/app/callback"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/conversation"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/services"
 	"nsvpn/internal/app/state"
 	"nsvpn/pkg/logger"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const flowPurchase = "purchase"
+
 type Subscriptions struct {
 	log                  *logger.Logger
 	bot                  *telebot.Bot
+	cb                   *callback.Router
 	ss                   *services.Subscriptions
 	cs                   *services.Country
 	ps                   *services.Payments
 	us                   *services.Users
 	ph                   *Payments
-	clientButtonsWithSub *services.Buttons
+	conv                 *conversation.Manager
+	clientButtonsWithSub *services.LocalizedButtons
 }
 
-func NewSubscriptions(log *logger.Logger, bot *telebot.Bot, ss *services.Subscriptions,
-	cs *services.Country, ps *services.Payments, us *services.Users, ph *Payments, clientButtonsWithSub *services.Buttons) *Subscriptions {
+func NewSubscriptions(log *logger.Logger, bot *telebot.Bot, cb *callback.Router, ss *services.Subscriptions,
+	cs *services.Country, ps *services.Payments, us *services.Users, ph *Payments, conv *conversation.Manager, clientButtonsWithSub *services.LocalizedButtons) *Subscriptions {
 	return &Subscriptions{
 		log:                  log,
 		bot:                  bot,
+		cb:                   cb,
 		ss:                   ss,
 		cs:                   cs,
 		ps:                   ps,
 		us:                   us,
 		ph:                   ph,
+		conv:                 conv,
 		clientButtonsWithSub: clientButtonsWithSub,
 	}
 }
 
+func (s *Subscriptions) RegisterHandlers() {
+	s.cb.Handle("sub_plan", s.PlanHandler)
+	s.cb.Handle("trial", s.TrialHandler)
+	s.conv.Register(&conversation.Flow{
+		Name:    flowPurchase,
+		Start:   "payment",
+		Timeout: 10 * time.Minute,
+		Steps: map[string]*conversation.Step{
+			"payment": {Handle: s.handlePurchase},
+		},
+		OnTimeout: s.purchaseTimeout,
+		OnCancel:  s.cancelPurchase,
+	})
+}
+
 func (s *Subscriptions) ChooseDurationHandler(c telebot.Context) error {
 	defer func(c telebot.Context) {
 		if err := c.Respond(); err != nil {
@@ -50,54 +74,108 @@ func (s *Subscriptions) ChooseDurationHandler(c telebot.Context) error {
 	btns := getReplyButtons(c)
 	plans, err := s.ss.Plans.GetAll()
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	countries, err := s.cs.GetAll()
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	buttons, layout := s.ss.Plans.ProcessButtons(plans)
-	subBtns := services.NewButtons(buttons, layout, "inline")
-
-	for _, btn := range subBtns.GetBtns() {
-		for _, plan := range plans {
-			if btn.Unique != fmt.Sprintf("sub_plan_%d", plan.ID) {
-				continue
-			}
-
-			s.bot.Handle(btn, func(c telebot.Context) error {
-				return s.AddSubHandler(c, plan)
-			})
-		}
+	if user, ok := c.Get("user").(*models.User); ok && s.ss.Trials.CanOffer(user) {
+		buttons = append([]models.ButtonOption{{Value: "trial", Display: tr(c, "button.trial"), Action: "trial"}}, buttons...)
+		layout = append([]int{1}, layout...)
+	}
+	subBtns, err := services.NewCallbackButtons(s.cb, buttons, layout)
+	if err != nil {
+		s.log.Error("Failed to create plan buttons", err)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	msg := "🔥 Вы оформляете подписку на NSVPN.\n\n🌏 Доступные страны:\n"
+	lang := i18n.FromContext(c)
+	msg := tr(c, "subscriptions.intro")
 	for i, country := range countries {
 		if i == len(countries)-1 {
-			msg += fmt.Sprintf("└ %s %s\n", country.Emoji, country.NameRU)
+			msg += fmt.Sprintf("└ %s %s\n", country.Emoji, countryName(lang, country))
 			break
 		}
-		msg += fmt.Sprintf("├ %s %s\n", country.Emoji, country.NameRU)
+		msg += fmt.Sprintf("├ %s %s\n", country.Emoji, countryName(lang, country))
 	}
 
 	return c.Send(msg, subBtns.AddBtns())
 }
 
+func (s *Subscriptions) PlanHandler(c telebot.Context, args []string) error {
+	btns := getReplyButtons(c)
+	if len(args) != 1 {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	plan, err := s.ss.Plans.GetByID(uint(id))
+	if err != nil || plan == nil {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	return s.AddSubHandler(c, plan)
+}
+
+func (s *Subscriptions) TrialHandler(c telebot.Context, _ []string) error {
+	btns := getReplyButtons(c)
+	if err := c.Respond(); err != nil {
+		s.log.Error("Failed to send message", err)
+	}
+
+	_, err := s.ss.Trials.Grant(c.Sender().ID)
+	switch {
+	case errors.Is(err, constants.ErrTrialUsed):
+		return c.Send(tr(c, "trials.used"), btns)
+	case errors.Is(err, constants.ErrTrialNotEligible):
+		return c.Send(tr(c, "trials.not_eligible"), btns)
+	case errors.Is(err, constants.ErrTrialDisabled):
+		return c.Send(tr(c, "trials.disabled"), btns)
+	case err != nil:
+		s.log.Error("Failed to grant trial", err)
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+
+	countries := tr(c, "trials.all_countries")
+	if list := s.ss.Trials.Countries(); len(list) > 0 {
+		countries = strings.Join(list, ", ")
+	}
+	traffic := tr(c, "trials.unlimited")
+	if limit := s.ss.Trials.TrafficLimit(); limit > 0 {
+		traffic = fmt.Sprintf("%d MB", limit>>20)
+	}
+
+	if err = s.bot.Delete(c.Message()); err != nil {
+		s.log.Warn("Failed to delete message", err)
+	}
+	return c.Send(tr(c, "trials.activated", int(s.ss.Trials.Duration().Hours()), countries, traffic), s.clientButtonsWithSub.Get(i18n.FromContext(c)).AddBtns())
+}
+
 func (s *Subscriptions) AddSubHandler(c telebot.Context, subPlan *models.SubscriptionPlan) error {
 	btns := getReplyButtons(c)
 	startTime := time.Now()
 
 	currentSub, subOk := c.Get("sub").(*models.Subscription)
-	if subOk && currentSub.IsActive && currentSub.EndDate.After(time.Now()) {
+	// пробный период не продлевается: платная подписка начинается сразу
+	if subOk && currentSub.IsTrial {
+		currentSub = nil
+	}
+	if currentSub != nil && currentSub.IsActive && currentSub.EndDate.After(time.Now()) {
 		startTime = currentSub.EndDate
 	}
 
 	sub, err := s.createOrUpdateSubscription(c.Sender().ID, currentSub, subPlan, startTime)
 	if err != nil {
 		s.log.Error("Subscription error", err)
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	if err := c.Respond(); err != nil {
@@ -105,19 +183,20 @@ func (s *Subscriptions) AddSubHandler(c telebot.Context, subPlan *models.Subscri
 	}
 
 	amount := subPlan.SubscriptionPrice.Price
-	if err := s.handlePayment(c, sub, subPlan, amount); err != nil {
-		if errors.Is(err, constants.ErrPaymentTimeExpired) {
-			return c.Send("❌ Время оплаты истекло", btns)
-		}
-
+	note := "Покупка подписки на " + subPlan.Name
+	err = s.balancePayment(c.Sender().ID, sub.ID, note, amount)
+	switch {
+	case errors.Is(err, constants.ErrInsufficientFunds):
+		return s.startPurchase(c, sub, subPlan.ID, note, amount)
+	case err != nil:
 		s.log.Error("Payment error", err)
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	if err := s.bot.Delete(c.Message()); err != nil {
 		s.log.Warn("Failed to delete message", err)
 	}
-	return c.Send("✅ Подписка активирована!", s.clientButtonsWithSub.AddBtns())
+	return c.Send(tr(c, "subscriptions.activated"), s.clientButtonsWithSub.Get(i18n.FromContext(c)).AddBtns())
 }
 
 func (s *Subscriptions) createOrUpdateSubscription(userID int64, currentSub *models.Subscription, subPlan *models.SubscriptionPlan, startTime time.Time) (*models.Subscription, error) {
@@ -145,86 +224,97 @@ func (s *Subscriptions) createOrUpdateSubscription(userID int64, currentSub *mod
 	return sub, nil
 }
 
-func (s *Subscriptions) handlePayment(c telebot.Context, sub *models.Subscription, subPlan *models.SubscriptionPlan, amount float64) error {
-	if err := s.balancePayment(c, c.Sender().ID, sub, subPlan, amount); err == nil {
-		return nil
-	} else if !errors.Is(err, constants.ErrInsufficientFunds) {
-		s.log.Error("Payment error", err)
-		return err
-	}
-
-	return s.handleExternalPayment(c, c.Sender().ID, sub, subPlan, amount)
-}
-
-func (s *Subscriptions) balancePayment(c telebot.Context, userID int64, sub *models.Subscription, subPlan *models.SubscriptionPlan, amount float64) error {
+func (s *Subscriptions) balancePayment(userID int64, subID uint, note string, amount float64) error {
 	if err := s.us.DecrementBalance(userID, amount); err != nil {
-		s.log.Error("Payment error", err)
 		return err
 	}
 
 	err := s.ps.Add(&models.Payment{
-		UserID:      c.Sender().ID,
+		UserID:      userID,
 		Amount:      amount,
 		Type:        "expense",
 		Payload:     uuid.New().String(),
-		Note:        "Покупка подписки на " + subPlan.Name,
+		Note:        note,
 		IsCompleted: true,
 	})
 	if err != nil {
 		return err
 	}
 
-	if err := s.ss.UpdateIsActive(sub.ID, userID, true); err != nil {
+	if err := s.ss.UpdateIsActive(subID, userID, true); err != nil {
 		if compErr := s.us.IncrementBalance(userID, amount); compErr != nil {
 			s.log.Error("Balance compensation failed", compErr)
 		}
 		return err
 	}
 
+	if err := s.ss.Trials.End(userID, subID); err != nil {
+		s.log.Error("Failed to end trial", err)
+	}
 	return nil
 }
 
-func (s *Subscriptions) handleExternalPayment(c telebot.Context, userID int64, sub *models.Subscription, subPlan *models.SubscriptionPlan, amount float64) error {
+func (s *Subscriptions) startPurchase(c telebot.Context, sub *models.Subscription, planID uint, note string, amount float64) error {
+	btns := getReplyButtons(c)
 	paymentID, err := uuid.NewUUID()
 	if err != nil {
 		s.log.Error("UUID generation failed", err)
-		return err
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
+	s.ph.ResetState(strconv.FormatInt(c.Sender().ID, 10))
 	s.ph.PaymentsState.Set(strconv.FormatInt(c.Sender().ID, 10), state.PaymentsState{
 		Amount:            amount,
 		Payload:           paymentID.String(),
-		Note:              "Покупка подписки на " + subPlan.Name,
+		Note:              note,
+		PlanID:            planID,
 		IsBuySubscription: true,
 	})
-	if err := s.ph.ChooseCurrencyHandler(c); err != nil {
-		return fmt.Errorf("payment init failed: %w", err)
+
+	err = s.conv.Start(c, flowPurchase, map[string]string{
+		"payment_id": paymentID.String(),
+		"sub_id":     strconv.FormatUint(uint64(sub.ID), 10),
+		"amount":     strconv.FormatFloat(amount, 'f', -1, 64),
+		"note":       note,
+	})
+	if err != nil {
+		s.log.Error("Failed to start purchase", err)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
-	return s.waitForPaymentConfirmation(c, userID, sub, subPlan, amount, paymentID.String())
+	return s.ph.ChooseCurrencyHandler(c)
 }
 
-func (s *Subscriptions) waitForPaymentConfirmation(c telebot.Context, userID int64, sub *models.Subscription, subPlan *models.SubscriptionPlan, amount float64, paymentID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return constants.ErrPaymentTimeExpired
-		case <-ticker.C:
-			payment, err := s.ps.Get(userID, paymentID)
-			if err != nil {
-				s.log.Error("Payment status check failed", err)
-				continue
-			}
-
-			if payment != nil && payment.IsCompleted {
-				return s.balancePayment(c, userID, sub, subPlan, amount)
-			}
-		}
+func (s *Subscriptions) handlePurchase(c telebot.Context, sess *conversation.Session) (string, error) {
+	btns := getReplyButtons(c)
+	userID := c.Sender().ID
+
+	payment, err := s.ps.Get(userID, sess.Data["payment_id"])
+	if err != nil {
+		s.log.Error("Payment status check failed", err)
+		return sess.Step, c.Send(tr(c, constants.UserError), btns)
+	}
+	if payment == nil || !payment.IsCompleted {
+		return sess.Step, c.Send(tr(c, "subscriptions.waiting_payment"), btns)
+	}
+
+	subID, _ := strconv.ParseUint(sess.Data["sub_id"], 10, 64)
+	amount, _ := strconv.ParseFloat(sess.Data["amount"], 64)
+	if err = s.balancePayment(userID, uint(subID), sess.Data["note"], amount); err != nil {
+		s.log.Error("Payment error", err)
+		return conversation.Done, c.Send(tr(c, constants.UserError), btns)
 	}
+
+	return conversation.Done, c.Send(tr(c, "subscriptions.activated"), s.clientButtonsWithSub.Get(i18n.FromContext(c)).AddBtns())
+}
+
+func (s *Subscriptions) purchaseTimeout(to telebot.Recipient, sess *conversation.Session) error {
+	s.ph.ResetState(to.Recipient())
+	_, err := s.bot.Send(to, i18n.T(sess.Lang, "subscriptions.payment_timeout"))
+	return err
+}
+
+func (s *Subscriptions) cancelPurchase(c telebot.Context, _ *conversation.Session) error {
+	s.ph.ResetState(strconv.FormatInt(c.Sender().ID, 10))
+	return nil
 }
@@ -1,10 +1,12 @@
 package handlers
 
 import (
-	"fmt"
 	"github.com/google/uuid"
 	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/callback"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/services"
 	"nsvpn/internal/app/state"
@@ -19,70 +21,135 @@ type Users struct {
 	us  *services.Users
 	ss  *Subscriptions
 	ph  *Payments
+	rh  *Referrals
+	gh  *Gifts
+
+	profileBtns        *services.LocalizedButtons
+	profileBtnsWithSub *services.LocalizedButtons
+	languageBtns       *services.Buttons
+	clientButtons      *services.LocalizedButtons
+	clientButtonsSub   *services.LocalizedButtons
 }
 
-func NewUsers(log *logger.Logger, bot *telebot.Bot, us *services.Users, ss *Subscriptions, ph *Payments) *Users {
-	return &Users{
+func NewUsers(log *logger.Logger, bot *telebot.Bot, cb *callback.Router, us *services.Users, ss *Subscriptions, ph *Payments, rh *Referrals, gh *Gifts, clientButtons, clientButtonsWithSub *services.LocalizedButtons) *Users {
+	languageBtns, err := services.NewCallbackButtons(cb, []models.ButtonOption{
+		{Value: i18n.RU, Display: "🇷🇺 Русский", Action: "lang", Args: []string{i18n.RU}},
+		{Value: i18n.EN, Display: "🇬🇧 English", Action: "lang", Args: []string{i18n.EN}},
+	}, []int{2})
+	if err != nil {
+		log.Error("Failed to create language buttons", err)
+	}
+
+	u := &Users{
 		log: log,
 		bot: bot,
 		us:  us,
 		ss:  ss,
 		ph:  ph,
+		rh:  rh,
+		gh:  gh,
+
+		profileBtns: services.NewLocalizedButtons([]models.ButtonOption{
+			{Value: "top_balance", Display: "💸 Пополнить баланс"},
+			{Value: "history_payments", Display: "🧾 История платежей"},
+			{Value: "referrals", Display: "🤝 Рефералы"},
+			{Value: "gift", Display: "🎁 Подарить подписку"},
+			{Value: "language", Display: "🌐 Язык / Language"},
+		}, []int{1, 1, 1, 1, 1}, "inline"),
+		profileBtnsWithSub: services.NewLocalizedButtons([]models.ButtonOption{
+			{Value: "top_balance", Display: "💸 Пополнить баланс"},
+			{Value: "extend_sub", Display: "⏳ Продлить подписку"},
+			{Value: "history_payments", Display: "🧾 История платежей"},
+			{Value: "referrals", Display: "🤝 Рефералы"},
+			{Value: "gift", Display: "🎁 Подарить подписку"},
+			{Value: "language", Display: "🌐 Язык / Language"},
+		}, []int{1, 1, 1, 1, 1, 1}, "inline"),
+		languageBtns:     languageBtns,
+		clientButtons:    clientButtons,
+		clientButtonsSub: clientButtonsWithSub,
 	}
+	cb.Handle("lang", u.ChangeLanguageHandler)
+	return u
 }
 
-func (u *Users) ProfileHandler(c telebot.Context) error {
-	btns := getReplyButtons(c)
-	user, userOk := c.Get("user").(*models.User)
-	if !userOk {
-		return c.Send(constants.UserError, btns)
+func (u *Users) RegisterHandlers() {
+	u.bot.Handle(u.profileBtnsWithSub.GetBtn("top_balance"), u.TopBalanceHandler)
+	u.bot.Handle(u.profileBtnsWithSub.GetBtn("extend_sub"), u.ss.ChooseDurationHandler)
+	u.bot.Handle(u.profileBtnsWithSub.GetBtn("history_payments"), u.ph.PaginationHandler("first"))
+	u.bot.Handle(u.profileBtnsWithSub.GetBtn("referrals"), u.rh.ReferralsHandler)
+	u.bot.Handle(u.profileBtnsWithSub.GetBtn("gift"), u.gh.ChoosePlanHandler)
+	u.bot.Handle(u.profileBtnsWithSub.GetBtn("language"), u.LanguageHandler)
+	u.bot.Handle("/language", u.LanguageHandler)
+}
+
+func (u *Users) LanguageHandler(c telebot.Context) error {
+	defer func() {
+		if c.Callback() != nil {
+			_ = c.Respond()
+		}
+	}()
+
+	if u.languageBtns == nil {
+		return c.Send(tr(c, constants.UserError), getReplyButtons(c))
 	}
-	sub, subOk := c.Get("sub").(*models.Subscription)
+	return c.Send(tr(c, "language.choose"), u.languageBtns.AddBtns())
+}
 
-	subMsg := "🎟️ *Статус подписки*: неактивно ❌"
-	btnOpts := []models.ButtonOption{
-		{
-			Value:   "top_balance",
-			Display: "💸 Пополнить баланс",
-		},
+func (u *Users) ChangeLanguageHandler(c telebot.Context, args []string) error {
+	defer func() { _ = c.Respond() }()
+
+	if len(args) != 1 || !i18n.IsSupported(args[0]) {
+		return c.Send(tr(c, constants.UserError), getReplyButtons(c))
 	}
-	layout := []int{1}
-	if subOk && sub != nil && sub.EndDate.After(time.Now().UTC()) && sub.IsActive {
-		subMsg = "🎟️ *Статус подписки*: активно ✅\n📅 *Срок окончания*: " + sub.EndDate.Format("02-01-2006 15:04:05")
-		btnOpts = append(btnOpts, models.ButtonOption{
-			Value:   "extend_sub",
-			Display: "⏳ Продлить подписку",
-		})
-		layout = append(layout, 1)
+	lang := args[0]
+
+	if err := u.us.UpdateLanguage(c.Sender().ID, lang); err != nil {
+		u.log.Error("Failed to update user language", err, slog.Int64("user_id", c.Sender().ID), slog.String("language", lang))
+		return c.Send(tr(c, constants.UserError), getReplyButtons(c))
 	}
-	btnOpts = append(btnOpts, models.ButtonOption{
-		Value:   "history_payments",
-		Display: "🧾 История платежей",
-	})
-	layout = append(layout, 1)
+	i18n.SetContext(c, lang)
 
-	balanceBtns := services.NewButtons(btnOpts, layout, "inline")
-	u.bot.Handle(balanceBtns.GetBtn("top_balance"), func(c telebot.Context) error {
-		u.ph.PaymentsState.Set(strconv.FormatInt(c.Sender().ID, 10), state.PaymentsState{
-			Payload:     uuid.New().String(),
-			Description: "Пополнение баланса",
-			Note:        "Пополнение баланса",
-		})
+	btns := u.clientButtons.Get(lang)
+	if sub, ok := c.Get("sub").(*models.Subscription); ok && sub != nil && sub.IsActive {
+		btns = u.clientButtonsSub.Get(lang)
+	}
+	return c.Send(i18n.T(lang, "language.changed"), btns.AddBtns())
+}
 
-		return u.ph.RequestAmount(c)
+func (u *Users) TopBalanceHandler(c telebot.Context) error {
+	u.ph.ResetState(strconv.FormatInt(c.Sender().ID, 10))
+	u.ph.PaymentsState.Set(strconv.FormatInt(c.Sender().ID, 10), state.PaymentsState{
+		Payload:     uuid.New().String(),
+		Description: "Пополнение баланса",
+		Note:        "Пополнение баланса",
 	})
+
+	return u.ph.RequestAmount(c)
+}
+
+func (u *Users) ProfileHandler(c telebot.Context) error {
+	btns := getReplyButtons(c)
+	user, userOk := c.Get("user").(*models.User)
+	if !userOk {
+		return c.Send(tr(c, constants.UserError), btns)
+	}
+	sub, subOk := c.Get("sub").(*models.Subscription)
+
+	lang := i18n.FromContext(c)
+	subMsg := tr(c, "users.sub_inactive")
+	balanceBtns := u.profileBtns.Get(lang)
 	if subOk && sub != nil && sub.EndDate.After(time.Now().UTC()) && sub.IsActive {
-		u.bot.Handle(balanceBtns.GetBtn("extend_sub"), u.ss.ChooseDurationHandler)
+		subMsg = tr(c, "users.sub_active", sub.EndDate.Format("02-01-2006 15:04:05"))
+		balanceBtns = u.profileBtnsWithSub.Get(lang)
 	}
-	u.bot.Handle(balanceBtns.GetBtn("history_payments"), u.ph.PaginationHandler("first"))
 
 	partners, err := u.us.CountPartners(c.Sender().ID)
 	if err != nil {
-		return c.Send(constants.UserError, btns)
+		return c.Send(tr(c, constants.UserError), btns)
 	}
 
 	return c.Send(
-		fmt.Sprintf("👔 *Ваш профиль*:\n\n🙎🏻 *Имя:* %s\n🆔 *ID:* %d\n\n💰 *Баланс*: %0.f₽\n🤝 *Кол-во рефералов*: %d чел.\n\n%s", c.Sender().FirstName, c.Sender().ID, user.Balance, partners, subMsg),
+		tr(c, "users.profile", c.Sender().FirstName, c.Sender().ID, user.Balance, partners, u.rh.Link(c.Sender().ID), subMsg),
 		&telebot.SendOptions{
 			ReplyMarkup: balanceBtns.AddBtns(),
 			ParseMode:   telebot.ModeMarkdown,
@@ -3,6 +3,7 @@ package handlers
 import (
 	"gopkg.in/telebot.v4"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/services"
 	"time"
@@ -15,6 +16,17 @@ func getReplyButtons(c telebot.Context) *telebot.ReplyMarkup {
 	return &telebot.ReplyMarkup{}
 }
 
+func tr(c telebot.Context, key string, args ...any) string {
+	return i18n.T(i18n.FromContext(c), key, args...)
+}
+
+func countryName(lang string, country *models.Country) string {
+	if lang == i18n.EN && country.NameEN != "" {
+		return country.NameEN
+	}
+	return country.NameRU
+}
+
 func getUser(c telebot.Context, us *services.Users) *models.User {
 	if user, ok := c.Get("user").(*models.User); ok {
 		return user
@@ -45,12 +57,12 @@ func validateSubscription(c telebot.Context, ss *services.Subscriptions) error {
 		var err error
 		sub, err = ss.GetLastByUserID(c.Sender().ID, true)
 		if err != nil {
-			return c.Send(constants.UserHasNoRights, getReplyButtons(c))
+			return c.Send(tr(c, constants.UserHasNoRights), getReplyButtons(c))
 		}
 	}
 
 	if !sub.IsActive && sub.EndDate.Before(time.Now()) && (!sub.EndDate.IsZero() || sub.ID == 0) {
-		return c.Send(constants.UserHasNoRights, getReplyButtons(c))
+		return c.Send(tr(c, constants.UserHasNoRights), getReplyButtons(c))
 	}
 	return nil
 }
@@ -0,0 +1,159 @@
+package i18n
+
+var en = map[string]string{
+	"error.internal":  "❌ Oops! Something went wrong. Please try again later or contact support",
+	"error.no_rights": "❌ You are not allowed to use this command",
+
+	"button.accept_offer":       "✅ Accept the terms",
+	"button.attach_vpn":         "🔒 Get VPN",
+	"button.profile":            "👔 Profile",
+	"button.technical_support":  "💬 Support",
+	"button.info":               "💡 Information",
+	"button.list_servers":       "🌐 Server list",
+	"button.get_key":            "📥 Get key",
+	"button.update_key":         "🔄 Update key",
+	"button.choose_transport":   "🔀 Change protocol",
+	"button.pay_bankcard":       "💳 Bank card/SBP",
+	"button.pay_stars":          "⭐ Telegram Stars",
+	"button.pay_cryptocurrency": "💎 Cryptocurrency",
+	"button.proceed_payment":    "Proceed to payment",
+	"button.tech_support":       "Support",
+	"button.skip_promocode":     "Skip",
+	"button.top_balance":        "💸 Top up balance",
+	"button.extend_sub":         "⏳ Extend subscription",
+	"button.history_payments":   "🧾 Payment history",
+	"button.language":           "🌐 Язык / Language",
+	"button.referrals":          "🤝 Referrals",
+	"button.withdraw":           "💸 Withdraw",
+	"button.referral_history":   "🧾 Earnings",
+	"button.trial":              "🎁 Free trial",
+	"button.gift":               "🎁 Gift a subscription",
+
+	"base.welcome":         "👋 Welcome, %s!",
+	"base.unknown_command": "🤔 Unknown command. Use /help to see the list of commands",
+	"base.info":            "💡 Information",
+	"base.support":         "💬 If you have any questions or problems, please contact our support team",
+	"base.offer":           "To start using NSVPN, please accept the terms of the public [offer](https://teletype.in/@nsvpn/Dpvwcj7llQx).",
+
+	"language.choose":  "🌐 Choose your language:",
+	"language.changed": "✅ Interface language: English",
+
+	"conversation.cancelled":         "❌ Action cancelled",
+	"conversation.nothing_to_cancel": "🤷 Nothing to cancel",
+	"callback.expired":               "⌛ This button has expired, please open the menu again",
+
+	"keys.key":              "🔑 Your key for server %s %s (%s):\n```%s```",
+	"keys.new_key":          "🔑 Your new key for server %s %s (%s):\n```%s```",
+	"keys.choose_transport": "🔀 Choose a connection protocol. If the key does not work on your network, try another one:",
+	"keys.moved":            "🔄 Your server %s %s is overloaded or unavailable, so we moved you to another one. New key (%s):\n```%s```",
+	"keys.reality_rotated":  "🔐 We are rotating the encryption keys of server %s %s. The old key will stop working on %s, please replace it with the new one (%s):\n```%s```",
+
+	"servers.list":          "✈️ Available countries",
+	"servers.load":          "%s %s\n🎛 Server load: %s\n\n",
+	"servers.load_low":      "low 🟢",
+	"servers.load_medium":   "medium 🌕",
+	"servers.load_high":     "high 🟠",
+	"servers.load_critical": "critical 🔴",
+	"servers.load_down":     "not responding 🔴",
+
+	"payments.enter_amount":      "💳 Enter the amount in RUB:",
+	"payments.invalid_amount":    "❌ Invalid amount, please try again",
+	"payments.amount_timeout":    "⌛ Time to enter the amount has expired",
+	"payments.promocode_timeout": "⌛ Time to enter the promo code has expired",
+	"payments.balance":           "💰 Your current balance: %.f RUB\n",
+	"payments.choose_method":     "💵 Amount to pay: %.f RUB\n📦 Payment number: %s\n\nChoose a payment method:",
+	"payments.method_bankcard":   "Bank card/SBP",
+	"payments.method_crypto":     "Cryptocurrency",
+	"payments.method_stars":      "Telegram Stars",
+	"payments.invoice_title":     "Balance top-up",
+	"payments.invoice_label":     "To pay",
+	"payments.invoice":           "🧾 The invoice has been created.\n\n💸 Price: %.0f RUB\n💳 Payment method: %s\n📦 Payment number: %s\n\nPlease pay before %s. If you have any problems, feel free to contact our support",
+	"payments.success":           "✅ Payment completed successfully!",
+	"payments.history_empty":     "🧾 You have no paid payments yet",
+	"payments.history_title":     "🧾 Payment history (page %d of %d):\n",
+
+	"promocodes.enter":          "💳 Enter a promo code:",
+	"promocodes.not_found":      "❌ Promo code not found",
+	"promocodes.limit":          "❌ The promo code has reached its activation limit",
+	"promocodes.only_new":       "❌ The promo code is only available to new users",
+	"promocodes.expired":        "❌ The promo code has expired",
+	"promocodes.already_used":   "❌ The promo code has already been used",
+	"promocodes.applied":        "✅ Promo code applied, discount %.f₽",
+	"promocodes.not_applicable": "❌ The promo code does not apply to this plan or payment method",
+	"promocodes.use_command":    "❌ This promo code is not a discount. Activate it with /promo CODE",
+	"promocodes.use_payment":    "❌ This promo code is a discount and is applied during payment",
+	"promocodes.enter_command":  "Send the promo code with /promo CODE",
+	"promocodes.days_added":     "✅ Promo code activated, %d days of subscription added",
+	"promocodes.balance_added":  "✅ Promo code activated, %.f₽ added to your balance",
+
+	"subscriptions.intro":           "🔥 You are subscribing to NSVPN.\n\n🌏 Available countries:\n",
+	"subscriptions.activated":       "✅ Subscription activated!",
+	"subscriptions.waiting_payment": "⏳ Waiting for the subscription payment. Use /cancel to cancel",
+	"subscriptions.payment_timeout": "❌ Payment time has expired",
+	"subscriptions.renewed":         "Your subscription has been renewed",
+	"subscriptions.expires_at":      "Your subscription expires at %s",
+	"subscriptions.expired":         "Your subscription has expired",
+
+	"trials.activated":           "🎁 Free trial activated!\n\n⏳ Duration: %d h\n🌏 Countries: %s\n📶 Traffic: %s",
+	"trials.all_countries":       "all",
+	"trials.unlimited":           "unlimited",
+	"trials.used":                "❌ You have already used the free trial",
+	"trials.not_eligible":        "❌ The free trial is only available to new users",
+	"trials.disabled":            "❌ The free trial is currently unavailable",
+	"trials.country_unavailable": "❌ This country is not available during the free trial. Subscribe to connect",
+	"trials.ends_at":             "⏳ Your free trial ends at %s. Subscribe to keep your access",
+	"trials.expired":             "Your free trial has ended. Subscribe to keep using the VPN",
+	"trials.traffic_exceeded":    "📶 Your free trial traffic is used up. Subscribe to keep using the VPN",
+
+	"gifts.choose_plan":      "🎁 Choose the plan you want to gift. After payment you will get a code and a link for the recipient",
+	"gifts.purchased":        "🎁 Gift subscription \"%s\" is paid!\n\n🔑 Code: `%s`\n🔗 Link: `%s`\n\nForward the link to the recipient or ask them to send the bot /redeem %s",
+	"gifts.list_empty":       "You have not bought any gifts yet",
+	"gifts.list_title":       "🎁 *Your gifts*:\n\n",
+	"gifts.list_item":        "%d) `%s` — %d days — %s\n",
+	"gifts.status_active":    "not redeemed",
+	"gifts.status_redeemed":  "redeemed %s",
+	"gifts.enter_code":       "Send the gift code with /redeem CODE",
+	"gifts.not_found":        "❌ No gift found with this code",
+	"gifts.already_redeemed": "❌ This gift has already been redeemed",
+	"gifts.buyer_notice":     "🎁 Your gift %s was redeemed by %s",
+	"gifts.redeemed":         "🎁 Gift redeemed! %d days of subscription were added, it is valid until %s",
+
+	"users.profile":      "👔 *Your profile*:\n\n🙎🏻 *Name:* %s\n🆔 *ID:* %d\n\n💰 *Balance*: %0.f₽\n🤝 *Referrals*: %d\n🔗 *Referral link*: `%s`\n\n%s",
+	"users.sub_inactive": "🎟️ *Subscription*: inactive ❌",
+	"users.sub_active":   "🎟️ *Subscription*: active ✅\n📅 *Expires*: %s",
+
+	"referrals.info":                    "🤝 *Referral program*\n\n🔗 Your link: `%s`\n👥 Invited: %d\n💰 Earned in total: %.f₽\n💸 Available to withdraw: %.f₽\n\n🎁 Rewards:\n",
+	"referrals.tier":                    "• from %d referrals: %s\n",
+	"referrals.rule_percent":            "%.f%% of every top-up",
+	"referrals.rule_percent_first":      "%.f%% of the first top-up",
+	"referrals.rule_fixed":              "%.f₽ for every top-up",
+	"referrals.rule_fixed_first":        "%.f₽ for the first top-up",
+	"referrals.reward":                  "🎁 Your referral has topped up the balance, you received %.f₽",
+	"referrals.history_empty":           "🧾 No earnings yet",
+	"referrals.history_title":           "🧾 Latest earnings:\n",
+	"referrals.history_item":            "%d) +%.f₽ — %s (top-up of %.f₽)\n",
+	"referrals.withdrawal_unavailable":  "💸 The minimum withdrawal is %.f₽, you have %.f₽ available",
+	"referrals.enter_amount":            "💸 Enter the withdrawal amount (from %.f₽, %.f₽ available):",
+	"referrals.withdrawal_too_small":    "❌ The minimum withdrawal is %.f₽",
+	"referrals.withdrawal_insufficient": "❌ Insufficient funds, %.f₽ available",
+	"referrals.enter_details":           "💳 Enter the payout details (card number or phone number for SBP and the bank):",
+	"referrals.withdrawal_created":      "✅ Withdrawal request #%d has been created. We will let you know once it is processed",
+	"referrals.withdrawal_timeout":      "⌛ Time to create the withdrawal request has expired",
+	"referrals.withdrawal_approved":     "✅ Withdrawal request #%d has been paid: %.f₽",
+	"referrals.withdrawal_rejected":     "❌ Withdrawal request #%d has been rejected, %.f₽ returned to your balance",
+
+	"admin.server_down":            "🔴 Server %s is unreachable\nError: %s",
+	"admin.server_overloaded":      "🟠 Server %s is overloaded\nLoad: %.0f%%",
+	"admin.server_recovered":       "🟢 Server %s is reachable again\nLatency: %d ms",
+	"admin.server_load_normal":     "🟢 Load on server %s is back to normal\nLoad: %.0f%%",
+	"admin.server_load_threshold":  "🟠 Server %s reported exceeding the load threshold\nLoad: %.0f%%",
+	"admin.server_config_reloaded": "⚙️ Server %s reloaded its configuration\n%s",
+	"admin.reconcile_dry_run":      "🔍 Drift check (no changes made)\n\n",
+	"admin.reconcile_applied":      "🛠 Node sync completed\n\n",
+	"admin.reconcile_server":       "%s: ➕ %d, ➖ %d, 🔄 %d\n",
+	"admin.reconcile_server_down":  "%s: ❌ node is unreachable\n",
+	"admin.reconcile_unassigned":   "\nKeys without an assigned server: %d\n",
+	"admin.reality_usage":          "Specify a country code, for example: /rotate_reality NL",
+	"admin.reality_overlap":        "⏳ %s nodes are still serving keys from the previous rotation, retry after the overlap ends",
+	"admin.reality_rotated":        "✅ Reality keys for %s have been rotated, the old ones stay valid for %s. Users queued for notification: %d",
+}
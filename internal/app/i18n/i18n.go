@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"fmt"
+	"gopkg.in/telebot.v4"
+	"strings"
+)
+
+const (
+	RU = "ru"
+	EN = "en"
+
+	Default = RU
+
+	contextKey = "lang"
+)
+
+var catalogs = map[string]map[string]string{
+	RU: ru,
+	EN: en,
+}
+
+// языки, пользователи которых скорее читают по-русски, чем по-английски
+var russianSpeaking = map[string]struct{}{
+	"ru": {}, "uk": {}, "be": {}, "kk": {}, "uz": {}, "ky": {}, "tg": {}, "hy": {}, "az": {},
+}
+
+func Supported() []string {
+	return []string{RU, EN}
+}
+
+func IsSupported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+func Parse(code string) string {
+	if code == "" {
+		return Default
+	}
+
+	base, _, _ := strings.Cut(strings.ToLower(code), "-")
+	if IsSupported(base) {
+		return base
+	}
+	if _, ok := russianSpeaking[base]; ok {
+		return RU
+	}
+	return EN
+}
+
+func Resolve(override, code string) string {
+	if IsSupported(override) {
+		return override
+	}
+	return Parse(code)
+}
+
+func Has(lang, key string) bool {
+	_, ok := catalogs[lang][key]
+	return ok
+}
+
+func T(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		if msg, ok = catalogs[Default][key]; !ok {
+			return key
+		}
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+func Labels(key string) []string {
+	seen := make(map[string]struct{})
+	labels := make([]string, 0, len(catalogs))
+	for _, lang := range Supported() {
+		label, ok := catalogs[lang][key]
+		if _, dup := seen[label]; !ok || dup {
+			continue
+		}
+		seen[label] = struct{}{}
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+func SetContext(c telebot.Context, lang string) {
+	c.Set(contextKey, lang)
+}
+
+func FromContext(c telebot.Context) string {
+	if lang, ok := c.Get(contextKey).(string); ok && lang != "" {
+		return lang
+	}
+	if sender := c.Sender(); sender != nil {
+		return Parse(sender.LanguageCode)
+	}
+	return Default
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"":      Default,
+		"ru":    RU,
+		"en":    EN,
+		"en-US": EN,
+		"uk":    RU,
+		"kk":    RU,
+		"de":    EN,
+		"PT-BR": EN,
+	}
+	for code, want := range tests {
+		if got := Parse(code); got != want {
+			t.Errorf("Parse(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if got := Resolve(EN, "ru"); got != EN {
+		t.Fatalf("override ignored: got %q", got)
+	}
+	if got := Resolve("", "en-GB"); got != EN {
+		t.Fatalf("telegram language ignored: got %q", got)
+	}
+	if got := Resolve("fr", "ru"); got != RU {
+		t.Fatalf("unsupported override not ignored: got %q", got)
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T(EN, "base.welcome", "Bob"); got != "👋 Welcome, Bob!" {
+		t.Fatalf("got %q", got)
+	}
+	if got := T("fr", "base.welcome", "Боб"); got != "👋 Добро пожаловать, Боб!" {
+		t.Fatalf("no fallback to default language: got %q", got)
+	}
+	if got := T(EN, "missing.key"); got != "missing.key" {
+		t.Fatalf("no fallback to key: got %q", got)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	if labels := Labels("button.profile"); len(labels) != 2 {
+		t.Fatalf("got %v", labels)
+	}
+	if labels := Labels("button.language"); len(labels) != 1 {
+		t.Fatalf("duplicate labels not merged: %v", labels)
+	}
+}
+
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	for _, lang := range Supported() {
+		for key := range catalogs[Default] {
+			if !Has(lang, key) {
+				t.Errorf("%s: missing key %q", lang, key)
+			}
+		}
+		for key := range catalogs[lang] {
+			if !Has(Default, key) {
+				t.Errorf("%s: unknown key %q", lang, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package i18n
+
+var ru = map[string]string{
+	"error.internal":  "❌ Упс! Что-то сломалось. Повторите попытку чуть позже или обратитесь в службу поддержки",
+	"error.no_rights": "❌ У вас нет прав на выполнение данной команды",
+
+	"button.accept_offer":       "✅ Принять условия",
+	"button.attach_vpn":         "🔒 Подключить VPN",
+	"button.profile":            "👔 Профиль",
+	"button.technical_support":  "💬 Техподдержка",
+	"button.info":               "💡 Информация",
+	"button.list_servers":       "🌐 Список серверов",
+	"button.get_key":            "📥 Получить ключ",
+	"button.update_key":         "🔄 Обновить ключ",
+	"button.choose_transport":   "🔀 Сменить протокол",
+	"button.pay_bankcard":       "💳 Банковская карта/СБП",
+	"button.pay_stars":          "⭐ Telegram Stars",
+	"button.pay_cryptocurrency": "💎 Криптовалюта",
+	"button.proceed_payment":    "Перейти к оплате",
+	"button.tech_support":       "Техподдержка",
+	"button.skip_promocode":     "Пропустить",
+	"button.top_balance":        "💸 Пополнить баланс",
+	"button.extend_sub":         "⏳ Продлить подписку",
+	"button.history_payments":   "🧾 История платежей",
+	"button.language":           "🌐 Язык / Language",
+	"button.referrals":          "🤝 Рефералы",
+	"button.withdraw":           "💸 Вывести",
+	"button.referral_history":   "🧾 Начисления",
+	"button.trial":              "🎁 Пробный период",
+	"button.gift":               "🎁 Подарить подписку",
+
+	"base.welcome":         "👋 Добро пожаловать, %s!",
+	"base.unknown_command": "🤔 Неизвестная команда. Используйте /help для получения списка команд",
+	"base.info":            "💡 Информация",
+	"base.support":         "💬 Если у вас возникли какие-либо вопросы или проблемы, обратитесь в нашу техподдержку",
+	"base.offer":           "Чтобы начать пользоваться NSVPN, необходимо принять условия публичной [оферты](https://teletype.in/@nsvpn/Dpvwcj7llQx).",
+
+	"language.choose":  "🌐 Выберите язык:",
+	"language.changed": "✅ Язык интерфейса: русский",
+
+	"conversation.cancelled":         "❌ Действие отменено",
+	"conversation.nothing_to_cancel": "🤷 Нечего отменять",
+	"callback.expired":               "⌛ Кнопка устарела, откройте меню заново",
+
+	"keys.key":              "🔑 Ваш ключ для сервера %s %s (%s):\n```%s```",
+	"keys.new_key":          "🔑 Ваш новый ключ для сервера %s %s (%s):\n```%s```",
+	"keys.choose_transport": "🔀 Выберите протокол подключения. Если ключ не работает в вашей сети, попробуйте другой вариант:",
+	"keys.moved":            "🔄 Ваш сервер %s %s перегружен или недоступен, мы перенесли вас на другой. Новый ключ (%s):\n```%s```",
+	"keys.reality_rotated":  "🔐 Мы обновляем ключи шифрования сервера %s %s. Старый ключ перестанет работать %s, замените его на новый (%s):\n```%s```",
+
+	"servers.list":          "✈️ Список доступных стран",
+	"servers.load":          "%s %s\n🎛 Нагрузка на сервер: %s\n\n",
+	"servers.load_low":      "низкая 🟢",
+	"servers.load_medium":   "средняя 🌕",
+	"servers.load_high":     "высокая 🟠",
+	"servers.load_critical": "критическая 🔴",
+	"servers.load_down":     "не отвечает 🔴",
+
+	"payments.enter_amount":      "💳 Введите сумму в RUB:",
+	"payments.invalid_amount":    "❌ Некорректная сумма, попробуйте ещё раз",
+	"payments.amount_timeout":    "⌛ Время ввода суммы истекло",
+	"payments.promocode_timeout": "⌛ Время ввода промокода истекло",
+	"payments.balance":           "💰 Ваш текущий баланс: %.f RUB\n",
+	"payments.choose_method":     "💵 Сумма к оплате: %.f RUB\n📦 Номер платежа: %s\n\nВыберите удобный для Вас способ оплаты:",
+	"payments.method_bankcard":   "Банковская карта/СБП",
+	"payments.method_crypto":     "Криптовалюта",
+	"payments.method_stars":      "Telegram Stars",
+	"payments.invoice_title":     "Пополнение баланса",
+	"payments.invoice_label":     "К оплате",
+	"payments.invoice":           "🧾 Счет на оплату подписки успешно создан.\n\n💸 Стоимость: %.0f руб.\n💳 Метод оплаты: %s\n📦 Номер платежа: %s\n\nВам нужно оплатить счет до %s. При возникновении каких-либо проблем не стесняйтесь обращаться в нашу поддержку",
+	"payments.success":           "✅ Платёж успешно завершен!",
+	"payments.history_empty":     "🧾 У вас пока нету оплаченных платежей",
+	"payments.history_title":     "🧾 История платежей (страница %d из %d):\n",
+
+	"promocodes.enter":          "💳 Введите промокод:",
+	"promocodes.not_found":      "❌ Промокод не найден",
+	"promocodes.limit":          "❌ Количество активаций промокода превышено",
+	"promocodes.only_new":       "❌ Промокод доступен только для новых пользователей",
+	"promocodes.expired":        "❌ Срок действия промокода истёк",
+	"promocodes.already_used":   "❌ Промокод уже был применён",
+	"promocodes.applied":        "✅ Промокод применён, скидка %.f₽",
+	"promocodes.not_applicable": "❌ Промокод не действует для этого тарифа или способа оплаты",
+	"promocodes.use_command":    "❌ Этот промокод не даёт скидку. Активируйте его командой /promo КОД",
+	"promocodes.use_payment":    "❌ Этот промокод даёт скидку и применяется при оплате",
+	"promocodes.enter_command":  "Отправьте промокод командой /promo КОД",
+	"promocodes.days_added":     "✅ Промокод активирован, добавлено %d дн. подписки",
+	"promocodes.balance_added":  "✅ Промокод активирован, на баланс зачислено %.f₽",
+
+	"subscriptions.intro":           "🔥 Вы оформляете подписку на NSVPN.\n\n🌏 Доступные страны:\n",
+	"subscriptions.activated":       "✅ Подписка активирована!",
+	"subscriptions.waiting_payment": "⏳ Ожидаем оплату подписки. Для отмены используйте /cancel",
+	"subscriptions.payment_timeout": "❌ Время оплаты истекло",
+	"subscriptions.renewed":         "Ваша подписка успешно продлена",
+	"subscriptions.expires_at":      "Ваша подписка истечёт в %s",
+	"subscriptions.expired":         "Ваша подписка истекла",
+
+	"trials.activated":           "🎁 Пробный период активирован!\n\n⏳ Длительность: %d ч.\n🌏 Страны: %s\n📶 Трафик: %s",
+	"trials.all_countries":       "все",
+	"trials.unlimited":           "без ограничений",
+	"trials.used":                "❌ Вы уже использовали пробный период",
+	"trials.not_eligible":        "❌ Пробный период доступен только новым пользователям",
+	"trials.disabled":            "❌ Пробный период сейчас недоступен",
+	"trials.country_unavailable": "❌ Эта страна недоступна в пробном периоде. Оформите подписку, чтобы подключиться",
+	"trials.ends_at":             "⏳ Пробный период закончится в %s. Оформите подписку, чтобы не потерять доступ",
+	"trials.expired":             "Пробный период закончился. Оформите подписку, чтобы продолжить пользоваться VPN",
+	"trials.traffic_exceeded":    "📶 Трафик пробного периода исчерпан. Оформите подписку, чтобы продолжить пользоваться VPN",
+
+	"gifts.choose_plan":      "🎁 Выберите тариф, который хотите подарить. После оплаты вы получите код и ссылку для получателя",
+	"gifts.purchased":        "🎁 Подарочная подписка «%s» оплачена!\n\n🔑 Код: `%s`\n🔗 Ссылка: `%s`\n\nПерешлите ссылку получателю или попросите его отправить боту команду /redeem %s",
+	"gifts.list_empty":       "У вас пока нет купленных подарков",
+	"gifts.list_title":       "🎁 *Ваши подарки*:\n\n",
+	"gifts.list_item":        "%d) `%s` — %d дн. — %s\n",
+	"gifts.status_active":    "не активирован",
+	"gifts.status_redeemed":  "активирован %s",
+	"gifts.enter_code":       "Отправьте код подарка командой /redeem КОД",
+	"gifts.not_found":        "❌ Подарок с таким кодом не найден",
+	"gifts.already_redeemed": "❌ Этот подарок уже активирован",
+	"gifts.buyer_notice":     "🎁 Ваш подарок %s активировал(а) %s",
+	"gifts.redeemed":         "🎁 Подарок активирован! Добавлено %d дн. подписки, она действует до %s",
+
+	"users.profile":      "👔 *Ваш профиль*:\n\n🙎🏻 *Имя:* %s\n🆔 *ID:* %d\n\n💰 *Баланс*: %0.f₽\n🤝 *Кол-во рефералов*: %d чел.\n🔗 *Реферальная ссылка*: `%s`\n\n%s",
+	"users.sub_inactive": "🎟️ *Статус подписки*: неактивно ❌",
+	"users.sub_active":   "🎟️ *Статус подписки*: активно ✅\n📅 *Срок окончания*: %s",
+
+	"referrals.info":                    "🤝 *Реферальная программа*\n\n🔗 Ваша ссылка: `%s`\n👥 Приглашено: %d чел.\n💰 Заработано всего: %.f₽\n💸 Доступно к выводу: %.f₽\n\n🎁 Вознаграждение:\n",
+	"referrals.tier":                    "• от %d реф.: %s\n",
+	"referrals.rule_percent":            "%.f%% с каждого пополнения",
+	"referrals.rule_percent_first":      "%.f%% с первого пополнения",
+	"referrals.rule_fixed":              "%.f₽ за каждое пополнение",
+	"referrals.rule_fixed_first":        "%.f₽ за первое пополнение",
+	"referrals.reward":                  "🎁 Ваш реферал пополнил баланс, вам начислено %.f₽",
+	"referrals.history_empty":           "🧾 Начислений пока нет",
+	"referrals.history_title":           "🧾 Последние начисления:\n",
+	"referrals.history_item":            "%d) +%.f₽ — %s (пополнение на %.f₽)\n",
+	"referrals.withdrawal_unavailable":  "💸 Минимальная сумма вывода — %.f₽, сейчас доступно %.f₽",
+	"referrals.enter_amount":            "💸 Введите сумму вывода (от %.f₽, доступно %.f₽):",
+	"referrals.withdrawal_too_small":    "❌ Минимальная сумма вывода — %.f₽",
+	"referrals.withdrawal_insufficient": "❌ Недостаточно средств, доступно %.f₽",
+	"referrals.enter_details":           "💳 Укажите реквизиты для выплаты (номер карты или телефона для СБП и банк):",
+	"referrals.withdrawal_created":      "✅ Заявка на вывод #%d создана. Мы сообщим, когда она будет обработана",
+	"referrals.withdrawal_timeout":      "⌛ Время оформления заявки на вывод истекло",
+	"referrals.withdrawal_approved":     "✅ Заявка на вывод #%d выплачена: %.f₽",
+	"referrals.withdrawal_rejected":     "❌ Заявка на вывод #%d отклонена, %.f₽ возвращены на баланс",
+
+	"admin.server_down":            "🔴 Сервер %s недоступен\nОшибка: %s",
+	"admin.server_overloaded":      "🟠 Сервер %s перегружен\nНагрузка: %.0f%%",
+	"admin.server_recovered":       "🟢 Сервер %s снова доступен\nЗадержка: %d мс",
+	"admin.server_load_normal":     "🟢 Нагрузка на сервер %s нормализовалась\nНагрузка: %.0f%%",
+	"admin.server_load_threshold":  "🟠 Сервер %s сообщил о превышении порога нагрузки\nНагрузка: %.0f%%",
+	"admin.server_config_reloaded": "⚙️ Сервер %s перезагрузил конфигурацию\n%s",
+	"admin.reconcile_dry_run":      "🔍 Проверка расхождений (без изменений)\n\n",
+	"admin.reconcile_applied":      "🛠 Синхронизация нод выполнена\n\n",
+	"admin.reconcile_server":       "%s: ➕ %d, ➖ %d, 🔄 %d\n",
+	"admin.reconcile_server_down":  "%s: ❌ нода недоступна\n",
+	"admin.reconcile_unassigned":   "\nКлючей без назначенного сервера: %d\n",
+	"admin.reality_usage":          "Укажите код страны, например: /rotate_reality NL",
+	"admin.reality_overlap":        "⏳ Ноды %s ещё обслуживают ключи прошлой ротации, повторите после окончания перекрытия",
+	"admin.reality_rotated":        "✅ Ключи Reality для %s обновлены, старые действуют ещё %s. Пользователей в очереди на уведомление: %d",
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"nsvpn/internal/app/constants"
 	"nsvpn/internal/app/handlers"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/services"
 	"nsvpn/pkg/logger"
@@ -16,12 +17,14 @@ type Users struct {
 	bot                  *telebot.Bot
 	us                   *services.Users
 	ss                   *services.Subscriptions
-	clientButtons        *services.Buttons
-	clientButtonsWithSub *services.Buttons
+	clientButtons        *services.LocalizedButtons
+	clientButtonsWithSub *services.LocalizedButtons
 	bh                   *handlers.Base
+
+	acceptOfferBtns *services.LocalizedButtons
 }
 
-func NewUsers(log *logger.Logger, bot *telebot.Bot, us *services.Users, ss *services.Subscriptions, clientButtons, clientButtonsWithSub *services.Buttons, bh *handlers.Base) *Users {
+func NewUsers(log *logger.Logger, bot *telebot.Bot, us *services.Users, ss *services.Subscriptions, clientButtons, clientButtonsWithSub *services.LocalizedButtons, bh *handlers.Base) *Users {
 	return &Users{
 		log:                  log,
 		bot:                  bot,
@@ -30,9 +33,15 @@ func NewUsers(log *logger.Logger, bot *telebot.Bot, us *services.Users, ss *serv
 		clientButtons:        clientButtons,
 		clientButtonsWithSub: clientButtonsWithSub,
 		bh:                   bh,
+
+		acceptOfferBtns: services.NewLocalizedButtons(models.AcceptOfferButton, []int{1}, "inline"),
 	}
 }
 
+func (u *Users) RegisterHandlers() {
+	u.bot.Handle(u.acceptOfferBtns.GetBtn("accept_offer"), u.bh.AcceptOfferHandler)
+}
+
 func (u *Users) IsUser(next telebot.HandlerFunc) telebot.HandlerFunc {
 	return func(c telebot.Context) error {
 		user, err := u.getOrCreateUser(c)
@@ -40,12 +49,18 @@ func (u *Users) IsUser(next telebot.HandlerFunc) telebot.HandlerFunc {
 			return err
 		}
 
-		if user.Username != c.Sender().Username || user.Firstname != c.Sender().FirstName || user.Lastname != c.Sender().LastName {
-			if err := u.us.Update(c.Sender().ID, user); err != nil {
+		if user.Username != c.Sender().Username || user.Firstname != c.Sender().FirstName || user.Lastname != c.Sender().LastName || user.LanguageCode != c.Sender().LanguageCode {
+			updated := *user
+			updated.Username = c.Sender().Username
+			updated.Firstname = c.Sender().FirstName
+			updated.Lastname = c.Sender().LastName
+			updated.LanguageCode = c.Sender().LanguageCode
+			if err := u.us.Update(c.Sender().ID, &updated); err != nil {
 				u.log.Error("Error while updating user", err)
 			}
 		}
 		c.Set("user", user)
+		i18n.SetContext(c, i18n.Resolve(user.Language, c.Sender().LanguageCode))
 
 		if shouldCheckSign(user, c) {
 			u.isSign(c)
@@ -65,7 +80,7 @@ func (u *Users) IsUser(next telebot.HandlerFunc) telebot.HandlerFunc {
 func (u *Users) getOrCreateUser(c telebot.Context) (*models.User, error) {
 	user, err := u.us.Get(c.Sender().ID)
 	if err != nil {
-		_ = c.Send(constants.UserError)
+		_ = c.Send(i18n.T(i18n.FromContext(c), constants.UserError))
 		u.log.Error("Error while fetching user", err)
 		return nil, err
 	}
@@ -83,17 +98,18 @@ func (u *Users) createNewUser(c telebot.Context) (*models.User, error) {
 	}
 
 	user := &models.User{
-		ID:        c.Sender().ID,
-		Username:  c.Sender().Username,
-		Firstname: c.Sender().FirstName,
-		Lastname:  c.Sender().LastName,
-		PartnerID: partnerID,
-		IsAdmin:   false,
-		IsSign:    false,
+		ID:           c.Sender().ID,
+		Username:     c.Sender().Username,
+		Firstname:    c.Sender().FirstName,
+		Lastname:     c.Sender().LastName,
+		PartnerID:    partnerID,
+		IsAdmin:      false,
+		IsSign:       false,
+		LanguageCode: c.Sender().LanguageCode,
 	}
 
 	if err := u.us.Add(user); err != nil {
-		_ = c.Send(constants.UserError)
+		_ = c.Send(i18n.T(i18n.FromContext(c), constants.UserError))
 		u.log.Error("Failed to create new user", err, slog.Int64("userId", c.Sender().ID))
 		return nil, err
 	}
@@ -122,7 +138,7 @@ func shouldCheckSign(user *models.User, c telebot.Context) bool {
 func (u *Users) handleSubscription(c telebot.Context) (*models.Subscription, error) {
 	sub, err := u.ss.GetLastByUserID(c.Sender().ID, true)
 	if err != nil {
-		_ = c.Send(constants.UserError)
+		_ = c.Send(i18n.T(i18n.FromContext(c), constants.UserError))
 		u.log.Error("Error while fetching subscription", err)
 		return nil, err
 	}
@@ -137,19 +153,18 @@ func (u *Users) setSubscriptionContext(c telebot.Context, sub *models.Subscripti
 }
 
 func (u *Users) isSign(c telebot.Context) {
-	acceptOfferButtons := services.NewButtons(models.AcceptOfferButton, []int{1}, "inline")
-	u.bot.Handle(acceptOfferButtons.GetBtn("accept_offer"), u.bh.AcceptOfferHandler)
-
-	err := c.Send("Чтобы начать пользоваться NSVPN, необходимо принять условия публичной [оферты](https://teletype.in/@nsvpn/Dpvwcj7llQx).", acceptOfferButtons.AddBtns(), telebot.ModeMarkdown)
+	lang := i18n.FromContext(c)
+	err := c.Send(i18n.T(lang, "base.offer"), u.acceptOfferBtns.Get(lang).AddBtns(), telebot.ModeMarkdown)
 	if err != nil {
 		u.log.Error("Error while sending message", err)
 	}
 }
 
 func (u *Users) isSubActive(c telebot.Context, sub *models.Subscription) {
-	buttons := u.clientButtonsWithSub.AddBtns()
+	lang := i18n.FromContext(c)
+	buttons := u.clientButtonsWithSub.Get(lang).AddBtns()
 	if sub == nil || !sub.IsActive {
-		buttons = u.clientButtons.AddBtns()
+		buttons = u.clientButtons.Get(lang).AddBtns()
 	}
 	c.Set("replyKeyboard", buttons)
 }
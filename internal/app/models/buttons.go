@@ -4,6 +4,9 @@ type ButtonOption struct {
 	Value   string
 	Display string
 	URL     string
+	Action  string   // действие параметризованной callback-кнопки
+	Args    []string // аргументы действия, передаются в подписанных данных кнопки
+	Data    string   // закодированные данные callback-кнопки
 }
 
 var AcceptOfferButton = []ButtonOption{
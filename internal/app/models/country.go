@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Country struct {
 	ID          uint   `gorm:"primaryKey;autoIncrement"`
 	Code        string `gorm:"size:2;unique;not null"`
@@ -13,4 +15,44 @@ type Country struct {
 	Dest        string `gorm:"size:255;not null"`
 	ServerNames string `gorm:"size:255;not null"`
 	ShortIDs    string `gorm:"size:255;not null"`
+
+	PrevPrivateKey   string    `gorm:"size:512"` // приватный ключ до ротации, ноды обслуживают его до ShortIDsExpireAt
+	PrevShortIDs     string    `gorm:"size:255"` // short ID до ротации, валидны до ShortIDsExpireAt
+	ShortIDsExpireAt time.Time // конец окна перекрытия старых short ID
+	KeysRotatedAt    time.Time // время последней ротации ключей Reality
+}
+
+const (
+	ProtocolVLESS       = "vless"
+	ProtocolTrojan      = "trojan"
+	ProtocolShadowsocks = "shadowsocks"
+
+	NetworkTCP   = "tcp"
+	NetworkXHTTP = "xhttp"
+	NetworkGRPC  = "grpc"
+	NetworkWS    = "ws"
+
+	SecurityReality = "reality"
+	SecurityTLS     = "tls"
+	SecurityNone    = "none"
+)
+
+type CountryTransport struct {
+	ID          uint    `gorm:"primaryKey;autoIncrement"`
+	CountryID   uint    `gorm:"not null;index"`
+	Country     Country `gorm:"foreignKey:CountryID;references:ID"`
+	Name        string  `gorm:"size:64;not null"`
+	Protocol    string  `gorm:"size:16;not null;default:vless"`
+	Network     string  `gorm:"size:16;not null;default:tcp"`
+	Port        uint    `gorm:"not null;default:443"`
+	Security    string  `gorm:"size:16;not null;default:reality"`
+	Host        string  `gorm:"size:255"` // SNI для tls, Host-заголовок для ws/xhttp
+	Path        string  `gorm:"size:255"` // путь для ws/xhttp
+	ServiceName string  `gorm:"size:255"` // serviceName для grpc
+	Fingerprint string  `gorm:"size:32;default:random"`
+	ALPN        string  `gorm:"size:64"`  // через запятую, например "h2,http/1.1"
+	Method      string  `gorm:"size:64"`  // метод шифрования Shadowsocks-2022, например "2022-blake3-aes-128-gcm"
+	ServerPSK   string  `gorm:"size:128"` // серверный PSK Shadowsocks-2022
+	Priority    int     `gorm:"default:0"`
+	IsActive    bool    `gorm:"default:true"`
 }
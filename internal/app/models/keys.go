@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Key struct {
 	ID           uint  `gorm:"primaryKey;autoIncrement"`
 	UserID       int64 `gorm:"not null;uniqueIndex:idx_user_country"`
@@ -7,8 +9,42 @@ type Key struct {
 	CountryID    uint `gorm:"not null;uniqueIndex:idx_user_country"`
 	Country      Country
 	UUID         string `gorm:"size:512;not null"`
+	Password     string `gorm:"size:512"` // пароль Trojan
+	PSK          string `gorm:"size:512"` // пользовательский ключ Shadowsocks-2022 (base64, 32 байта)
 	SpeedLimit   uint64
 	TrafficLimit uint64
 	TrafficUsed  uint64
 	IsActive     bool `gorm:"default:true"`
 }
+
+type NodeClient struct {
+	UUID      string
+	Email     string
+	Protocol  string
+	Password  string
+	ExpiresAt time.Time
+}
+
+type NodeClientResult struct {
+	UUID     string
+	Protocol string
+	Error    string
+}
+
+const (
+	KeyStatusAdding  = "adding"  // новый UUID добавляется на сервер
+	KeyStatusActive  = "active"  // сервер обслуживает актуальный UUID
+	KeyStatusCleanup = "cleanup" // старый UUID не удалось снять, его удалит сверка
+	KeyStatusFailed  = "failed"  // сервер не принял новый UUID, ротация откатана
+)
+
+type KeyStatus struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	KeyID     uint      `gorm:"not null;uniqueIndex:idx_key_status_server"`
+	ServerID  uint      `gorm:"not null;uniqueIndex:idx_key_status_server"`
+	Server    Server    `gorm:"foreignKey:ServerID;references:ID"`
+	UUID      string    `gorm:"size:512;not null"` // UUID, к которому относится статус
+	Status    string    `gorm:"size:16;not null"`
+	Error     string    `gorm:"size:512"`
+	UpdatedAt time.Time `gorm:""`
+}
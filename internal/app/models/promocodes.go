@@ -2,14 +2,29 @@ package models
 
 import "time"
 
+const (
+	PromocodeTypePercent = "percent" // скидка Discount процентов на оплату
+	PromocodeTypeFixed   = "fixed"   // скидка Value рублей на оплату
+	PromocodeTypeBalance = "balance" // начисление Value рублей на баланс
+	PromocodeTypeDays    = "days"    // Value бесплатных дней подписки
+
+	PaymentTypePromocode = "promocode"
+)
+
 type Promocode struct {
 	ID                 uint   `gorm:"primaryKey;autoIncrement"`
-	Code               string `gorm:"size:15;unique;not null"`
+	Code               string `gorm:"size:32;unique;not null"`
+	Type               string `gorm:"size:10;not null;default:percent"`
 	Discount           int    `gorm:"not null"`
+	Value              float64
 	TotalActivations   uint
 	CurrentActivations uint      `gorm:"default:0"`
+	PerUserLimit       uint      // 0 — без ограничения
 	OnlyNewUsers       bool      `gorm:"default:false"`
+	PlanIDs            string    `gorm:"size:255"` // ID тарифов через запятую, пусто — любой тариф
+	Providers          string    `gorm:"size:64"`  // способы оплаты через запятую (bankcard, stars, cryptocurrency), пусто — любой
 	IsActive           bool      `gorm:"default:true"`
+	CampaignID         *uint     `gorm:"index"`
 	UserID             *int64    `gorm:"default:null"`
 	User               User      `gorm:"foreignKey:UserID;references:ID"`
 	StartAt            time.Time `gorm:"autoCreateTime"`
@@ -18,9 +33,48 @@ type Promocode struct {
 
 type PromocodeActivations struct {
 	ID          uint `gorm:"primaryKey"`
-	PromocodeID uint `gorm:"not null"`
+	PromocodeID uint `gorm:"not null;uniqueIndex:idx_promocode_user"`
 	Promocode   Promocode
-	UserID      int64 `gorm:"not null"`
+	UserID      int64 `gorm:"not null;uniqueIndex:idx_promocode_user"`
 	User        User
+	Uses        uint      `gorm:"not null;default:0"` // оплаченные активации пользователем
+	Reserved    uint      `gorm:"not null;default:0"` // активации в ожидании оплаты, вместе с Uses не больше PerUserLimit
+	Amount      float64   // сумма оплат с учётом скидки
+	Discount    float64   // сумма скидок или начисленных бонусов
+	IsOverLimit bool      `gorm:"default:false"` // оплату засчитали сверх лимита: резерв истёк, а лимит успели занять
 	ActivatedAt time.Time `gorm:"autoCreateTime"`
 }
+
+const (
+	ReservationStatusPending   = "pending"   // промокод применён к платежу, который ещё не оплачен
+	ReservationStatusConfirmed = "confirmed" // платёж оплачен, активация засчитана
+	ReservationStatusReleased  = "released"  // резерв снят по таймауту или отмене
+)
+
+type PromocodeReservation struct {
+	ID           uint `gorm:"primaryKey"`
+	ActivationID uint `gorm:"not null;index"`
+	Activation   PromocodeActivations
+	Status       string    `gorm:"size:16;not null"`
+	ExpiresAt    time.Time `gorm:"index"` // резерв снимается, если платёж не оплачен до этого времени
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+type PromocodeCampaign struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement"`
+	Name string `gorm:"size:64;unique;not null"`
+
+	// общие правила, копируются в каждый сгенерированный промокод
+	Type            string  `gorm:"size:10;not null"`
+	Discount        int     `gorm:"not null;default:0"`
+	Value           float64 `gorm:"not null;default:0"`
+	CodeActivations uint    `gorm:"not null;default:1"` // активаций на один код
+	PerUserLimit    uint    // 0 — без ограничения
+	OnlyNewUsers    bool    `gorm:"default:false"`
+	PlanIDs         string  `gorm:"size:255"`
+	Providers       string  `gorm:"size:64"`
+	EndAt           *time.Time
+
+	IsActive  bool      `gorm:"default:true"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	ReferralRewardPercent = "percent"
+	ReferralRewardFixed   = "fixed"
+
+	WithdrawalStatusPending  = "pending"
+	WithdrawalStatusApproved = "approved"
+	WithdrawalStatusRejected = "rejected"
+
+	PaymentTypeReferral = "referral"
+)
+
+type ReferralRule struct {
+	ID               uint    `gorm:"primaryKey;autoIncrement"`
+	MinReferrals     int64   `gorm:"not null;default:0"` // уровень: правило действует начиная с этого числа рефералов
+	Type             string  `gorm:"size:10;not null"`   // "percent" или "fixed"
+	Value            float64 `gorm:"not null"`
+	FirstPaymentOnly bool    `gorm:"default:false"` // начислять только за первое пополнение реферала
+	IsActive         bool    `gorm:"default:true"`
+}
+
+type ReferralEarning struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	PartnerID     int64     `gorm:"not null;index"`
+	ReferralID    int64     `gorm:"not null"`
+	Payload       string    `gorm:"size:512;uniqueIndex;not null"` // платёж реферала, защищает от повторного начисления
+	PaymentAmount float64   `gorm:"not null"`
+	Amount        float64   `gorm:"not null"`
+	RuleID        uint      `gorm:""`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+type ReferralWithdrawal struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	UserID      int64     `gorm:"not null;index"`
+	Amount      float64   `gorm:"not null"`
+	Details     string    `gorm:"size:255;not null"` // реквизиты для выплаты
+	Status      string    `gorm:"size:10;not null;default:pending"`
+	AdminID     *int64    `gorm:"default:null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ProcessedAt *time.Time
+}
@@ -1,10 +1,76 @@
 package models
 
+import "time"
+
 type Server struct {
-	ID           uint    `gorm:"primaryKey;autoIncrement"`
-	IP           string  `gorm:"size:15;unique;not null"`
-	CountryID    uint    `gorm:"not null"`
-	Country      Country `gorm:"foreignKey:CountryID;references:ID"`
-	ChannelSpeed uint64  `gorm:"not null"`
-	Port         uint    `gorm:"not null"`
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	IP            string    `gorm:"size:15;unique;not null"`
+	CountryID     uint      `gorm:"not null"`
+	Country       Country   `gorm:"foreignKey:CountryID;references:ID"`
+	ChannelSpeed  uint64    `gorm:"not null"`
+	Port          uint      `gorm:"not null"`
+	Hostname      string    `gorm:"size:255"`      // адрес сервера в ключах, по умолчанию домен страны
+	ServerName    string    `gorm:"size:255"`      // имя сервера в сертификате, по умолчанию IP
+	CACert        string    `gorm:"type:text"`     // PEM сертификат CA ноды, без него соединение запрещено, если не разрешено Insecure
+	Insecure      bool      `gorm:"default:false"` // явное разрешение соединяться без TLS, только для разработки и тестов
+	ClientCert    string    `gorm:"type:text"`     // PEM клиентский сертификат для mTLS
+	ClientKey     string    `gorm:"type:text"`     // PEM клиентский ключ для mTLS
+	AuthKeyID     string    `gorm:"size:32"`       // идентификатор HMAC-ключа ноды
+	AuthSecret    string    `gorm:"size:128"`      // секрет HMAC-ключа ноды
+	AuthRotatedAt time.Time // время последней ротации ключа
+
+	// ключ, отправленный ноде, но ещё не подтверждённый: хранится до отправки, чтобы его можно было
+	// подтвердить или отправить повторно, если ответ ноды или запись в базу потеряются
+	PendingAuthKeyID  string `gorm:"size:32"`
+	PendingAuthSecret string `gorm:"size:128"`
+	PendingAuthAt     *time.Time
+}
+
+const (
+	ServerStatusUp         = "up"
+	ServerStatusDown       = "down"
+	ServerStatusOverloaded = "overloaded"
+)
+
+type ServerStatus struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	ServerID  uint      `gorm:"not null;index"`
+	Server    Server    `gorm:"foreignKey:ServerID;references:ID"`
+	Status    string    `gorm:"size:16;not null"`
+	Health    string    `gorm:"size:64"` // ответ GetHealth ноды
+	Load      float64   `gorm:""`
+	Latency   int64     `gorm:"not null"` // задержка ответа в миллисекундах
+	Error     string    `gorm:"size:512"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+type Assignment struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    int64     `gorm:"not null;uniqueIndex:idx_assignment_user_country"`
+	CountryID uint      `gorm:"not null;uniqueIndex:idx_assignment_user_country"`
+	ServerID  uint      `gorm:"not null;index"`
+	Server    Server    `gorm:"foreignKey:ServerID;references:ID"`
+	CreatedAt time.Time `gorm:""`
+	UpdatedAt time.Time `gorm:""`
+}
+
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+type ProvisioningJob struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	ServerID  uint      `gorm:"not null;index"`
+	Server    Server    `gorm:"foreignKey:ServerID;references:ID"`
+	Status    string    `gorm:"size:16;not null;default:pending;index"`
+	Cursor    uint      `gorm:"not null;default:0"` // ID последнего обработанного ключа
+	Total     int64     `gorm:"not null;default:0"`
+	Processed int64     `gorm:"not null;default:0"`
+	Failed    int64     `gorm:"not null;default:0"`
+	Error     string    `gorm:"size:512"`
+	CreatedAt time.Time `gorm:""`
+	UpdatedAt time.Time `gorm:""`
 }
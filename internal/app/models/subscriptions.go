@@ -9,6 +9,9 @@ type Subscription struct {
 	StartDate time.Time `gorm:"autoCreateTime"`
 	EndDate   time.Time
 	IsActive  bool `gorm:"default:false"`
+
+	IsTrial      bool  `gorm:"default:false"`
+	TrafficLimit int64 `gorm:"default:0"` // байты, 0 — без ограничения
 }
 
 type SubscriptionPlan struct {
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	VoucherStatusActive   = "active"
+	VoucherStatusRedeemed = "redeemed"
+)
+
+type Voucher struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement"`
+	Code         string `gorm:"size:16;uniqueIndex;not null"` // в отличие от промокода не даёт скидку, а сам является оплаченной подпиской
+	PlanID       uint   `gorm:"not null"`
+	DurationDays uint   `gorm:"not null"` // срок тарифа на момент покупки, не зависит от последующих правок плана
+	Amount       float64
+	BuyerID      int64     `gorm:"not null;index"`
+	RecipientID  *int64    `gorm:"default:null"`
+	Payload      string    `gorm:"size:512;not null"` // платёж покупателя
+	Status       string    `gorm:"size:10;not null;default:active"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	RedeemedAt   *time.Time
+}
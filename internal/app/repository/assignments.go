@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log/slog"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+type Assignments struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+}
+
+func NewAssignments(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Assignments {
+	return &Assignments{
+		log:   log,
+		db:    db,
+		cache: cache,
+	}
+}
+
+func (ar *Assignments) GetAll() (assignments []*models.Assignment, err error) {
+	if err = ar.db.Find(&assignments).Error; err != nil {
+		ar.log.Error("Failed to get data from db", err)
+		return nil, err
+	}
+
+	ar.log.Debug("Returning assignments from db", slog.Int("count", len(assignments)))
+	return assignments, nil
+}
+
+func (ar *Assignments) Get(userID int64, countryID uint) (assignment *models.Assignment, err error) {
+	cacheKey := fmt.Sprintf("assignment:user_id:%d:country_id:%d", userID, countryID)
+	if err = ar.cache.Get(cacheKey, &assignment); err == nil {
+		ar.log.Debug("Returning assignment from cache", slog.String("cache_key", cacheKey), slog.Int64("user_id", userID), slog.Uint64("country_id", uint64(countryID)))
+		return assignment, nil
+	}
+
+	if err = ar.db.Preload("Server.Country").Where("user_id = ? AND country_id = ?", userID, countryID).First(&assignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ar.log.Debug("Assignment not found in database", slog.Int64("user_id", userID), slog.Uint64("country_id", uint64(countryID)))
+			return nil, nil
+		}
+
+		ar.log.Error("Failed to get data from db", err, slog.Int64("user_id", userID), slog.Uint64("country_id", uint64(countryID)))
+		return nil, err
+	}
+
+	ar.cache.Set(cacheKey, assignment, 15*time.Minute)
+	ar.log.Debug("Returning assignment from db", slog.Int64("user_id", userID), slog.Uint64("country_id", uint64(countryID)))
+	return assignment, nil
+}
+
+func (ar *Assignments) GetAllByServerID(serverID uint, limit int) (assignments []*models.Assignment, err error) {
+	query := ar.db.Where("server_id = ?", serverID).Order("updated_at ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err = query.Find(&assignments).Error; err != nil {
+		ar.log.Error("Failed to get data from db", err, slog.Uint64("server_id", uint64(serverID)))
+		return nil, err
+	}
+
+	ar.log.Debug("Returning assignments from db", slog.Uint64("server_id", uint64(serverID)), slog.Int("count", len(assignments)))
+	return assignments, nil
+}
+
+func (ar *Assignments) CountByServerID(serverID uint) (count int64, err error) {
+	if err = ar.db.Model(&models.Assignment{}).Where("server_id = ?", serverID).Count(&count).Error; err != nil {
+		ar.log.Error("Failed to count assignments", err, slog.Uint64("server_id", uint64(serverID)))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (ar *Assignments) Add(assignment *models.Assignment) error {
+	if err := ar.db.Create(&assignment).Error; err != nil {
+		ar.log.Error("Failed to execute query from db", err, slog.Any("assignment", assignment))
+		return err
+	}
+
+	ar.cache.Delete(fmt.Sprintf("assignment:user_id:%d:country_id:%d", assignment.UserID, assignment.CountryID))
+	ar.log.Debug("Added new assignment in db", slog.Int64("user_id", assignment.UserID), slog.Uint64("server_id", uint64(assignment.ServerID)))
+	return nil
+}
+
+func (ar *Assignments) UpdateServerID(userID int64, countryID, serverID uint) error {
+	if err := ar.db.Model(&models.Assignment{}).Where("user_id = ? AND country_id = ?", userID, countryID).Update("server_id", serverID).Error; err != nil {
+		ar.log.Error("Failed to update server_id", err, slog.Int64("user_id", userID), slog.Uint64("country_id", uint64(countryID)), slog.Uint64("server_id", uint64(serverID)))
+		return err
+	}
+
+	ar.cache.Delete(fmt.Sprintf("assignment:user_id:%d:country_id:%d", userID, countryID))
+	ar.log.Debug("Successfully updated assignment", slog.Int64("user_id", userID), slog.Uint64("country_id", uint64(countryID)), slog.Uint64("server_id", uint64(serverID)))
+	return nil
+}
+
+func (ar *Assignments) Delete(userID int64, countryID uint) error {
+	if err := ar.db.Where("user_id = ? AND country_id = ?", userID, countryID).Delete(&models.Assignment{}).Error; err != nil {
+		ar.log.Error("Failed to delete assignment from db", err, slog.Int64("user_id", userID), slog.Uint64("country_id", uint64(countryID)))
+		return err
+	}
+
+	ar.cache.Delete(fmt.Sprintf("assignment:user_id:%d:country_id:%d", userID, countryID))
+	ar.log.Debug("Deleted assignment from db", slog.Int64("user_id", userID), slog.Uint64("country_id", uint64(countryID)))
+	return nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
 	"log/slog"
 	"nsvpn/internal/app/models"
@@ -14,6 +15,8 @@ type Country struct {
 	log   *logger.Logger
 	db    *gorm.DB
 	cache *cache.Cache
+
+	Transports *CountryTransports
 }
 
 func NewCountry(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Country {
@@ -21,6 +24,11 @@ func NewCountry(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Country {
 		log:   log,
 		db:    db,
 		cache: cache,
+		Transports: &CountryTransports{
+			log:   log,
+			db:    db,
+			cache: cache,
+		},
 	}
 }
 
@@ -145,6 +153,27 @@ func (cr *Country) Update(code string, newCountry *models.Country) error {
 	return nil
 }
 
+func (cr *Country) UpdateReality(country *models.Country) error {
+	if err := cr.db.Model(&models.Country{}).Where("id = ?", country.ID).Updates(map[string]interface{}{
+		"private_key":         country.PrivateKey,
+		"public_key":          country.PublicKey,
+		"short_ids":           country.ShortIDs,
+		"prev_private_key":    country.PrevPrivateKey,
+		"prev_short_ids":      country.PrevShortIDs,
+		"short_ids_expire_at": country.ShortIDsExpireAt,
+		"keys_rotated_at":     country.KeysRotatedAt,
+	}).Error; err != nil {
+		cr.log.Error("Failed to update reality keys", err, slog.String("code", country.Code))
+		return err
+	}
+
+	// страна с ключами Reality закэширована и внутри ключей, серверов и назначений, по которым собираются ссылки
+	cr.cache.Delete("country:all", "country:"+country.Code, "servers:all", fmt.Sprintf("servers:country_id:%d", country.ID), "servers:id:*",
+		"key:user_id:*", fmt.Sprintf("assignment:user_id:*:country_id:%d", country.ID))
+	cr.log.Debug("Successfully updated reality keys", slog.String("code", country.Code))
+	return nil
+}
+
 func (cr *Country) Delete(code string) error {
 	if err := cr.db.Where("code = ?", code).Delete(&models.Country{}).Error; err != nil {
 		cr.log.Error("Failed to delete country", err, slog.String("code", code))
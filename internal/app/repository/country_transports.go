@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log/slog"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+type CountryTransports struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+}
+
+func (cr *CountryTransports) GetAllByCountryID(countryID uint) (transports []*models.CountryTransport, err error) {
+	cacheKey := fmt.Sprintf("country_transport:country_id:%d", countryID)
+	if err = cr.cache.Get(cacheKey, &transports); err == nil {
+		cr.log.Debug("Returning country transports from cache", slog.String("cache_key", cacheKey), slog.Int("count", len(transports)))
+		return transports, nil
+	}
+
+	if err = cr.db.Where("country_id = ? AND is_active = ?", countryID, true).Order("priority ASC, id ASC").Find(&transports).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cr.cache.Set(cacheKey, transports, 15*time.Minute)
+			cr.log.Debug("No country transports found in database", slog.Uint64("country_id", uint64(countryID)))
+			return nil, nil
+		}
+
+		cr.log.Error("Failed to get data from db", err, slog.Uint64("country_id", uint64(countryID)))
+		return nil, err
+	}
+
+	cr.cache.Set(cacheKey, transports, 15*time.Minute)
+	cr.log.Debug("Returning country transports from db", slog.Uint64("country_id", uint64(countryID)), slog.Int("count", len(transports)))
+	return transports, nil
+}
+
+func (cr *CountryTransports) Get(id uint) (transport *models.CountryTransport, err error) {
+	cacheKey := fmt.Sprintf("country_transport:%d", id)
+	if err = cr.cache.Get(cacheKey, &transport); err == nil {
+		cr.log.Debug("Returning country transport from cache", slog.String("cache_key", cacheKey), slog.Uint64("id", uint64(id)))
+		return transport, nil
+	}
+
+	if err = cr.db.First(&transport, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cr.cache.Set(cacheKey, transport, 15*time.Minute)
+			cr.log.Debug("Country transport not found in database", slog.Uint64("id", uint64(id)))
+			return nil, nil
+		}
+
+		cr.log.Error("Failed to get data from db", err, slog.Uint64("id", uint64(id)))
+		return nil, err
+	}
+
+	cr.cache.Set(cacheKey, transport, 15*time.Minute)
+	cr.log.Debug("Returning country transport from db", slog.Uint64("id", uint64(id)))
+	return transport, nil
+}
+
+func (cr *CountryTransports) Add(transport *models.CountryTransport) error {
+	if err := cr.db.Create(&transport).Error; err != nil {
+		cr.log.Error("Failed to execute query from db", err, slog.Any("transport", transport))
+		return err
+	}
+
+	cr.cache.Delete(fmt.Sprintf("country_transport:country_id:%d", transport.CountryID))
+	cr.log.Debug("Added new country transport in db", slog.Uint64("id", uint64(transport.ID)), slog.Uint64("country_id", uint64(transport.CountryID)))
+	return nil
+}
+
+func (cr *CountryTransports) Update(id uint, newTransport *models.CountryTransport) error {
+	transport, err := cr.Get(id)
+	if err != nil {
+		cr.log.Error("Failed to execute query from db", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	tx := cr.db.Begin()
+	if tx.Error != nil {
+		cr.log.Error("Failed to begin transaction", tx.Error, slog.Uint64("id", uint64(id)))
+		return tx.Error
+	}
+
+	if err = updateField(cr.log, tx, transport, "name", transport.Name, newTransport.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "protocol", transport.Protocol, newTransport.Protocol); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "network", transport.Network, newTransport.Network); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "port", transport.Port, newTransport.Port); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "security", transport.Security, newTransport.Security); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "host", transport.Host, newTransport.Host); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "path", transport.Path, newTransport.Path); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "service_name", transport.ServiceName, newTransport.ServiceName); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "fingerprint", transport.Fingerprint, newTransport.Fingerprint); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "alpn", transport.ALPN, newTransport.ALPN); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "method", transport.Method, newTransport.Method); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "server_psk", transport.ServerPSK, newTransport.ServerPSK); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "priority", transport.Priority, newTransport.Priority); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(cr.log, tx, transport, "is_active", transport.IsActive, newTransport.IsActive); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		cr.log.Error("Failed to commit transaction", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	cr.cache.Delete(fmt.Sprintf("country_transport:%d", id), fmt.Sprintf("country_transport:country_id:%d", transport.CountryID))
+	cr.log.Debug("Successfully updated country transport", slog.Uint64("id", uint64(id)))
+	return nil
+}
+
+func (cr *CountryTransports) Delete(id uint) error {
+	transport, err := cr.Get(id)
+	if err != nil {
+		cr.log.Error("Failed to execute query from db", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	if err = cr.db.Delete(&models.CountryTransport{}, id).Error; err != nil {
+		cr.log.Error("Failed to delete country transport from db", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	if transport != nil {
+		cr.cache.Delete(fmt.Sprintf("country_transport:country_id:%d", transport.CountryID))
+	}
+	cr.cache.Delete(fmt.Sprintf("country_transport:%d", id))
+	cr.log.Debug("Deleted country transport from db", slog.Uint64("id", uint64(id)))
+	return nil
+}
@@ -15,6 +15,8 @@ type Keys struct {
 	log   *logger.Logger
 	db    *gorm.DB
 	cache *cache.Cache
+
+	Statuses *KeysStatuses
 }
 
 func NewKeys(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Keys {
@@ -22,6 +24,11 @@ func NewKeys(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Keys {
 		log:   log,
 		db:    db,
 		cache: cache,
+		Statuses: &KeysStatuses{
+			log:   log,
+			db:    db,
+			cache: cache,
+		},
 	}
 }
 
@@ -48,6 +55,53 @@ func (kr *Keys) GetAll(userID int64) (keys []*models.Key, err error) {
 	return keys, nil
 }
 
+func (kr *Keys) GetAllActive() (keys []*models.Key, err error) {
+	if err = kr.db.Where("is_active = ?", true).Find(&keys).Error; err != nil {
+		kr.log.Error("Failed to get active keys from db", err)
+		return nil, err
+	}
+
+	kr.log.Debug("Returning active keys from db", slog.Int("count", len(keys)))
+	return keys, nil
+}
+
+func (kr *Keys) GetActiveByCountryID(countryID, afterID uint, limit int) (keys []*models.Key, err error) {
+	if err = kr.db.Where("country_id = ? AND is_active = ? AND id > ?", countryID, true, afterID).Order("id ASC").Limit(limit).Find(&keys).Error; err != nil {
+		kr.log.Error("Failed to get active keys from db", err, slog.Uint64("country_id", uint64(countryID)))
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (kr *Keys) CountActiveByCountryID(countryID uint) (count int64, err error) {
+	if err = kr.db.Model(&models.Key{}).Where("country_id = ? AND is_active = ?", countryID, true).Count(&count).Error; err != nil {
+		kr.log.Error("Failed to count active keys", err, slog.Uint64("country_id", uint64(countryID)))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (kr *Keys) SumTrafficUsed(userID int64) (sum uint64, err error) {
+	err = kr.db.Model(&models.Key{}).Select("COALESCE(SUM(traffic_used), 0)").Where("user_id = ?", userID).Scan(&sum).Error
+	if err != nil {
+		kr.log.Error("Failed to sum key traffic", err, slog.Int64("user_id", userID))
+		return 0, err
+	}
+
+	return sum, nil
+}
+
+func (kr *Keys) AddTrafficUsed(uuid string, bytes uint64) error {
+	if err := kr.db.Model(&models.Key{}).Where("uuid = ?", uuid).Update("traffic_used", gorm.Expr("traffic_used + ?", bytes)).Error; err != nil {
+		kr.log.Error("Failed to add key traffic", err, slog.String("uuid", uuid), slog.Uint64("bytes", bytes))
+		return err
+	}
+
+	return nil
+}
+
 func (kr *Keys) Get(countryID uint, userID int64) (key *models.Key, err error) {
 	cacheKey := fmt.Sprintf("key:user_id:%d:country_id:%d", userID, countryID)
 	if err = kr.cache.Get(cacheKey, &key); err == nil {
@@ -99,6 +153,14 @@ func (kr *Keys) Update(countryID uint, userID int64, newKey *models.Key) error {
 		tx.Rollback()
 		return err
 	}
+	if err = updateField(kr.log, tx, key, "password", key.Password, newKey.Password); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(kr.log, tx, key, "psk", key.PSK, newKey.PSK); err != nil {
+		tx.Rollback()
+		return err
+	}
 	if err = updateField(kr.log, tx, key, "speed_limit", key.SpeedLimit, newKey.SpeedLimit); err != nil {
 		tx.Rollback()
 		return err
@@ -122,6 +184,27 @@ func (kr *Keys) Update(countryID uint, userID int64, newKey *models.Key) error {
 	return nil
 }
 
+func (kr *Keys) FillSecrets(key *models.Key, password, psk string) error {
+	// заполняются только пустые секреты, поэтому параллельная догенерация не заменит уже выданный
+	err := kr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Key{}).Where("id = ? AND (password = '' OR password IS NULL)", key.ID).Update("password", password).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Key{}).Where("id = ? AND (psk = '' OR psk IS NULL)", key.ID).Update("psk", psk).Error; err != nil {
+			return err
+		}
+		return tx.Select("password", "psk").First(key, key.ID).Error
+	})
+	if err != nil {
+		kr.log.Error("Failed to fill key secrets", err, slog.Uint64("id", uint64(key.ID)))
+		return err
+	}
+
+	kr.cache.Delete(fmt.Sprintf("key:user_id:%d", key.UserID), fmt.Sprintf("key:user_id:%d:country_id:%d", key.UserID, key.CountryID))
+	kr.log.Debug("Filled empty key secrets", slog.Uint64("id", uint64(key.ID)))
+	return nil
+}
+
 func (kr *Keys) UpdateIsActive(countryID uint, userID int64, isActive bool) error {
 	if err := kr.db.Model(&models.Key{}).Where("country_id = ? AND user_id = ?", countryID, userID).Update("is_active", isActive).Error; err != nil {
 		kr.log.Error("Failed to update is_active", err, slog.Uint64("country_id", uint64(countryID)), slog.Int64("user_id", userID), slog.Bool("is_active", isActive))
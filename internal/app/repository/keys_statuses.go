@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+)
+
+type KeysStatuses struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+}
+
+func (kr *KeysStatuses) GetAll(keyID uint) (statuses []*models.KeyStatus, err error) {
+	if err = kr.db.Where("key_id = ?", keyID).Order("server_id ASC").Find(&statuses).Error; err != nil {
+		kr.log.Error("Failed to get key statuses from db", err, slog.Uint64("key_id", uint64(keyID)))
+		return nil, err
+	}
+
+	kr.log.Debug("Returning key statuses from db", slog.Uint64("key_id", uint64(keyID)), slog.Int("count", len(statuses)))
+	return statuses, nil
+}
+
+func (kr *KeysStatuses) Set(status *models.KeyStatus) error {
+	if err := kr.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key_id"}, {Name: "server_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"uuid", "status", "error", "updated_at"}),
+	}).Create(&status).Error; err != nil {
+		kr.log.Error("Failed to save key status", err, slog.Uint64("key_id", uint64(status.KeyID)), slog.Uint64("server_id", uint64(status.ServerID)))
+		return err
+	}
+
+	kr.log.Debug("Saved key status", slog.Uint64("key_id", uint64(status.KeyID)), slog.Uint64("server_id", uint64(status.ServerID)), slog.String("status", status.Status))
+	return nil
+}
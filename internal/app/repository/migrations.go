@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"log/slog"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/logger"
+)
+
+func Migrate(log *logger.Logger, db *gorm.DB) error {
+	return migratePromocodeActivations(log, db)
+}
+
+func migratePromocodeActivations(log *logger.Logger, db *gorm.DB) error {
+	// раньше каждая активация была отдельной строкой, теперь строка одна на пару (promocode_id, user_id)
+	activations := &models.PromocodeActivations{}
+	if m := db.Migrator(); !m.HasTable(activations) || m.HasColumn(activations, "uses") {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, field := range []string{"Uses", "Amount", "Discount"} {
+			if err := tx.Migrator().AddColumn(activations, field); err != nil {
+				return err
+			}
+		}
+
+		// сумм оплат старая схема не хранила, поэтому они начинаются с нуля
+		err := tx.Exec(`UPDATE promocode_activations SET uses = (
+			SELECT COUNT(*) FROM promocode_activations AS d WHERE d.promocode_id = promocode_activations.promocode_id AND d.user_id = promocode_activations.user_id
+		), amount = 0, discount = 0`).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Exec("DELETE FROM promocode_activations WHERE id NOT IN (SELECT MIN(id) FROM promocode_activations GROUP BY promocode_id, user_id)")
+		if result.Error != nil {
+			return result.Error
+		}
+		log.Info("Merged duplicate promocode activations", slog.Int64("deleted", result.RowsAffected))
+		return nil
+	})
+	if err != nil {
+		log.Error("Failed to migrate promocode activations", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/logger"
+)
+
+func TestMigratePromocodeActivations(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/test.db"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err = db.AutoMigrate(&models.Promocode{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	// исходная схема: строка на каждую активацию, без счётчиков и уникального индекса
+	err = db.Exec("CREATE TABLE `promocode_activations` (`id` integer PRIMARY KEY AUTOINCREMENT,`promocode_id` integer NOT NULL,`user_id` integer NOT NULL,`activated_at` datetime)").Error
+	if err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+
+	promocode := &models.Promocode{Code: "OLD", Discount: 10, TotalActivations: 10, CurrentActivations: 3, PerUserLimit: 3, IsActive: true}
+	if err = db.Create(promocode).Error; err != nil {
+		t.Fatalf("create promocode: %v", err)
+	}
+	for _, userID := range []int64{1, 1, 2} {
+		if err = db.Exec("INSERT INTO promocode_activations (promocode_id, user_id, activated_at) VALUES (?, ?, CURRENT_TIMESTAMP)", promocode.ID, userID).Error; err != nil {
+			t.Fatalf("create legacy activation: %v", err)
+		}
+	}
+
+	log := logger.NewDiscard()
+	if err = Migrate(log, db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if err = db.AutoMigrate(&models.PromocodeActivations{}, &models.PromocodeReservation{}); err != nil {
+		t.Fatalf("AutoMigrate after Migrate: %v", err)
+	}
+	if err = Migrate(log, db); err != nil {
+		t.Fatalf("repeated Migrate: %v", err)
+	}
+
+	var activations []models.PromocodeActivations
+	db.Order("user_id").Find(&activations)
+	if len(activations) != 2 {
+		t.Fatalf("activations = %+v, want one row per user", activations)
+	}
+	if a := activations[0]; a.UserID != 1 || a.Uses != 2 || a.Amount != 0 || a.Discount != 0 {
+		t.Fatalf("user 1 activation = %+v", a)
+	}
+	if a := activations[1]; a.UserID != 2 || a.Uses != 1 {
+		t.Fatalf("user 2 activation = %+v", a)
+	}
+
+	if err = db.Create(&models.PromocodeActivations{PromocodeID: promocode.ID, UserID: 2}).Error; err == nil {
+		t.Fatal("unique (promocode_id, user_id) index is missing")
+	}
+}
@@ -74,6 +74,15 @@ func (pr *Payments) GetPaymentsCount(userID int64) (count int64, err error) {
 	return count, err
 }
 
+func (pr *Payments) CountByType(userID int64, paymentType string) (count int64, err error) {
+	err = pr.db.Model(&models.Payment{}).Where("user_id = ? AND type = ? AND is_completed = true", userID, paymentType).Count(&count).Error
+	if err != nil {
+		pr.log.Error("Failed to get count from db", err, slog.Int64("user_id", userID), slog.String("type", paymentType))
+		return 0, err
+	}
+	return count, nil
+}
+
 func (pr *Payments) Get(userID int64, payload string) (payment *models.Payment, err error) {
 	cacheKey := fmt.Sprintf("payment:user_id:%d:payload:%s", userID, payload)
 	if err = pr.cache.Get(cacheKey, &payment); err == nil {
@@ -18,6 +18,7 @@ type Promocodes struct {
 	cache *cache.Cache
 
 	Activations *PromocodesActivations
+	Campaigns   *PromocodesCampaigns
 }
 
 func NewPromocodes(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Promocodes {
@@ -30,6 +31,11 @@ func NewPromocodes(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Promoco
 			db:    db,
 			cache: cache,
 		},
+		Campaigns: &PromocodesCampaigns{
+			log:   log,
+			db:    db,
+			cache: cache,
+		},
 	}
 }
 
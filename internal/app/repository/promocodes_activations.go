@@ -4,13 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log/slog"
+	"nsvpn/internal/app/constants"
 	"nsvpn/internal/app/models"
 	"nsvpn/pkg/cache"
 	"nsvpn/pkg/logger"
 	"time"
 )
 
+var errReservationConfirmed = errors.New("promocode reservation already confirmed")
+
 type PromocodesActivations struct {
 	log   *logger.Logger
 	db    *gorm.DB
@@ -132,6 +136,190 @@ func (pr *PromocodesActivations) Get(promocodeID uint, userID int64) (activation
 	return activation, nil
 }
 
+func (pr *PromocodesActivations) GetExpired(now time.Time) (reservations []*models.PromocodeReservation, err error) {
+	if err = pr.db.Preload("Activation").Where("status = ? AND expires_at < ?", models.ReservationStatusPending, now).Find(&reservations).Error; err != nil {
+		pr.log.Error("Failed to get expired promocode reservations", err)
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (pr *PromocodesActivations) Reserve(promocode *models.Promocode, userID int64, expiresAt time.Time) (*models.PromocodeReservation, error) {
+	reservation := &models.PromocodeReservation{
+		Status:    models.ReservationStatusPending,
+		ExpiresAt: expiresAt,
+	}
+
+	err := pr.db.Transaction(func(tx *gorm.DB) error {
+		// счётчик увеличивается условным UPDATE, поэтому параллельные запросы не превысят общий лимит
+		result := tx.Model(&models.Promocode{}).
+			Where("id = ? AND is_active = ? AND (total_activations = 0 OR current_activations < total_activations)", promocode.ID, true).
+			Update("current_activations", gorm.Expr("current_activations + ?", 1))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return constants.ErrPromocodeLimit
+		}
+
+		// строка активации одна на пользователя, лимит на пользователя проверяется на ней же
+		result = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "promocode_id"}, {Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]any{"reserved": gorm.Expr("promocode_activations.reserved + 1")}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "? = 0 OR promocode_activations.uses + promocode_activations.reserved < ?", Vars: []any{promocode.PerUserLimit, promocode.PerUserLimit}},
+			}},
+		}).Create(&models.PromocodeActivations{PromocodeID: promocode.ID, UserID: userID, Reserved: 1})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return constants.ErrPromocodeUsed
+		}
+
+		if err := tx.Where("promocode_id = ? AND user_id = ?", promocode.ID, userID).First(&reservation.Activation).Error; err != nil {
+			return err
+		}
+		reservation.ActivationID = reservation.Activation.ID
+		return tx.Omit(clause.Associations).Create(reservation).Error
+	})
+	if err != nil {
+		if !errors.Is(err, constants.ErrPromocodeLimit) && !errors.Is(err, constants.ErrPromocodeUsed) {
+			pr.log.Error("Failed to reserve promocode", err, slog.Uint64("promocode_id", uint64(promocode.ID)), slog.Int64("user_id", userID))
+		}
+		return nil, err
+	}
+
+	reservation.Activation.Promocode = *promocode
+	pr.invalidate(&reservation.Activation, promocode.Code)
+	pr.log.Debug("Reserved promocode", slog.Uint64("id", uint64(reservation.ID)), slog.Uint64("promocode_id", uint64(promocode.ID)), slog.Int64("user_id", userID))
+	return reservation, nil
+}
+
+func (pr *PromocodesActivations) Confirm(id uint, userID int64, amount, discount float64) error {
+	var reservation *models.PromocodeReservation
+	late := false
+	err := pr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Activation.Promocode").First(&reservation, id).Error; err != nil {
+			return err
+		}
+		if reservation.Activation.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+
+		result := tx.Model(&models.PromocodeReservation{}).Where("id = ? AND status = ?", id, models.ReservationStatusPending).Update("status", models.ReservationStatusConfirmed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			return tx.Model(&models.PromocodeActivations{}).
+				Where("id = ? AND reserved > 0", reservation.ActivationID).
+				Updates(map[string]any{
+					"uses":     gorm.Expr("uses + ?", 1),
+					"reserved": gorm.Expr("reserved - ?", 1),
+					"amount":   gorm.Expr("amount + ?", amount),
+					"discount": gorm.Expr("discount + ?", discount),
+				}).Error
+		}
+
+		result = tx.Model(&models.PromocodeReservation{}).Where("id = ? AND status = ?", id, models.ReservationStatusReleased).Update("status", models.ReservationStatusConfirmed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errReservationConfirmed
+		}
+
+		// оплата пришла после снятия резерва: скидка уже дана, поэтому активация засчитывается заново и помечается, если лимит успели занять
+		late = true
+		return confirmReleased(tx, reservation, amount, discount)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return constants.ErrPromocodeNotReserved
+	case errors.Is(err, errReservationConfirmed):
+		pr.log.Debug("Promocode reservation already confirmed", slog.Uint64("id", uint64(id)))
+		return nil
+	case err != nil:
+		pr.log.Error("Failed to confirm promocode reservation", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	pr.invalidate(&reservation.Activation, reservation.Activation.Promocode.Code)
+	if late {
+		pr.log.Warn("Confirmed promocode reservation after it was released", slog.Uint64("id", uint64(id)), slog.Int64("user_id", reservation.Activation.UserID))
+	}
+	pr.log.Debug("Confirmed promocode reservation", slog.Uint64("id", uint64(id)))
+	return nil
+}
+
+func confirmReleased(tx *gorm.DB, reservation *models.PromocodeReservation, amount, discount float64) error {
+	activation, promocode := reservation.Activation, reservation.Activation.Promocode
+	result := tx.Model(&models.Promocode{}).
+		Where("id = ? AND (total_activations = 0 OR current_activations < total_activations)", promocode.ID).
+		Update("current_activations", gorm.Expr("current_activations + ?", 1))
+	if result.Error != nil {
+		return result.Error
+	}
+	overLimit := result.RowsAffected == 0 || (promocode.PerUserLimit != 0 && activation.Uses+activation.Reserved >= promocode.PerUserLimit)
+	if result.RowsAffected == 0 {
+		if err := tx.Model(&models.Promocode{}).Where("id = ?", promocode.ID).Update("current_activations", gorm.Expr("current_activations + ?", 1)).Error; err != nil {
+			return err
+		}
+	}
+
+	updates := map[string]any{
+		"uses":     gorm.Expr("uses + ?", 1),
+		"amount":   gorm.Expr("amount + ?", amount),
+		"discount": gorm.Expr("discount + ?", discount),
+	}
+	if overLimit {
+		updates["is_over_limit"] = true
+	}
+	return tx.Model(&models.PromocodeActivations{}).Where("id = ?", activation.ID).Updates(updates).Error
+}
+
+func (pr *PromocodesActivations) Release(id uint, userID int64) error {
+	var reservation *models.PromocodeReservation
+	err := pr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Activation.Promocode").Where("id = ? AND status = ?", id, models.ReservationStatusPending).First(&reservation).Error; err != nil {
+			return err
+		}
+		if reservation.Activation.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+
+		result := tx.Model(&models.PromocodeReservation{}).Where("id = ? AND status = ?", id, models.ReservationStatusPending).Update("status", models.ReservationStatusReleased)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Model(&models.PromocodeActivations{}).
+			Where("id = ? AND reserved > 0", reservation.ActivationID).
+			Update("reserved", gorm.Expr("reserved - ?", 1)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Promocode{}).
+			Where("id = ? AND current_activations > 0", reservation.Activation.PromocodeID).
+			Update("current_activations", gorm.Expr("current_activations - ?", 1)).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		pr.log.Debug("Promocode reservation already released or confirmed", slog.Uint64("id", uint64(id)))
+		return nil
+	}
+	if err != nil {
+		pr.log.Error("Failed to release promocode reservation", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	pr.invalidate(&reservation.Activation, reservation.Activation.Promocode.Code)
+	pr.log.Debug("Released promocode reservation", slog.Uint64("id", uint64(id)), slog.Uint64("promocode_id", uint64(reservation.Activation.PromocodeID)))
+	return nil
+}
+
 func (pr *PromocodesActivations) Add(activation *models.PromocodeActivations) error {
 	if err := pr.db.Create(&activation).Error; err != nil {
 		pr.log.Error("Failed to execute query from db", err, slog.Uint64("promocode_id", uint64(activation.PromocodeID)), slog.Int64("user_id", activation.UserID))
@@ -158,3 +346,8 @@ func (pr *PromocodesActivations) Delete(id uint) error {
 	pr.log.Debug("Deleted promocode activation from db", slog.Uint64("id", uint64(id)))
 	return nil
 }
+
+func (pr *PromocodesActivations) invalidate(activation *models.PromocodeActivations, code string) {
+	pr.cache.Delete("promocode_activations:all", fmt.Sprintf("promocode_activations:%d", activation.ID), fmt.Sprintf("promocode_activations:promocode:%d", activation.PromocodeID), fmt.Sprintf("promocode_activations:user:%d", activation.UserID), fmt.Sprintf("promocode_activations:promocode:%d:user:%d", activation.PromocodeID, activation.UserID))
+	pr.cache.Delete("promocodes:all", "promocodes:only_active", fmt.Sprintf("promocodes:%d", activation.PromocodeID), "promocodes:"+code)
+}
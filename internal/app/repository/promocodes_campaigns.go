@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+)
+
+const codesBatchSize = 500
+
+type PromocodesCampaigns struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+}
+
+type CampaignStats struct {
+	Codes       int64   // сгенерировано кодов
+	UsedCodes   int64   // кодов с хотя бы одной оплаченной активацией
+	Redemptions int64   // оплаченных активаций
+	Users       int64   // уникальных пользователей
+	Revenue     float64 // сумма платежей с промокодами кампании
+	Discount    float64 // сумма скидок и бонусов
+}
+
+func (pr *PromocodesCampaigns) GetAll() (campaigns []*models.PromocodeCampaign, err error) {
+	if err = pr.db.Order("id DESC").Find(&campaigns).Error; err != nil {
+		pr.log.Error("Failed to get promocode campaigns from db", err)
+		return nil, err
+	}
+
+	pr.log.Debug("Returning promocode campaigns from db", slog.Int("count", len(campaigns)))
+	return campaigns, nil
+}
+
+func (pr *PromocodesCampaigns) GetByID(id uint) (campaign *models.PromocodeCampaign, err error) {
+	if err = pr.db.Where("id = ?", id).First(&campaign).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			pr.log.Debug("Promocode campaign not found in database", slog.Uint64("id", uint64(id)))
+			return nil, nil
+		}
+
+		pr.log.Error("Failed to get promocode campaign from db", err, slog.Uint64("id", uint64(id)))
+		return nil, err
+	}
+	return campaign, nil
+}
+
+func (pr *PromocodesCampaigns) Add(campaign *models.PromocodeCampaign) error {
+	if err := pr.db.Create(&campaign).Error; err != nil {
+		pr.log.Error("Failed to create promocode campaign in db", err, slog.String("name", campaign.Name))
+		return err
+	}
+
+	pr.log.Debug("Added new promocode campaign in db", slog.Uint64("id", uint64(campaign.ID)), slog.String("name", campaign.Name))
+	return nil
+}
+
+func (pr *PromocodesCampaigns) AddCodes(promocodes []*models.Promocode) (int64, error) {
+	if len(promocodes) == 0 {
+		return 0, nil
+	}
+
+	// коды, совпавшие с уже существующими, пропускаются, вызывающий догенерирует недостающие
+	result := pr.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoNothing: true,
+	}).CreateInBatches(&promocodes, codesBatchSize)
+	if result.Error != nil {
+		pr.log.Error("Failed to create campaign promocodes in db", result.Error, slog.Int("count", len(promocodes)))
+		return 0, result.Error
+	}
+
+	pr.cache.Delete("promocodes:all", "promocodes:only_active")
+	pr.log.Debug("Added campaign promocodes in db", slog.Int("count", len(promocodes)), slog.Int64("inserted", result.RowsAffected))
+	return result.RowsAffected, nil
+}
+
+func (pr *PromocodesCampaigns) GetCodes(campaignID uint) (promocodes []*models.Promocode, err error) {
+	if err = pr.db.Where("campaign_id = ?", campaignID).Order("id").Find(&promocodes).Error; err != nil {
+		pr.log.Error("Failed to get campaign promocodes from db", err, slog.Uint64("campaign_id", uint64(campaignID)))
+		return nil, err
+	}
+
+	pr.log.Debug("Returning campaign promocodes from db", slog.Uint64("campaign_id", uint64(campaignID)), slog.Int("count", len(promocodes)))
+	return promocodes, nil
+}
+
+func (pr *PromocodesCampaigns) UpdateIsActive(id uint, isActive bool) error {
+	err := pr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PromocodeCampaign{}).Where("id = ?", id).Update("is_active", isActive).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Promocode{}).Where("campaign_id = ?", id).Update("is_active", isActive).Error
+	})
+	if err != nil {
+		pr.log.Error("Failed to update promocode campaign is_active", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	// коды кампании закэшированы по отдельности, поэтому сбрасываем весь кэш промокодов
+	pr.cache.Delete("promocodes:*")
+	pr.log.Debug("Successfully updated campaign is_active", slog.Uint64("id", uint64(id)), slog.Bool("is_active", isActive))
+	return nil
+}
+
+func (pr *PromocodesCampaigns) GetStats(campaignID uint) (stats CampaignStats, err error) {
+	// резервы ещё не оплачены, поэтому в статистику попадают только подтверждённые активации
+	err = pr.db.Table("promocode_activations AS a").
+		Select("COUNT(DISTINCT a.promocode_id) AS used_codes, COALESCE(SUM(a.uses), 0) AS redemptions, COUNT(DISTINCT a.user_id) AS users, "+
+			"COALESCE(SUM(a.amount), 0) AS revenue, COALESCE(SUM(a.discount), 0) AS discount").
+		Joins("JOIN promocodes AS p ON p.id = a.promocode_id").
+		Where("p.campaign_id = ? AND a.uses > 0", campaignID).
+		Scan(&stats).Error
+	if err != nil {
+		pr.log.Error("Failed to get campaign stats from db", err, slog.Uint64("campaign_id", uint64(campaignID)))
+		return CampaignStats{}, err
+	}
+	if err = pr.db.Model(&models.Promocode{}).Where("campaign_id = ?", campaignID).Count(&stats.Codes).Error; err != nil {
+		pr.log.Error("Failed to count campaign promocodes", err, slog.Uint64("campaign_id", uint64(campaignID)))
+		return CampaignStats{}, err
+	}
+
+	pr.log.Debug("Returning campaign stats from db", slog.Uint64("campaign_id", uint64(campaignID)))
+	return stats, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"log/slog"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+)
+
+type ProvisioningJobs struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+}
+
+func NewProvisioningJobs(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *ProvisioningJobs {
+	return &ProvisioningJobs{
+		log:   log,
+		db:    db,
+		cache: cache,
+	}
+}
+
+func (pr *ProvisioningJobs) Get(id uint) (job *models.ProvisioningJob, err error) {
+	if err = pr.db.Preload("Server.Country").First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			pr.log.Debug("Provisioning job not found in database", slog.Uint64("id", uint64(id)))
+			return nil, nil
+		}
+
+		pr.log.Error("Failed to get data from db", err, slog.Uint64("id", uint64(id)))
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (pr *ProvisioningJobs) GetAllUnfinished() (jobs []*models.ProvisioningJob, err error) {
+	if err = pr.db.Preload("Server.Country").Where("status IN ?", []string{models.JobStatusPending, models.JobStatusRunning}).Order("id ASC").Find(&jobs).Error; err != nil {
+		pr.log.Error("Failed to get data from db", err)
+		return nil, err
+	}
+
+	pr.log.Debug("Returning unfinished provisioning jobs from db", slog.Int("count", len(jobs)))
+	return jobs, nil
+}
+
+func (pr *ProvisioningJobs) Add(job *models.ProvisioningJob) error {
+	if err := pr.db.Create(&job).Error; err != nil {
+		pr.log.Error("Failed to execute query from db", err, slog.Any("job", job))
+		return err
+	}
+
+	pr.log.Debug("Added new provisioning job in db", slog.Uint64("id", uint64(job.ID)), slog.Uint64("server_id", uint64(job.ServerID)))
+	return nil
+}
+
+func (pr *ProvisioningJobs) UpdateProgress(job *models.ProvisioningJob) error {
+	if err := pr.db.Model(&models.ProvisioningJob{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":    job.Status,
+		"cursor":    job.Cursor,
+		"total":     job.Total,
+		"processed": job.Processed,
+		"failed":    job.Failed,
+		"error":     job.Error,
+	}).Error; err != nil {
+		pr.log.Error("Failed to update provisioning job", err, slog.Uint64("id", uint64(job.ID)))
+		return err
+	}
+
+	pr.log.Debug("Updated provisioning job", slog.Uint64("id", uint64(job.ID)), slog.String("status", job.Status), slog.Int64("processed", job.Processed))
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log/slog"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+type Referrals struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+
+	Earnings    *ReferralEarnings
+	Withdrawals *ReferralWithdrawals
+}
+
+func NewReferrals(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Referrals {
+	return &Referrals{
+		log:   log,
+		db:    db,
+		cache: cache,
+		Earnings: &ReferralEarnings{
+			log:   log,
+			db:    db,
+			cache: cache,
+		},
+		Withdrawals: &ReferralWithdrawals{
+			log:   log,
+			db:    db,
+			cache: cache,
+		},
+	}
+}
+
+func (rr *Referrals) GetRules() (rules []*models.ReferralRule, err error) {
+	cacheKey := "referral_rules:active"
+	if err = rr.cache.Get(cacheKey, &rules); err == nil {
+		rr.log.Debug("Returning referral rules from cache", slog.String("cache_key", cacheKey), slog.Int("count", len(rules)))
+		return rules, nil
+	}
+
+	if err = rr.db.Where("is_active = ?", true).Order("min_referrals ASC").Find(&rules).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rr.cache.Set(cacheKey, rules, 15*time.Minute)
+			rr.log.Debug("No referral rules found in database")
+			return nil, nil
+		}
+
+		rr.log.Error("Failed to get data from db", err)
+		return nil, err
+	}
+
+	rr.cache.Set(cacheKey, rules, 15*time.Minute)
+	rr.log.Debug("Returning referral rules from db", slog.String("cache_key", cacheKey), slog.Int("count", len(rules)))
+	return rules, nil
+}
+
+func (rr *Referrals) AddRule(rule *models.ReferralRule) error {
+	if err := rr.db.Create(&rule).Error; err != nil {
+		rr.log.Error("Failed to create referral rule in db", err, slog.Int64("min_referrals", rule.MinReferrals))
+		return err
+	}
+
+	rr.cache.Delete("referral_rules:active")
+	rr.log.Debug("Added new referral rule in db", slog.Uint64("id", uint64(rule.ID)), slog.Int64("min_referrals", rule.MinReferrals))
+	return nil
+}
+
+func (rr *Referrals) DeleteRule(id uint) error {
+	result := rr.db.Where("id = ?", id).Delete(&models.ReferralRule{})
+	if result.Error != nil {
+		rr.log.Error("Failed to delete referral rule from db", result.Error, slog.Uint64("id", uint64(id)))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	rr.cache.Delete("referral_rules:active")
+	rr.log.Debug("Deleted referral rule from db", slog.Uint64("id", uint64(id)))
+	return nil
+}
+
+func referralStatsKey(partnerID int64) string {
+	return fmt.Sprintf("referral_earnings:partner:%d:stats", partnerID)
+}
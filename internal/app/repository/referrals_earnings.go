@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+type ReferralEarnings struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+}
+
+type ReferralStats struct {
+	Count int64   // количество начислений
+	Total float64 // сумма начислений
+}
+
+func (rr *ReferralEarnings) GetByPartnerID(partnerID int64, limit int) (earnings []*models.ReferralEarning, err error) {
+	dbQuery := rr.db.Where("partner_id = ?", partnerID).Order("id DESC")
+	if limit > 0 {
+		dbQuery = dbQuery.Limit(limit)
+	}
+
+	if err = dbQuery.Find(&earnings).Error; err != nil {
+		rr.log.Error("Failed to get data from db", err, slog.Int64("partner_id", partnerID))
+		return nil, err
+	}
+
+	rr.log.Debug("Returning referral earnings from db", slog.Int64("partner_id", partnerID), slog.Int("count", len(earnings)))
+	return earnings, nil
+}
+
+func (rr *ReferralEarnings) GetStats(partnerID int64) (stats ReferralStats, err error) {
+	cacheKey := referralStatsKey(partnerID)
+	if err = rr.cache.Get(cacheKey, &stats); err == nil {
+		rr.log.Debug("Returning referral stats from cache", slog.String("cache_key", cacheKey), slog.Int64("partner_id", partnerID))
+		return stats, nil
+	}
+
+	err = rr.db.Model(&models.ReferralEarning{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+		Where("partner_id = ?", partnerID).
+		Scan(&stats).Error
+	if err != nil {
+		rr.log.Error("Failed to get referral stats from db", err, slog.Int64("partner_id", partnerID))
+		return ReferralStats{}, err
+	}
+
+	rr.cache.Set(cacheKey, stats, 15*time.Minute)
+	rr.log.Debug("Returning referral stats from db", slog.String("cache_key", cacheKey), slog.Int64("partner_id", partnerID))
+	return stats, nil
+}
+
+func (rr *ReferralEarnings) Add(earning *models.ReferralEarning) (bool, error) {
+	added := false
+	err := rr.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "payload"}},
+			DoNothing: true,
+		}).Create(&earning)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		// начисление и пополнение баланса партнёра фиксируются вместе, иначе повтор платежа не вернёт потерянное пополнение
+		result = tx.Model(&models.User{}).Where("id = ?", earning.PartnerID).Update("balance", gorm.Expr("balance + ?", earning.Amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return constants.ErrUserNotFound
+		}
+
+		added = true
+		return nil
+	})
+	if err != nil {
+		rr.log.Error("Failed to credit referral earning in db", err, slog.Int64("partner_id", earning.PartnerID), slog.String("payload", earning.Payload))
+		return false, err
+	}
+	if !added {
+		rr.log.Debug("Referral earning already exists", slog.String("payload", earning.Payload))
+		return false, nil
+	}
+
+	rr.cache.Delete(fmt.Sprintf("referral_earnings:partner:%d:*", earning.PartnerID), fmt.Sprintf("user:%d", earning.PartnerID))
+	rr.log.Debug("Added new referral earning in db", slog.Int64("partner_id", earning.PartnerID), slog.Float64("amount", earning.Amount))
+	return true, nil
+}
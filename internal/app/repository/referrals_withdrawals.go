@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+type ReferralWithdrawals struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+}
+
+func (rr *ReferralWithdrawals) GetByID(id uint) (withdrawal *models.ReferralWithdrawal, err error) {
+	if err = rr.db.First(&withdrawal, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rr.log.Debug("Withdrawal not found in database", slog.Uint64("id", uint64(id)))
+			return nil, nil
+		}
+
+		rr.log.Error("Failed to get withdrawal from db", err, slog.Uint64("id", uint64(id)))
+		return nil, err
+	}
+	return withdrawal, nil
+}
+
+func (rr *ReferralWithdrawals) GetByStatus(status string) (withdrawals []*models.ReferralWithdrawal, err error) {
+	if err = rr.db.Where("status = ?", status).Order("id ASC").Find(&withdrawals).Error; err != nil {
+		rr.log.Error("Failed to get withdrawals from db", err, slog.String("status", status))
+		return nil, err
+	}
+
+	rr.log.Debug("Returning withdrawals from db", slog.String("status", status), slog.Int("count", len(withdrawals)))
+	return withdrawals, nil
+}
+
+func (rr *ReferralWithdrawals) SumByUserID(userID int64) (sum float64, err error) {
+	err = rr.db.Model(&models.ReferralWithdrawal{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ? AND status <> ?", userID, models.WithdrawalStatusRejected).
+		Scan(&sum).Error
+	if err != nil {
+		rr.log.Error("Failed to sum withdrawals in db", err, slog.Int64("user_id", userID))
+		return 0, err
+	}
+	return sum, nil
+}
+
+func (rr *ReferralWithdrawals) Request(withdrawal *models.ReferralWithdrawal) error {
+	err := rr.db.Transaction(func(tx *gorm.DB) error {
+		// строка пользователя блокируется, чтобы параллельные заявки проверяли лимит по очереди
+		var user *models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, withdrawal.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return constants.ErrUserNotFound
+			}
+			return err
+		}
+
+		var earned, withdrawn float64
+		err := tx.Model(&models.ReferralEarning{}).Select("COALESCE(SUM(amount), 0)").Where("partner_id = ?", withdrawal.UserID).Scan(&earned).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.ReferralWithdrawal{}).Select("COALESCE(SUM(amount), 0)").
+			Where("user_id = ? AND status <> ?", withdrawal.UserID, models.WithdrawalStatusRejected).Scan(&withdrawn).Error
+		if err != nil {
+			return err
+		}
+
+		// вывести можно только заработанное на рефералах и ещё не потраченное
+		if withdrawal.Amount > min(earned-withdrawn, user.Balance) {
+			return constants.ErrInsufficientFunds
+		}
+
+		// средства списываются сразу и возвращаются, если заявку отклонят
+		if err = tx.Model(&models.User{}).Where("id = ?", withdrawal.UserID).Update("balance", gorm.Expr("balance - ?", withdrawal.Amount)).Error; err != nil {
+			return err
+		}
+		return tx.Create(withdrawal).Error
+	})
+	if err != nil {
+		if !errors.Is(err, constants.ErrInsufficientFunds) && !errors.Is(err, constants.ErrUserNotFound) {
+			rr.log.Error("Failed to request withdrawal", err, slog.Int64("user_id", withdrawal.UserID), slog.Float64("amount", withdrawal.Amount))
+		}
+		return err
+	}
+
+	rr.cache.Delete(fmt.Sprintf("user:%d", withdrawal.UserID))
+	rr.log.Debug("Added new withdrawal in db", slog.Uint64("id", uint64(withdrawal.ID)), slog.Int64("user_id", withdrawal.UserID))
+	return nil
+}
+
+func (rr *ReferralWithdrawals) Process(id uint, status string, adminID int64) error {
+	now := time.Now().UTC()
+	result := rr.db.Model(&models.ReferralWithdrawal{}).
+		Where("id = ? AND status = ?", id, models.WithdrawalStatusPending).
+		Updates(map[string]any{"status": status, "admin_id": adminID, "processed_at": now})
+	if result.Error != nil {
+		rr.log.Error("Failed to process withdrawal", result.Error, slog.Uint64("id", uint64(id)), slog.String("status", status))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return constants.ErrWithdrawalProcessed
+	}
+
+	rr.log.Debug("Processed withdrawal", slog.Uint64("id", uint64(id)), slog.String("status", status), slog.Int64("admin_id", adminID))
+	return nil
+}
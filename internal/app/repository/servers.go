@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gorm.io/gorm"
 	"log/slog"
+	"nsvpn/internal/app/constants"
 	"nsvpn/internal/app/models"
 	"nsvpn/pkg/cache"
 	"nsvpn/pkg/logger"
@@ -15,6 +16,8 @@ type Servers struct {
 	log   *logger.Logger
 	db    *gorm.DB
 	cache *cache.Cache
+
+	Statuses *ServersStatuses
 }
 
 func NewServers(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Servers {
@@ -22,6 +25,11 @@ func NewServers(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Servers {
 		log:   log,
 		db:    db,
 		cache: cache,
+		Statuses: &ServersStatuses{
+			log:   log,
+			db:    db,
+			cache: cache,
+		},
 	}
 }
 
@@ -32,7 +40,7 @@ func (sr *Servers) GetAll() (servers []*models.Server, err error) {
 		return servers, nil
 	}
 
-	if err = sr.db.Find(&servers).Error; err != nil {
+	if err = sr.db.Preload("Country").Find(&servers).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			sr.cache.Set(cacheKey, servers, 15*time.Minute)
 			sr.log.Debug("No servers found in database")
@@ -78,7 +86,7 @@ func (sr *Servers) Get(id uint) (server *models.Server, err error) {
 		return server, nil
 	}
 
-	if err = sr.db.First(server, id).Error; err != nil {
+	if err = sr.db.Preload("Country").First(&server, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			sr.cache.Set(cacheKey, server, 15*time.Minute)
 			sr.log.Debug("Server not found in database", slog.Uint64("id", uint64(id)))
@@ -100,7 +108,7 @@ func (sr *Servers) Add(server *models.Server) error {
 		return err
 	}
 
-	sr.cache.Delete("servers:all", fmt.Sprintf("servers:country_id:%d", server.ID))
+	sr.cache.Delete("servers:all", fmt.Sprintf("servers:country_id:%d", server.CountryID))
 	sr.log.Debug("Added new server in db", slog.Uint64("id", uint64(server.ID)))
 	return nil
 }
@@ -134,6 +142,26 @@ func (sr *Servers) Update(id uint, newServer *models.Server) error {
 		tx.Rollback()
 		return err
 	}
+	if err = updateField(sr.log, tx, server, "hostname", server.Hostname, newServer.Hostname); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(sr.log, tx, server, "server_name", server.ServerName, newServer.ServerName); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(sr.log, tx, server, "ca_cert", server.CACert, newServer.CACert); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(sr.log, tx, server, "client_cert", server.ClientCert, newServer.ClientCert); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = updateField(sr.log, tx, server, "client_key", server.ClientKey, newServer.ClientKey); err != nil {
+		tx.Rollback()
+		return err
+	}
 
 	if err = tx.Commit().Error; err != nil {
 		sr.log.Error("Failed to commit transaction", err)
@@ -145,6 +173,58 @@ func (sr *Servers) Update(id uint, newServer *models.Server) error {
 	return nil
 }
 
+func (sr *Servers) SetPendingAuthKey(id uint, keyID, secret string, createdAt time.Time) error {
+	return sr.updateAuthFields(id, "Successfully saved pending auth key", "pending_auth_key_id = ?", "", map[string]interface{}{
+		"pending_auth_key_id": keyID,
+		"pending_auth_secret": secret,
+		"pending_auth_at":     createdAt,
+	})
+}
+
+func (sr *Servers) PromoteAuthKey(id uint, keyID string, rotatedAt time.Time) error {
+	return sr.updateAuthFields(id, "Successfully promoted pending auth key", "pending_auth_key_id = ?", keyID, map[string]interface{}{
+		"auth_key_id":         gorm.Expr("pending_auth_key_id"),
+		"auth_secret":         gorm.Expr("pending_auth_secret"),
+		"auth_rotated_at":     rotatedAt,
+		"pending_auth_key_id": "",
+		"pending_auth_secret": "",
+		"pending_auth_at":     nil,
+	})
+}
+
+func (sr *Servers) ClearPendingAuthKey(id uint, keyID string) error {
+	return sr.updateAuthFields(id, "Successfully cleared pending auth key", "pending_auth_key_id = ?", keyID, map[string]interface{}{
+		"pending_auth_key_id": "",
+		"pending_auth_secret": "",
+		"pending_auth_at":     nil,
+	})
+}
+
+func (sr *Servers) updateAuthFields(id uint, msg, condition, pendingKeyID string, fields map[string]interface{}) error {
+	server, err := sr.Get(id)
+	if err != nil {
+		sr.log.Error("Failed to execute query from db", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	// условие по ожидающему ключу не даёт двум ротациям перезаписать друг друга
+	result := sr.db.Model(&models.Server{}).Where("id = ?", id).Where(condition, pendingKeyID).Updates(fields)
+	if result.Error != nil {
+		sr.log.Error("Failed to update auth key", result.Error, slog.Uint64("id", uint64(id)))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return constants.ErrAuthKeyChanged
+	}
+
+	sr.cache.Delete("servers:all", fmt.Sprintf("servers:id:%d", id))
+	if server != nil {
+		sr.cache.Delete(fmt.Sprintf("servers:country_id:%d", server.CountryID))
+	}
+	sr.log.Debug(msg, slog.Uint64("id", uint64(id)))
+	return nil
+}
+
 func (sr *Servers) Delete(id uint) error {
 	server, err := sr.Get(id)
 	if err != nil {
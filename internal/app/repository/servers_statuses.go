@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log/slog"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+type ServersStatuses struct {
+	log   *logger.Logger
+	db    *gorm.DB
+	cache *cache.Cache
+}
+
+func (sr *ServersStatuses) GetLast(serverID uint) (status *models.ServerStatus, err error) {
+	cacheKey := fmt.Sprintf("server_status:last:%d", serverID)
+	if err = sr.cache.Get(cacheKey, &status); err == nil {
+		sr.log.Debug("Returning last server status from cache", slog.String("cache_key", cacheKey), slog.Uint64("server_id", uint64(serverID)))
+		return status, nil
+	}
+
+	if err = sr.db.Where("server_id = ?", serverID).Order("created_at DESC").First(&status).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			sr.log.Debug("No server status found in database", slog.Uint64("server_id", uint64(serverID)))
+			return nil, nil
+		}
+
+		sr.log.Error("Failed to get data from db", err, slog.Uint64("server_id", uint64(serverID)))
+		return nil, err
+	}
+
+	sr.cache.Set(cacheKey, status, 15*time.Minute)
+	sr.log.Debug("Returning last server status from db", slog.Uint64("server_id", uint64(serverID)))
+	return status, nil
+}
+
+func (sr *ServersStatuses) GetHistory(serverID uint, limit int) (statuses []*models.ServerStatus, err error) {
+	if err = sr.db.Where("server_id = ?", serverID).Order("created_at DESC").Limit(limit).Find(&statuses).Error; err != nil {
+		sr.log.Error("Failed to get data from db", err, slog.Uint64("server_id", uint64(serverID)))
+		return nil, err
+	}
+
+	sr.log.Debug("Returning server status history from db", slog.Uint64("server_id", uint64(serverID)), slog.Int("count", len(statuses)))
+	return statuses, nil
+}
+
+func (sr *ServersStatuses) Add(status *models.ServerStatus) error {
+	if err := sr.db.Create(&status).Error; err != nil {
+		sr.log.Error("Failed to execute query from db", err, slog.Any("status", status))
+		return err
+	}
+
+	sr.cache.Set(fmt.Sprintf("server_status:last:%d", status.ServerID), status, 15*time.Minute)
+	sr.log.Debug("Added new server status in db", slog.Uint64("server_id", uint64(status.ServerID)), slog.String("status", status.Status))
+	return nil
+}
+
+func (sr *ServersStatuses) DeleteOlderThan(before time.Time) error {
+	if err := sr.db.Where("created_at < ?", before).Delete(&models.ServerStatus{}).Error; err != nil {
+		sr.log.Error("Failed to delete old server statuses from db", err, slog.Time("before", before))
+		return err
+	}
+
+	sr.log.Debug("Deleted old server statuses from db", slog.Time("before", before))
+	return nil
+}
@@ -16,8 +16,9 @@ type Subscriptions struct {
 	db    *gorm.DB
 	cache *cache.Cache
 
-	Plans  *SubscriptionsPlans
-	Prices *SubscriptionsPrices
+	Plans    *SubscriptionsPlans
+	Prices   *SubscriptionsPrices
+	Vouchers *SubscriptionsVouchers
 }
 
 func NewSubscriptions(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Subscriptions {
@@ -35,6 +36,10 @@ func NewSubscriptions(log *logger.Logger, db *gorm.DB, cache *cache.Cache) *Subs
 			db:    db,
 			cache: cache,
 		},
+		Vouchers: &SubscriptionsVouchers{
+			log: log,
+			db:  db,
+		},
 	}
 }
 
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"log/slog"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+type SubscriptionsVouchers struct {
+	log *logger.Logger
+	db  *gorm.DB
+}
+
+func (sr *SubscriptionsVouchers) GetByCode(code string) (voucher *models.Voucher, err error) {
+	if err = sr.db.Where("code = ?", code).First(&voucher).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			sr.log.Debug("Voucher not found in database", slog.String("code", code))
+			return nil, nil
+		}
+
+		sr.log.Error("Failed to get voucher from db", err, slog.String("code", code))
+		return nil, err
+	}
+	return voucher, nil
+}
+
+func (sr *SubscriptionsVouchers) GetByBuyerID(buyerID int64) (vouchers []*models.Voucher, err error) {
+	if err = sr.db.Where("buyer_id = ?", buyerID).Order("id DESC").Find(&vouchers).Error; err != nil {
+		sr.log.Error("Failed to get vouchers from db", err, slog.Int64("buyer_id", buyerID))
+		return nil, err
+	}
+
+	sr.log.Debug("Returning vouchers from db", slog.Int64("buyer_id", buyerID), slog.Int("count", len(vouchers)))
+	return vouchers, nil
+}
+
+func (sr *SubscriptionsVouchers) Add(voucher *models.Voucher) error {
+	if err := sr.db.Create(&voucher).Error; err != nil {
+		sr.log.Error("Failed to create voucher in db", err, slog.Int64("buyer_id", voucher.BuyerID), slog.Uint64("plan_id", uint64(voucher.PlanID)))
+		return err
+	}
+
+	sr.log.Debug("Added new voucher in db", slog.Uint64("id", uint64(voucher.ID)), slog.Int64("buyer_id", voucher.BuyerID))
+	return nil
+}
+
+func (sr *SubscriptionsVouchers) Redeem(id uint, recipientID int64) error {
+	now := time.Now().UTC()
+	result := sr.db.Model(&models.Voucher{}).
+		Where("id = ? AND status = ?", id, models.VoucherStatusActive).
+		Updates(map[string]any{"status": models.VoucherStatusRedeemed, "recipient_id": recipientID, "redeemed_at": now})
+	if result.Error != nil {
+		sr.log.Error("Failed to redeem voucher", result.Error, slog.Uint64("id", uint64(id)), slog.Int64("recipient_id", recipientID))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return constants.ErrVoucherRedeemed
+	}
+
+	sr.log.Debug("Redeemed voucher", slog.Uint64("id", uint64(id)), slog.Int64("recipient_id", recipientID))
+	return nil
+}
+
+func (sr *SubscriptionsVouchers) Release(id uint) error {
+	err := sr.db.Model(&models.Voucher{}).
+		Where("id = ? AND status = ?", id, models.VoucherStatusRedeemed).
+		Updates(map[string]any{"status": models.VoucherStatusActive, "recipient_id": nil, "redeemed_at": nil}).Error
+	if err != nil {
+		sr.log.Error("Failed to release voucher", err, slog.Uint64("id", uint64(id)))
+		return err
+	}
+
+	sr.log.Debug("Released voucher", slog.Uint64("id", uint64(id)))
+	return nil
+}
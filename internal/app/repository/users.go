@@ -49,6 +49,16 @@ func (ur *Users) Get(id int64) (user *models.User, err error) {
 	return user, nil
 }
 
+func (ur *Users) GetAdmins() (users []*models.User, err error) {
+	if err = ur.db.Where("is_admin = ?", true).Find(&users).Error; err != nil {
+		ur.log.Error("Failed to get admins from db", err)
+		return nil, err
+	}
+
+	ur.log.Debug("Returning admins from db", slog.Int("count", len(users)))
+	return users, nil
+}
+
 func (ur *Users) CountPartners(id int64) (count int64, err error) {
 	cacheKey := fmt.Sprintf("user:%d:count_partners", id)
 	if err = ur.cache.Get(cacheKey, &count); err == nil {
@@ -109,6 +119,10 @@ func (ur *Users) Update(id int64, newUser *models.User) error {
 		tx.Rollback()
 		return err
 	}
+	if err = updateField(ur.log, tx, user, "language_code", user.LanguageCode, newUser.LanguageCode); err != nil {
+		tx.Rollback()
+		return err
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		ur.log.Error("Error committing transaction", err)
@@ -142,6 +156,40 @@ func (ur *Users) UpdateIsSign(id int64, isSign bool) error {
 	return nil
 }
 
+func (ur *Users) UpdateLanguage(id int64, language string) error {
+	if err := ur.db.Model(&models.User{}).Where("id = ?", id).Update("language", language).Error; err != nil {
+		ur.log.Error("Failed to update language", err, slog.Int64("id", id), slog.String("language", language))
+		return err
+	}
+
+	ur.cache.Delete(fmt.Sprintf("user:%d", id))
+	ur.log.Debug("Successfully updated language", slog.Int64("id", id), slog.String("language", language))
+	return nil
+}
+
+func (ur *Users) ClaimTrial(id int64) (bool, error) {
+	result := ur.db.Model(&models.User{}).Where("id = ? AND trial_used = ?", id, false).Update("trial_used", true)
+	if result.Error != nil {
+		ur.log.Error("Failed to claim trial", result.Error, slog.Int64("id", id))
+		return false, result.Error
+	}
+
+	ur.cache.Delete(fmt.Sprintf("user:%d", id))
+	ur.log.Debug("Claimed trial", slog.Int64("id", id), slog.Bool("claimed", result.RowsAffected > 0))
+	return result.RowsAffected > 0, nil
+}
+
+func (ur *Users) ReleaseTrial(id int64) error {
+	if err := ur.db.Model(&models.User{}).Where("id = ?", id).Update("trial_used", false).Error; err != nil {
+		ur.log.Error("Failed to release trial", err, slog.Int64("id", id))
+		return err
+	}
+
+	ur.cache.Delete(fmt.Sprintf("user:%d", id))
+	ur.log.Debug("Released trial", slog.Int64("id", id))
+	return nil
+}
+
 func (ur *Users) IncrementBalance(id int64, amount float64) error {
 	result := ur.db.Model(&models.User{}).
 		Where("id = ?", id).
@@ -2,6 +2,8 @@ package services
 
 import (
 	"gopkg.in/telebot.v4"
+	"nsvpn/internal/app/callback"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 )
 
@@ -32,6 +34,56 @@ func NewButtons(buttons []models.ButtonOption, layout []int, typeKeyboard string
 	}
 }
 
+type LocalizedButtons struct {
+	byLang map[string]*Buttons
+}
+
+func NewLocalizedButtons(buttons []models.ButtonOption, layout []int, typeKeyboard string) *LocalizedButtons {
+	lb := &LocalizedButtons{byLang: make(map[string]*Buttons)}
+	for _, lang := range i18n.Supported() {
+		lb.byLang[lang] = NewButtons(localizeButtons(lang, buttons), layout, typeKeyboard)
+	}
+	return lb
+}
+
+func (lb *LocalizedButtons) Get(lang string) *Buttons {
+	if bs, ok := lb.byLang[lang]; ok {
+		return bs
+	}
+	return lb.byLang[i18n.Default]
+}
+
+func (lb *LocalizedButtons) GetBtn(value string) *telebot.Btn {
+	return lb.Get(i18n.Default).GetBtn(value)
+}
+
+func localizeButtons(lang string, buttons []models.ButtonOption) []models.ButtonOption {
+	localized := make([]models.ButtonOption, len(buttons))
+	for i, item := range buttons {
+		if key := "button." + item.Value; i18n.Has(lang, key) {
+			item.Display = i18n.T(lang, key)
+		}
+		localized[i] = item
+	}
+	return localized
+}
+
+func NewCallbackButtons(router *callback.Router, buttons []models.ButtonOption, layout []int) (*Buttons, error) {
+	encoded := make([]models.ButtonOption, len(buttons))
+	for i, item := range buttons {
+		if item.Action != "" {
+			data, err := router.Encode(item.Action, item.Args...)
+			if err != nil {
+				return nil, err
+			}
+			item.Data = data
+		}
+		encoded[i] = item
+	}
+
+	return NewButtons(encoded, layout, KeyboardTypeInline), nil
+}
+
 func createButtonRows(menu *telebot.ReplyMarkup, buttons []models.ButtonOption, layout []int, typeKeyboard string) ([]telebot.Row, map[string]*telebot.Btn) {
 	btns := make(map[string]*telebot.Btn, len(buttons))
 
@@ -44,9 +96,12 @@ func createButtonRows(menu *telebot.ReplyMarkup, buttons []models.ButtonOption,
 	case KeyboardTypeInline:
 		for _, item := range buttons {
 			var btn telebot.Btn
-			if item.URL != "" {
+			switch {
+			case item.URL != "":
 				btn = menu.URL(item.Display, item.URL)
-			} else {
+			case item.Data != "":
+				btn = telebot.Btn{Text: item.Display, Data: item.Data}
+			default:
 				btn = menu.Data(item.Display, item.Value)
 			}
 			btns[item.Value] = &btn
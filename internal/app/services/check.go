@@ -4,6 +4,7 @@ import (
 	"gopkg.in/telebot.v4"
 	"log/slog"
 	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/pkg/logger"
 	"time"
@@ -16,11 +17,14 @@ type Check struct {
 	subs          *Subscriptions
 	servs         *Servers
 	us            *Users
+	cs            *Country
 	api           *api.API
-	clientButtons *Buttons
+	clientButtons *LocalizedButtons
+
+	counters map[uint]map[string]uint64 // последние счётчики трафика узлов по server_id и UUID
 }
 
-func NewCheck(log *logger.Logger, bot *telebot.Bot, ks *Keys, subs *Subscriptions, servs *Servers, us *Users, api *api.API, clientButtons *Buttons) *Check {
+func NewCheck(log *logger.Logger, bot *telebot.Bot, ks *Keys, subs *Subscriptions, servs *Servers, us *Users, cs *Country, api *api.API, clientButtons *LocalizedButtons) *Check {
 	return &Check{
 		log:           log,
 		bot:           bot,
@@ -28,8 +32,10 @@ func NewCheck(log *logger.Logger, bot *telebot.Bot, ks *Keys, subs *Subscription
 		subs:          subs,
 		servs:         servs,
 		us:            us,
+		cs:            cs,
 		api:           api,
 		clientButtons: clientButtons,
+		counters:      make(map[uint]map[string]uint64),
 	}
 }
 
@@ -46,54 +52,138 @@ func (c *Check) Run() {
 		return
 	}
 
+	var expired []*models.Subscription
 	for _, sub := range subscriptions {
-		isExpired, msg, opts := c.checkSubscriptionExpiration(sub)
+		lang := c.us.Language(sub.UserID)
+		isExpired, msg, opts := c.checkSubscriptionExpiration(sub, lang)
 		if !isExpired {
 			continue
 		}
 
-		if ok := c.tryRenewSubscription(sub, servers); ok {
-			msg = "Ваша подписка успешно продлена"
+		// напоминания только уведомляют, продление и отключение — после фактического окончания
+		if hasEnded(sub) {
+			if !sub.IsTrial && c.tryRenewSubscription(sub) {
+				msg, opts = i18n.T(lang, "subscriptions.renewed"), nil
+			} else {
+				expired = append(expired, sub)
+			}
 		}
 
 		if _, err := c.bot.Send(&telebot.User{ID: sub.UserID}, msg, opts); err != nil {
 			c.log.Error("Failed to send message", err)
 		}
 	}
+
+	c.accumulateTraffic(servers)
+	for _, sub := range c.trialsOverTraffic(subscriptions) {
+		expired = append(expired, sub)
+
+		lang := c.us.Language(sub.UserID)
+		if _, err := c.bot.Send(&telebot.User{ID: sub.UserID}, i18n.T(lang, "trials.traffic_exceeded"), c.clientButtons.Get(lang).AddBtns()); err != nil {
+			c.log.Error("Failed to send message", err)
+		}
+	}
+
+	c.processServers(expired, servers)
 }
 
-func (c *Check) checkSubscriptionExpiration(sub *models.Subscription) (bool, string, *telebot.ReplyMarkup) {
+func (c *Check) checkSubscriptionExpiration(sub *models.Subscription, lang string) (bool, string, *telebot.ReplyMarkup) {
 	expireTime := time.Until(sub.EndDate)
-	now := time.Now()
 
 	isExpired := (expireTime <= 3*time.Hour && expireTime > 2*time.Hour) ||
 		(expireTime <= 24*time.Hour && expireTime > 23*time.Hour) ||
 		(expireTime <= 72*time.Hour && expireTime > 71*time.Hour) ||
 		(expireTime <= 168*time.Hour && expireTime > 167*time.Hour) ||
-		(sub.EndDate.Before(now) && !sub.EndDate.IsZero())
+		hasEnded(sub)
 
-	msg := "Ваша подписка истечёт в " + sub.EndDate.Format("2006-01-02 15:04:05")
+	msg := i18n.T(lang, "subscriptions.expires_at", sub.EndDate.Format("2006-01-02 15:04:05"))
 	var opts *telebot.ReplyMarkup
 
-	if sub.EndDate.Before(now) && !sub.EndDate.IsZero() {
-		msg = "Ваша подписка истекла"
-		opts = c.clientButtons.AddBtns()
+	if sub.IsTrial {
+		// пробный период короткий: напоминаем за сутки и за несколько часов, предлагая оформить подписку
+		isExpired = isExpired && expireTime <= 24*time.Hour
+		msg = i18n.T(lang, "trials.ends_at", sub.EndDate.Format("2006-01-02 15:04:05"))
+		opts = c.clientButtons.Get(lang).AddBtns()
+	}
+
+	if hasEnded(sub) {
+		msg = i18n.T(lang, "subscriptions.expired")
+		if sub.IsTrial {
+			msg = i18n.T(lang, "trials.expired")
+		}
+		opts = c.clientButtons.Get(lang).AddBtns()
 	}
 
 	return isExpired, msg, opts
 }
 
-func (c *Check) tryRenewSubscription(sub *models.Subscription, servers []*models.Server) bool {
+func (c *Check) trialsOverTraffic(subs []*models.Subscription) []*models.Subscription {
+	var exceeded []*models.Subscription
+	for _, sub := range subs {
+		if !sub.IsTrial || sub.TrafficLimit <= 0 || hasEnded(sub) {
+			continue
+		}
+
+		used, err := c.ks.SumTrafficUsed(sub.UserID)
+		if err != nil {
+			continue
+		}
+		if used >= uint64(sub.TrafficLimit) {
+			c.log.Info("Trial traffic limit exceeded", slog.Int64("user_id", sub.UserID), slog.Uint64("used", used), slog.Int64("limit", sub.TrafficLimit))
+			exceeded = append(exceeded, sub)
+		}
+	}
+	return exceeded
+}
+
+// счётчики узла сбрасываются при ротации и перезапуске, поэтому в ключ копится только прирост с прошлой проверки
+func (c *Check) accumulateTraffic(servers []*models.Server) {
+	deltas := make(map[string]uint64)
+	for _, serv := range servers {
+		traffic, err := c.api.ListClientsTrafficRequest(serv)
+		if err != nil {
+			c.log.Warn("Failed to get clients traffic", slog.Any("server", serv), slog.String("error", err.Error()))
+			continue
+		}
+
+		// первый опрос после запуска только запоминает счётчики: прошлые значения неизвестны
+		prev, seen := c.counters[serv.ID]
+		c.counters[serv.ID] = traffic
+		if !seen {
+			continue
+		}
+
+		for uuid, bytes := range traffic {
+			if last, ok := prev[uuid]; ok && bytes >= last {
+				bytes -= last
+			}
+			deltas[uuid] += bytes
+		}
+	}
+
+	for uuid, bytes := range deltas {
+		if bytes == 0 {
+			continue
+		}
+		if err := c.ks.AddTrafficUsed(uuid, bytes); err != nil {
+			c.log.Error("Failed to save key traffic", err, slog.String("uuid", uuid))
+		}
+	}
+}
+
+func hasEnded(sub *models.Subscription) bool {
+	return sub.EndDate.Before(time.Now()) && !sub.EndDate.IsZero()
+}
+
+func (c *Check) tryRenewSubscription(sub *models.Subscription) bool {
 	plan, err := c.subs.Plans.GetByDays(30)
 	if err != nil {
 		c.log.Error("Failed to get plan", err)
-		c.processServers(sub, servers)
 		return false
 	}
 
 	if err := c.us.DecrementBalance(sub.UserID, plan.SubscriptionPrice.Price); err != nil {
 		c.log.Error("Failed to decrement balance", err)
-		c.processServers(sub, servers)
 		return false
 	}
 
@@ -105,29 +195,65 @@ func (c *Check) tryRenewSubscription(sub *models.Subscription, servers []*models
 			c.log.Error("Failed to rollback balance", rerr)
 		}
 
-		c.processServers(sub, servers)
 		return false
 	}
 
 	return true
 }
 
-func (c *Check) processServers(sub *models.Subscription, servers []*models.Server) {
+func (c *Check) processServers(subs []*models.Subscription, servers []*models.Server) {
+	if len(subs) == 0 {
+		return
+	}
+
 	for _, serv := range servers {
-		key, err := c.ks.Get(serv.ID, sub.UserID)
+		transports, err := c.cs.Transports.GetAllByCountryID(serv.CountryID)
 		if err != nil {
-			c.log.Error("Failed to get server key", err, slog.Any("server", serv))
+			c.log.Error("Failed to get country transports", err, slog.Any("server", serv))
 			continue
 		}
+		protocols := c.cs.Transports.GetProtocols(transports)
+
+		clients := make([]*models.NodeClient, 0, len(subs)*len(protocols))
+		for _, sub := range subs {
+			key, err := c.ks.Get(serv.CountryID, sub.UserID)
+			if err != nil {
+				c.log.Error("Failed to get server key", err, slog.Any("server", serv))
+				continue
+			}
+			if key == nil {
+				continue
+			}
 
-		if err := c.api.DeleteRequest(serv, key.UUID); err != nil {
-			c.log.Error("Failed to delete client", err, slog.Any("server", serv), slog.Any("key", key))
-		} else {
-			c.log.Info("Client deleted due to expiration", slog.Any("server", serv), slog.Any("key", key))
+			for _, protocol := range protocols {
+				clients = append(clients, &models.NodeClient{UUID: key.UUID, Protocol: protocol.Protocol})
+			}
 		}
+		c.deleteClients(serv, clients)
 	}
 
-	if err := c.subs.UpdateIsActive(sub.ID, sub.UserID, false); err != nil {
-		c.log.Error("Failed to deactivate subscription", err)
+	for _, sub := range subs {
+		if err := c.subs.UpdateIsActive(sub.ID, sub.UserID, false); err != nil {
+			c.log.Error("Failed to deactivate subscription", err)
+		}
 	}
 }
+
+func (c *Check) deleteClients(serv *models.Server, clients []*models.NodeClient) int {
+	if len(clients) == 0 {
+		return 0
+	}
+
+	failed, err := c.api.BatchDeleteRequest(serv, clients)
+	if err != nil {
+		c.log.Error("Failed to delete clients", err, slog.Any("server", serv), slog.Int("count", len(clients)))
+		return 0
+	}
+	for _, result := range failed {
+		c.log.Warn("Failed to delete client", slog.Any("server", serv), slog.String("uuid", result.UUID), slog.String("protocol", result.Protocol), slog.String("error", result.Error))
+	}
+
+	deleted := len(clients) - len(failed)
+	c.log.Info("Clients deleted due to expiration", slog.Any("server", serv), slog.Int("count", deleted))
+	return deleted
+}
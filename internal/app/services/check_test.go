@@ -0,0 +1,169 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	pbClient "nsvpn/pkg/client/v1"
+	"nsvpn/pkg/fakenode"
+	"nsvpn/pkg/logger"
+)
+
+func TestCheckSubscriptionExpiration(t *testing.T) {
+	c := NewCheck(logger.NewDiscard(), nil, nil, nil, nil, nil, nil, nil, NewLocalizedButtons(nil, nil, KeyboardTypeInline))
+
+	tests := []struct {
+		name    string
+		until   time.Duration
+		expired bool
+		markup  bool
+	}{
+		{"week left", 167*time.Hour + 30*time.Minute, true, false},
+		{"three days left", 71*time.Hour + 30*time.Minute, true, false},
+		{"day left", 23*time.Hour + 30*time.Minute, true, false},
+		{"hours left", 2*time.Hour + 30*time.Minute, true, false},
+		{"between reminders", 48 * time.Hour, false, false},
+		{"expired", -time.Minute, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isExpired, msg, markup := c.checkSubscriptionExpiration(&models.Subscription{EndDate: time.Now().Add(tt.until)}, i18n.RU)
+			if isExpired != tt.expired || (markup != nil) != tt.markup || msg == "" {
+				t.Fatalf("got %v, %q, %v", isExpired, msg, markup)
+			}
+		})
+	}
+}
+
+func TestCheckDeleteClients(t *testing.T) {
+	serv := &models.Server{ID: 1, IP: "10.0.0.1", Insecure: true}
+	node := fakenode.New(fakenode.Config{})
+	node.AddClient(&pbClient.Client{Uuid: "uuid-1", Protocol: pbClient.Protocol_PROTOCOL_VLESS})
+	node.AddClient(&pbClient.Client{Uuid: "uuid-1", Protocol: pbClient.Protocol_PROTOCOL_TROJAN})
+	node.AddClient(&pbClient.Client{Uuid: "uuid-2", Protocol: pbClient.Protocol_PROTOCOL_VLESS})
+	a := newTestAPI(t, map[*models.Server]*fakenode.Node{serv: node})
+	c := NewCheck(logger.NewDiscard(), nil, nil, nil, nil, nil, nil, a, nil)
+
+	deleted := c.deleteClients(serv, []*models.NodeClient{
+		{UUID: "uuid-1", Protocol: models.ProtocolVLESS},
+		{UUID: "uuid-1", Protocol: models.ProtocolTrojan},
+		{UUID: "uuid-3", Protocol: models.ProtocolVLESS},
+	})
+	if deleted != 2 {
+		t.Fatalf("deleted %d clients, want 2", deleted)
+	}
+
+	clients := node.Clients()
+	if len(clients) != 1 || clients[0].GetUuid() != "uuid-2" {
+		t.Fatalf("unexpected clients left on node %v", clients)
+	}
+}
+
+func TestCheckDeleteClientsNodeDown(t *testing.T) {
+	serv := &models.Server{ID: 1, IP: "10.0.0.1", Insecure: true}
+	node := fakenode.New(fakenode.Config{FailureRate: 1})
+	a := newTestAPI(t, map[*models.Server]*fakenode.Node{serv: node})
+	c := NewCheck(logger.NewDiscard(), nil, nil, nil, nil, nil, nil, a, nil)
+
+	if deleted := c.deleteClients(serv, []*models.NodeClient{{UUID: "uuid-1", Protocol: models.ProtocolVLESS}}); deleted != 0 {
+		t.Fatalf("deleted %d clients on a failing node", deleted)
+	}
+}
+
+func TestCheckTrialExpiration(t *testing.T) {
+	c := NewCheck(logger.NewDiscard(), nil, nil, nil, nil, nil, nil, nil, NewLocalizedButtons(nil, nil, KeyboardTypeInline))
+
+	tests := []struct {
+		name    string
+		until   time.Duration
+		expired bool
+	}{
+		{"three days left", 71*time.Hour + 30*time.Minute, false},
+		{"day left", 23*time.Hour + 30*time.Minute, true},
+		{"hours left", 2*time.Hour + 30*time.Minute, true},
+		{"expired", -time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isExpired, msg, markup := c.checkSubscriptionExpiration(&models.Subscription{EndDate: time.Now().Add(tt.until), IsTrial: true}, i18n.RU)
+			if isExpired != tt.expired || markup == nil || msg == "" {
+				t.Fatalf("got %v, %q, %v", isExpired, msg, markup)
+			}
+		})
+	}
+}
+
+func TestCheckAccumulateTraffic(t *testing.T) {
+	db, cache := newTestStore(t)
+	log := logger.NewDiscard()
+	ks := NewKeys(log, repository.NewKeys(log, db, cache))
+
+	first := &models.Server{ID: 1, IP: "10.0.0.1", Insecure: true}
+	second := &models.Server{ID: 2, IP: "10.0.0.2", Insecure: true}
+	down := &models.Server{ID: 3, IP: "10.0.0.3", Insecure: true}
+
+	node := fakenode.New(fakenode.Config{})
+	node.AddClient(&pbClient.Client{Uuid: "uuid-1", Protocol: pbClient.Protocol_PROTOCOL_VLESS})
+	node.AddClient(&pbClient.Client{Uuid: "uuid-1", Protocol: pbClient.Protocol_PROTOCOL_TROJAN})
+	node.SetTraffic(pbClient.Protocol_PROTOCOL_VLESS, "uuid-1", 100, 200)
+	node.SetTraffic(pbClient.Protocol_PROTOCOL_TROJAN, "uuid-1", 10, 20)
+
+	other := fakenode.New(fakenode.Config{})
+	other.AddClient(&pbClient.Client{Uuid: "uuid-2", Protocol: pbClient.Protocol_PROTOCOL_VLESS})
+	other.SetTraffic(pbClient.Protocol_PROTOCOL_VLESS, "uuid-2", 1000, 0)
+
+	a := newTestAPI(t, map[*models.Server]*fakenode.Node{
+		first:  node,
+		second: other,
+		down:   fakenode.New(fakenode.Config{FailureRate: 1}),
+	})
+	c := NewCheck(log, nil, ks, nil, nil, nil, nil, a, nil)
+
+	mustCreate(t, db, &models.User{ID: 1})
+	mustCreate(t, db, &models.Country{ID: 1, Code: "de", PublicKey: "de", PrivateKey: "de"})
+	mustCreate(t, db, &models.Country{ID: 2, Code: "nl", PublicKey: "nl", PrivateKey: "nl"})
+	mustCreate(t, db, &models.Key{UserID: 1, CountryID: 1, UUID: "uuid-1"})
+	mustCreate(t, db, &models.Key{UserID: 1, CountryID: 2, UUID: "uuid-2"})
+
+	servers := []*models.Server{first, second, down}
+	used := func() uint64 {
+		t.Helper()
+		sum, err := ks.SumTrafficUsed(1)
+		if err != nil {
+			t.Fatalf("SumTrafficUsed: %v", err)
+		}
+		return sum
+	}
+
+	// первый опрос только запоминает счётчики
+	c.accumulateTraffic(servers)
+	if got := used(); got != 0 {
+		t.Fatalf("traffic after baseline = %d, want 0", got)
+	}
+
+	node.SetTraffic(pbClient.Protocol_PROTOCOL_VLESS, "uuid-1", 150, 250)
+	other.SetTraffic(pbClient.Protocol_PROTOCOL_VLESS, "uuid-2", 1500, 0)
+	c.accumulateTraffic(servers)
+	if got := used(); got != 600 {
+		t.Fatalf("traffic = %d, want 600", got)
+	}
+
+	// после ротации клиент появляется на узле заново со сброшенным счётчиком
+	other.SetTraffic(pbClient.Protocol_PROTOCOL_VLESS, "uuid-2", 40, 0)
+	c.accumulateTraffic(servers)
+	if got := used(); got != 640 {
+		t.Fatalf("traffic after counter reset = %d, want 640", got)
+	}
+
+	trial := &models.Subscription{UserID: 1, IsTrial: true, TrafficLimit: 640, EndDate: time.Now().Add(time.Hour)}
+	if exceeded := c.trialsOverTraffic([]*models.Subscription{trial}); len(exceeded) != 1 {
+		t.Fatalf("trial over the limit on all keys was not detected: %v", exceeded)
+	}
+	trial.TrafficLimit = 641
+	if exceeded := c.trialsOverTraffic([]*models.Subscription{trial}); len(exceeded) != 0 {
+		t.Fatalf("trial under the limit reported: %v", exceeded)
+	}
+}
@@ -14,12 +14,18 @@ import (
 type Country struct {
 	log *logger.Logger
 	cr  *repository.Country
+
+	Transports *CountryTransports
 }
 
 func NewCountry(log *logger.Logger, cr *repository.Country) *Country {
 	return &Country{
 		log: log,
 		cr:  cr,
+		Transports: &CountryTransports{
+			log: log,
+			cr:  cr,
+		},
 	}
 }
 
@@ -68,6 +74,8 @@ func (cs *Country) ProcessButtons(countries []*models.Country) ([]models.ButtonO
 		listCountries = append(listCountries, models.ButtonOption{
 			Value:   country.Code,
 			Display: fmt.Sprintf("%s %s", country.Emoji, country.Code),
+			Action:  "country",
+			Args:    []string{country.Code},
 		})
 	}
 
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"strconv"
+)
+
+type CountryTransports struct {
+	log *logger.Logger
+	cr  *repository.Country
+}
+
+func (cs *CountryTransports) GetAllByCountryID(countryID uint) (transports []*models.CountryTransport, err error) {
+	if countryID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	transports, err = cs.cr.Transports.GetAllByCountryID(countryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transports) == 0 {
+		return []*models.CountryTransport{cs.Default(countryID)}, nil
+	}
+	return transports, nil
+}
+
+func (cs *CountryTransports) Get(id uint) (transport *models.CountryTransport, err error) {
+	if id == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return cs.cr.Transports.Get(id)
+}
+
+func (cs *CountryTransports) Add(transport *models.CountryTransport) error {
+	if transport.CountryID == 0 || transport.Name == "" || transport.Protocol == "" || transport.Network == "" ||
+		transport.Security == "" || transport.Port == 0 {
+		return constants.ErrEmptyFields
+	}
+
+	return cs.cr.Transports.Add(transport)
+}
+
+func (cs *CountryTransports) Update(id uint, newTransport *models.CountryTransport) error {
+	if id == 0 || newTransport == nil {
+		return constants.ErrEmptyFields
+	}
+
+	return cs.cr.Transports.Update(id, newTransport)
+}
+
+func (cs *CountryTransports) Delete(id uint) error {
+	if id == 0 {
+		return constants.ErrEmptyFields
+	}
+
+	return cs.cr.Transports.Delete(id)
+}
+
+func (cs *CountryTransports) Default(countryID uint) *models.CountryTransport {
+	return &models.CountryTransport{
+		CountryID:   countryID,
+		Name:        "TCP Reality",
+		Protocol:    models.ProtocolVLESS,
+		Network:     models.NetworkTCP,
+		Port:        443,
+		Security:    models.SecurityReality,
+		Fingerprint: "random",
+		IsActive:    true,
+	}
+}
+
+func (cs *CountryTransports) ProcessButtons(transports []*models.CountryTransport) ([]models.ButtonOption, []int) {
+	listTransports := make([]models.ButtonOption, 0, len(transports))
+
+	for _, transport := range transports {
+		listTransports = append(listTransports, models.ButtonOption{
+			Value:   fmt.Sprintf("transport_%d", transport.ID),
+			Display: transport.Name,
+			Action:  "transport",
+			Args:    []string{strconv.FormatUint(uint64(transport.ID), 10)},
+		})
+	}
+
+	var groups []int
+	remaining := len(listTransports)
+	for remaining > 0 {
+		if remaining >= 2 {
+			groups = append(groups, 2)
+			remaining -= 2
+		} else {
+			groups = append(groups, remaining)
+			break
+		}
+	}
+
+	return listTransports, groups
+}
+
+func (cs *CountryTransports) GetProtocols(transports []*models.CountryTransport) []*models.CountryTransport {
+	seen := make(map[string]bool, len(transports))
+	protocols := make([]*models.CountryTransport, 0, len(transports))
+
+	for _, transport := range transports {
+		if seen[transport.Protocol] {
+			continue
+		}
+		seen[transport.Protocol] = true
+		protocols = append(protocols, transport)
+	}
+
+	return protocols
+}
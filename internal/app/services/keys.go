@@ -1,25 +1,36 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"github.com/skip2/go-qrcode"
 	"net"
 	"net/url"
 	"nsvpn/internal/app/constants"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/repository"
 	"nsvpn/pkg/logger"
+	"strconv"
 	"strings"
 )
 
 type Keys struct {
 	log *logger.Logger
 	kr  *repository.Keys
+
+	Statuses *KeysStatuses
 }
 
 func NewKeys(log *logger.Logger, kr *repository.Keys) *Keys {
 	return &Keys{
 		log: log,
 		kr:  kr,
+		Statuses: &KeysStatuses{
+			log: log,
+			kr:  kr,
+		},
 	}
 }
 
@@ -27,6 +38,42 @@ func (ks *Keys) GetAll(userID int64) (keys []*models.Key, err error) {
 	return ks.kr.GetAll(userID)
 }
 
+func (ks *Keys) GetAllActive() (keys []*models.Key, err error) {
+	return ks.kr.GetAllActive()
+}
+
+func (ks *Keys) GetActiveByCountryID(countryID, afterID uint, limit int) (keys []*models.Key, err error) {
+	if countryID == 0 || limit <= 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return ks.kr.GetActiveByCountryID(countryID, afterID, limit)
+}
+
+func (ks *Keys) CountActiveByCountryID(countryID uint) (count int64, err error) {
+	if countryID == 0 {
+		return 0, constants.ErrEmptyFields
+	}
+
+	return ks.kr.CountActiveByCountryID(countryID)
+}
+
+func (ks *Keys) SumTrafficUsed(userID int64) (uint64, error) {
+	if userID == 0 {
+		return 0, constants.ErrEmptyFields
+	}
+
+	return ks.kr.SumTrafficUsed(userID)
+}
+
+func (ks *Keys) AddTrafficUsed(uuid string, bytes uint64) error {
+	if uuid == "" || bytes == 0 {
+		return constants.ErrEmptyFields
+	}
+
+	return ks.kr.AddTrafficUsed(uuid, bytes)
+}
+
 func (ks *Keys) Get(countryID uint, userID int64) (key *models.Key, err error) {
 	if countryID == 0 || userID == 0 {
 		return nil, constants.ErrEmptyFields
@@ -67,6 +114,162 @@ func (ks *Keys) Delete(countryID uint, userID int64) error {
 	return ks.kr.Delete(countryID, userID)
 }
 
-func (ks *Keys) GetVlessKey(uuid string, country *models.Country, countryCode string) string {
-	return fmt.Sprintf("vless://%s@%s/?type=tcp&security=reality&pbk=%s&flow=%s&fp=random&sni=%s&sid=%s&spx=%s#%s", uuid, net.JoinHostPort(country.Domain, "443"), country.PublicKey, country.Flow, strings.TrimSuffix(country.Dest, ":443"), country.ShortIDs, url.QueryEscape("/"), countryCode)
+func (ks *Keys) GenerateSecrets(key *models.Key) error {
+	password := make([]byte, 16)
+	if _, err := rand.Read(password); err != nil {
+		ks.log.Error("Failed to generate trojan password", err)
+		return err
+	}
+
+	psk := make([]byte, 32)
+	if _, err := rand.Read(psk); err != nil {
+		ks.log.Error("Failed to generate shadowsocks psk", err)
+		return err
+	}
+
+	key.Password = hex.EncodeToString(password)
+	key.PSK = base64.StdEncoding.EncodeToString(psk)
+	return nil
+}
+
+func (ks *Keys) EnsureSecrets(key *models.Key) (bool, error) {
+	if key.Password != "" && key.PSK != "" {
+		return false, nil
+	}
+
+	// ключи, созданные до появления Trojan и Shadowsocks, хранятся без секретов
+	secrets := *key
+	if err := ks.GenerateSecrets(&secrets); err != nil {
+		return false, err
+	}
+	if err := ks.kr.FillSecrets(key, secrets.Password, secrets.PSK); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (ks *Keys) GetEmail(userID int64, countryCode, protocol string) string {
+	email := fmt.Sprintf("nsvpn-%d-%s", userID, strings.ToLower(countryCode))
+	if protocol != "" && protocol != models.ProtocolVLESS {
+		email += "-" + protocol
+	}
+	return email
+}
+
+func (ks *Keys) GetSecret(key *models.Key, transport *models.CountryTransport) string {
+	switch transport.Protocol {
+	case models.ProtocolTrojan:
+		return key.Password
+	case models.ProtocolShadowsocks:
+		return ks.getShadowsocksPSK(key.PSK, transport.Method)
+	default:
+		return ""
+	}
+}
+
+func (ks *Keys) GetKey(key *models.Key, host string, country *models.Country, transport *models.CountryTransport, name string) string {
+	switch transport.Protocol {
+	case models.ProtocolTrojan:
+		return ks.GetTrojanKey(key.Password, host, country, transport, name)
+	case models.ProtocolShadowsocks:
+		return ks.GetShadowsocksKey(key.PSK, host, country, transport, name)
+	default:
+		return ks.GetVlessKey(key.UUID, host, country, transport, name)
+	}
+}
+
+func (ks *Keys) GetQRCode(link string) ([]byte, error) {
+	png, err := qrcode.Encode(link, qrcode.Medium, 512)
+	if err != nil {
+		ks.log.Error("Failed to generate qr code", err)
+		return nil, err
+	}
+
+	return png, nil
+}
+
+func (ks *Keys) GetVlessKey(uuid, host string, country *models.Country, transport *models.CountryTransport, name string) string {
+	query := ks.transportQuery(country, transport)
+	query.Set("encryption", "none")
+	if transport.Network == models.NetworkTCP && transport.Security == models.SecurityReality && country.Flow != "" {
+		query.Set("flow", country.Flow)
+	}
+
+	return fmt.Sprintf("vless://%s@%s?%s#%s", uuid, ks.getAddress(host, transport), query.Encode(), url.PathEscape(name))
+}
+
+func (ks *Keys) GetTrojanKey(password, host string, country *models.Country, transport *models.CountryTransport, name string) string {
+	query := ks.transportQuery(country, transport)
+	return fmt.Sprintf("trojan://%s@%s?%s#%s", url.PathEscape(password), ks.getAddress(host, transport), query.Encode(), url.PathEscape(name))
+}
+
+func (ks *Keys) GetShadowsocksKey(psk, host string, country *models.Country, transport *models.CountryTransport, name string) string {
+	password := ks.getShadowsocksPSK(psk, transport.Method)
+	if transport.ServerPSK != "" {
+		password = transport.ServerPSK + ":" + password
+	}
+
+	return fmt.Sprintf("ss://%s:%s@%s#%s", url.QueryEscape(transport.Method), url.QueryEscape(password), ks.getAddress(host, transport), url.PathEscape(name))
+}
+
+func (ks *Keys) getAddress(host string, transport *models.CountryTransport) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(transport.Port)))
+}
+
+func (ks *Keys) getShadowsocksPSK(psk, method string) string {
+	raw, err := base64.StdEncoding.DecodeString(psk)
+	if err != nil {
+		ks.log.Error("Failed to decode shadowsocks psk", err)
+		return psk
+	}
+
+	if method == "2022-blake3-aes-128-gcm" && len(raw) > 16 {
+		raw = raw[:16]
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func (ks *Keys) transportQuery(country *models.Country, transport *models.CountryTransport) url.Values {
+	query := url.Values{}
+	query.Set("type", transport.Network)
+	query.Set("security", transport.Security)
+
+	switch transport.Security {
+	case models.SecurityReality:
+		query.Set("pbk", country.PublicKey)
+		query.Set("sid", strings.TrimSpace(strings.Split(country.ShortIDs, ",")[0]))
+		query.Set("spx", "/")
+		query.Set("sni", strings.TrimSuffix(country.Dest, ":443"))
+		if transport.Host != "" {
+			query.Set("sni", transport.Host)
+		}
+		query.Set("fp", transport.Fingerprint)
+	case models.SecurityTLS:
+		query.Set("sni", country.Domain)
+		if transport.Host != "" {
+			query.Set("sni", transport.Host)
+		}
+		query.Set("fp", transport.Fingerprint)
+		if transport.ALPN != "" {
+			query.Set("alpn", transport.ALPN)
+		}
+	}
+
+	switch transport.Network {
+	case models.NetworkWS, models.NetworkXHTTP:
+		query.Set("path", transport.Path)
+		if transport.Host != "" {
+			query.Set("host", transport.Host)
+		}
+	case models.NetworkGRPC:
+		query.Set("serviceName", transport.ServiceName)
+		query.Set("mode", "gun")
+	}
+
+	for key, values := range query {
+		if len(values) == 0 || values[0] == "" {
+			query.Del(key)
+		}
+	}
+	return query
 }
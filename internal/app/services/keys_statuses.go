@@ -0,0 +1,29 @@
+package services
+
+import (
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+)
+
+type KeysStatuses struct {
+	log *logger.Logger
+	kr  *repository.Keys
+}
+
+func (ks *KeysStatuses) GetAll(keyID uint) (statuses []*models.KeyStatus, err error) {
+	if keyID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return ks.kr.Statuses.GetAll(keyID)
+}
+
+func (ks *KeysStatuses) Set(status *models.KeyStatus) error {
+	if status.KeyID == 0 || status.ServerID == 0 || status.UUID == "" || status.Status == "" {
+		return constants.ErrEmptyFields
+	}
+
+	return ks.kr.Statuses.Set(status)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/logger"
+)
+
+func testCountry() *models.Country {
+	return &models.Country{
+		Code:      "DE",
+		Domain:    "de.example.com",
+		PublicKey: "public-key",
+		Flow:      "xtls-rprx-vision",
+		Dest:      "www.example.com:443",
+		ShortIDs:  "abcd, ef01",
+	}
+}
+
+func TestGetVlessKeyReality(t *testing.T) {
+	ks := NewKeys(logger.NewDiscard(), nil)
+	transport := &models.CountryTransport{
+		Protocol:    models.ProtocolVLESS,
+		Network:     models.NetworkTCP,
+		Security:    models.SecurityReality,
+		Port:        443,
+		Fingerprint: "chrome",
+	}
+
+	link := ks.GetVlessKey("uuid-1", "1.2.3.4", testCountry(), transport, "DE 1")
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("invalid link %q: %v", link, err)
+	}
+	if u.Scheme != "vless" || u.User.Username() != "uuid-1" || u.Host != "1.2.3.4:443" || u.Fragment != "DE 1" {
+		t.Fatalf("unexpected link %q", link)
+	}
+
+	query := u.Query()
+	want := map[string]string{
+		"type":       "tcp",
+		"security":   "reality",
+		"encryption": "none",
+		"flow":       "xtls-rprx-vision",
+		"pbk":        "public-key",
+		"sid":        "abcd",
+		"sni":        "www.example.com",
+		"fp":         "chrome",
+	}
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestGetVlessKeyWebsocketTLS(t *testing.T) {
+	ks := NewKeys(logger.NewDiscard(), nil)
+	transport := &models.CountryTransport{
+		Protocol: models.ProtocolVLESS,
+		Network:  models.NetworkWS,
+		Security: models.SecurityTLS,
+		Port:     8443,
+		Path:     "/ws",
+	}
+
+	u, err := url.Parse(ks.GetVlessKey("uuid-1", "de.example.com", testCountry(), transport, "DE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := u.Query()
+	if query.Get("sni") != "de.example.com" || query.Get("path") != "/ws" || query.Has("flow") || query.Has("pbk") || query.Has("fp") {
+		t.Fatalf("unexpected query %v", query)
+	}
+}
+
+func TestGetKeyByProtocol(t *testing.T) {
+	ks := NewKeys(logger.NewDiscard(), nil)
+	key := &models.Key{UUID: "uuid-1"}
+	if err := ks.GenerateSecrets(key); err != nil {
+		t.Fatal(err)
+	}
+
+	trojan := &models.CountryTransport{Protocol: models.ProtocolTrojan, Network: models.NetworkTCP, Security: models.SecurityTLS, Port: 443}
+	if link := ks.GetKey(key, "1.2.3.4", testCountry(), trojan, "DE"); !strings.HasPrefix(link, "trojan://"+key.Password+"@") {
+		t.Fatalf("unexpected trojan link %q", link)
+	}
+
+	ss := &models.CountryTransport{Protocol: models.ProtocolShadowsocks, Method: "2022-blake3-aes-128-gcm", ServerPSK: "server-psk", Port: 8388}
+	link := ks.GetKey(key, "1.2.3.4", testCountry(), ss, "DE")
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, _ := u.User.Password()
+	serverPSK, userPSK, ok := strings.Cut(password, ":")
+	if u.Scheme != "ss" || u.User.Username() != ss.Method || !ok || serverPSK != ss.ServerPSK {
+		t.Fatalf("unexpected shadowsocks link %q", link)
+	}
+	if raw, err := base64.StdEncoding.DecodeString(userPSK); err != nil || len(raw) != 16 {
+		t.Fatalf("user psk %q is not a 16 byte key for aes-128", userPSK)
+	}
+	if userPSK != ks.GetSecret(key, ss) {
+		t.Fatal("link psk differs from the secret sent to the node")
+	}
+}
+
+func TestGetEmail(t *testing.T) {
+	ks := NewKeys(logger.NewDiscard(), nil)
+
+	tests := []struct {
+		protocol string
+		want     string
+	}{
+		{"", "nsvpn-42-de"},
+		{models.ProtocolVLESS, "nsvpn-42-de"},
+		{models.ProtocolTrojan, "nsvpn-42-de-trojan"},
+		{models.ProtocolShadowsocks, "nsvpn-42-de-shadowsocks"},
+	}
+	for _, tt := range tests {
+		if got := ks.GetEmail(42, "DE", tt.protocol); got != tt.want {
+			t.Errorf("GetEmail(%q) = %q, want %q", tt.protocol, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/events"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+	"nsvpn/pkg/logger"
+	"sync"
+	"time"
+)
+
+const (
+	overloadThreshold = 0.95
+	statusRetention   = 7 * 24 * time.Hour
+)
+
+type Monitor struct {
+	log   *logger.Logger
+	bot   *telebot.Bot
+	servs *Servers
+	us    *Users
+	api   *api.API
+}
+
+func NewMonitor(log *logger.Logger, bot *telebot.Bot, servs *Servers, us *Users, api *api.API) *Monitor {
+	return &Monitor{
+		log:   log,
+		bot:   bot,
+		servs: servs,
+		us:    us,
+		api:   api,
+	}
+}
+
+func (m *Monitor) Run() {
+	servers, err := m.servs.GetAll()
+	if err != nil {
+		m.log.Error("Failed to get all servers", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, serv := range servers {
+		wg.Add(1)
+		go func(server *models.Server) {
+			defer wg.Done()
+			m.checkServer(server)
+		}(serv)
+	}
+	wg.Wait()
+
+	if err := m.servs.Statuses.DeleteOlderThan(time.Now().Add(-statusRetention)); err != nil {
+		m.log.Error("Failed to delete old server statuses", err)
+	}
+}
+
+func (m *Monitor) Listen(bus *events.Bus) {
+	ch, _ := bus.Subscribe(events.LoadThreshold, events.ConfigReloaded)
+	go func() {
+		for event := range ch {
+			server, err := m.servs.Get(event.ServerID)
+			if err != nil || server == nil {
+				continue
+			}
+
+			name := fmt.Sprintf("%s %s (%s)", server.Country.Emoji, server.Country.Code, server.IP)
+			switch event.Type {
+			case events.LoadThreshold:
+				m.notifyAdmins("admin.server_load_threshold", name, event.Load*100)
+			case events.ConfigReloaded:
+				m.notifyAdmins("admin.server_config_reloaded", name, event.Message)
+			}
+		}
+	}()
+}
+
+func (m *Monitor) checkServer(server *models.Server) {
+	prev, err := m.servs.Statuses.GetLast(server.ID)
+	if err != nil {
+		m.log.Error("Failed to get last server status", err, slog.Uint64("server_id", uint64(server.ID)))
+	}
+
+	status := m.poll(server)
+	if err := m.servs.Statuses.Add(status); err != nil {
+		m.log.Error("Failed to save server status", err, slog.Uint64("server_id", uint64(server.ID)))
+		return
+	}
+
+	if prev == nil || prev.Status == status.Status {
+		return
+	}
+
+	m.log.Info("Server status changed", slog.Uint64("server_id", uint64(server.ID)), slog.String("from", prev.Status), slog.String("to", status.Status))
+	key, args := m.buildAlert(server, prev, status)
+	m.notifyAdmins(key, args...)
+}
+
+func (m *Monitor) poll(server *models.Server) *models.ServerStatus {
+	status := &models.ServerStatus{
+		ServerID: server.ID,
+		Status:   models.ServerStatusUp,
+	}
+
+	start := time.Now()
+	health, err := m.api.GetHealthRequest(server)
+	status.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		status.Status = models.ServerStatusDown
+		status.Error = err.Error()
+		return status
+	}
+	status.Health = health
+
+	load, err := m.api.GetLoadRequest(server)
+	if err != nil {
+		status.Status = models.ServerStatusDown
+		status.Error = err.Error()
+		return status
+	}
+	status.Load = load
+
+	if load >= overloadThreshold {
+		status.Status = models.ServerStatusOverloaded
+	}
+	return status
+}
+
+func (m *Monitor) buildAlert(server *models.Server, prev, status *models.ServerStatus) (string, []any) {
+	name := fmt.Sprintf("%s %s (%s)", server.Country.Emoji, server.Country.Code, server.IP)
+
+	switch {
+	case status.Status == models.ServerStatusDown:
+		return "admin.server_down", []any{name, status.Error}
+	case status.Status == models.ServerStatusOverloaded:
+		return "admin.server_overloaded", []any{name, status.Load * 100}
+	case prev.Status == models.ServerStatusDown:
+		return "admin.server_recovered", []any{name, status.Latency}
+	default:
+		return "admin.server_load_normal", []any{name, status.Load * 100}
+	}
+}
+
+func (m *Monitor) notifyAdmins(key string, args ...any) {
+	admins, err := m.us.GetAdmins()
+	if err != nil {
+		m.log.Error("Failed to get admins", err)
+		return
+	}
+
+	for _, admin := range admins {
+		msg := i18n.T(i18n.Resolve(admin.Language, admin.LanguageCode), key, args...)
+		if _, err := m.bot.Send(&telebot.User{ID: admin.ID}, msg); err != nil {
+			m.log.Error("Failed to send message", err, slog.Int64("admin_id", admin.ID))
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"nsvpn/internal/app/config"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/repository"
 	"nsvpn/pkg/logger"
@@ -50,6 +51,14 @@ func (ps *Payments) GetPaymentsCount(userID int64) (int64, error) {
 	return ps.pr.GetPaymentsCount(userID)
 }
 
+func (ps *Payments) CountByType(userID int64, paymentType string) (int64, error) {
+	if userID == 0 || paymentType == "" {
+		return 0, constants.ErrEmptyFields
+	}
+
+	return ps.pr.CountByType(userID, paymentType)
+}
+
 func (ps *Payments) Get(userID int64, payload string) (payment *models.Payment, err error) {
 	if userID == 0 || payload == "" {
 		return nil, constants.ErrEmptyFields
@@ -90,7 +99,7 @@ func (ps *Payments) Delete(userID int64, payload string) error {
 	return ps.pr.Delete(userID, payload)
 }
 
-func (ps *Payments) CreateInvoice(amount float64, title, description, payload string) telebot.Invoice {
+func (ps *Payments) CreateInvoice(lang string, amount float64, title, description, payload string) telebot.Invoice {
 	invoice := telebot.Invoice{
 		Title:       title,
 		Description: description,
@@ -98,7 +107,7 @@ func (ps *Payments) CreateInvoice(amount float64, title, description, payload st
 		Currency:    "XTR",
 		Prices: []telebot.Price{
 			{
-				Label:  "К оплате",
+				Label:  i18n.T(lang, "payments.invoice_label"),
 				Amount: int(math.Round(amount)),
 			},
 		},
@@ -338,12 +347,6 @@ func (ps *Payments) CheckCryptoPayment(uuid, orderID string) error {
 	}
 }
 
-func (ps *Payments) CreatePaymentMessage(amount float64, paymentTime time.Time, method, payload string) string {
-	msg := "🧾 Счет на оплату подписки успешно создан.\n\n"
-	msg += fmt.Sprintf("💸 Стоимость: %.0f руб.\n", amount)
-	msg += fmt.Sprintf("💳 Метод оплаты: %s\n", method)
-	msg += fmt.Sprintf("📦 Номер платежа: %s\n\n", payload)
-	msg += fmt.Sprintf("Вам нужно оплатить счет до %s. При возникновении каких-либо проблем не стесняйтесь обращаться в нашу поддержку", paymentTime.Format("02-01-2006 15:04:05"))
-
-	return msg
+func (ps *Payments) CreatePaymentMessage(lang string, amount float64, paymentTime time.Time, method, payload string) string {
+	return i18n.T(lang, "payments.invoice", amount, method, payload, paymentTime.Format("02-01-2006 15:04:05"))
 }
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"sync"
+	"time"
+)
+
+const rebalanceBatch = 20
+
+type Placement struct {
+	log   *logger.Logger
+	bot   *telebot.Bot
+	ar    *repository.Assignments
+	servs *Servers
+	ks    *Keys
+	cs    *Country
+	subs  *Subscriptions
+	us    *Users
+	api   *api.API
+}
+
+func NewPlacement(log *logger.Logger, bot *telebot.Bot, ar *repository.Assignments, servs *Servers, ks *Keys, cs *Country, subs *Subscriptions, us *Users, api *api.API) *Placement {
+	return &Placement{
+		log:   log,
+		bot:   bot,
+		ar:    ar,
+		servs: servs,
+		ks:    ks,
+		cs:    cs,
+		subs:  subs,
+		us:    us,
+		api:   api,
+	}
+}
+
+func (ps *Placement) GetHost(server *models.Server, country *models.Country) string {
+	if server != nil && server.Hostname != "" {
+		return server.Hostname
+	}
+	return country.Domain
+}
+
+func (ps *Placement) GetUserHost(userID int64, country *models.Country) string {
+	assignment, err := ps.ar.Get(userID, country.ID)
+	if err != nil || assignment == nil {
+		return country.Domain
+	}
+	return ps.GetHost(&assignment.Server, country)
+}
+
+func (ps *Placement) Assign(userID int64, countryID uint) (*models.Server, error) {
+	if userID == 0 || countryID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	assignment, err := ps.ar.Get(userID, countryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if assignment != nil {
+		if _, ok := ps.getScore(&assignment.Server); ok {
+			return &assignment.Server, nil
+		}
+	}
+
+	excludeID := uint(0)
+	if assignment != nil {
+		excludeID = assignment.ServerID
+	}
+
+	server, err := ps.pickServer(countryID, excludeID)
+	if err != nil {
+		if assignment != nil {
+			ps.log.Warn("No better server available, keeping assignment", slog.Int64("user_id", userID), slog.Uint64("server_id", uint64(assignment.ServerID)))
+			return &assignment.Server, nil
+		}
+		return nil, err
+	}
+
+	if assignment == nil {
+		if err = ps.ar.Add(&models.Assignment{UserID: userID, CountryID: countryID, ServerID: server.ID}); err != nil {
+			return nil, err
+		}
+		return server, nil
+	}
+
+	if err = ps.ar.UpdateServerID(userID, countryID, server.ID); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+func (ps *Placement) Provision(server *models.Server, key *models.Key, countryCode string, endDate time.Time, transports []*models.CountryTransport) error {
+	filled, err := ps.ks.EnsureSecrets(key)
+	if err != nil {
+		return err
+	}
+
+	for _, transport := range ps.cs.Transports.GetProtocols(transports) {
+		// клиент, добавленный раньше с пустым секретом, пересоздаётся с новым
+		if filled && ps.ks.GetSecret(key, transport) != "" {
+			if err = ps.api.DeleteRequest(server, transport.Protocol, key.UUID); err != nil && !api.IsNotFound(err) {
+				ps.log.Error("Failed delete request", err, slog.Uint64("server_id", uint64(server.ID)))
+				return err
+			}
+		} else {
+			found, err := ps.api.IsFoundRequest(server, transport.Protocol, key.UUID)
+			if err != nil {
+				ps.log.Error("Failed check if request", err, slog.Uint64("server_id", uint64(server.ID)))
+				return err
+			}
+			if found {
+				continue
+			}
+		}
+
+		email := ps.ks.GetEmail(key.UserID, countryCode, transport.Protocol)
+		if err = ps.api.AddRequest(server, transport.Protocol, key.UUID, ps.ks.GetSecret(key, transport), email, endDate); err != nil {
+			ps.log.Error("Failed add request", err, slog.Uint64("server_id", uint64(server.ID)))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ps *Placement) Deprovision(server *models.Server, uuid string, transports []*models.CountryTransport) error {
+	for _, transport := range ps.cs.Transports.GetProtocols(transports) {
+		if err := ps.api.DeleteRequest(server, transport.Protocol, uuid); err != nil && !api.IsNotFound(err) {
+			ps.log.Error("Failed delete request", err, slog.Uint64("server_id", uint64(server.ID)))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ps *Placement) RotateKey(key *models.Key, country *models.Country, endDate time.Time, transports []*models.CountryTransport) (*models.Key, *models.Server, error) {
+	if key == nil || country == nil || len(transports) == 0 {
+		return nil, nil, constants.ErrEmptyFields
+	}
+
+	previous, err := ps.ar.Get(key.UserID, key.CountryID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	server, err := ps.Assign(key.UserID, key.CountryID)
+	if err != nil {
+		return nil, nil, err
+	}
+	servers := []*models.Server{server}
+	// Assign мог перенести пользователя с недоступного сервера, и старый UUID остался на прежнем
+	moved := previous != nil && previous.ServerID != server.ID
+
+	newKey := *key
+	newKey.UUID = uuid.New().String()
+	if err = ps.ks.GenerateSecrets(&newKey); err != nil {
+		return nil, nil, err
+	}
+
+	failed := ps.forEachServer(servers, func(server *models.Server) error {
+		ps.setKeyStatus(key.ID, server.ID, newKey.UUID, models.KeyStatusAdding, nil)
+		return ps.Provision(server, &newKey, country.Code, endDate, transports)
+	})
+	if len(failed) == 0 {
+		err = ps.ks.Update(key.CountryID, key.UserID, &newKey)
+	}
+	if len(failed) > 0 || err != nil {
+		ps.rollbackRotation(key, &newKey, servers, transports, failed)
+		if moved {
+			if rerr := ps.ar.UpdateServerID(key.UserID, key.CountryID, previous.ServerID); rerr != nil {
+				ps.log.Error("Failed to restore assignment after rotation rollback", rerr, slog.Int64("user_id", key.UserID), slog.Uint64("server_id", uint64(previous.ServerID)))
+			}
+		}
+		if err == nil {
+			err = constants.ErrProcessServers
+		}
+		return nil, nil, err
+	}
+
+	ps.forEachServer(servers, func(server *models.Server) error {
+		if err := ps.Deprovision(server, key.UUID, transports); err != nil {
+			ps.setKeyStatus(key.ID, server.ID, newKey.UUID, models.KeyStatusCleanup, err)
+			return err
+		}
+
+		ps.setKeyStatus(key.ID, server.ID, newKey.UUID, models.KeyStatusActive, nil)
+		return nil
+	})
+	if moved {
+		// старый сервер часто недоступен, поэтому не удалённый с него ключ снимет сверка
+		if err = ps.Deprovision(&previous.Server, key.UUID, transports); err != nil {
+			ps.log.Warn("Failed to remove rotated key from previous server", slog.Int64("user_id", key.UserID), slog.Uint64("server_id", uint64(previous.ServerID)))
+		}
+	}
+
+	ps.log.Info("Rotated key", slog.Int64("user_id", key.UserID), slog.Uint64("country_id", uint64(key.CountryID)), slog.Uint64("server_id", uint64(server.ID)))
+	return &newKey, server, nil
+}
+
+func (ps *Placement) rollbackRotation(key, newKey *models.Key, servers []*models.Server, transports []*models.CountryTransport, failed map[uint]error) {
+	ps.log.Warn("Rolling back key rotation", slog.Int64("user_id", key.UserID), slog.Uint64("country_id", uint64(key.CountryID)), slog.Int("failed", len(failed)))
+
+	ps.forEachServer(servers, func(server *models.Server) error {
+		if err := ps.Deprovision(server, newKey.UUID, transports); err != nil {
+			ps.log.Error("Failed to remove new uuid during rollback", err, slog.Uint64("server_id", uint64(server.ID)), slog.Int64("user_id", key.UserID))
+		}
+
+		if err, ok := failed[server.ID]; ok {
+			ps.setKeyStatus(key.ID, server.ID, key.UUID, models.KeyStatusFailed, err)
+		} else {
+			ps.setKeyStatus(key.ID, server.ID, key.UUID, models.KeyStatusActive, nil)
+		}
+		return nil
+	})
+}
+
+func (ps *Placement) setKeyStatus(keyID, serverID uint, uuid, status string, cause error) {
+	keyStatus := &models.KeyStatus{KeyID: keyID, ServerID: serverID, UUID: uuid, Status: status, UpdatedAt: time.Now()}
+	if cause != nil {
+		keyStatus.Error = cause.Error()
+	}
+
+	if err := ps.ks.Statuses.Set(keyStatus); err != nil {
+		ps.log.Error("Failed to save key status", err, slog.Uint64("key_id", uint64(keyID)), slog.Uint64("server_id", uint64(serverID)))
+	}
+}
+
+func (ps *Placement) forEachServer(servers []*models.Server, fn func(server *models.Server) error) map[uint]error {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed = make(map[uint]error)
+	)
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *models.Server) {
+			defer wg.Done()
+			if err := fn(server); err != nil {
+				mu.Lock()
+				failed[server.ID] = err
+				mu.Unlock()
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	return failed
+}
+
+func (ps *Placement) Rebalance() {
+	servers, err := ps.servs.GetAll()
+	if err != nil {
+		ps.log.Error("Failed to get all servers", err)
+		return
+	}
+
+	for _, server := range servers {
+		status, err := ps.servs.Statuses.GetLast(server.ID)
+		if err != nil || status == nil || status.Status == models.ServerStatusUp {
+			continue
+		}
+
+		limit := 0
+		if status.Status == models.ServerStatusOverloaded {
+			limit = rebalanceBatch
+		}
+
+		assignments, err := ps.ar.GetAllByServerID(server.ID, limit)
+		if err != nil {
+			continue
+		}
+
+		for _, assignment := range assignments {
+			if err = ps.move(server, assignment); err != nil {
+				if errors.Is(err, constants.ErrNoAvailableServers) {
+					break
+				}
+				ps.log.Error("Failed to move assignment", err, slog.Int64("user_id", assignment.UserID), slog.Uint64("server_id", uint64(server.ID)))
+			}
+		}
+	}
+}
+
+func (ps *Placement) move(from *models.Server, assignment *models.Assignment) error {
+	to, err := ps.pickServer(assignment.CountryID, from.ID)
+	if err != nil {
+		return err
+	}
+
+	key, err := ps.ks.Get(assignment.CountryID, assignment.UserID)
+	if err != nil {
+		return err
+	}
+
+	sub, err := ps.subs.GetLastByUserID(assignment.UserID, true)
+	if err != nil {
+		return err
+	}
+
+	if key == nil || sub == nil || !key.IsActive {
+		return ps.ar.Delete(assignment.UserID, assignment.CountryID)
+	}
+
+	transports, err := ps.cs.Transports.GetAllByCountryID(assignment.CountryID)
+	if err != nil {
+		return err
+	}
+
+	if err = ps.Provision(to, key, to.Country.Code, sub.EndDate, transports); err != nil {
+		return err
+	}
+
+	if err = ps.ar.UpdateServerID(assignment.UserID, assignment.CountryID, to.ID); err != nil {
+		return err
+	}
+
+	ps.CompleteClaim(to, from, key, transports)
+	return nil
+}
+
+func (ps *Placement) Claim(server *models.Server, key *models.Key) (bool, *models.Server, error) {
+	assignment, err := ps.ar.Get(key.UserID, key.CountryID)
+	if err != nil {
+		return false, nil, err
+	}
+	if assignment != nil && assignment.ServerID == server.ID {
+		return true, nil, nil
+	}
+
+	// на новый сервер переходят те пользователи, для которых распределение выбрало бы именно его
+	best, err := ps.pickServer(key.CountryID, 0)
+	if errors.Is(err, constants.ErrNoAvailableServers) {
+		return false, nil, nil
+	}
+	if err != nil || best.ID != server.ID {
+		return false, nil, err
+	}
+
+	if assignment == nil {
+		return true, nil, ps.ar.Add(&models.Assignment{UserID: key.UserID, CountryID: key.CountryID, ServerID: server.ID})
+	}
+	if err = ps.ar.UpdateServerID(key.UserID, key.CountryID, server.ID); err != nil {
+		return false, nil, err
+	}
+	return true, &assignment.Server, nil
+}
+
+func (ps *Placement) CompleteClaim(server, previous *models.Server, key *models.Key, transports []*models.CountryTransport) {
+	// старый сервер часто недоступен, поэтому не удалённый с него ключ снимет сверка
+	if err := ps.Deprovision(previous, key.UUID, transports); err != nil {
+		ps.log.Warn("Failed to remove key from previous server", slog.Int64("user_id", key.UserID), slog.Uint64("server_id", uint64(previous.ID)))
+	}
+
+	ps.log.Info("Moved user to another server", slog.Int64("user_id", key.UserID), slog.Uint64("from", uint64(previous.ID)), slog.Uint64("to", uint64(server.ID)))
+	ps.notifyUser(key, server, transports)
+}
+
+func (ps *Placement) RevertClaim(previous *models.Server, key *models.Key) error {
+	if err := ps.ar.UpdateServerID(key.UserID, key.CountryID, previous.ID); err != nil {
+		return err
+	}
+
+	ps.log.Warn("Returned user to previous server after failed provisioning", slog.Int64("user_id", key.UserID), slog.Uint64("server_id", uint64(previous.ID)))
+	return nil
+}
+
+func (ps *Placement) notifyUser(key *models.Key, server *models.Server, transports []*models.CountryTransport) {
+	if len(transports) == 0 || server.Hostname == "" {
+		return
+	}
+
+	email := ps.ks.GetEmail(key.UserID, server.Country.Code, models.ProtocolVLESS)
+	link := ps.ks.GetKey(key, ps.GetHost(server, &server.Country), &server.Country, transports[0], email)
+	msg := i18n.T(ps.us.Language(key.UserID), "keys.moved", server.Country.Emoji, server.Country.Code, transports[0].Name, link)
+	if _, err := ps.bot.Send(&telebot.User{ID: key.UserID}, msg, telebot.ModeMarkdown); err != nil {
+		ps.log.Error("Failed to send message", err, slog.Int64("user_id", key.UserID))
+	}
+}
+
+func (ps *Placement) pickServer(countryID, excludeID uint) (*models.Server, error) {
+	servers, err := ps.servs.GetAllByCountryID(countryID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		best      *models.Server
+		bestScore float64
+	)
+	for _, server := range servers {
+		if server.ID == excludeID {
+			continue
+		}
+
+		score, ok := ps.getScore(server)
+		if !ok {
+			continue
+		}
+
+		if count, err := ps.ar.CountByServerID(server.ID); err == nil {
+			score /= float64(count + 1)
+		}
+
+		if best == nil || score > bestScore {
+			best, bestScore = server, score
+		}
+	}
+
+	if best == nil {
+		return nil, constants.ErrNoAvailableServers
+	}
+	return best, nil
+}
+
+func (ps *Placement) getScore(server *models.Server) (float64, bool) {
+	if !ps.api.IsHealthy(server) {
+		return 0, false
+	}
+
+	status, err := ps.servs.Statuses.GetLast(server.ID)
+	if err != nil {
+		return 0, false
+	}
+
+	load := 0.0
+	if status != nil {
+		if status.Status != models.ServerStatusUp {
+			return 0, false
+		}
+		load = status.Load
+	} else if load, err = ps.api.GetLoadRequest(server); err != nil {
+		return 0, false
+	}
+
+	return float64(server.ChannelSpeed) * (1 - load), true
+}
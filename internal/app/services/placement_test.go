@@ -0,0 +1,366 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/config"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/cache"
+	pbClient "nsvpn/pkg/client/v1"
+	"nsvpn/pkg/fakenode"
+	"nsvpn/pkg/logger"
+)
+
+type placementFixture struct {
+	db         *gorm.DB
+	cache      *cache.Cache
+	ar         *repository.Assignments
+	servs      *Servers
+	ks         *Keys
+	cs         *Country
+	subs       *Subscriptions
+	ps         *Placement
+	api        *api.API
+	country    *models.Country
+	transports []*models.CountryTransport
+	servers    []*models.Server
+	nodes      []*fakenode.Node
+}
+
+// newPlacementFixture создаёт страну с одним транспортом VLESS и count серверов на фейковых нодах
+func newPlacementFixture(t *testing.T, count int) *placementFixture {
+	t.Helper()
+
+	db, c := newTestStore(t)
+	log := logger.NewDiscard()
+	f := &placementFixture{db: db, cache: c}
+
+	f.country = &models.Country{Code: "DE", Emoji: "🇩🇪", NameRU: "Германия", NameEN: "Germany", Domain: "de.example.com",
+		PrivateKey: "private", PublicKey: "public", Dest: "example.com:443", ServerNames: "example.com", ShortIDs: "ab"}
+	mustCreate(t, db, f.country)
+	transport := &models.CountryTransport{CountryID: f.country.ID, Name: "VLESS", Protocol: models.ProtocolVLESS, Network: models.NetworkTCP, Security: models.SecurityReality}
+	mustCreate(t, db, transport)
+	f.transports = []*models.CountryTransport{transport}
+
+	nodes := make(map[*models.Server]*fakenode.Node, count)
+	for i := 0; i < count; i++ {
+		serv := &models.Server{IP: fmt.Sprintf("10.0.0.%d", i+1), Insecure: true, CountryID: f.country.ID, ChannelSpeed: 1000, Port: 50051}
+		mustCreate(t, db, serv)
+		serv.Country = *f.country
+
+		node := fakenode.New(fakenode.Config{})
+		nodes[serv] = node
+		f.servers = append(f.servers, serv)
+		f.nodes = append(f.nodes, node)
+	}
+	f.api = newTestAPI(t, nodes)
+
+	ur := repository.NewUsers(log, db, c)
+	f.ar = repository.NewAssignments(log, db, c)
+	f.servs = NewServers(log, repository.NewServers(log, db, c), f.api)
+	f.ks = NewKeys(log, repository.NewKeys(log, db, c))
+	f.cs = NewCountry(log, repository.NewCountry(log, db, c))
+	f.subs = NewSubscriptions(log, repository.NewSubscriptions(log, db, c), ur, repository.NewPayments(log, db, c), config.Trial{})
+	f.ps = NewPlacement(log, nil, f.ar, f.servs, f.ks, f.cs, f.subs, NewUsers(log, ur), f.api)
+	return f
+}
+
+// addUser создаёт пользователя с активной подпиской и ключом, назначенным на сервер
+func (f *placementFixture) addUser(t *testing.T, userID int64, server *models.Server) *models.Key {
+	t.Helper()
+
+	mustCreate(t, f.db, &models.User{ID: userID})
+	mustCreate(t, f.db, &models.Subscription{UserID: userID, EndDate: time.Now().Add(24 * time.Hour), IsActive: true})
+	key := &models.Key{UserID: userID, CountryID: f.country.ID, UUID: fmt.Sprintf("uuid-%d", userID), IsActive: true}
+	if err := f.ks.GenerateSecrets(key); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, f.db, key)
+	if err := f.ar.Add(&models.Assignment{UserID: userID, CountryID: f.country.ID, ServerID: server.ID}); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value any) {
+	t.Helper()
+
+	if err := db.Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hasClient(node *fakenode.Node, uuid string) bool {
+	_, ok := node.Client(pbClient.Protocol_PROTOCOL_VLESS, uuid)
+	return ok
+}
+
+func TestProvisionAndDeprovision(t *testing.T) {
+	serv := &models.Server{ID: 1, IP: "10.0.0.1", Insecure: true}
+	node := fakenode.New(fakenode.Config{})
+	a := newTestAPI(t, map[*models.Server]*fakenode.Node{serv: node})
+
+	log := logger.NewDiscard()
+	ks := NewKeys(log, nil)
+	ps := NewPlacement(log, nil, nil, nil, ks, NewCountry(log, nil), nil, nil, a)
+
+	key := &models.Key{UserID: 42, UUID: "uuid-1"}
+	if err := ks.GenerateSecrets(key); err != nil {
+		t.Fatal(err)
+	}
+	transports := []*models.CountryTransport{
+		{Protocol: models.ProtocolVLESS, Network: models.NetworkTCP},
+		{Protocol: models.ProtocolVLESS, Network: models.NetworkXHTTP},
+		{Protocol: models.ProtocolTrojan},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ps.Provision(serv, key, "DE", time.Now().Add(time.Hour), transports); err != nil {
+			t.Fatalf("Provision #%d: %v", i+1, err)
+		}
+	}
+	if got := len(node.Clients()); got != 2 {
+		t.Fatalf("node has %d clients, want one per protocol", got)
+	}
+	trojan, ok := node.Client(pbClient.Protocol_PROTOCOL_TROJAN, "uuid-1")
+	if !ok || trojan.GetEmail() != "nsvpn-42-de-trojan" || trojan.GetPassword() != key.Password {
+		t.Fatalf("unexpected trojan client %v", trojan)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ps.Deprovision(serv, "uuid-1", transports); err != nil {
+			t.Fatalf("Deprovision #%d: %v", i+1, err)
+		}
+	}
+	if got := len(node.Clients()); got != 0 {
+		t.Fatalf("node has %d clients after deprovision", got)
+	}
+}
+
+func TestRebalanceMovesKeyOffDownServer(t *testing.T) {
+	f := newPlacementFixture(t, 2)
+	from, to := f.servers[0], f.servers[1]
+	key := f.addUser(t, 42, from)
+	if err := f.ps.Provision(from, key, "DE", time.Now().Add(time.Hour), f.transports); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, f.db, &models.ServerStatus{ServerID: from.ID, Status: models.ServerStatusDown, CreatedAt: time.Now()})
+
+	f.ps.Rebalance()
+
+	assignment, err := f.ar.Get(42, f.country.ID)
+	if err != nil || assignment == nil || assignment.ServerID != to.ID {
+		t.Fatalf("user was not moved: %+v, %v", assignment, err)
+	}
+	if !hasClient(f.nodes[1], key.UUID) {
+		t.Fatal("key was not provisioned on new server")
+	}
+	if hasClient(f.nodes[0], key.UUID) {
+		t.Fatal("key was left on previous server")
+	}
+}
+
+func TestRotateKeyOnlyTouchesAssignedServer(t *testing.T) {
+	f := newPlacementFixture(t, 2)
+	key := f.addUser(t, 42, f.servers[0])
+	if err := f.ps.Provision(f.servers[0], key, "DE", time.Now().Add(time.Hour), f.transports); err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, server, err := f.ps.RotateKey(key, f.country, time.Now().Add(time.Hour), f.transports)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if server.ID != f.servers[0].ID {
+		t.Fatalf("RotateKey returned server %d, want the assigned %d", server.ID, f.servers[0].ID)
+	}
+	if !hasClient(f.nodes[0], newKey.UUID) || hasClient(f.nodes[0], key.UUID) {
+		t.Fatalf("assigned server was not rotated: %v", f.nodes[0].Clients())
+	}
+	if clients := f.nodes[1].Clients(); len(clients) != 0 {
+		t.Fatalf("key was pushed to unassigned server: %v", clients)
+	}
+}
+
+func TestRotateKeyMovesOffDownServer(t *testing.T) {
+	f := newPlacementFixture(t, 2)
+	from, to := f.servers[0], f.servers[1]
+	key := f.addUser(t, 42, from)
+	if err := f.ps.Provision(from, key, "DE", time.Now().Add(time.Hour), f.transports); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, f.db, &models.ServerStatus{ServerID: from.ID, Status: models.ServerStatusDown, CreatedAt: time.Now()})
+
+	newKey, server, err := f.ps.RotateKey(key, f.country, time.Now().Add(time.Hour), f.transports)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if server.ID != to.ID {
+		t.Fatalf("RotateKey returned server %d, want %d", server.ID, to.ID)
+	}
+	if !hasClient(f.nodes[1], newKey.UUID) {
+		t.Fatal("new key was not provisioned on the new server")
+	}
+	if hasClient(f.nodes[0], key.UUID) || hasClient(f.nodes[0], newKey.UUID) {
+		t.Fatalf("previous server still has the user's clients: %v", f.nodes[0].Clients())
+	}
+}
+
+func TestReconcilerKeepsKeysOnAssignedServer(t *testing.T) {
+	f := newPlacementFixture(t, 2)
+	key := f.addUser(t, 42, f.servers[0])
+	// ключ остался на втором сервере после старой раздачи на все серверы страны
+	for _, serv := range f.servers {
+		if err := f.ps.Provision(serv, key, "DE", time.Now().Add(time.Hour), f.transports); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewReconciler(logger.NewDiscard(), f.ar, f.servs, f.ks, f.cs, f.subs, f.ps, f.api)
+	if _, err := r.Run(false); err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasClient(f.nodes[0], key.UUID) {
+		t.Fatal("key was removed from assigned server")
+	}
+	if hasClient(f.nodes[1], key.UUID) {
+		t.Fatal("key was not removed from unassigned server")
+	}
+}
+
+func TestProvisioningOnlyAssignedKeys(t *testing.T) {
+	f := newPlacementFixture(t, 2)
+	other := f.addUser(t, 41, f.servers[0])
+	key := f.addUser(t, 42, f.servers[1])
+
+	pjr := repository.NewProvisioningJobs(logger.NewDiscard(), f.db, nil)
+	p := NewProvisioning(logger.NewDiscard(), pjr, f.ks, f.cs, f.subs, f.ps, f.api)
+	p.Start(f.servers[1])
+	waitProvisioning(t, pjr)
+
+	if !hasClient(f.nodes[1], key.UUID) {
+		t.Fatal("assigned key was not provisioned")
+	}
+	if hasClient(f.nodes[1], other.UUID) {
+		t.Fatal("key assigned to another server was provisioned")
+	}
+}
+
+func TestProvisioningFreshServerTakesOverUsers(t *testing.T) {
+	f := newPlacementFixture(t, 2)
+	from, to := f.servers[0], f.servers[1]
+	var keys []*models.Key
+	for userID := int64(41); userID <= 43; userID++ {
+		key := f.addUser(t, userID, from)
+		if err := f.ps.Provision(from, key, "DE", time.Now().Add(time.Hour), f.transports); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	pjr := repository.NewProvisioningJobs(logger.NewDiscard(), f.db, nil)
+	p := NewProvisioning(logger.NewDiscard(), pjr, f.ks, f.cs, f.subs, f.ps, f.api)
+	p.Start(to)
+	waitProvisioning(t, pjr)
+
+	moved := 0
+	for _, key := range keys {
+		assignment, err := f.ar.Get(key.UserID, f.country.ID)
+		if err != nil || assignment == nil {
+			t.Fatalf("assignment of user %d: %+v, %v", key.UserID, assignment, err)
+		}
+		onNew, onOld := hasClient(f.nodes[1], key.UUID), hasClient(f.nodes[0], key.UUID)
+		if assignment.ServerID == to.ID {
+			moved++
+			if !onNew || onOld {
+				t.Fatalf("user %d moved to the new server, but clients are new=%v old=%v", key.UserID, onNew, onOld)
+			}
+		} else if onNew || !onOld {
+			t.Fatalf("user %d kept on the old server, but clients are new=%v old=%v", key.UserID, onNew, onOld)
+		}
+	}
+	if moved == 0 || moved == len(keys) {
+		t.Fatalf("moved %d of %d users, want the new server to take part of the load", moved, len(keys))
+	}
+}
+
+func waitProvisioning(t *testing.T, pjr *repository.ProvisioningJobs) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		jobs, err := pjr.GetAllUnfinished()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("provisioning job did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProvisionFillsLegacyKeySecrets(t *testing.T) {
+	f := newPlacementFixture(t, 1)
+	trojan := &models.CountryTransport{CountryID: f.country.ID, Name: "Trojan", Protocol: models.ProtocolTrojan, Security: models.SecurityTLS}
+	mustCreate(t, f.db, trojan)
+	transports := append(f.transports, trojan)
+
+	// ключ создан до появления Trojan, клиент на ноде добавлен с пустым паролем
+	key := f.addUser(t, 42, f.servers[0])
+	if err := f.db.Model(key).Updates(map[string]any{"password": "", "psk": ""}).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.nodes[0].AddClient(&pbClient.Client{Uuid: key.UUID, Email: "nsvpn-42-de-trojan", Protocol: pbClient.Protocol_PROTOCOL_TROJAN})
+
+	legacy, err := f.ks.Get(f.country.ID, 42)
+	if err != nil || legacy.Password != "" {
+		t.Fatalf("key secrets were not cleared: %+v, %v", legacy, err)
+	}
+	if err = f.ps.Provision(f.servers[0], legacy, "DE", time.Now().Add(time.Hour), transports); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := f.ks.Get(f.country.ID, 42)
+	if err != nil || stored.Password == "" || stored.PSK == "" || stored.Password != legacy.Password {
+		t.Fatalf("secrets were not persisted: %+v, %v", stored, err)
+	}
+	client, ok := f.nodes[0].Client(pbClient.Protocol_PROTOCOL_TROJAN, key.UUID)
+	if !ok || client.GetPassword() != stored.Password {
+		t.Fatalf("trojan client was not recreated with new password: %v", client)
+	}
+}
+
+func TestReconcilerReplacesClientWithStaleSecret(t *testing.T) {
+	f := newPlacementFixture(t, 1)
+	trojan := &models.CountryTransport{CountryID: f.country.ID, Name: "Trojan", Protocol: models.ProtocolTrojan, Security: models.SecurityTLS}
+	mustCreate(t, f.db, trojan)
+
+	key := f.addUser(t, 42, f.servers[0])
+	f.nodes[0].AddClient(&pbClient.Client{Uuid: key.UUID, Email: "nsvpn-42-de-trojan", Protocol: pbClient.Protocol_PROTOCOL_TROJAN})
+
+	r := NewReconciler(logger.NewDiscard(), f.ar, f.servs, f.ks, f.cs, f.subs, f.ps, f.api)
+	if _, err := r.Run(false); err != nil {
+		t.Fatal(err)
+	}
+
+	client, ok := f.nodes[0].Client(pbClient.Protocol_PROTOCOL_TROJAN, key.UUID)
+	if !ok || client.GetPassword() != key.Password {
+		t.Fatalf("trojan client with empty password was not replaced: %v", client)
+	}
+	if !hasClient(f.nodes[0], key.UUID) {
+		t.Fatal("vless client was not added")
+	}
+}
@@ -1,28 +1,50 @@
 package services
 
 import (
+	"github.com/google/uuid"
 	"log/slog"
+	"math"
 	"nsvpn/internal/app/constants"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/repository"
 	"nsvpn/pkg/logger"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minCharge      = 1                // платёжные системы не принимают нулевую сумму
+	reservationTTL = 15 * time.Minute // с запасом на 10 минут ожидания оплаты
 )
 
 type Promocodes struct {
-	log *logger.Logger
-	pr  *repository.Promocodes
+	log  *logger.Logger
+	pr   *repository.Promocodes
+	ur   *repository.Users
+	payr *repository.Payments
+	sr   *repository.Subscriptions
 
 	Activations *PromocodesActivations
+	Campaigns   *PromocodesCampaigns
 }
 
-func NewPromocodes(log *logger.Logger, pr *repository.Promocodes) *Promocodes {
+func NewPromocodes(log *logger.Logger, pr *repository.Promocodes, ur *repository.Users, payr *repository.Payments, sr *repository.Subscriptions) *Promocodes {
 	return &Promocodes{
-		log: log,
-		pr:  pr,
+		log:  log,
+		pr:   pr,
+		ur:   ur,
+		payr: payr,
+		sr:   sr,
 		Activations: &PromocodesActivations{
 			log: log,
 			pr:  pr,
 		},
+		Campaigns: &PromocodesCampaigns{
+			log: log,
+			pr:  pr,
+		},
 	}
 }
 
@@ -47,9 +69,15 @@ func (ps *Promocodes) Get(code string) (promocode *models.Promocode, err error)
 }
 
 func (ps *Promocodes) Add(promocode *models.Promocode) error {
-	if promocode.Code == "" || promocode.Discount == 0 || promocode.CurrentActivations == 0 {
+	if promocode.Code == "" {
 		return constants.ErrEmptyFields
 	}
+	if promocode.Type == "" {
+		promocode.Type = models.PromocodeTypePercent
+	}
+	if err := validatePromocodeValue(promocode); err != nil {
+		return err
+	}
 
 	return ps.pr.Add(promocode)
 }
@@ -102,6 +130,146 @@ func (ps *Promocodes) Delete(code string) error {
 	return ps.pr.Delete(code)
 }
 
+func (ps *Promocodes) ApplyDiscount(code string, userID int64, amount float64, planID uint, provider string) (*models.PromocodeReservation, float64, error) {
+	promocode, err := ps.check(code, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !isDiscountPromocode(promocode) {
+		return nil, 0, constants.ErrPromocodeWrongType
+	}
+	if !promocodeAllows(promocode, planID, provider) {
+		return nil, 0, constants.ErrPromocodeNotApplicable
+	}
+
+	reservation, err := ps.pr.Activations.Reserve(promocode, userID, time.Now().Add(reservationTTL))
+	if err != nil {
+		return nil, 0, err
+	}
+	return reservation, promocodeDiscount(promocode, amount), nil
+}
+
+func (ps *Promocodes) Redeem(code string, userID int64) (*models.Promocode, error) {
+	promocode, err := ps.check(code, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isDiscountPromocode(promocode) {
+		return nil, constants.ErrPromocodeWrongType
+	}
+
+	reservation, err := ps.pr.Activations.Reserve(promocode, userID, time.Now().Add(reservationTTL))
+	if err != nil {
+		return nil, err
+	}
+	if err = ps.apply(promocode, userID); err != nil {
+		if rerr := ps.Release(reservation.ID, userID); rerr != nil {
+			ps.log.Error("Failed to release promocode", rerr, slog.Uint64("reservation_id", uint64(reservation.ID)))
+		}
+		return nil, err
+	}
+	// для бонусных промокодов сохраняем размер бонуса, чтобы учитывать его в статистике активаций
+	bonus := 0.0
+	if promocode.Type == models.PromocodeTypeBalance {
+		bonus = promocode.Value
+	}
+	if err = ps.Confirm(reservation.ID, userID, 0, bonus); err != nil {
+		ps.log.Error("Failed to confirm promocode", err, slog.Uint64("reservation_id", uint64(reservation.ID)))
+	}
+
+	ps.log.Info("Promocode redeemed", slog.String("code", promocode.Code), slog.String("type", promocode.Type), slog.Int64("user_id", userID))
+	return promocode, nil
+}
+
+func (ps *Promocodes) Confirm(reservationID uint, userID int64, amount, discount float64) error {
+	if reservationID == 0 || userID == 0 {
+		return constants.ErrEmptyFields
+	}
+
+	return ps.pr.Activations.Confirm(reservationID, userID, amount, discount)
+}
+
+func (ps *Promocodes) Release(reservationID uint, userID int64) error {
+	if reservationID == 0 || userID == 0 {
+		return constants.ErrEmptyFields
+	}
+
+	return ps.pr.Activations.Release(reservationID, userID)
+}
+
+func (ps *Promocodes) ReleaseExpired() {
+	reservations, err := ps.pr.Activations.GetExpired(time.Now())
+	if err != nil {
+		return
+	}
+
+	for _, reservation := range reservations {
+		if err = ps.pr.Activations.Release(reservation.ID, reservation.Activation.UserID); err != nil {
+			continue
+		}
+		ps.log.Info("Released expired promocode reservation", slog.Uint64("reservation_id", uint64(reservation.ID)), slog.Int64("user_id", reservation.Activation.UserID))
+	}
+}
+
+func (ps *Promocodes) apply(promocode *models.Promocode, userID int64) error {
+	switch promocode.Type {
+	case models.PromocodeTypeBalance:
+		if err := ps.ur.IncrementBalance(userID, promocode.Value); err != nil {
+			return err
+		}
+		err := ps.payr.Add(&models.Payment{
+			UserID:      userID,
+			Amount:      promocode.Value,
+			Type:        models.PaymentTypePromocode,
+			Payload:     uuid.New().String(),
+			Note:        "Промокод " + promocode.Code,
+			IsCompleted: true,
+		})
+		if err != nil {
+			ps.log.Error("Failed to add promocode payment", err, slog.Int64("user_id", userID))
+		}
+		return nil
+	case models.PromocodeTypeDays:
+		_, err := extendSubscription(ps.log, ps.sr, userID, uint(promocode.Value))
+		return err
+	}
+	return constants.ErrPromocodeWrongType
+}
+
+func (ps *Promocodes) check(code string, userID int64) (*models.Promocode, error) {
+	code = strings.TrimSpace(code)
+	if code == "" || userID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	promocode, err := ps.pr.Get(code)
+	if err != nil {
+		return nil, err
+	}
+	if promocode == nil {
+		return nil, constants.ErrPromocodeNotFound
+	}
+
+	payments, err := ps.payr.GetPaymentsCount(userID)
+	if err != nil {
+		return nil, err
+	}
+	activation, err := ps.pr.Activations.Get(promocode.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var used int64
+	if activation != nil {
+		used = int64(activation.Uses + activation.Reserved)
+	}
+
+	// предварительная проверка по кэшу, окончательно лимиты соблюдаются при резервировании
+	if err = validatePromocode(promocode, time.Now(), payments, used); err != nil {
+		return nil, err
+	}
+	return promocode, nil
+}
+
 func (ps *Promocodes) IsWork(code string, isNewUsers bool) bool {
 	if code == "" {
 		return false
@@ -119,3 +287,71 @@ func (ps *Promocodes) IsWork(code string, isNewUsers bool) bool {
 
 	return true
 }
+
+func validatePromocode(promocode *models.Promocode, now time.Time, payments, used int64) error {
+	switch {
+	case !promocode.IsActive:
+		return constants.ErrPromocodeNotFound
+	case promocode.EndAt != nil && promocode.EndAt.Before(now):
+		return constants.ErrPromocodeExpired
+	case promocode.TotalActivations != 0 && promocode.CurrentActivations >= promocode.TotalActivations:
+		return constants.ErrPromocodeLimit
+	case promocode.OnlyNewUsers && payments > 0:
+		return constants.ErrPromocodeOnlyNew
+	case promocode.PerUserLimit != 0 && used >= int64(promocode.PerUserLimit):
+		return constants.ErrPromocodeUsed
+	}
+	return nil
+}
+
+func validatePromocodeValue(promocode *models.Promocode) error {
+	switch promocode.Type {
+	case models.PromocodeTypePercent:
+		if promocode.Discount <= 0 || promocode.Discount > 100 {
+			return constants.ErrInvalidPromocode
+		}
+	case models.PromocodeTypeFixed, models.PromocodeTypeBalance:
+		if promocode.Value <= 0 {
+			return constants.ErrInvalidPromocode
+		}
+	case models.PromocodeTypeDays:
+		if promocode.Value < 1 || promocode.Value != math.Trunc(promocode.Value) {
+			return constants.ErrInvalidPromocode
+		}
+	default:
+		return constants.ErrInvalidPromocode
+	}
+	return nil
+}
+
+func isDiscountPromocode(promocode *models.Promocode) bool {
+	return promocode.Type == "" || promocode.Type == models.PromocodeTypePercent || promocode.Type == models.PromocodeTypeFixed
+}
+
+func promocodeAllows(promocode *models.Promocode, planID uint, provider string) bool {
+	if promocode.PlanIDs != "" && !slices.Contains(splitList(promocode.PlanIDs), strconv.FormatUint(uint64(planID), 10)) {
+		return false
+	}
+	if promocode.Providers != "" && !slices.Contains(splitList(promocode.Providers), provider) {
+		return false
+	}
+	return true
+}
+
+func promocodeDiscount(promocode *models.Promocode, amount float64) float64 {
+	discount := amount * float64(promocode.Discount) / 100
+	if promocode.Type == models.PromocodeTypeFixed {
+		discount = promocode.Value
+	}
+	return max(0, min(discount, amount-minCharge))
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"math"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultCodeAlphabet = voucherAlphabet
+	DefaultCodeLength   = 8
+
+	maxCampaignCodes   = 10000 // за один запуск генератора
+	maxPromocodeLength = 32    // размер колонки promocodes.code
+	generateAttempts   = 5
+)
+
+type PromocodesCampaigns struct {
+	log *logger.Logger
+	pr  *repository.Promocodes
+}
+
+func (pc *PromocodesCampaigns) GetAll() ([]*models.PromocodeCampaign, error) {
+	return pc.pr.Campaigns.GetAll()
+}
+
+func (pc *PromocodesCampaigns) Get(id uint) (*models.PromocodeCampaign, error) {
+	if id == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	campaign, err := pc.pr.Campaigns.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, constants.ErrCampaignNotFound
+	}
+	return campaign, nil
+}
+
+func (pc *PromocodesCampaigns) Create(campaign *models.PromocodeCampaign) error {
+	campaign.Name = strings.TrimSpace(campaign.Name)
+	if campaign.Name == "" {
+		return constants.ErrEmptyFields
+	}
+	if campaign.Type == "" {
+		campaign.Type = models.PromocodeTypePercent
+	}
+	if campaign.CodeActivations == 0 {
+		campaign.CodeActivations = 1
+	}
+	if err := validatePromocodeValue(&models.Promocode{Type: campaign.Type, Discount: campaign.Discount, Value: campaign.Value}); err != nil {
+		return constants.ErrInvalidCampaign
+	}
+
+	return pc.pr.Campaigns.Add(campaign)
+}
+
+func (pc *PromocodesCampaigns) Generate(campaignID uint, count int, prefix, alphabet string, length int) (int, error) {
+	if count <= 0 || count > maxCampaignCodes {
+		return 0, constants.ErrInvalidCodeFormat
+	}
+	if alphabet == "" {
+		alphabet = DefaultCodeAlphabet
+	}
+	if length == 0 {
+		length = DefaultCodeLength
+	}
+	if err := validateCodeFormat(count, prefix, alphabet, length); err != nil {
+		return 0, err
+	}
+
+	campaign, err := pc.Get(campaignID)
+	if err != nil {
+		return 0, err
+	}
+	if !campaign.IsActive {
+		return 0, constants.ErrCampaignNotFound
+	}
+
+	generated := 0
+	for attempt := 0; generated < count && attempt < generateAttempts; attempt++ {
+		codes, err := generatePromocodes(count-generated, prefix, alphabet, length)
+		if err != nil {
+			return generated, err
+		}
+
+		inserted, err := pc.pr.Campaigns.AddCodes(campaignPromocodes(campaign, codes))
+		if err != nil {
+			return generated, err
+		}
+		generated += int(inserted)
+	}
+
+	if generated < count {
+		pc.log.Warn("Not all campaign promocodes were generated", slog.Uint64("campaign_id", uint64(campaignID)), slog.Int("count", count), slog.Int("generated", generated))
+	}
+	pc.log.Info("Generated campaign promocodes", slog.Uint64("campaign_id", uint64(campaignID)), slog.Int("count", generated))
+	return generated, nil
+}
+
+func (pc *PromocodesCampaigns) Export(campaignID uint, w io.Writer) (int, error) {
+	if _, err := pc.Get(campaignID); err != nil {
+		return 0, err
+	}
+
+	promocodes, err := pc.pr.Campaigns.GetCodes(campaignID)
+	if err != nil {
+		return 0, err
+	}
+	return len(promocodes), writePromocodesCSV(w, promocodes)
+}
+
+func (pc *PromocodesCampaigns) Stats(campaignID uint) (repository.CampaignStats, error) {
+	if _, err := pc.Get(campaignID); err != nil {
+		return repository.CampaignStats{}, err
+	}
+
+	return pc.pr.Campaigns.GetStats(campaignID)
+}
+
+func (pc *PromocodesCampaigns) UpdateIsActive(campaignID uint, isActive bool) error {
+	if _, err := pc.Get(campaignID); err != nil {
+		return err
+	}
+
+	return pc.pr.Campaigns.UpdateIsActive(campaignID, isActive)
+}
+
+func validateCodeFormat(count int, prefix, alphabet string, length int) error {
+	if length < 4 || len(prefix)+length > maxPromocodeLength {
+		return constants.ErrInvalidCodeFormat
+	}
+	if !isCodeCharset(prefix) || !isCodeCharset(alphabet) {
+		return constants.ErrInvalidCodeFormat
+	}
+
+	seen := make(map[rune]struct{}, len(alphabet))
+	for _, r := range alphabet {
+		if _, ok := seen[r]; ok {
+			return constants.ErrInvalidCodeFormat
+		}
+		seen[r] = struct{}{}
+	}
+
+	// пространство кодов должно быть намного больше их числа, иначе коды легко подобрать
+	if len(seen) < 2 || math.Pow(float64(len(seen)), float64(length)) < float64(count)*1000 {
+		return constants.ErrInvalidCodeFormat
+	}
+	return nil
+}
+
+func isCodeCharset(value string) bool {
+	for _, r := range value {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func generatePromocodes(count int, prefix, alphabet string, length int) ([]string, error) {
+	seen := make(map[string]struct{}, count)
+	codes := make([]string, 0, count)
+	for len(codes) < count {
+		code, err := randomCode(alphabet, length)
+		if err != nil {
+			return nil, err
+		}
+
+		code = prefix + code
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func randomCode(alphabet string, length int) (string, error) {
+	// байты из неполного последнего блока отбрасываются, чтобы символы выпадали равновероятно
+	limit := 256 - 256%len(alphabet)
+	code := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(code) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit || len(code) == length {
+				continue
+			}
+			code = append(code, alphabet[int(b)%len(alphabet)])
+		}
+	}
+	return string(code), nil
+}
+
+func campaignPromocodes(campaign *models.PromocodeCampaign, codes []string) []*models.Promocode {
+	promocodes := make([]*models.Promocode, 0, len(codes))
+	for _, code := range codes {
+		promocodes = append(promocodes, &models.Promocode{
+			Code:             code,
+			Type:             campaign.Type,
+			Discount:         campaign.Discount,
+			Value:            campaign.Value,
+			TotalActivations: campaign.CodeActivations,
+			PerUserLimit:     campaign.PerUserLimit,
+			OnlyNewUsers:     campaign.OnlyNewUsers,
+			PlanIDs:          campaign.PlanIDs,
+			Providers:        campaign.Providers,
+			IsActive:         true,
+			CampaignID:       &campaign.ID,
+			EndAt:            campaign.EndAt,
+		})
+	}
+	return promocodes
+}
+
+func writePromocodesCSV(w io.Writer, promocodes []*models.Promocode) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"code", "type", "discount", "value", "activations", "total_activations", "is_active", "end_at"}); err != nil {
+		return err
+	}
+
+	for _, p := range promocodes {
+		endAt := ""
+		if p.EndAt != nil {
+			endAt = p.EndAt.Format(time.RFC3339)
+		}
+
+		err := cw.Write([]string{
+			p.Code,
+			p.Type,
+			strconv.Itoa(p.Discount),
+			strconv.FormatFloat(p.Value, 'f', -1, 64),
+			strconv.FormatUint(uint64(p.CurrentActivations), 10),
+			strconv.FormatUint(uint64(p.TotalActivations), 10),
+			strconv.FormatBool(p.IsActive),
+			endAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGeneratePromocodes(t *testing.T) {
+	codes, err := generatePromocodes(1000, "BLOG-", "ABC123", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 1000 {
+		t.Fatalf("got %d codes", len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		body, ok := strings.CutPrefix(code, "BLOG-")
+		if !ok || len(body) != 8 {
+			t.Fatalf("code %q has wrong format", code)
+		}
+		if strings.Trim(body, "ABC123") != "" {
+			t.Fatalf("code %q contains symbols outside alphabet", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestValidateCodeFormat(t *testing.T) {
+	if err := validateCodeFormat(1000, "BLOG_", DefaultCodeAlphabet, DefaultCodeLength); err != nil {
+		t.Fatalf("valid format rejected: %v", err)
+	}
+
+	tests := map[string]struct {
+		count    int
+		prefix   string
+		alphabet string
+		length   int
+	}{
+		"short code":        {10, "", DefaultCodeAlphabet, 3},
+		"too long":          {10, strings.Repeat("P", 25), DefaultCodeAlphabet, 8},
+		"bad prefix":        {10, "СКИДКА", DefaultCodeAlphabet, 8},
+		"bad alphabet":      {10, "", "AB CD", 8},
+		"repeated alphabet": {10, "", "AABC", 8},
+		"small code space":  {1000, "", "AB", 8},
+		"single symbol":     {1, "", "A", 8},
+	}
+	for name, tt := range tests {
+		if err := validateCodeFormat(tt.count, tt.prefix, tt.alphabet, tt.length); !errors.Is(err, constants.ErrInvalidCodeFormat) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
+
+func TestCampaignPromocodes(t *testing.T) {
+	endAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	campaign := &models.PromocodeCampaign{
+		ID:              7,
+		Type:            models.PromocodeTypeFixed,
+		Value:           150,
+		CodeActivations: 1,
+		PerUserLimit:    1,
+		OnlyNewUsers:    true,
+		Providers:       "stars",
+		EndAt:           &endAt,
+	}
+
+	promocodes := campaignPromocodes(campaign, []string{"A1", "A2"})
+	if len(promocodes) != 2 {
+		t.Fatalf("got %d promocodes", len(promocodes))
+	}
+	for _, p := range promocodes {
+		if p.CampaignID == nil || *p.CampaignID != 7 || p.Type != models.PromocodeTypeFixed || p.Value != 150 ||
+			p.TotalActivations != 1 || !p.OnlyNewUsers || p.Providers != "stars" || p.EndAt != &endAt || !p.IsActive {
+			t.Fatalf("rules are not copied: %+v", p)
+		}
+	}
+}
+
+func TestWritePromocodesCSV(t *testing.T) {
+	endAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	promocodes := []*models.Promocode{
+		{Code: "BLOG-AAAA", Type: models.PromocodeTypePercent, Discount: 20, TotalActivations: 1, CurrentActivations: 1, IsActive: true, EndAt: &endAt},
+		{Code: "BLOG-BBBB", Type: models.PromocodeTypeBalance, Value: 99.5, TotalActivations: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := writePromocodesCSV(&buf, promocodes); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "code,type,discount,value,activations,total_activations,is_active,end_at\n" +
+		"BLOG-AAAA,percent,20,0,1,1,true,2025-06-01T00:00:00Z\n" +
+		"BLOG-BBBB,balance,0,99.5,0,1,false,\n"
+	if buf.String() != want {
+		t.Fatalf("got:\n%s", buf.String())
+	}
+}
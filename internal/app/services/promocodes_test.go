@@ -0,0 +1,303 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+)
+
+func newTestPromocodes(t *testing.T) (*gorm.DB, *Promocodes) {
+	t.Helper()
+
+	db, c := newTestStore(t)
+	log := logger.NewDiscard()
+	ps := NewPromocodes(log, repository.NewPromocodes(log, db, c), repository.NewUsers(log, db, c), repository.NewPayments(log, db, c), repository.NewSubscriptions(log, db, c))
+	return db, ps
+}
+
+func TestValidatePromocode(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		promocode models.Promocode
+		payments  int64
+		used      int64
+		want      error
+	}{
+		{"valid", models.Promocode{IsActive: true, PerUserLimit: 1}, 0, 0, nil},
+		{"inactive", models.Promocode{}, 0, 0, constants.ErrPromocodeNotFound},
+		{"expired", models.Promocode{IsActive: true, EndAt: &past}, 0, 0, constants.ErrPromocodeExpired},
+		{"total limit", models.Promocode{IsActive: true, TotalActivations: 5, CurrentActivations: 5}, 0, 0, constants.ErrPromocodeLimit},
+		{"only new users", models.Promocode{IsActive: true, OnlyNewUsers: true}, 1, 0, constants.ErrPromocodeOnlyNew},
+		{"used once", models.Promocode{IsActive: true, PerUserLimit: 1}, 0, 1, constants.ErrPromocodeUsed},
+		{"per user limit not reached", models.Promocode{IsActive: true, PerUserLimit: 3}, 0, 2, nil},
+		{"per user limit reached", models.Promocode{IsActive: true, PerUserLimit: 3}, 0, 3, constants.ErrPromocodeUsed},
+		{"no per user limit", models.Promocode{IsActive: true}, 0, 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePromocode(&tt.promocode, now, tt.payments, tt.used); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePromocodeValue(t *testing.T) {
+	tests := []struct {
+		promocode models.Promocode
+		valid     bool
+	}{
+		{models.Promocode{Type: models.PromocodeTypePercent, Discount: 20}, true},
+		{models.Promocode{Type: models.PromocodeTypePercent, Discount: 120}, false},
+		{models.Promocode{Type: models.PromocodeTypeFixed, Value: 50}, true},
+		{models.Promocode{Type: models.PromocodeTypeBalance}, false},
+		{models.Promocode{Type: models.PromocodeTypeDays, Value: 7}, true},
+		{models.Promocode{Type: models.PromocodeTypeDays, Value: 1.5}, false},
+		{models.Promocode{Type: "unknown", Value: 1}, false},
+	}
+	for _, tt := range tests {
+		if err := validatePromocodeValue(&tt.promocode); (err == nil) != tt.valid {
+			t.Errorf("%+v: got %v", tt.promocode, err)
+		}
+	}
+}
+
+func TestPromocodeDiscount(t *testing.T) {
+	tests := []struct {
+		promocode models.Promocode
+		amount    float64
+		want      float64
+	}{
+		{models.Promocode{Type: models.PromocodeTypePercent, Discount: 10}, 300, 30},
+		{models.Promocode{Discount: 10}, 300, 30},
+		{models.Promocode{Type: models.PromocodeTypeFixed, Value: 100}, 300, 100},
+		{models.Promocode{Type: models.PromocodeTypeFixed, Value: 500}, 300, 299},
+		{models.Promocode{Type: models.PromocodeTypePercent, Discount: 100}, 300, 299},
+	}
+	for _, tt := range tests {
+		if got := promocodeDiscount(&tt.promocode, tt.amount); got != tt.want {
+			t.Errorf("%+v on %.f: got %.2f, want %.2f", tt.promocode, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestPromocodeAllows(t *testing.T) {
+	promocode := &models.Promocode{PlanIDs: "1, 3", Providers: "bankcard,Stars"}
+
+	if !promocodeAllows(promocode, 3, "stars") {
+		t.Fatal("allowed plan and provider rejected")
+	}
+	if promocodeAllows(promocode, 2, "stars") {
+		t.Fatal("restricted plan accepted")
+	}
+	if promocodeAllows(promocode, 1, "cryptocurrency") {
+		t.Fatal("restricted provider accepted")
+	}
+	if promocodeAllows(promocode, 0, "bankcard") {
+		t.Fatal("plan-restricted code accepted for a top-up")
+	}
+	if !promocodeAllows(&models.Promocode{}, 0, "cryptocurrency") {
+		t.Fatal("unrestricted code rejected")
+	}
+}
+
+func TestPromocodeUnlimitedPerUser(t *testing.T) {
+	db, ps := newTestPromocodes(t)
+
+	if err := ps.Add(&models.Promocode{Code: "FREE", Discount: 10, PerUserLimit: 0, IsActive: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	var promocode models.Promocode
+	if err := db.Where("code = ?", "FREE").First(&promocode).Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if promocode.PerUserLimit != 0 {
+		t.Fatalf("PerUserLimit = %d, want 0", promocode.PerUserLimit)
+	}
+
+	campaign := &models.PromocodeCampaign{Name: "spring", Type: models.PromocodeTypePercent, Discount: 10, PerUserLimit: 0, IsActive: true}
+	if err := ps.Campaigns.Create(campaign); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := ps.Campaigns.Generate(campaign.ID, 3, "SP", "", 0); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	var limits []uint
+	if err := db.Model(&models.PromocodeCampaign{}).Where("id = ?", campaign.ID).Pluck("per_user_limit", &limits).Error; err != nil || len(limits) != 1 || limits[0] != 0 {
+		t.Fatalf("campaign per_user_limit = %v, %v, want [0]", limits, err)
+	}
+	if err := db.Model(&models.Promocode{}).Where("campaign_id = ?", campaign.ID).Pluck("per_user_limit", &limits).Error; err != nil || len(limits) != 3 {
+		t.Fatalf("Pluck: %v, %v", limits, err)
+	}
+	for _, limit := range limits {
+		if limit != 0 {
+			t.Fatalf("generated code per_user_limit = %d, want 0", limit)
+		}
+	}
+}
+
+func newTestPromocode(t *testing.T, db *gorm.DB, code string, total, perUser uint) *models.Promocode {
+	t.Helper()
+
+	promocode := &models.Promocode{Code: code, Type: models.PromocodeTypePercent, Discount: 10, TotalActivations: total, PerUserLimit: perUser, IsActive: true}
+	mustCreate(t, db, promocode)
+	return promocode
+}
+
+func getActivation(t *testing.T, db *gorm.DB, promocodeID uint, userID int64) models.PromocodeActivations {
+	t.Helper()
+
+	var activation models.PromocodeActivations
+	if err := db.Preload("Promocode").Where("promocode_id = ? AND user_id = ?", promocodeID, userID).First(&activation).Error; err != nil {
+		t.Fatalf("activation of user %d: %v", userID, err)
+	}
+	return activation
+}
+
+func TestPromocodeReservationExpiry(t *testing.T) {
+	db, ps := newTestPromocodes(t)
+	promocode := newTestPromocode(t, db, "ONCE", 1, 1)
+
+	first, _, err := ps.ApplyDiscount("ONCE", 1, 1000, 0, "bankcard")
+	if err != nil {
+		t.Fatalf("ApplyDiscount: %v", err)
+	}
+	if _, _, err = ps.ApplyDiscount("ONCE", 2, 1000, 0, "bankcard"); !errors.Is(err, constants.ErrPromocodeLimit) {
+		t.Fatalf("second reservation error = %v, want ErrPromocodeLimit", err)
+	}
+
+	db.Model(&models.PromocodeReservation{}).Where("id = ?", first.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	ps.ReleaseExpired()
+
+	second, _, err := ps.ApplyDiscount("ONCE", 2, 1000, 0, "bankcard")
+	if err != nil {
+		t.Fatalf("ApplyDiscount after expiry: %v", err)
+	}
+	if err = ps.Confirm(second.ID, 2, 900, 100); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if activation := getActivation(t, db, promocode.ID, 2); activation.Uses != 1 || activation.Reserved != 0 || activation.IsOverLimit {
+		t.Fatalf("second user activation = %+v", activation)
+	}
+
+	// первый пользователь оплатил уже после снятия резерва, а единственную активацию занял второй
+	if err = ps.Confirm(first.ID, 1, 900, 100); err != nil {
+		t.Fatalf("late Confirm: %v", err)
+	}
+	activation := getActivation(t, db, promocode.ID, 1)
+	if activation.Uses != 1 || activation.Amount != 900 || !activation.IsOverLimit {
+		t.Fatalf("late activation = %+v, want one use flagged over the limit", activation)
+	}
+	if activation.Promocode.CurrentActivations != 2 {
+		t.Fatalf("CurrentActivations = %d, want 2", activation.Promocode.CurrentActivations)
+	}
+}
+
+func TestPromocodeLateConfirmWithinLimits(t *testing.T) {
+	db, ps := newTestPromocodes(t)
+	promocode := newTestPromocode(t, db, "LATE", 0, 1)
+
+	reservation, _, err := ps.ApplyDiscount("LATE", 1, 1000, 0, "bankcard")
+	if err != nil {
+		t.Fatalf("ApplyDiscount: %v", err)
+	}
+	if err = ps.Release(reservation.ID, 1); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err = ps.Confirm(reservation.ID, 1, 900, 100); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+
+	activation := getActivation(t, db, promocode.ID, 1)
+	if activation.Uses != 1 || activation.IsOverLimit || activation.Promocode.CurrentActivations != 1 {
+		t.Fatalf("activation = %+v, want one use within limits", activation)
+	}
+	if _, _, err = ps.ApplyDiscount("LATE", 1, 1000, 0, "bankcard"); !errors.Is(err, constants.ErrPromocodeUsed) {
+		t.Fatalf("reservation over the per-user limit error = %v, want ErrPromocodeUsed", err)
+	}
+}
+
+func TestPromocodeDoubleConfirm(t *testing.T) {
+	db, ps := newTestPromocodes(t)
+	promocode := newTestPromocode(t, db, "TWICE", 0, 0)
+
+	reservation, _, err := ps.ApplyDiscount("TWICE", 1, 1000, 0, "bankcard")
+	if err != nil {
+		t.Fatalf("ApplyDiscount: %v", err)
+	}
+	if err = ps.Confirm(reservation.ID, 2, 900, 100); !errors.Is(err, constants.ErrPromocodeNotReserved) {
+		t.Fatalf("Confirm by another user error = %v, want ErrPromocodeNotReserved", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = ps.Confirm(reservation.ID, 1, 900, 100); err != nil {
+			t.Fatalf("Confirm #%d: %v", i+1, err)
+		}
+	}
+	if err = ps.Release(reservation.ID, 1); err != nil {
+		t.Fatalf("Release after Confirm: %v", err)
+	}
+
+	activation := getActivation(t, db, promocode.ID, 1)
+	if activation.Uses != 1 || activation.Amount != 900 || activation.Discount != 100 || activation.Promocode.CurrentActivations != 1 {
+		t.Fatalf("activation = %+v, want a single confirmed use", activation)
+	}
+}
+
+func TestPromocodeConcurrentReserve(t *testing.T) {
+	db, ps := newTestPromocodes(t)
+	shared := newTestPromocode(t, db, "SHARED", 3, 1)
+	personal := newTestPromocode(t, db, "PERSONAL", 0, 2)
+
+	reserve := func(code string, userID func(i int) int64) (succeeded int) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, _, err := ps.ApplyDiscount(code, userID(i), 1000, 0, "bankcard")
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			switch {
+			case err == nil:
+				succeeded++
+			case !errors.Is(err, constants.ErrPromocodeLimit) && !errors.Is(err, constants.ErrPromocodeUsed):
+				t.Fatalf("ApplyDiscount(%s): %v", code, err)
+			}
+		}
+		return succeeded
+	}
+
+	if got := reserve("SHARED", func(i int) int64 { return int64(i + 1) }); got != 3 {
+		t.Fatalf("SHARED reservations = %d, want 3", got)
+	}
+	if got := reserve("PERSONAL", func(int) int64 { return 1 }); got != 2 {
+		t.Fatalf("PERSONAL reservations = %d, want 2", got)
+	}
+
+	var promocodes []models.Promocode
+	db.Where("id IN ?", []uint{shared.ID, personal.ID}).Order("id").Find(&promocodes)
+	if promocodes[0].CurrentActivations != 3 || promocodes[1].CurrentActivations != 2 {
+		t.Fatalf("CurrentActivations = %d, %d, want 3, 2", promocodes[0].CurrentActivations, promocodes[1].CurrentActivations)
+	}
+	if activation := getActivation(t, db, personal.ID, 1); activation.Reserved != 2 {
+		t.Fatalf("PERSONAL reserved = %d, want 2", activation.Reserved)
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"log/slog"
+	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"sync"
+	"time"
+)
+
+const provisioningBatch = 100
+
+type Provisioning struct {
+	log  *logger.Logger
+	pjr  *repository.ProvisioningJobs
+	ks   *Keys
+	cs   *Country
+	subs *Subscriptions
+	ps   *Placement
+	api  *api.API
+
+	mu      sync.Mutex
+	running map[uint]bool
+}
+
+func NewProvisioning(log *logger.Logger, pjr *repository.ProvisioningJobs, ks *Keys, cs *Country, subs *Subscriptions, ps *Placement, api *api.API) *Provisioning {
+	return &Provisioning{
+		log:     log,
+		pjr:     pjr,
+		ks:      ks,
+		cs:      cs,
+		subs:    subs,
+		ps:      ps,
+		api:     api,
+		running: make(map[uint]bool),
+	}
+}
+
+func (p *Provisioning) Start(server *models.Server) {
+	total, err := p.ks.CountActiveByCountryID(server.CountryID)
+	if err != nil {
+		p.log.Error("Failed to count active keys", err, slog.Uint64("server_id", uint64(server.ID)))
+		return
+	}
+
+	job := &models.ProvisioningJob{
+		ServerID: server.ID,
+		Status:   models.JobStatusPending,
+		Total:    total,
+	}
+	if err = p.pjr.Add(job); err != nil {
+		return
+	}
+
+	job, err = p.pjr.Get(job.ID)
+	if err != nil || job == nil {
+		return
+	}
+
+	go p.run(job)
+}
+
+func (p *Provisioning) Resume() {
+	jobs, err := p.pjr.GetAllUnfinished()
+	if err != nil {
+		p.log.Error("Failed to get unfinished provisioning jobs", err)
+		return
+	}
+
+	for _, job := range jobs {
+		p.log.Info("Resuming provisioning job", slog.Uint64("id", uint64(job.ID)), slog.Uint64("server_id", uint64(job.ServerID)), slog.Int64("processed", job.Processed))
+		go p.run(job)
+	}
+}
+
+func (p *Provisioning) run(job *models.ProvisioningJob) {
+	p.mu.Lock()
+	if p.running[job.ID] {
+		p.mu.Unlock()
+		return
+	}
+	p.running[job.ID] = true
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.running, job.ID)
+		p.mu.Unlock()
+	}()
+
+	server := &job.Server
+	transports, err := p.cs.Transports.GetAllByCountryID(server.CountryID)
+	if err != nil {
+		p.finish(job, err)
+		return
+	}
+
+	job.Status = models.JobStatusRunning
+	if err = p.pjr.UpdateProgress(job); err != nil {
+		return
+	}
+
+	existing := make(map[string]bool)
+	clients, err := p.api.ListClientsRequest(server)
+	if err != nil {
+		p.finish(job, err)
+		return
+	}
+	for _, client := range clients {
+		existing[client.UUID+":"+client.Protocol] = true
+	}
+
+	protocols := p.cs.Transports.GetProtocols(transports)
+	for {
+		keys, err := p.ks.GetActiveByCountryID(server.CountryID, job.Cursor, provisioningBatch)
+		if err != nil {
+			p.finish(job, err)
+			return
+		}
+		if len(keys) == 0 {
+			break
+		}
+
+		claimed, previous := p.claim(server, keys)
+		failed := make(map[string]bool)
+		batch := p.buildBatch(server, claimed, protocols, existing)
+		if len(batch) > 0 {
+			results, err := p.api.BatchAddRequest(server, batch)
+			if err != nil {
+				p.log.Error("Failed to provision keys batch", err, slog.Uint64("server_id", uint64(server.ID)))
+				job.Failed += int64(len(claimed))
+				for _, key := range claimed {
+					failed[key.UUID] = true
+				}
+			} else {
+				job.Failed += int64(len(results))
+				for _, result := range results {
+					failed[result.UUID] = true
+				}
+			}
+		}
+		p.settle(server, claimed, previous, failed, transports)
+
+		job.Processed += int64(len(keys))
+		job.Cursor = keys[len(keys)-1].ID
+
+		if err = p.pjr.UpdateProgress(job); err != nil {
+			return
+		}
+		p.log.Debug("Provisioning job progress", slog.Uint64("id", uint64(job.ID)), slog.Int64("processed", job.Processed), slog.Int64("total", job.Total))
+	}
+
+	p.finish(job, nil)
+}
+
+func (p *Provisioning) claim(server *models.Server, keys []*models.Key) ([]*models.Key, map[string]*models.Server) {
+	claimed := make([]*models.Key, 0, len(keys))
+	previous := make(map[string]*models.Server)
+	for _, key := range keys {
+		ok, from, err := p.ps.Claim(server, key)
+		if err != nil {
+			p.log.Error("Failed to claim key for server", err, slog.Int64("user_id", key.UserID), slog.Uint64("server_id", uint64(server.ID)))
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		claimed = append(claimed, key)
+		if from != nil {
+			previous[key.UUID] = from
+		}
+	}
+	return claimed, previous
+}
+
+func (p *Provisioning) settle(server *models.Server, claimed []*models.Key, previous map[string]*models.Server, failed map[string]bool, transports []*models.CountryTransport) {
+	for _, key := range claimed {
+		from, ok := previous[key.UUID]
+		if !ok {
+			continue
+		}
+
+		// ключ не выдан на новом сервере, поэтому пользователь остаётся там, где он уже работает
+		if failed[key.UUID] {
+			if err := p.ps.RevertClaim(from, key); err != nil {
+				p.log.Error("Failed to revert claim", err, slog.Int64("user_id", key.UserID), slog.Uint64("server_id", uint64(from.ID)))
+			}
+			continue
+		}
+		p.ps.CompleteClaim(server, from, key, transports)
+	}
+}
+
+func (p *Provisioning) buildBatch(server *models.Server, keys []*models.Key, protocols []*models.CountryTransport, existing map[string]bool) []*models.NodeClient {
+	batch := make([]*models.NodeClient, 0, len(keys)*len(protocols))
+	for _, key := range keys {
+		sub, err := p.subs.GetLastByUserID(key.UserID, true)
+		if err != nil || sub == nil || (!sub.EndDate.IsZero() && sub.EndDate.Before(time.Now())) {
+			continue
+		}
+		if _, err = p.ks.EnsureSecrets(key); err != nil {
+			continue
+		}
+
+		for _, protocol := range protocols {
+			if existing[key.UUID+":"+protocol.Protocol] {
+				continue
+			}
+
+			batch = append(batch, &models.NodeClient{
+				UUID:      key.UUID,
+				Email:     p.ks.GetEmail(key.UserID, server.Country.Code, protocol.Protocol),
+				Protocol:  protocol.Protocol,
+				Password:  p.ks.GetSecret(key, protocol),
+				ExpiresAt: sub.EndDate,
+			})
+		}
+	}
+	return batch
+}
+
+func (p *Provisioning) finish(job *models.ProvisioningJob, err error) {
+	job.Status = models.JobStatusDone
+	if err != nil {
+		job.Status = models.JobStatusFailed
+		job.Error = err.Error()
+	}
+
+	if err := p.pjr.UpdateProgress(job); err != nil {
+		return
+	}
+	p.log.Info("Provisioning job finished", slog.Uint64("id", uint64(job.ID)), slog.String("status", job.Status),
+		slog.Int64("processed", job.Processed), slog.Int64("failed", job.Failed))
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"gopkg.in/telebot.v4"
+	"log/slog"
+	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"strings"
+	"time"
+)
+
+const (
+	realityShortIDs     = 3
+	realityNotifyBatch  = 100
+	realityShortIDBytes = 8
+	realityNotifyRate   = 50 * time.Millisecond // ниже лимита Telegram на рассылку
+	realityNoticeQueue  = 16
+)
+
+type Reality struct {
+	log   *logger.Logger
+	bot   *telebot.Bot
+	cr    *repository.Country
+	cs    *Country
+	servs *Servers
+	ks    *Keys
+	subs  *Subscriptions
+	us    *Users
+	ps    *Placement
+	api   *api.API
+
+	notices chan *realityNotice
+}
+
+type realityNotice struct {
+	code     string
+	userIDs  []int64
+	expireAt time.Time
+}
+
+func NewReality(log *logger.Logger, bot *telebot.Bot, cr *repository.Country, cs *Country, servs *Servers, ks *Keys, subs *Subscriptions, us *Users, ps *Placement, api *api.API) *Reality {
+	return &Reality{
+		log:   log,
+		bot:   bot,
+		cr:    cr,
+		cs:    cs,
+		servs: servs,
+		ks:    ks,
+		subs:  subs,
+		us:    us,
+		ps:    ps,
+		api:   api,
+
+		notices: make(chan *realityNotice, realityNoticeQueue),
+	}
+}
+
+func (rs *Reality) Rotate(code string, overlap time.Duration) (queued int, err error) {
+	if code == "" {
+		return 0, constants.ErrEmptyFields
+	}
+
+	country, err := rs.cs.Get(code)
+	if err != nil {
+		return 0, err
+	}
+	if country == nil {
+		return 0, constants.ErrCountryNotFound
+	}
+	// новая ротация оборвала бы перекрытие прошлой для ещё не обновивших ключ пользователей
+	if country.PrevPrivateKey != "" && time.Now().Before(country.ShortIDsExpireAt) {
+		return 0, constants.ErrRealityOverlap
+	}
+
+	servers, err := rs.servs.GetAllByCountryID(country.ID)
+	if err != nil {
+		return 0, err
+	}
+	for _, server := range servers {
+		if server.AuthKeyID == "" {
+			return 0, constants.ErrLegacyNodeAuth
+		}
+	}
+
+	privateKey, publicKey, err := rs.generateKeyPair()
+	if err != nil {
+		return 0, err
+	}
+
+	shortIDs, err := rs.generateShortIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	oldShortIDs := splitShortIDs(country.ShortIDs)
+	expireAt := time.Now().Add(overlap)
+
+	// до конца перекрытия ноды принимают и старый ключ, поэтому выданные ссылки продолжают работать
+	for i, server := range servers {
+		if err = rs.api.UpdateRealityConfigRequest(server, privateKey, shortIDs, country.PrivateKey, oldShortIDs, expireAt); err != nil {
+			rs.log.Error("Failed to push reality config", err, slog.Uint64("server_id", uint64(server.ID)), slog.String("code", code))
+			rs.rollback(country, servers[:i])
+			return 0, err
+		}
+	}
+
+	rotated := *country
+	rotated.PrivateKey = privateKey
+	rotated.PublicKey = publicKey
+	rotated.ShortIDs = strings.Join(shortIDs, ",")
+	rotated.PrevPrivateKey = country.PrivateKey
+	rotated.PrevShortIDs = country.ShortIDs
+	rotated.ShortIDsExpireAt = expireAt
+	rotated.KeysRotatedAt = time.Now()
+
+	if err = rs.cr.UpdateReality(&rotated); err != nil {
+		rs.rollback(country, servers)
+		return 0, err
+	}
+
+	rs.log.Info("Rotated reality keys", slog.String("code", code), slog.Int("servers", len(servers)), slog.Time("expire_at", expireAt))
+
+	notice := &realityNotice{code: code, userIDs: rs.getRecipients(country.ID), expireAt: expireAt}
+	rs.notices <- notice
+	return len(notice.userIDs), nil
+}
+
+func (rs *Reality) rollback(country *models.Country, servers []*models.Server) {
+	for _, server := range servers {
+		err := rs.api.UpdateRealityConfigRequest(server, country.PrivateKey, splitShortIDs(country.ShortIDs), country.PrevPrivateKey,
+			splitShortIDs(country.PrevShortIDs), country.ShortIDsExpireAt)
+		if err != nil {
+			rs.log.Error("Failed to roll back reality config", err, slog.Uint64("server_id", uint64(server.ID)))
+		}
+	}
+}
+
+func (rs *Reality) Listen() {
+	go func() {
+		ticker := time.NewTicker(realityNotifyRate)
+		defer ticker.Stop()
+
+		for notice := range rs.notices {
+			rs.sendNotices(notice, ticker.C)
+		}
+	}()
+}
+
+func (rs *Reality) sendNotices(notice *realityNotice, tick <-chan time.Time) {
+	// страна перечитывается, чтобы ссылки собирались уже с новым публичным ключом
+	country, err := rs.cs.Get(notice.code)
+	if err != nil || country == nil {
+		rs.log.Error("Failed to get country for reality notices", err, slog.String("code", notice.code))
+		return
+	}
+
+	transports, err := rs.cs.Transports.GetAllByCountryID(country.ID)
+	if err != nil || len(transports) == 0 {
+		return
+	}
+	transport := transports[0]
+
+	notified := 0
+	for _, userID := range notice.userIDs {
+		<-tick
+
+		key, err := rs.ks.Get(country.ID, userID)
+		if err != nil || key == nil || !key.IsActive {
+			continue
+		}
+
+		email := rs.ks.GetEmail(userID, country.Code, models.ProtocolVLESS)
+		link := rs.ks.GetKey(key, rs.ps.GetUserHost(userID, country), country, transport, email)
+		msg := i18n.T(rs.us.Language(userID), "keys.reality_rotated", country.Emoji, country.Code,
+			notice.expireAt.Format("2006-01-02 15:04"), transport.Name, link)
+		if _, err = rs.bot.Send(&telebot.User{ID: userID}, msg, telebot.ModeMarkdown); err != nil {
+			rs.log.Error("Failed to send message", err, slog.Int64("user_id", userID))
+			continue
+		}
+		notified++
+	}
+
+	if time.Now().After(notice.expireAt) {
+		rs.log.Warn("Reality rotation notices were sent after the overlap ended", slog.String("code", country.Code))
+	}
+	rs.log.Info("Sent reality rotation notices", slog.String("code", country.Code), slog.Int("notified", notified), slog.Int("total", len(notice.userIDs)))
+}
+
+func (rs *Reality) getRecipients(countryID uint) []int64 {
+	var userIDs []int64
+	cursor := uint(0)
+	for {
+		keys, err := rs.ks.GetActiveByCountryID(countryID, cursor, realityNotifyBatch)
+		if err != nil || len(keys) == 0 {
+			return userIDs
+		}
+
+		for _, key := range keys {
+			cursor = key.ID
+
+			sub, err := rs.subs.GetLastByUserID(key.UserID, true)
+			if err != nil || sub == nil || (!sub.EndDate.IsZero() && sub.EndDate.Before(time.Now())) {
+				continue
+			}
+			userIDs = append(userIDs, key.UserID)
+		}
+	}
+}
+
+func (rs *Reality) generateKeyPair() (privateKey, publicKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		rs.log.Error("Failed to generate x25519 key", err)
+		return "", "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(key.Bytes()), base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+func (rs *Reality) generateShortIDs() ([]string, error) {
+	shortIDs := make([]string, 0, realityShortIDs)
+	for i := 0; i < realityShortIDs; i++ {
+		raw := make([]byte, realityShortIDBytes)
+		if _, err := rand.Read(raw); err != nil {
+			rs.log.Error("Failed to generate short id", err)
+			return nil, err
+		}
+		shortIDs = append(shortIDs, hex.EncodeToString(raw))
+	}
+	return shortIDs, nil
+}
+
+func splitShortIDs(shortIDs string) []string {
+	var result []string
+	for _, id := range strings.Split(shortIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			result = append(result, id)
+		}
+	}
+	return result
+}
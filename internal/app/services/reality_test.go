@@ -0,0 +1,170 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/telebot.v4"
+
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+)
+
+// newTestBot поднимает фейковый Bot API и возвращает тексты отправленных сообщений
+func newTestBot(t *testing.T) (*telebot.Bot, func() []string) {
+	t.Helper()
+
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			var req map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			text, _ := req["text"].(string)
+			mu.Lock()
+			sent = append(sent, text)
+			mu.Unlock()
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	bot, err := telebot.NewBot(telebot.Settings{URL: srv.URL, Token: "test", Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bot, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), sent...)
+	}
+}
+
+func newTestReality(t *testing.T, f *placementFixture, bot *telebot.Bot) *Reality {
+	t.Helper()
+
+	// ротация запрещена для нод со старой авторизацией по ключам Reality
+	if err := f.db.Model(&models.Server{}).Where("country_id = ?", f.country.ID).Update("auth_key_id", "key").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	log := logger.NewDiscard()
+	db, c := f.db, f.cache
+	return NewReality(log, bot, repository.NewCountry(log, db, c), f.cs, f.servs, f.ks, f.subs, NewUsers(log, repository.NewUsers(log, db, c)), f.ps, f.api)
+}
+
+func TestRealityRotateKeepsOldKeyDuringOverlap(t *testing.T) {
+	f := newPlacementFixture(t, 2)
+	key := f.addUser(t, 42, f.servers[0])
+	rs := newTestReality(t, f, nil)
+
+	// ключ с предзагруженной страной попадает в кэш до ротации
+	if cached, err := f.ks.Get(f.country.ID, 42); err != nil || cached.Country.PublicKey != "public" {
+		t.Fatalf("got %+v, %v", cached, err)
+	}
+
+	queued, err := rs.Rotate("DE", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued != 1 || len(rs.notices) != 1 {
+		t.Fatalf("queued %d notices, %d in queue", queued, len(rs.notices))
+	}
+
+	country, err := f.cs.Get("DE")
+	if err != nil || country.PrivateKey == "private" || country.PrevPrivateKey != "private" || country.PrevShortIDs != "ab" {
+		t.Fatalf("rotation was not saved: %+v, %v", country, err)
+	}
+	for _, node := range f.nodes {
+		cfg := node.Reality()
+		if cfg.GetPrivateKey() != country.PrivateKey || cfg.GetOldPrivateKey() != "private" || len(cfg.GetOldShortIds()) != 1 {
+			t.Fatalf("node does not serve both keys: %v", cfg)
+		}
+		if until := time.Until(cfg.GetOldShortIdsExpireAt().AsTime()); until < 59*time.Minute {
+			t.Fatalf("old key expires in %s", until)
+		}
+	}
+
+	cached, err := f.ks.Get(f.country.ID, key.UserID)
+	if err != nil || cached.Country.PublicKey != country.PublicKey {
+		t.Fatalf("cached key still has old public key: %+v, %v", cached, err)
+	}
+
+	if _, err = rs.Rotate("DE", time.Hour); !errors.Is(err, constants.ErrRealityOverlap) {
+		t.Fatalf("second rotation during overlap: got %v", err)
+	}
+}
+
+func TestRealityRotateRollback(t *testing.T) {
+	f := newPlacementFixture(t, 2)
+	rs := newTestReality(t, f, nil)
+	f.nodes[1].SetFailureRate(1)
+
+	if _, err := rs.Rotate("DE", time.Hour); err == nil {
+		t.Fatal("rotation succeeded with unavailable node")
+	}
+
+	country, err := f.cs.Get("DE")
+	if err != nil || country.PrivateKey != "private" || country.PrevPrivateKey != "" {
+		t.Fatalf("failed rotation was saved: %+v, %v", country, err)
+	}
+	if len(rs.notices) != 0 {
+		t.Fatal("users were notified about failed rotation")
+	}
+	if cfg := f.nodes[0].Reality(); cfg != nil && (cfg.GetPrivateKey() != "private" || cfg.GetOldPrivateKey() != "") {
+		t.Fatalf("node was not rolled back: %v", cfg)
+	}
+}
+
+func TestRealityNoticesAreRateLimited(t *testing.T) {
+	f := newPlacementFixture(t, 1)
+	f.addUser(t, 41, f.servers[0])
+	f.addUser(t, 42, f.servers[0])
+	bot, sent := newTestBot(t)
+	rs := newTestReality(t, f, bot)
+
+	queued, err := rs.Rotate("DE", time.Hour)
+	if err != nil || queued != 2 {
+		t.Fatalf("got %d, %v", queued, err)
+	}
+	country, err := f.cs.Get("DE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tick := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		rs.sendNotices(<-rs.notices, tick)
+		close(done)
+	}()
+
+	tick <- time.Now()
+	time.Sleep(50 * time.Millisecond)
+	if got := len(sent()); got != 1 {
+		t.Fatalf("sent %d messages after one tick", got)
+	}
+	tick <- time.Now()
+	<-done
+
+	messages := sent()
+	if len(messages) != 2 {
+		t.Fatalf("sent %d messages", len(messages))
+	}
+	for _, msg := range messages {
+		if !strings.Contains(msg, "pbk="+country.PublicKey) || !strings.Contains(msg, time.Now().Add(time.Hour).Format("2006-01-02")) {
+			t.Fatalf("notice does not contain new link and deadline: %q", msg)
+		}
+	}
+}
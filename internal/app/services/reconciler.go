@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"strings"
+	"time"
+)
+
+type Reconciler struct {
+	log   *logger.Logger
+	ar    *repository.Assignments
+	servs *Servers
+	ks    *Keys
+	cs    *Country
+	subs  *Subscriptions
+	ps    *Placement
+	api   *api.API
+}
+
+type ReconcileReport struct {
+	DryRun     bool
+	Unassigned int
+	Servers    []*ServerReconcileReport
+}
+
+type ServerReconcileReport struct {
+	Server  *models.Server
+	Added   int
+	Removed int
+	Updated int
+	Err     error
+}
+
+type desiredClient struct {
+	key       *models.Key
+	transport *models.CountryTransport
+	endDate   time.Time
+}
+
+func NewReconciler(log *logger.Logger, ar *repository.Assignments, servs *Servers, ks *Keys, cs *Country, subs *Subscriptions, ps *Placement, api *api.API) *Reconciler {
+	return &Reconciler{
+		log:   log,
+		ar:    ar,
+		servs: servs,
+		ks:    ks,
+		cs:    cs,
+		subs:  subs,
+		ps:    ps,
+		api:   api,
+	}
+}
+
+func (r *Reconciler) Run(dryRun bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{DryRun: dryRun}
+
+	endDates, err := r.getEndDates()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := r.ks.GetAllActive()
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := r.ar.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := make(map[string]uint, len(assignments))
+	for _, assignment := range assignments {
+		assigned[fmt.Sprintf("%d:%d", assignment.UserID, assignment.CountryID)] = assignment.ServerID
+	}
+
+	keysByUUID := make(map[string]*models.Key, len(keys))
+	for _, key := range keys {
+		if _, ok := endDates[key.UserID]; !ok {
+			continue
+		}
+		if !dryRun {
+			if _, err = r.ks.EnsureSecrets(key); err != nil {
+				continue
+			}
+		}
+		keysByUUID[key.UUID] = key
+
+		assignmentKey := fmt.Sprintf("%d:%d", key.UserID, key.CountryID)
+		if _, ok := assigned[assignmentKey]; ok {
+			continue
+		}
+
+		report.Unassigned++
+		if dryRun {
+			continue
+		}
+
+		server, err := r.ps.Assign(key.UserID, key.CountryID)
+		if err != nil {
+			r.log.Error("Failed to assign server", err, slog.Int64("user_id", key.UserID), slog.Uint64("country_id", uint64(key.CountryID)))
+			continue
+		}
+		assigned[assignmentKey] = server.ID
+	}
+
+	servers, err := r.servs.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range servers {
+		desired := make(map[string]*desiredClient)
+		for _, key := range keysByUUID {
+			if key.CountryID != server.CountryID {
+				continue
+			}
+			// ключ без назначения оставляем на всех серверах страны, чтобы сверка не отключила пользователя
+			if serverID, ok := assigned[fmt.Sprintf("%d:%d", key.UserID, key.CountryID)]; ok && serverID != server.ID {
+				continue
+			}
+
+			transports, err := r.cs.Transports.GetAllByCountryID(key.CountryID)
+			if err != nil {
+				continue
+			}
+
+			for _, transport := range r.cs.Transports.GetProtocols(transports) {
+				desired[key.UUID+":"+transport.Protocol] = &desiredClient{key: key, transport: transport, endDate: endDates[key.UserID]}
+			}
+		}
+
+		report.Servers = append(report.Servers, r.reconcileServer(server, desired, dryRun))
+	}
+
+	return report, nil
+}
+
+func (r *Reconciler) reconcileServer(server *models.Server, desired map[string]*desiredClient, dryRun bool) *ServerReconcileReport {
+	report := &ServerReconcileReport{Server: server}
+
+	clients, err := r.api.ListClientsRequest(server)
+	if err != nil {
+		r.log.Error("Failed to list clients", err, slog.Uint64("server_id", uint64(server.ID)))
+		report.Err = err
+		return report
+	}
+
+	var toAdd, toUpdate, toDelete []*models.NodeClient
+	existing := make(map[string]bool, len(clients))
+	for _, client := range clients {
+		id := client.UUID + ":" + client.Protocol
+		existing[id] = true
+
+		want, ok := desired[id]
+		if !ok {
+			if strings.HasPrefix(client.Email, "nsvpn-") {
+				toDelete = append(toDelete, client)
+			}
+			continue
+		}
+
+		// секрет клиента на ноде не обновить, поэтому клиент с устаревшим секретом пересоздаётся
+		if client.Password != r.ks.GetSecret(want.key, want.transport) {
+			toDelete = append(toDelete, client)
+			existing[id] = false
+			continue
+		}
+
+		if want.endDate.IsZero() || client.ExpiresAt.Truncate(time.Second).Equal(want.endDate.Truncate(time.Second)) {
+			continue
+		}
+		toUpdate = append(toUpdate, &models.NodeClient{UUID: client.UUID, Protocol: client.Protocol, ExpiresAt: want.endDate})
+	}
+
+	for id, want := range desired {
+		if existing[id] {
+			continue
+		}
+
+		toAdd = append(toAdd, &models.NodeClient{
+			UUID:      want.key.UUID,
+			Email:     r.ks.GetEmail(want.key.UserID, server.Country.Code, want.transport.Protocol),
+			Protocol:  want.transport.Protocol,
+			Password:  r.ks.GetSecret(want.key, want.transport),
+			ExpiresAt: want.endDate,
+		})
+	}
+
+	report.Added, report.Updated, report.Removed = len(toAdd), len(toUpdate), len(toDelete)
+	if !dryRun {
+		r.apply(server, "delete", toDelete, r.api.BatchDeleteRequest)
+		r.apply(server, "add", toAdd, r.api.BatchAddRequest)
+		r.apply(server, "update", toUpdate, r.api.BatchUpdateRequest)
+	}
+
+	r.log.Info("Reconciled server", slog.Uint64("server_id", uint64(server.ID)), slog.Bool("dry_run", dryRun),
+		slog.Int("added", report.Added), slog.Int("removed", report.Removed), slog.Int("updated", report.Updated))
+	return report
+}
+
+func (r *Reconciler) apply(server *models.Server, action string, clients []*models.NodeClient, fn func(*models.Server, []*models.NodeClient) ([]*models.NodeClientResult, error)) {
+	if len(clients) == 0 {
+		return
+	}
+
+	failed, err := fn(server, clients)
+	if err != nil {
+		r.log.Error("Failed to apply batch", err, slog.Uint64("server_id", uint64(server.ID)), slog.String("action", action), slog.Int("count", len(clients)))
+		return
+	}
+
+	for _, result := range failed {
+		r.log.Warn("Failed to reconcile client", slog.Uint64("server_id", uint64(server.ID)), slog.String("action", action),
+			slog.String("uuid", result.UUID), slog.String("protocol", result.Protocol), slog.String("error", result.Error))
+	}
+}
+
+func (r *Reconciler) getEndDates() (map[int64]time.Time, error) {
+	subscriptions, err := r.subs.GetAllActive()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	endDates := make(map[int64]time.Time, len(subscriptions))
+	for _, sub := range subscriptions {
+		if !sub.EndDate.IsZero() && sub.EndDate.Before(now) {
+			continue
+		}
+
+		if current, ok := endDates[sub.UserID]; !ok || sub.EndDate.After(current) {
+			endDates[sub.UserID] = sub.EndDate
+		}
+	}
+	return endDates, nil
+}
+
+func (r *ReconcileReport) Format(lang string) string {
+	var sb strings.Builder
+	if r.DryRun {
+		sb.WriteString(i18n.T(lang, "admin.reconcile_dry_run"))
+	} else {
+		sb.WriteString(i18n.T(lang, "admin.reconcile_applied"))
+	}
+
+	for _, server := range r.Servers {
+		name := fmt.Sprintf("%s %s (%s)", server.Server.Country.Emoji, server.Server.Country.Code, server.Server.IP)
+		if server.Err != nil {
+			sb.WriteString(i18n.T(lang, "admin.reconcile_server_down", name))
+			continue
+		}
+		sb.WriteString(i18n.T(lang, "admin.reconcile_server", name, server.Added, server.Removed, server.Updated))
+	}
+
+	if r.Unassigned > 0 {
+		sb.WriteString(i18n.T(lang, "admin.reconcile_unassigned", r.Unassigned))
+	}
+	return sb.String()
+}
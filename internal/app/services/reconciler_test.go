@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+)
+
+func TestReconcileReportFormat(t *testing.T) {
+	country := models.Country{Code: "de", Emoji: "🇩🇪"}
+	report := &ReconcileReport{
+		DryRun:     true,
+		Unassigned: 2,
+		Servers: []*ServerReconcileReport{
+			{Server: &models.Server{IP: "10.0.0.1", Country: country}, Added: 1, Removed: 2, Updated: 3},
+			{Server: &models.Server{IP: "10.0.0.2", Country: country}, Err: errors.New("unavailable")},
+		},
+	}
+
+	tests := map[string]string{
+		i18n.EN: "🔍 Drift check (no changes made)\n\n" +
+			"🇩🇪 de (10.0.0.1): ➕ 1, ➖ 2, 🔄 3\n" +
+			"🇩🇪 de (10.0.0.2): ❌ node is unreachable\n" +
+			"\nKeys without an assigned server: 2\n",
+		i18n.RU: "🔍 Проверка расхождений (без изменений)\n\n" +
+			"🇩🇪 de (10.0.0.1): ➕ 1, ➖ 2, 🔄 3\n" +
+			"🇩🇪 de (10.0.0.2): ❌ нода недоступна\n" +
+			"\nКлючей без назначенного сервера: 2\n",
+	}
+	for lang, want := range tests {
+		if got := report.Format(lang); got != want {
+			t.Errorf("%s:\ngot  %q\nwant %q", lang, got, want)
+		}
+	}
+}
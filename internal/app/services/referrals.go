@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"math"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+)
+
+// действует, пока в базе нет ни одного активного правила
+var defaultReferralRule = &models.ReferralRule{Type: models.ReferralRewardPercent, Value: 15}
+
+type Referrals struct {
+	log *logger.Logger
+	rr  *repository.Referrals
+	us  *Users
+	ps  *Payments
+
+	Withdrawals *ReferralWithdrawals
+}
+
+func NewReferrals(log *logger.Logger, rr *repository.Referrals, us *Users, ps *Payments, minWithdrawal float64) *Referrals {
+	return &Referrals{
+		log: log,
+		rr:  rr,
+		us:  us,
+		ps:  ps,
+		Withdrawals: &ReferralWithdrawals{
+			log:       log,
+			rr:        rr,
+			us:        us,
+			minAmount: minWithdrawal,
+		},
+	}
+}
+
+func (rs *Referrals) Link(botUsername string, userID int64) string {
+	return fmt.Sprintf("https://t.me/%s?start=%d", botUsername, userID)
+}
+
+func (rs *Referrals) GetRules() ([]*models.ReferralRule, error) {
+	rules, err := rs.rr.GetRules()
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return []*models.ReferralRule{defaultReferralRule}, nil
+	}
+	return rules, nil
+}
+
+func (rs *Referrals) GetRule(partnerID int64) (*models.ReferralRule, error) {
+	if partnerID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	count, err := rs.us.CountPartners(partnerID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := rs.GetRules()
+	if err != nil {
+		return nil, err
+	}
+	return pickReferralRule(rules, count), nil
+}
+
+func (rs *Referrals) AddRule(rule *models.ReferralRule) error {
+	if rule == nil || rule.Value <= 0 || rule.MinReferrals < 0 {
+		return constants.ErrInvalidReferralRule
+	}
+	if rule.Type != models.ReferralRewardPercent && rule.Type != models.ReferralRewardFixed {
+		return constants.ErrInvalidReferralRule
+	}
+	if rule.Type == models.ReferralRewardPercent && rule.Value > 100 {
+		return constants.ErrInvalidReferralRule
+	}
+
+	rule.IsActive = true
+	return rs.rr.AddRule(rule)
+}
+
+func (rs *Referrals) DeleteRule(id uint) error {
+	if id == 0 {
+		return constants.ErrEmptyFields
+	}
+
+	return rs.rr.DeleteRule(id)
+}
+
+func (rs *Referrals) GetEarnings(partnerID int64, limit int) ([]*models.ReferralEarning, error) {
+	if partnerID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return rs.rr.Earnings.GetByPartnerID(partnerID, limit)
+}
+
+func (rs *Referrals) GetStats(partnerID int64) (repository.ReferralStats, error) {
+	if partnerID == 0 {
+		return repository.ReferralStats{}, constants.ErrEmptyFields
+	}
+
+	return rs.rr.Earnings.GetStats(partnerID)
+}
+
+func (rs *Referrals) Reward(referralID int64, payload string, amount float64) (*models.ReferralEarning, error) {
+	if referralID == 0 || payload == "" || amount <= 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	user, err := rs.us.Get(referralID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.PartnerID == 0 {
+		return nil, nil
+	}
+
+	rule, err := rs.GetRule(user.PartnerID)
+	if err != nil || rule == nil {
+		return nil, err
+	}
+
+	if rule.FirstPaymentOnly {
+		count, err := rs.ps.CountByType(referralID, "income")
+		if err != nil {
+			return nil, err
+		}
+		// текущий платёж уже отмечен оплаченным
+		if count > 1 {
+			return nil, nil
+		}
+	}
+
+	reward := calcReferralReward(rule, amount)
+	if reward < 1 {
+		return nil, nil
+	}
+
+	earning := &models.ReferralEarning{
+		PartnerID:     user.PartnerID,
+		ReferralID:    referralID,
+		Payload:       payload,
+		PaymentAmount: amount,
+		Amount:        reward,
+		RuleID:        rule.ID,
+	}
+	added, err := rs.rr.Earnings.Add(earning)
+	if err != nil || !added {
+		return nil, err
+	}
+
+	err = rs.ps.Add(&models.Payment{
+		UserID:      user.PartnerID,
+		Amount:      reward,
+		Type:        models.PaymentTypeReferral,
+		Payload:     uuid.New().String(),
+		Note:        "Вознаграждение за пополнение баланса рефералом",
+		IsCompleted: true,
+	})
+	if err != nil {
+		rs.log.Error("Failed to add referral payment", err, slog.Int64("partner_id", user.PartnerID))
+	}
+
+	rs.log.Info("Credited referral reward", slog.Int64("partner_id", user.PartnerID), slog.Int64("referral_id", referralID), slog.Float64("amount", reward))
+	return earning, nil
+}
+
+func pickReferralRule(rules []*models.ReferralRule, referrals int64) *models.ReferralRule {
+	var picked *models.ReferralRule
+	for _, rule := range rules {
+		if rule.MinReferrals > referrals {
+			continue
+		}
+		if picked == nil || rule.MinReferrals > picked.MinReferrals {
+			picked = rule
+		}
+	}
+	return picked
+}
+
+func calcReferralReward(rule *models.ReferralRule, amount float64) float64 {
+	switch rule.Type {
+	case models.ReferralRewardPercent:
+		return math.Round(amount * rule.Value / 100)
+	case models.ReferralRewardFixed:
+		return rule.Value
+	}
+	return 0
+}
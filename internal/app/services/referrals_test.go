@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+)
+
+func TestPickReferralRule(t *testing.T) {
+	rules := []*models.ReferralRule{
+		{ID: 1, MinReferrals: 0, Type: models.ReferralRewardPercent, Value: 10},
+		{ID: 3, MinReferrals: 50, Type: models.ReferralRewardPercent, Value: 25},
+		{ID: 2, MinReferrals: 10, Type: models.ReferralRewardPercent, Value: 15},
+	}
+
+	tests := []struct {
+		referrals int64
+		want      uint
+	}{
+		{0, 1},
+		{9, 1},
+		{10, 2},
+		{49, 2},
+		{50, 3},
+		{500, 3},
+	}
+	for _, tt := range tests {
+		if got := pickReferralRule(rules, tt.referrals); got == nil || got.ID != tt.want {
+			t.Errorf("pickReferralRule(%d) = %v, want rule %d", tt.referrals, got, tt.want)
+		}
+	}
+
+	if got := pickReferralRule(rules[1:], 5); got != nil {
+		t.Fatalf("got rule %d below the lowest tier", got.ID)
+	}
+}
+
+func TestCalcReferralReward(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   *models.ReferralRule
+		amount float64
+		want   float64
+	}{
+		{"percent", &models.ReferralRule{Type: models.ReferralRewardPercent, Value: 15}, 300, 45},
+		{"percent rounded", &models.ReferralRule{Type: models.ReferralRewardPercent, Value: 15}, 99, 15},
+		{"fixed", &models.ReferralRule{Type: models.ReferralRewardFixed, Value: 50}, 1000, 50},
+		{"unknown type", &models.ReferralRule{Type: "bonus", Value: 50}, 1000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calcReferralReward(tt.rule, tt.amount); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferralsAddRuleValidation(t *testing.T) {
+	rs := NewReferrals(nil, nil, nil, nil, 500)
+	for _, rule := range []*models.ReferralRule{
+		nil,
+		{Type: models.ReferralRewardPercent, Value: 0},
+		{Type: models.ReferralRewardPercent, Value: 120},
+		{Type: "bonus", Value: 10},
+		{Type: models.ReferralRewardFixed, Value: 10, MinReferrals: -1},
+	} {
+		if err := rs.AddRule(rule); err == nil {
+			t.Errorf("rule %+v accepted", rule)
+		}
+	}
+}
+
+func TestWithdrawalRequestDebitsBalanceOnce(t *testing.T) {
+	db, c := newTestStore(t)
+	log := logger.NewDiscard()
+	rs := NewReferrals(log, repository.NewReferrals(log, db, c), NewUsers(log, repository.NewUsers(log, db, c)), nil, 10)
+
+	mustCreate(t, db, &models.User{ID: 1, Balance: 100})
+	mustCreate(t, db, &models.ReferralEarning{PartnerID: 1, ReferralID: 2, Payload: "p1", PaymentAmount: 1000, Amount: 100})
+
+	// заявки идут в обход предварительных проверок сервиса, гонку ловит только транзакция списания
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- rs.rr.Withdrawals.Request(&models.ReferralWithdrawal{UserID: 1, Amount: 30, Details: "card", Status: models.WithdrawalStatusPending})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, constants.ErrInsufficientFunds):
+			t.Fatalf("Request: %v", err)
+		}
+	}
+	if succeeded != 3 {
+		t.Fatalf("succeeded = %d, want 3", succeeded)
+	}
+
+	var user models.User
+	if err := db.First(&user, 1).Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if user.Balance != 10 {
+		t.Fatalf("Balance = %v, want 10", user.Balance)
+	}
+	var count int64
+	db.Model(&models.ReferralWithdrawal{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("withdrawals = %d, want 3", count)
+	}
+
+	if _, err := rs.Withdrawals.Request(1, 20, "card"); !errors.Is(err, constants.ErrInsufficientFunds) {
+		t.Fatalf("Request over balance error = %v, want ErrInsufficientFunds", err)
+	}
+}
+
+func TestWithdrawalRequestCapsEarnedAmountConcurrently(t *testing.T) {
+	db, c := newTestStore(t)
+	log := logger.NewDiscard()
+	rs := NewReferrals(log, repository.NewReferrals(log, db, c), NewUsers(log, repository.NewUsers(log, db, c)), nil, 10)
+
+	// баланс пополнен и самим пользователем, вывести можно только заработанные 100
+	mustCreate(t, db, &models.User{ID: 1, Balance: 200})
+	mustCreate(t, db, &models.ReferralEarning{PartnerID: 1, ReferralID: 2, Payload: "p1", PaymentAmount: 1000, Amount: 100})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := rs.Withdrawals.Request(1, 30, "card")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, constants.ErrInsufficientFunds):
+			t.Fatalf("Request: %v", err)
+		}
+	}
+	if succeeded != 3 {
+		t.Fatalf("succeeded = %d, want 3", succeeded)
+	}
+
+	var user models.User
+	if err := db.First(&user, 1).Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if user.Balance != 110 {
+		t.Fatalf("Balance = %v, want 110", user.Balance)
+	}
+}
+
+func TestEarningCreditsBalanceInOneTransaction(t *testing.T) {
+	db, c := newTestStore(t)
+	log := logger.NewDiscard()
+	rr := repository.NewReferrals(log, db, c)
+
+	mustCreate(t, db, &models.User{ID: 1, Balance: 5})
+
+	// повтор того же платежа не должен пополнить баланс второй раз
+	for i, want := range []bool{true, false} {
+		added, err := rr.Earnings.Add(&models.ReferralEarning{PartnerID: 1, ReferralID: 2, Payload: "p1", PaymentAmount: 100, Amount: 10})
+		if err != nil {
+			t.Fatalf("Add #%d: %v", i, err)
+		}
+		if added != want {
+			t.Fatalf("Add #%d = %v, want %v", i, added, want)
+		}
+	}
+
+	var user models.User
+	if err := db.First(&user, 1).Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if user.Balance != 15 {
+		t.Fatalf("Balance = %v, want 15", user.Balance)
+	}
+
+	// без партнёра начисление откатывается целиком, и повтор платежа сможет его записать
+	if _, err := rr.Earnings.Add(&models.ReferralEarning{PartnerID: 9, ReferralID: 2, Payload: "p2", PaymentAmount: 100, Amount: 10}); !errors.Is(err, constants.ErrUserNotFound) {
+		t.Fatalf("Add for missing partner error = %v, want ErrUserNotFound", err)
+	}
+	var count int64
+	db.Model(&models.ReferralEarning{}).Where("payload = ?", "p2").Count(&count)
+	if count != 0 {
+		t.Fatalf("earnings for p2 = %d, want 0", count)
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"log/slog"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+)
+
+type ReferralWithdrawals struct {
+	log       *logger.Logger
+	rr        *repository.Referrals
+	us        *Users
+	minAmount float64
+}
+
+func (rw *ReferralWithdrawals) MinAmount() float64 {
+	return rw.minAmount
+}
+
+func (rw *ReferralWithdrawals) Get(id uint) (*models.ReferralWithdrawal, error) {
+	if id == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return rw.rr.Withdrawals.GetByID(id)
+}
+
+func (rw *ReferralWithdrawals) GetPending() ([]*models.ReferralWithdrawal, error) {
+	return rw.rr.Withdrawals.GetByStatus(models.WithdrawalStatusPending)
+}
+
+func (rw *ReferralWithdrawals) Available(userID int64) (float64, error) {
+	if userID == 0 {
+		return 0, constants.ErrEmptyFields
+	}
+
+	stats, err := rw.rr.Earnings.GetStats(userID)
+	if err != nil {
+		return 0, err
+	}
+	withdrawn, err := rw.rr.Withdrawals.SumByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	user, err := rw.us.Get(userID)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil {
+		return 0, constants.ErrUserNotFound
+	}
+
+	// вывести можно только заработанное на рефералах и ещё не потраченное
+	return max(0, min(stats.Total-withdrawn, user.Balance)), nil
+}
+
+func (rw *ReferralWithdrawals) Request(userID int64, amount float64, details string) (*models.ReferralWithdrawal, error) {
+	if userID == 0 || amount <= 0 || details == "" {
+		return nil, constants.ErrEmptyFields
+	}
+	if amount < rw.minAmount {
+		return nil, constants.ErrWithdrawalTooSmall
+	}
+
+	// лимит заработанного и баланс проверяются в транзакции списания
+	withdrawal := &models.ReferralWithdrawal{
+		UserID:  userID,
+		Amount:  amount,
+		Details: details,
+		Status:  models.WithdrawalStatusPending,
+	}
+	if err := rw.rr.Withdrawals.Request(withdrawal); err != nil {
+		return nil, err
+	}
+
+	rw.log.Info("Withdrawal requested", slog.Uint64("id", uint64(withdrawal.ID)), slog.Int64("user_id", userID), slog.Float64("amount", amount))
+	return withdrawal, nil
+}
+
+func (rw *ReferralWithdrawals) Approve(id uint, adminID int64) (*models.ReferralWithdrawal, error) {
+	return rw.process(id, adminID, models.WithdrawalStatusApproved)
+}
+
+func (rw *ReferralWithdrawals) Reject(id uint, adminID int64) (*models.ReferralWithdrawal, error) {
+	withdrawal, err := rw.process(id, adminID, models.WithdrawalStatusRejected)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = rw.us.IncrementBalance(withdrawal.UserID, withdrawal.Amount); err != nil {
+		rw.log.Error("Failed to refund rejected withdrawal", err, slog.Uint64("id", uint64(id)), slog.Int64("user_id", withdrawal.UserID))
+		return nil, err
+	}
+	return withdrawal, nil
+}
+
+func (rw *ReferralWithdrawals) process(id uint, adminID int64, status string) (*models.ReferralWithdrawal, error) {
+	if id == 0 || adminID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	withdrawal, err := rw.rr.Withdrawals.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal == nil {
+		return nil, constants.ErrWithdrawalProcessed
+	}
+
+	if err = rw.rr.Withdrawals.Process(id, status, adminID); err != nil {
+		return nil, err
+	}
+
+	withdrawal.Status = status
+	withdrawal.AdminID = &adminID
+	rw.log.Info("Withdrawal processed", slog.Uint64("id", uint64(id)), slog.String("status", status), slog.Int64("admin_id", adminID))
+	return withdrawal, nil
+}
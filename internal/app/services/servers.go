@@ -3,19 +3,27 @@ package services
 import (
 	"fmt"
 	"go.uber.org/atomic"
+	"log/slog"
 	"nsvpn/internal/app/api"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/repository"
 	"nsvpn/pkg/logger"
+	"nsvpn/pkg/nodeauth"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Servers struct {
 	log *logger.Logger
 	sr  *repository.Servers
 	api *api.API
+
+	onAdd []func(server *models.Server)
+
+	Statuses *ServersStatuses
 }
 
 func NewServers(log *logger.Logger, sr *repository.Servers, api *api.API) *Servers {
@@ -23,6 +31,10 @@ func NewServers(log *logger.Logger, sr *repository.Servers, api *api.API) *Serve
 		log: log,
 		sr:  sr,
 		api: api,
+		Statuses: &ServersStatuses{
+			log: log,
+			sr:  sr,
+		},
 	}
 }
 
@@ -51,7 +63,18 @@ func (ss *Servers) Add(server *models.Server) error {
 		return constants.ErrEmptyFields
 	}
 
-	return ss.sr.Add(server)
+	if err := ss.sr.Add(server); err != nil {
+		return err
+	}
+
+	for _, fn := range ss.onAdd {
+		fn(server)
+	}
+	return nil
+}
+
+func (ss *Servers) OnAdd(fn func(server *models.Server)) {
+	ss.onAdd = append(ss.onAdd, fn)
 }
 
 func (ss *Servers) Update(id uint, newServer *models.Server) error {
@@ -70,6 +93,86 @@ func (ss *Servers) Delete(id uint) error {
 	return ss.sr.Delete(id)
 }
 
+func (ss *Servers) RotateAuthKey(server *models.Server, gracePeriod time.Duration) error {
+	if server == nil || server.ID == 0 {
+		return constants.ErrEmptyFields
+	}
+	// секрет передаётся в теле запроса, поэтому без TLS его может перехватить любой на пути до ноды
+	if server.CACert == "" {
+		return constants.ErrInsecureNodeChannel
+	}
+	if server.PendingAuthKeyID != "" {
+		return ss.resolvePendingAuthKey(server, gracePeriod)
+	}
+
+	keyID, secret, err := nodeauth.GenerateKey()
+	if err != nil {
+		ss.log.Error("Failed to generate auth key", err)
+		return err
+	}
+
+	if err = ss.sr.SetPendingAuthKey(server.ID, keyID, secret, time.Now()); err != nil {
+		return err
+	}
+
+	if err = ss.api.RotateAuthKeyRequest(server, keyID, secret, gracePeriod); err != nil {
+		ss.log.Error("Failed to rotate auth key on node", err, slog.Uint64("id", uint64(server.ID)))
+		return err
+	}
+
+	return ss.sr.PromoteAuthKey(server.ID, keyID, time.Now())
+}
+
+func (ss *Servers) resolvePendingAuthKey(server *models.Server, gracePeriod time.Duration) error {
+	pending := *server
+	pending.AuthKeyID, pending.AuthSecret = server.PendingAuthKeyID, server.PendingAuthSecret
+
+	// нода уже приняла ключ, потерялся только её ответ или запись в базу
+	if _, err := ss.api.GetHealthRequest(&pending); err == nil {
+		ss.log.Info("Node already accepted pending auth key", slog.Uint64("id", uint64(server.ID)))
+		return ss.sr.PromoteAuthKey(server.ID, pending.AuthKeyID, time.Now())
+	}
+
+	err := ss.api.RotateAuthKeyRequest(server, pending.AuthKeyID, pending.AuthSecret, gracePeriod)
+	if err == nil {
+		return ss.sr.PromoteAuthKey(server.ID, pending.AuthKeyID, time.Now())
+	}
+	if api.IsUnavailable(err) {
+		ss.log.Warn("Node is unavailable, pending auth key will be retried", slog.Uint64("id", uint64(server.ID)))
+		return err
+	}
+
+	// нода доступна, но не принимает ключ: откатываем, следующая ротация начнётся с текущего ключа
+	ss.log.Error("Node rejected pending auth key, rolling back", err, slog.Uint64("id", uint64(server.ID)))
+	if rerr := ss.sr.ClearPendingAuthKey(server.ID, pending.AuthKeyID); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+func (ss *Servers) RotateExpiredAuthKeys(interval, gracePeriod time.Duration) {
+	servers, err := ss.sr.GetAll()
+	if err != nil {
+		ss.log.Error("Failed to get all servers", err)
+		return
+	}
+
+	for _, server := range servers {
+		if server.PendingAuthKeyID == "" && server.AuthKeyID != "" && time.Since(server.AuthRotatedAt) < interval {
+			continue
+		}
+		if server.CACert == "" {
+			ss.log.Warn("Skipping auth key rotation for server without TLS", slog.Uint64("id", uint64(server.ID)))
+			continue
+		}
+
+		if err = ss.RotateAuthKey(server, gracePeriod); err != nil {
+			continue
+		}
+		ss.log.Info("Rotated node auth key", slog.Uint64("id", uint64(server.ID)))
+	}
+}
+
 func (ss *Servers) ProcessButtons(servers []models.Server) ([]models.ButtonOption, []int) {
 	listServers := make([]models.ButtonOption, 0, len(servers))
 
@@ -112,6 +215,11 @@ func (ss *Servers) CalculateServerLoad(servers []*models.Server) LoadInfo {
 		wg.Add(1)
 		go func(server *models.Server) {
 			defer wg.Done()
+			if !ss.api.IsHealthy(server) {
+				inActive.Add(1)
+				return
+			}
+
 			if load, err := ss.api.GetLoadRequest(server); err != nil {
 				inActive.Add(1)
 			} else {
@@ -128,24 +236,20 @@ func (ss *Servers) CalculateServerLoad(servers []*models.Server) LoadInfo {
 	}
 }
 
-func (ss *Servers) BuildMessage(country *models.Country, info LoadInfo) string {
-	loadMsg := "критическая 🔴"
+func (ss *Servers) BuildMessage(lang string, country *models.Country, info LoadInfo) string {
+	loadMsg := "servers.load_critical"
 	avgLoad := info.TotalLoad / (float64(info.TotalCount) - float64(info.Inactive))
 
 	switch {
 	case avgLoad <= 0.3:
-		loadMsg = "низкая 🟢"
+		loadMsg = "servers.load_low"
 	case avgLoad <= 0.7:
-		loadMsg = "средняя 🌕"
+		loadMsg = "servers.load_medium"
 	case avgLoad <= 0.95:
-		loadMsg = "высокая 🟠"
+		loadMsg = "servers.load_high"
 	case int64(info.TotalCount) == info.Inactive:
-		loadMsg = "не отвечает 🔴"
+		loadMsg = "servers.load_down"
 	}
 
-	return fmt.Sprintf("%s %s\n🎛 Нагрузка на сервер: %s\n\n",
-		country.Emoji,
-		country.Code,
-		loadMsg,
-	)
+	return i18n.T(lang, "servers.load", country.Emoji, country.Code, i18n.T(lang, loadMsg))
 }
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+type ServersStatuses struct {
+	log *logger.Logger
+	sr  *repository.Servers
+}
+
+func (ss *ServersStatuses) GetLast(serverID uint) (status *models.ServerStatus, err error) {
+	if serverID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return ss.sr.Statuses.GetLast(serverID)
+}
+
+func (ss *ServersStatuses) GetHistory(serverID uint, limit int) (statuses []*models.ServerStatus, err error) {
+	if serverID == 0 || limit <= 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return ss.sr.Statuses.GetHistory(serverID, limit)
+}
+
+func (ss *ServersStatuses) Add(status *models.ServerStatus) error {
+	if status.ServerID == 0 || status.Status == "" {
+		return constants.ErrEmptyFields
+	}
+
+	return ss.sr.Statuses.Add(status)
+}
+
+func (ss *ServersStatuses) DeleteOlderThan(before time.Time) error {
+	if before.IsZero() {
+		return constants.ErrEmptyFields
+	}
+
+	return ss.sr.Statuses.DeleteOlderThan(before)
+}
+
+func (ss *ServersStatuses) IsCountryDown(servers []*models.Server) bool {
+	if len(servers) == 0 {
+		return false
+	}
+
+	for _, server := range servers {
+		status, err := ss.GetLast(server.ID)
+		if err != nil || status == nil || status.Status != models.ServerStatusDown {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/events"
+	"nsvpn/internal/app/i18n"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/cache"
+	"nsvpn/pkg/fakenode"
+	"nsvpn/pkg/logger"
+)
+
+func newTestAPI(t *testing.T, nodes map[*models.Server]*fakenode.Node) *api.API {
+	t.Helper()
+
+	network := fakenode.NewNetwork()
+	for serv, node := range nodes {
+		network.Serve(serv.IP+":50051", node)
+		serv.Port = 50051
+	}
+
+	log := logger.NewDiscard()
+	a := api.NewAPI(log, events.NewBus(log), api.WithDialer(network.Dial))
+	t.Cleanup(func() {
+		a.Close()
+		network.Stop()
+	})
+	return a
+}
+
+// newTestStore поднимает sqlite и miniredis вместо postgres и redis для тестов сервисов с репозиториями
+func newTestStore(t *testing.T) (*gorm.DB, *cache.Cache) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/test.db?_pragma=busy_timeout(5000)"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Country{},
+		&models.CountryTransport{},
+		&models.Server{},
+		&models.ServerStatus{},
+		&models.Assignment{},
+		&models.ProvisioningJob{},
+		&models.Subscription{},
+		&models.SubscriptionPlan{},
+		&models.SubscriptionPrice{},
+		&models.Voucher{},
+		&models.Payment{},
+		&models.Key{},
+		&models.KeyStatus{},
+		&models.PromocodeCampaign{},
+		&models.Promocode{},
+		&models.PromocodeActivations{},
+		&models.PromocodeReservation{},
+		&models.ReferralRule{},
+		&models.ReferralEarning{},
+		&models.ReferralWithdrawal{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return db, cache.New(logger.NewDiscard(), client)
+}
+
+func TestCalculateServerLoad(t *testing.T) {
+	first := &models.Server{ID: 1, IP: "10.0.0.1", Insecure: true}
+	second := &models.Server{ID: 2, IP: "10.0.0.2", Insecure: true}
+	broken := &models.Server{ID: 3, IP: "10.0.0.3", Insecure: true}
+	a := newTestAPI(t, map[*models.Server]*fakenode.Node{
+		first:  fakenode.New(fakenode.Config{Load: 0.2}),
+		second: fakenode.New(fakenode.Config{Load: 0.6}),
+		broken: fakenode.New(fakenode.Config{FailureRate: 1}),
+	})
+	ss := NewServers(logger.NewDiscard(), nil, a)
+
+	info := ss.CalculateServerLoad([]*models.Server{first, second, broken})
+	if math.Abs(info.TotalLoad-0.8) > 1e-9 || info.Inactive != 1 || info.TotalCount != 3 {
+		t.Fatalf("unexpected load info %+v", info)
+	}
+
+	msg := ss.BuildMessage(i18n.RU, &models.Country{Code: "DE", Emoji: "🇩🇪"}, info)
+	if !strings.Contains(msg, "средняя") {
+		t.Fatalf("average load of 0.4 rendered as %q", msg)
+	}
+}
+
+func TestBuildMessageAllInactive(t *testing.T) {
+	ss := NewServers(logger.NewDiscard(), nil, nil)
+
+	msg := ss.BuildMessage(i18n.RU, &models.Country{Code: "DE"}, LoadInfo{Inactive: 2, TotalCount: 2})
+	if !strings.Contains(msg, "не отвечает") {
+		t.Fatalf("unreachable servers rendered as %q", msg)
+	}
+}
+
+func TestRotateAuthKeyRequiresTLS(t *testing.T) {
+	ss := NewServers(logger.NewDiscard(), nil, nil)
+
+	err := ss.RotateAuthKey(&models.Server{ID: 1, IP: "10.0.0.1", Insecure: true, AuthKeyID: "key"}, time.Minute)
+	if !errors.Is(err, constants.ErrInsecureNodeChannel) {
+		t.Fatalf("got %v, want ErrInsecureNodeChannel", err)
+	}
+}
+
+func TestResolvePendingAuthKey(t *testing.T) {
+	tests := []struct {
+		name string
+		// ключи, которые нода принимает до восстановления
+		nodeKeys []string
+		failNext int
+		wantErr  bool
+		wantKey  string
+	}{
+		{"node accepted pending key", []string{"old", "new"}, 0, false, "new"},
+		{"node missed rotation", []string{"old"}, 0, false, "new"},
+		{"node unavailable", []string{"old"}, 10, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, c := newTestStore(t)
+			sr := repository.NewServers(logger.NewDiscard(), db, c)
+
+			serv := &models.Server{IP: "10.0.0.1", Insecure: true, CountryID: 1, ChannelSpeed: 1000, Port: 50051, AuthKeyID: "old", AuthSecret: "old-secret"}
+			if err := sr.Add(serv); err != nil {
+				t.Fatal(err)
+			}
+			if err := sr.SetPendingAuthKey(serv.ID, "new", "new-secret", time.Now()); err != nil {
+				t.Fatal(err)
+			}
+
+			node := fakenode.New(fakenode.Config{})
+			for _, keyID := range tt.nodeKeys {
+				node.EnableAuth(keyID, keyID+"-secret")
+			}
+			a := newTestAPI(t, map[*models.Server]*fakenode.Node{serv: node})
+			ss := NewServers(logger.NewDiscard(), sr, a)
+
+			stored, err := sr.Get(serv.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			node.FailNext(tt.failNext)
+			err = ss.resolvePendingAuthKey(stored, time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, wantErr %v", err, tt.wantErr)
+			}
+
+			stored, err = sr.Get(serv.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr {
+				if stored.AuthKeyID != "old" || stored.PendingAuthKeyID != "new" {
+					t.Fatalf("pending key was not kept for retry: %+v", stored)
+				}
+				return
+			}
+			if stored.AuthKeyID != tt.wantKey || stored.AuthSecret != "new-secret" || stored.PendingAuthKeyID != "" {
+				t.Fatalf("pending key was not promoted: %+v", stored)
+			}
+			if _, ok := node.Keyring().Lookup(tt.wantKey); !ok {
+				t.Fatal("node does not accept promoted key")
+			}
+		})
+	}
+}
+
+func TestResolvePendingAuthKeyRollback(t *testing.T) {
+	db, c := newTestStore(t)
+	sr := repository.NewServers(logger.NewDiscard(), db, c)
+
+	serv := &models.Server{IP: "10.0.0.1", Insecure: true, CountryID: 1, ChannelSpeed: 1000, Port: 50051, AuthKeyID: "old", AuthSecret: "old-secret"}
+	if err := sr.Add(serv); err != nil {
+		t.Fatal(err)
+	}
+	if err := sr.SetPendingAuthKey(serv.ID, "new", "new-secret", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// нода знает другой секрет старого ключа, поэтому отклоняет и проверку, и повторную отправку
+	node := fakenode.New(fakenode.Config{})
+	node.EnableAuth("old", "other-secret")
+	a := newTestAPI(t, map[*models.Server]*fakenode.Node{serv: node})
+	ss := NewServers(logger.NewDiscard(), sr, a)
+
+	stored, err := sr.Get(serv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ss.resolvePendingAuthKey(stored, time.Minute); err == nil {
+		t.Fatal("rejected key resolved without error")
+	}
+
+	stored, err = sr.Get(serv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.AuthKeyID != "old" || stored.PendingAuthKeyID != "" {
+		t.Fatalf("pending key was not rolled back: %+v", stored)
+	}
+}
+
+func TestAddServerInvalidatesCountryCache(t *testing.T) {
+	db, c := newTestStore(t)
+	ss := NewServers(logger.NewDiscard(), repository.NewServers(logger.NewDiscard(), db, c), nil)
+
+	if servers, err := ss.GetAllByCountryID(5); err != nil || len(servers) != 0 {
+		t.Fatalf("got %v, %v", servers, err)
+	}
+
+	var added []uint
+	ss.OnAdd(func(server *models.Server) {
+		added = append(added, server.ID)
+	})
+	if err := ss.Add(&models.Server{IP: "10.0.0.1", Insecure: true, CountryID: 5, ChannelSpeed: 1000, Port: 50051}); err != nil {
+		t.Fatal(err)
+	}
+
+	servers, err := ss.GetAllByCountryID(5)
+	if err != nil || len(servers) != 1 {
+		t.Fatalf("new server is missing from cached country list: %v, %v", servers, err)
+	}
+	if len(added) != 1 || added[0] != servers[0].ID {
+		t.Fatalf("provisioning hook was not called for new server: %v", added)
+	}
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"log/slog"
+	"nsvpn/internal/app/config"
 	"nsvpn/internal/app/constants"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/repository"
@@ -12,11 +14,13 @@ type Subscriptions struct {
 	log *logger.Logger
 	sr  *repository.Subscriptions
 
-	Plans  *SubscriptionsPlans
-	Prices *SubscriptionsPrices
+	Plans    *SubscriptionsPlans
+	Prices   *SubscriptionsPrices
+	Trials   *SubscriptionsTrials
+	Vouchers *SubscriptionsVouchers
 }
 
-func NewSubscriptions(log *logger.Logger, sr *repository.Subscriptions) *Subscriptions {
+func NewSubscriptions(log *logger.Logger, sr *repository.Subscriptions, ur *repository.Users, pr *repository.Payments, trial config.Trial) *Subscriptions {
 	return &Subscriptions{
 		log: log,
 		sr:  sr,
@@ -28,6 +32,17 @@ func NewSubscriptions(log *logger.Logger, sr *repository.Subscriptions) *Subscri
 			log: log,
 			sr:  sr,
 		},
+		Trials: &SubscriptionsTrials{
+			log: log,
+			sr:  sr,
+			ur:  ur,
+			pr:  pr,
+			cfg: trial,
+		},
+		Vouchers: &SubscriptionsVouchers{
+			log: log,
+			sr:  sr,
+		},
 	}
 }
 
@@ -103,3 +118,41 @@ func (ss *Subscriptions) IsActive(userID int64, isActive bool) (bool, error) {
 	}
 	return false, nil
 }
+
+func extendSubscription(log *logger.Logger, sr *repository.Subscriptions, userID int64, days uint) (*models.Subscription, error) {
+	current, err := sr.GetLastByUserID(userID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if current != nil && current.IsActive && !current.IsTrial && current.EndDate.After(now) {
+		endDate := planEndDate(current.EndDate, days)
+		if err = sr.UpdateEndDate(current.ID, userID, endDate); err != nil {
+			return nil, err
+		}
+		current.EndDate = endDate
+		return current, nil
+	}
+
+	sub := &models.Subscription{
+		UserID:   userID,
+		EndDate:  planEndDate(now, days),
+		IsActive: true,
+	}
+	if sub.ID, err = sr.Add(sub); err != nil {
+		return nil, err
+	}
+
+	// бесплатные дни заменяют пробный период, а не продлевают его
+	if current != nil && current.IsTrial && current.IsActive {
+		if err = sr.UpdateIsActive(current.ID, userID, false); err != nil {
+			log.Error("Failed to end trial", err, slog.Int64("user_id", userID))
+		}
+	}
+	return sub, nil
+}
+
+func planEndDate(start time.Time, days uint) time.Time {
+	return start.AddDate(0, int(days/30), int(days%30))
+}
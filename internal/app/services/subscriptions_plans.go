@@ -6,6 +6,7 @@ import (
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/repository"
 	"nsvpn/pkg/logger"
+	"strconv"
 )
 
 type SubscriptionsPlans struct {
@@ -62,7 +63,9 @@ func (ss *SubscriptionsPlans) ProcessButtons(subPlans []*models.SubscriptionPlan
 
 	for i, plan := range subPlans {
 		btn := models.ButtonOption{
-			Value: fmt.Sprintf("sub_plan_%d", plan.ID),
+			Value:  fmt.Sprintf("sub_plan_%d", plan.ID),
+			Action: "sub_plan",
+			Args:   []string{strconv.FormatUint(uint64(plan.ID), 10)},
 		}
 
 		priceText := fmt.Sprintf("%s (%.0f ₽", plan.Name, plan.SubscriptionPrice.Price)
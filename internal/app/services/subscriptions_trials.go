@@ -0,0 +1,152 @@
+package services
+
+import (
+	"log/slog"
+	"nsvpn/internal/app/config"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"strings"
+	"time"
+)
+
+type SubscriptionsTrials struct {
+	log *logger.Logger
+	sr  *repository.Subscriptions
+	ur  *repository.Users
+	pr  *repository.Payments
+	cfg config.Trial
+}
+
+func (st *SubscriptionsTrials) Enabled() bool {
+	return st.cfg.Duration > 0
+}
+
+func (st *SubscriptionsTrials) Duration() time.Duration {
+	return st.cfg.Duration
+}
+
+func (st *SubscriptionsTrials) Countries() []string {
+	return st.cfg.Countries
+}
+
+func (st *SubscriptionsTrials) TrafficLimit() int64 {
+	return st.cfg.TrafficLimitMB << 20
+}
+
+func (st *SubscriptionsTrials) CanOffer(user *models.User) bool {
+	return st.Enabled() && user != nil && !user.TrialUsed
+}
+
+func (st *SubscriptionsTrials) CountryAllowed(sub *models.Subscription, code string) bool {
+	if sub == nil || !sub.IsTrial || len(st.cfg.Countries) == 0 {
+		return true
+	}
+
+	for _, country := range st.cfg.Countries {
+		if strings.EqualFold(strings.TrimSpace(country), code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (st *SubscriptionsTrials) Grant(userID int64) (*models.Subscription, error) {
+	if userID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+	if !st.Enabled() {
+		return nil, constants.ErrTrialDisabled
+	}
+
+	user, err := st.ur.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, constants.ErrUserNotFound
+	}
+
+	var partner *models.User
+	if user.PartnerID != 0 {
+		if partner, err = st.ur.Get(user.PartnerID); err != nil {
+			return nil, err
+		}
+	}
+	payments, err := st.pr.GetPaymentsCount(userID)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := st.sr.GetAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkTrialEligibility(user, partner, payments, len(subs)); err != nil {
+		st.log.Info("Trial denied", slog.Int64("user_id", userID), slog.String("reason", err.Error()))
+		return nil, err
+	}
+
+	// проверка выше могла устареть, право на пробный период окончательно занимает ClaimTrial
+	claimed, err := st.ur.ClaimTrial(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, constants.ErrTrialUsed
+	}
+
+	sub := &models.Subscription{
+		UserID:       userID,
+		EndDate:      time.Now().Add(st.cfg.Duration),
+		IsActive:     true,
+		IsTrial:      true,
+		TrafficLimit: st.TrafficLimit(),
+	}
+	if sub.ID, err = st.sr.Add(sub); err != nil {
+		if rerr := st.ur.ReleaseTrial(userID); rerr != nil {
+			st.log.Error("Failed to release trial", rerr, slog.Int64("user_id", userID))
+		}
+		return nil, err
+	}
+
+	st.log.Info("Trial granted", slog.Int64("user_id", userID), slog.Uint64("sub_id", uint64(sub.ID)), slog.Time("end_date", sub.EndDate))
+	return sub, nil
+}
+
+func (st *SubscriptionsTrials) End(userID int64, exceptID uint) error {
+	if userID == 0 {
+		return constants.ErrEmptyFields
+	}
+
+	subs, err := st.sr.GetAllByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		if !sub.IsTrial || !sub.IsActive || sub.ID == exceptID {
+			continue
+		}
+		if err = st.sr.UpdateIsActive(sub.ID, userID, false); err != nil {
+			return err
+		}
+		st.log.Info("Trial ended by purchase", slog.Int64("user_id", userID), slog.Uint64("sub_id", uint64(sub.ID)))
+	}
+	return nil
+}
+
+func checkTrialEligibility(user, partner *models.User, payments int64, subscriptions int) error {
+	if user.TrialUsed {
+		return constants.ErrTrialUsed
+	}
+	if payments > 0 || subscriptions > 0 {
+		return constants.ErrTrialNotEligible
+	}
+	// взаимные приглашения — типичный способ накрутить пробные периоды со второго аккаунта
+	if user.PartnerID == user.ID || (partner != nil && partner.PartnerID == user.ID) {
+		return constants.ErrTrialNotEligible
+	}
+	return nil
+}
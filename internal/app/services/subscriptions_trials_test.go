@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"nsvpn/internal/app/config"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+)
+
+func TestCheckTrialEligibility(t *testing.T) {
+	tests := []struct {
+		name          string
+		user          *models.User
+		partner       *models.User
+		payments      int64
+		subscriptions int
+		want          error
+	}{
+		{"new user", &models.User{ID: 1}, nil, 0, 0, nil},
+		{"invited user", &models.User{ID: 1, PartnerID: 2}, &models.User{ID: 2}, 0, 0, nil},
+		{"already used", &models.User{ID: 1, TrialUsed: true}, nil, 0, 0, constants.ErrTrialUsed},
+		{"has payments", &models.User{ID: 1}, nil, 1, 0, constants.ErrTrialNotEligible},
+		{"has subscriptions", &models.User{ID: 1}, nil, 0, 1, constants.ErrTrialNotEligible},
+		{"self referral", &models.User{ID: 1, PartnerID: 1}, nil, 0, 0, constants.ErrTrialNotEligible},
+		{"mutual referral", &models.User{ID: 1, PartnerID: 2}, &models.User{ID: 2, PartnerID: 1}, 0, 0, constants.ErrTrialNotEligible},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkTrialEligibility(tt.user, tt.partner, tt.payments, tt.subscriptions); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrialCountryAllowed(t *testing.T) {
+	st := &SubscriptionsTrials{cfg: config.Trial{Countries: []string{"NL", " de"}}}
+	trial := &models.Subscription{IsTrial: true}
+
+	if !st.CountryAllowed(trial, "DE") || !st.CountryAllowed(trial, "nl") {
+		t.Fatal("allowed country rejected")
+	}
+	if st.CountryAllowed(trial, "US") {
+		t.Fatal("disallowed country accepted")
+	}
+	if !st.CountryAllowed(&models.Subscription{}, "US") {
+		t.Fatal("paid subscription restricted")
+	}
+	if !(&SubscriptionsTrials{}).CountryAllowed(trial, "US") {
+		t.Fatal("empty list should allow every country")
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/models"
+	"nsvpn/internal/app/repository"
+	"nsvpn/pkg/logger"
+	"strings"
+)
+
+const (
+	voucherAlphabet    = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // без похожих символов 0/O и 1/I
+	voucherCodeLength  = 12
+	voucherStartPrefix = "gift_"
+)
+
+type SubscriptionsVouchers struct {
+	log *logger.Logger
+	sr  *repository.Subscriptions
+}
+
+func (sv *SubscriptionsVouchers) Get(code string) (*models.Voucher, error) {
+	code = normalizeVoucherCode(code)
+	if code == "" {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return sv.sr.Vouchers.GetByCode(code)
+}
+
+func (sv *SubscriptionsVouchers) GetByBuyerID(buyerID int64) ([]*models.Voucher, error) {
+	if buyerID == 0 {
+		return nil, constants.ErrEmptyFields
+	}
+
+	return sv.sr.Vouchers.GetByBuyerID(buyerID)
+}
+
+func (sv *SubscriptionsVouchers) Create(buyerID int64, plan *models.SubscriptionPlan, payload string) (*models.Voucher, error) {
+	if buyerID == 0 || plan == nil || plan.DurationDays == 0 || payload == "" {
+		return nil, constants.ErrEmptyFields
+	}
+
+	code, err := generateVoucherCode()
+	if err != nil {
+		return nil, err
+	}
+
+	voucher := &models.Voucher{
+		Code:         code,
+		PlanID:       plan.ID,
+		DurationDays: plan.DurationDays,
+		Amount:       plan.SubscriptionPrice.Price,
+		BuyerID:      buyerID,
+		Payload:      payload,
+		Status:       models.VoucherStatusActive,
+	}
+	if err = sv.sr.Vouchers.Add(voucher); err != nil {
+		return nil, err
+	}
+
+	sv.log.Info("Voucher purchased", slog.Uint64("id", uint64(voucher.ID)), slog.Int64("buyer_id", buyerID), slog.Uint64("plan_id", uint64(plan.ID)))
+	return voucher, nil
+}
+
+func (sv *SubscriptionsVouchers) Redeem(code string, recipientID int64) (*models.Voucher, *models.Subscription, error) {
+	code = normalizeVoucherCode(code)
+	if code == "" || recipientID == 0 {
+		return nil, nil, constants.ErrEmptyFields
+	}
+
+	voucher, err := sv.sr.Vouchers.GetByCode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if voucher == nil {
+		return nil, nil, constants.ErrVoucherNotFound
+	}
+	if voucher.Status != models.VoucherStatusActive {
+		return nil, nil, constants.ErrVoucherRedeemed
+	}
+
+	// код гасится до выдачи подписки и возвращается, если продлить её не удалось
+	if err = sv.sr.Vouchers.Redeem(voucher.ID, recipientID); err != nil {
+		return nil, nil, err
+	}
+
+	sub, err := extendSubscription(sv.log, sv.sr, recipientID, voucher.DurationDays)
+	if err != nil {
+		if rerr := sv.sr.Vouchers.Release(voucher.ID); rerr != nil {
+			sv.log.Error("Failed to release voucher", rerr, slog.Uint64("id", uint64(voucher.ID)))
+		}
+		return nil, nil, err
+	}
+
+	voucher.Status = models.VoucherStatusRedeemed
+	voucher.RecipientID = &recipientID
+	sv.log.Info("Voucher redeemed", slog.Uint64("id", uint64(voucher.ID)), slog.Int64("buyer_id", voucher.BuyerID), slog.Int64("recipient_id", recipientID))
+	return voucher, sub, nil
+}
+
+func (sv *SubscriptionsVouchers) Link(botUsername, code string) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s%s", botUsername, voucherStartPrefix, code)
+}
+
+func (sv *SubscriptionsVouchers) CodeFromStart(payload string) (string, bool) {
+	code, ok := strings.CutPrefix(strings.TrimSpace(payload), voucherStartPrefix)
+	if !ok || code == "" {
+		return "", false
+	}
+	return normalizeVoucherCode(code), true
+}
+
+func generateVoucherCode() (string, error) {
+	return randomCode(voucherAlphabet, voucherCodeLength)
+}
+
+func normalizeVoucherCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
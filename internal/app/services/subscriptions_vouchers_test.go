@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateVoucherCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		code, err := generateVoucherCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != voucherCodeLength {
+			t.Fatalf("code %q has length %d", code, len(code))
+		}
+		for _, r := range code {
+			if !strings.ContainsRune(voucherAlphabet, r) {
+				t.Fatalf("code %q contains %q", code, r)
+			}
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestVoucherCodeFromStart(t *testing.T) {
+	sv := &SubscriptionsVouchers{}
+
+	if code, ok := sv.CodeFromStart("gift_abcd-2345"); !ok || code != "ABCD2345" {
+		t.Fatalf("got %q, %v", code, ok)
+	}
+	for _, payload := range []string{"", "12345", "gift_"} {
+		if _, ok := sv.CodeFromStart(payload); ok {
+			t.Fatalf("payload %q accepted", payload)
+		}
+	}
+	if link := sv.Link("nsvpn_bot", "ABCD"); link != "https://t.me/nsvpn_bot?start=gift_ABCD" {
+		t.Fatalf("got %q", link)
+	}
+}
+
+func TestPlanEndDate(t *testing.T) {
+	start := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := map[uint]time.Time{
+		7:  time.Date(2025, 2, 7, 12, 0, 0, 0, time.UTC),
+		30: time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC),
+		90: time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	for days, want := range tests {
+		if got := planEndDate(start, days); !got.Equal(want) {
+			t.Errorf("planEndDate(%d) = %v, want %v", days, got, want)
+		}
+	}
+}
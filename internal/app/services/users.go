@@ -3,6 +3,7 @@ package services
 import (
 	"log/slog"
 	"nsvpn/internal/app/constants"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/repository"
 	"nsvpn/pkg/logger"
@@ -68,6 +69,17 @@ func (us *Users) UpdateIsSign(id int64, isSign bool) error {
 	return us.ur.UpdateIsSign(id, isSign)
 }
 
+func (us *Users) UpdateLanguage(id int64, language string) error {
+	if id == 0 || language == "" {
+		return constants.ErrEmptyFields
+	}
+	if !i18n.IsSupported(language) {
+		return constants.ErrUnsupportedLanguage
+	}
+
+	return us.ur.UpdateLanguage(id, language)
+}
+
 func (us *Users) IncrementBalance(id int64, amount float64) error {
 	if id == 0 || amount == 0 {
 		return constants.ErrEmptyFields
@@ -109,6 +121,10 @@ func (us *Users) IsFound(id int64) (bool, error) {
 	return true, nil
 }
 
+func (us *Users) GetAdmins() (users []*models.User, err error) {
+	return us.ur.GetAdmins()
+}
+
 func (us *Users) IsAdmin(id int64) (bool, error) {
 	if id == 0 {
 		return false, constants.ErrEmptyFields
@@ -142,3 +158,11 @@ func (us *Users) IsSign(id int64) (bool, error) {
 	}
 	return data.IsSign, nil
 }
+
+func (us *Users) Language(id int64) string {
+	data, err := us.ur.Get(id)
+	if err != nil || data == nil {
+		return i18n.Default
+	}
+	return i18n.Resolve(data.Language, data.LanguageCode)
+}
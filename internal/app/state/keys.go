@@ -6,9 +6,11 @@ import (
 )
 
 type KeysState struct {
-	UUID    string
-	Email   string
-	EndDate time.Time
-	Country *models.Country
-	Servers []*models.Server
+	UUID       string
+	Email      string
+	Host       string
+	EndDate    time.Time
+	Country    *models.Country
+	Transport  *models.CountryTransport
+	Transports []*models.CountryTransport
 }
@@ -3,14 +3,21 @@ package state
 import "nsvpn/internal/app/models"
 
 type PaymentsState struct {
-	Amount            float64
+	Amount            float64 // сумма зачисления на баланс
+	Discount          float64 // скидка по промокоду, уменьшает сумму к оплате, но не зачисление
 	Payload           string
 	Description       string
 	Note              string
 	Promocode         *models.Promocode
+	ReservationID     uint // резерв активации промокода, подтверждается после оплаты
+	PlanID            uint // тариф покупки, 0 — пополнение баланса
 	IsBuySubscription bool
 }
 
+func (ps PaymentsState) Total() float64 {
+	return ps.Amount - ps.Discount
+}
+
 type PaginationState struct {
 	CurrentPage int
 	TotalPages  int
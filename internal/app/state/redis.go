@@ -0,0 +1,115 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"nsvpn/pkg/logger"
+	"time"
+)
+
+const (
+	KeyPrefix = "state:"
+
+	updateRetries = 10
+)
+
+var errUpdateConflict = errors.New("state update conflict")
+
+type redisState[T any] struct {
+	log    *logger.Logger
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+func NewRedisStorage[T any](log *logger.Logger, client *redis.Client, name string, ttl time.Duration) Storage[T] {
+	return &redisState[T]{
+		log:    log,
+		client: client,
+		prefix: KeyPrefix + name + ":",
+		ttl:    ttl,
+	}
+}
+
+func (rs *redisState[T]) Get(key string) (T, bool) {
+	var value T
+	data, err := rs.client.Get(context.Background(), rs.prefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			rs.log.Error("Failed to get state", err, slog.String("key", rs.prefix+key))
+		}
+		return value, false
+	}
+
+	if err = json.Unmarshal(data, &value); err != nil {
+		rs.log.Error("Failed to decode state", err, slog.String("key", rs.prefix+key))
+		var zero T
+		return zero, false
+	}
+	return value, true
+}
+
+func (rs *redisState[T]) Set(key string, value T) {
+	rs.SetWithTTL(key, value, rs.ttl)
+}
+
+func (rs *redisState[T]) SetWithTTL(key string, value T, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		rs.log.Error("Failed to encode state", err, slog.String("key", rs.prefix+key))
+		return
+	}
+
+	if err = rs.client.Set(context.Background(), rs.prefix+key, data, ttl).Err(); err != nil {
+		rs.log.Error("Failed to set state", err, slog.String("key", rs.prefix+key))
+	}
+}
+
+func (rs *redisState[T]) Update(key string, updater func(T) T) {
+	ctx := context.Background()
+	fullKey := rs.prefix + key
+
+	update := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, fullKey).Bytes()
+		if err != nil {
+			return err
+		}
+
+		var value T
+		if err = json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		if data, err = json.Marshal(updater(value)); err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, fullKey, data, redis.KeepTTL)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < updateRetries; i++ {
+		err := rs.client.Watch(ctx, update, fullKey)
+		switch {
+		case err == nil, errors.Is(err, redis.Nil):
+			return
+		case errors.Is(err, redis.TxFailedErr):
+			continue
+		default:
+			rs.log.Error("Failed to update state", err, slog.String("key", fullKey))
+			return
+		}
+	}
+	rs.log.Error("Failed to update state", errUpdateConflict, slog.String("key", fullKey))
+}
+
+func (rs *redisState[T]) Delete(key string) {
+	if err := rs.client.Del(context.Background(), rs.prefix+key).Err(); err != nil {
+		rs.log.Error("Failed to delete state", err, slog.String("key", rs.prefix+key))
+	}
+}
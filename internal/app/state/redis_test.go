@@ -0,0 +1,105 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"nsvpn/pkg/logger"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+func TestRedisStorageTTL(t *testing.T) {
+	mr, client := newTestRedis(t)
+	s := NewRedisStorage[PaginationState](logger.NewDiscard(), client, "pages", time.Hour)
+
+	s.Set("1", PaginationState{CurrentPage: 1, TotalPages: 3})
+	if ttl := mr.TTL(KeyPrefix + "pages:1"); ttl != time.Hour {
+		t.Fatalf("TTL = %v, want %v", ttl, time.Hour)
+	}
+	s.SetWithTTL("2", PaginationState{CurrentPage: 2}, time.Minute)
+
+	// Update не должен сбрасывать срок жизни ключа
+	s.Update("2", func(ps PaginationState) PaginationState { ps.CurrentPage++; return ps })
+	if ttl := mr.TTL(KeyPrefix + "pages:2"); ttl != time.Minute {
+		t.Fatalf("TTL after Update = %v, want %v", ttl, time.Minute)
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if _, ok := s.Get("2"); ok {
+		t.Fatal("expired key is still readable")
+	}
+	if ps, ok := s.Get("1"); !ok || ps.CurrentPage != 1 || ps.TotalPages != 3 {
+		t.Fatalf("Get(1) = %+v, %v", ps, ok)
+	}
+}
+
+func TestRedisStorageMissingKey(t *testing.T) {
+	mr, client := newTestRedis(t)
+	s := NewRedisStorage[PaymentsState](logger.NewDiscard(), client, "payments", 0)
+
+	if _, ok := s.Get("missing"); ok {
+		t.Fatal("Get returned a missing key")
+	}
+	s.Update("missing", func(ps PaymentsState) PaymentsState { ps.Amount = 1; return ps })
+	if mr.Exists(KeyPrefix + "payments:missing") {
+		t.Fatal("Update created a missing key")
+	}
+	s.Delete("missing")
+
+	if err := mr.Set(KeyPrefix+"payments:broken", "{"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, ok := s.Get("broken"); ok {
+		t.Fatal("Get decoded a broken value")
+	}
+}
+
+func TestRedisStorageUpdateRetriesOnConflict(t *testing.T) {
+	_, client := newTestRedis(t)
+	s := NewRedisStorage[PaymentsState](logger.NewDiscard(), client, "payments", time.Hour)
+	s.Set("1", PaymentsState{Amount: 100})
+
+	// первая попытка конкурирует с параллельной записью и должна быть повторена на свежем значении
+	calls := 0
+	s.Update("1", func(ps PaymentsState) PaymentsState {
+		calls++
+		if calls == 1 {
+			s.Set("1", PaymentsState{Amount: 50})
+		}
+		ps.Amount -= 10
+		return ps
+	})
+
+	if calls != 2 {
+		t.Fatalf("updater calls = %d, want 2", calls)
+	}
+	if ps, _ := s.Get("1"); ps.Amount != 40 {
+		t.Fatalf("Amount = %v, want 40", ps.Amount)
+	}
+}
+
+func TestRedisStorageDelete(t *testing.T) {
+	mr, client := newTestRedis(t)
+	s := NewRedisStorage[PaymentsState](logger.NewDiscard(), client, "payments", time.Hour)
+
+	s.Set("1", PaymentsState{Amount: 100})
+	s.Delete("1")
+	if mr.Exists(KeyPrefix + "payments:1") {
+		t.Fatal("key still exists after Delete")
+	}
+	if n, err := client.DBSize(context.Background()).Result(); err != nil || n != 0 {
+		t.Fatalf("DBSize = %d, %v", n, err)
+	}
+}
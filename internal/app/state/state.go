@@ -1,59 +1,144 @@
 package state
 
 import (
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"nsvpn/pkg/logger"
 	"sync"
+	"time"
 )
 
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+
+	cleanupInterval = time.Minute
+)
+
+var ErrUnknownBackend = errors.New("unknown state backend")
+
 type Storage[T any] interface {
 	Get(key string) (T, bool)
 	Set(key string, value T)
+	SetWithTTL(key string, value T, ttl time.Duration)
 	Update(key string, updater func(T) T)
 	Delete(key string)
 }
 
+type Backend struct {
+	log    *logger.Logger
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewBackend(log *logger.Logger, kind string, client *redis.Client, ttl time.Duration) (*Backend, error) {
+	switch kind {
+	case BackendMemory:
+		return &Backend{log: log, ttl: ttl}, nil
+	case BackendRedis:
+		if client == nil {
+			return nil, errors.New("redis client is required")
+		}
+		return &Backend{log: log, client: client, ttl: ttl}, nil
+	default:
+		return nil, ErrUnknownBackend
+	}
+}
+
+func New[T any](b *Backend, name string) Storage[T] {
+	if b == nil {
+		return NewMemoryStorage[T](0)
+	}
+	if b.client == nil {
+		return NewMemoryStorage[T](b.ttl)
+	}
+	return NewRedisStorage[T](b.log, b.client, name, b.ttl)
+}
+
+type memoryItem[T any] struct {
+	value     T
+	expiresAt time.Time // нулевое значение - без срока
+}
+
+func (mi memoryItem[T]) expired(now time.Time) bool {
+	return !mi.expiresAt.IsZero() && !now.Before(mi.expiresAt)
+}
+
 type memoryState[T any] struct {
 	mu    sync.RWMutex
-	store map[string]T
+	store map[string]memoryItem[T]
+	ttl   time.Duration
 }
 
-func NewMemoryStorage[T any]() Storage[T] {
+func NewMemoryStorage[T any](ttl time.Duration) Storage[T] {
 	ms := &memoryState[T]{
-		store: make(map[string]T),
+		store: make(map[string]memoryItem[T]),
+		ttl:   ttl,
 	}
+	go ms.cleanup()
 
 	return ms
 }
 
+// хранилища живут всё время работы бота, поэтому уборщик не останавливается
+func (ms *memoryState[T]) cleanup() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		ms.sweep(now)
+	}
+}
+
+func (ms *memoryState[T]) sweep(now time.Time) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for k, item := range ms.store {
+		if item.expired(now) {
+			delete(ms.store, k)
+		}
+	}
+}
+
 func (ms *memoryState[T]) Get(key string) (T, bool) {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
 	item, exists := ms.store[key]
-	if !exists {
+	if !exists || item.expired(time.Now()) {
 		var zero T
 		return zero, false
 	}
-	return item, true
+	return item.value, true
 }
 
 func (ms *memoryState[T]) Set(key string, value T) {
+	ms.SetWithTTL(key, value, ms.ttl)
+}
+
+func (ms *memoryState[T]) SetWithTTL(key string, value T, ttl time.Duration) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	ms.store[key] = value
+	item := memoryItem[T]{value: value}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+	ms.store[key] = item
 }
 
 func (ms *memoryState[T]) Update(key string, updater func(T) T) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	oldValue, exists := ms.store[key]
-	if !exists {
+	item, exists := ms.store[key]
+	if !exists || item.expired(time.Now()) {
 		return
 	}
 
-	newValue := updater(oldValue)
-	ms.store[key] = newValue
+	item.value = updater(item.value)
+	ms.store[key] = item
 }
 
 func (ms *memoryState[T]) Delete(key string) {
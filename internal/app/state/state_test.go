@@ -0,0 +1,82 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"nsvpn/pkg/logger"
+)
+
+func TestMemoryStorageTTL(t *testing.T) {
+	s := NewMemoryStorage[PaginationState](0)
+
+	s.SetWithTTL("short", PaginationState{CurrentPage: 1}, time.Millisecond)
+	s.Set("forever", PaginationState{CurrentPage: 2})
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := s.Get("short"); ok {
+		t.Fatal("expired key is still readable")
+	}
+	s.Update("short", func(ps PaginationState) PaginationState { ps.CurrentPage++; return ps })
+	if _, ok := s.Get("short"); ok {
+		t.Fatal("Update revived an expired key")
+	}
+	if ps, ok := s.Get("forever"); !ok || ps.CurrentPage != 2 {
+		t.Fatalf("Get(forever) = %+v, %v", ps, ok)
+	}
+}
+
+func TestMemoryStorageUpdate(t *testing.T) {
+	s := NewMemoryStorage[PaymentsState](time.Hour)
+
+	s.Update("missing", func(ps PaymentsState) PaymentsState { ps.Amount = 1; return ps })
+	if _, ok := s.Get("missing"); ok {
+		t.Fatal("Update created a missing key")
+	}
+
+	s.Set("1", PaymentsState{Amount: 100})
+	s.Update("1", func(ps PaymentsState) PaymentsState { ps.Amount -= 10; return ps })
+	if ps, _ := s.Get("1"); ps.Amount != 90 {
+		t.Fatalf("Amount = %v, want 90", ps.Amount)
+	}
+
+	s.Delete("1")
+	if _, ok := s.Get("1"); ok {
+		t.Fatal("key still exists after Delete")
+	}
+}
+
+func TestMemoryStorageSweep(t *testing.T) {
+	ms := NewMemoryStorage[PaginationState](0).(*memoryState[PaginationState])
+
+	ms.SetWithTTL("short", PaginationState{CurrentPage: 1}, time.Minute)
+	ms.Set("forever", PaginationState{CurrentPage: 2})
+	ms.sweep(time.Now().Add(2 * time.Minute))
+
+	if _, ok := ms.store["short"]; ok {
+		t.Fatal("sweep kept an expired key")
+	}
+	if _, ok := ms.store["forever"]; !ok {
+		t.Fatal("sweep removed a key without TTL")
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	log := logger.NewDiscard()
+
+	if _, err := NewBackend(log, "etcd", nil, 0); !errors.Is(err, ErrUnknownBackend) {
+		t.Fatalf("NewBackend(etcd) error = %v, want ErrUnknownBackend", err)
+	}
+	if _, err := NewBackend(log, BackendRedis, nil, 0); err == nil {
+		t.Fatal("redis backend created without a client")
+	}
+
+	b, err := NewBackend(log, BackendMemory, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("NewBackend(memory): %v", err)
+	}
+	if _, ok := New[KeysState](b, "keys").(*memoryState[KeysState]); !ok {
+		t.Fatal("memory backend returned a non-memory storage")
+	}
+}
@@ -7,13 +7,19 @@ import (
 	"gopkg.in/telebot.v4"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"log/slog"
 	"nsvpn/internal/app/api"
+	"nsvpn/internal/app/callback"
 	"nsvpn/internal/app/config"
+	"nsvpn/internal/app/conversation"
+	"nsvpn/internal/app/events"
 	"nsvpn/internal/app/handlers"
+	"nsvpn/internal/app/i18n"
 	"nsvpn/internal/app/middleware"
 	"nsvpn/internal/app/models"
 	"nsvpn/internal/app/repository"
 	"nsvpn/internal/app/services"
+	"nsvpn/internal/app/state"
 	"nsvpn/pkg/cache"
 	"nsvpn/pkg/logger"
 	"time"
@@ -24,8 +30,14 @@ type App struct {
 	log   *logger.Logger
 	db    *gorm.DB
 	cache *cache.Cache
+	redis *redis.Client
 	bot   *telebot.Bot
 	api   *api.API
+	bus   *events.Bus
+
+	conversations *conversation.Manager
+	callbacks     *callback.Router
+	state         *state.Backend
 
 	countryRepo       *repository.Country
 	keysRepo          *repository.Keys
@@ -34,12 +46,20 @@ type App struct {
 	serversRepo       *repository.Servers
 	subscriptionsRepo *repository.Subscriptions
 	usersRepo         *repository.Users
+	assignmentsRepo   *repository.Assignments
+	jobsRepo          *repository.ProvisioningJobs
+	referralsRepo     *repository.Referrals
 
-	clientButtons        *services.Buttons
-	clientButtonsWithSub *services.Buttons
+	clientButtons        *services.LocalizedButtons
+	clientButtonsWithSub *services.LocalizedButtons
 
 	baseService          *services.Base
 	checkService         *services.Check
+	monitorService       *services.Monitor
+	placementService     *services.Placement
+	reconcilerService    *services.Reconciler
+	provisioningService  *services.Provisioning
+	realityService       *services.Reality
 	countryService       *services.Country
 	keysService          *services.Keys
 	paymentsService      *services.Payments
@@ -47,9 +67,11 @@ type App struct {
 	serversService       *services.Servers
 	subscriptionsService *services.Subscriptions
 	usersService         *services.Users
+	referralsService     *services.Referrals
 
 	usersMiddleware *middleware.Users
 
+	adminHandler         *handlers.Admin
 	baseHandler          *handlers.Base
 	keysHandler          *handlers.Keys
 	paymentsHandler      *handlers.Payments
@@ -57,6 +79,8 @@ type App struct {
 	serversHandler       *handlers.Servers
 	subscriptionsHandler *handlers.Subscriptions
 	usersHandler         *handlers.Users
+	referralsHandler     *handlers.Referrals
+	giftsHandler         *handlers.Gifts
 }
 
 func New() error {
@@ -76,19 +100,30 @@ func New() error {
 		return err
 	}
 
+	if err := a.initState(); err != nil {
+		return err
+	}
+
 	if err := a.initBot(); err != nil {
 		return err
 	}
 
-	a.clientButtons = services.NewButtons(models.ClientButtons, []int{2, 2}, "reply")
-	a.clientButtonsWithSub = services.NewButtons(models.ClientButtonsWithSub, []int{2, 2}, "reply")
+	a.clientButtons = services.NewLocalizedButtons(models.ClientButtons, []int{2, 2}, "reply")
+	a.clientButtonsWithSub = services.NewLocalizedButtons(models.ClientButtonsWithSub, []int{2, 2}, "reply")
 
-	a.api = api.NewAPI(a.log)
+	a.bus = events.NewBus(a.log)
+	a.api = api.NewAPI(a.log, a.bus)
+	defer a.api.Close()
 	a.initRepo()
 	a.initServices()
 	a.initHandlers()
 	a.initMiddlewares()
 
+	a.provisioningService.Resume()
+	a.monitorService.Listen(a.bus)
+	a.realityService.Listen()
+	a.watchServers()
+
 	go func() {
 		ticker := time.NewTicker(15 * time.Minute)
 		defer ticker.Stop()
@@ -99,6 +134,59 @@ func New() error {
 		}
 	}()
 
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		a.monitorService.Run()
+		a.serversHandler.ReloadCountries()
+		a.placementService.Rebalance()
+		for range ticker.C {
+			a.monitorService.Run()
+			a.serversHandler.ReloadCountries()
+			a.placementService.Rebalance()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := a.reconcilerService.Run(false); err != nil {
+				a.log.Error("Failed to reconcile nodes", err)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		a.serversService.RotateExpiredAuthKeys(a.cfg.NodeAuth.RotationInterval, a.cfg.NodeAuth.GracePeriod)
+		for range ticker.C {
+			a.serversService.RotateExpiredAuthKeys(a.cfg.NodeAuth.RotationInterval, a.cfg.NodeAuth.GracePeriod)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			a.conversations.ExpireSessions()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			a.promocodesService.ReleaseExpired()
+		}
+	}()
+
 	return a.run()
 }
 
@@ -119,17 +207,32 @@ func (a *App) initDB() (err error) {
 	if err != nil {
 		return err
 	}
+	// старые данные приводятся к новой схеме до того, как AutoMigrate построит по ней индексы
+	if err = repository.Migrate(a.log, a.db); err != nil {
+		return err
+	}
 	return a.db.AutoMigrate(
 		&models.User{},
 		&models.Country{},
+		&models.CountryTransport{},
 		&models.Server{},
+		&models.ServerStatus{},
+		&models.Assignment{},
+		&models.ProvisioningJob{},
 		&models.Subscription{},
 		&models.SubscriptionPlan{},
 		&models.SubscriptionPrice{},
+		&models.Voucher{},
 		&models.Payment{},
 		&models.Key{},
+		&models.KeyStatus{},
+		&models.PromocodeCampaign{},
 		&models.Promocode{},
 		&models.PromocodeActivations{},
+		&models.PromocodeReservation{},
+		&models.ReferralRule{},
+		&models.ReferralEarning{},
+		&models.ReferralWithdrawal{},
 	)
 }
 
@@ -145,9 +248,18 @@ func (a *App) initCache() error {
 	if err != nil {
 		return err
 	}
-	client.FlushAll(ctx)
-
+	a.redis = client
 	a.cache = cache.New(a.log, client)
+	a.cache.Flush(conversation.KeyPrefix, state.KeyPrefix)
+	return nil
+}
+
+func (a *App) initState() (err error) {
+	a.state, err = state.NewBackend(a.log, a.cfg.State.Backend, a.redis, a.cfg.State.TTL)
+	if err != nil {
+		a.log.Error("Failed creating state backend", err, slog.String("backend", a.cfg.State.Backend))
+		return err
+	}
 	return nil
 }
 
@@ -172,18 +284,52 @@ func (a *App) initRepo() {
 	a.serversRepo = repository.NewServers(a.log, a.db, a.cache)
 	a.subscriptionsRepo = repository.NewSubscriptions(a.log, a.db, a.cache)
 	a.usersRepo = repository.NewUsers(a.log, a.db, a.cache)
+	a.assignmentsRepo = repository.NewAssignments(a.log, a.db, a.cache)
+	a.jobsRepo = repository.NewProvisioningJobs(a.log, a.db, a.cache)
+	a.referralsRepo = repository.NewReferrals(a.log, a.db, a.cache)
 }
 
 func (a *App) initServices() {
 	a.baseService = services.NewBase(a.log)
 	a.countryService = services.NewCountry(a.log, a.countryRepo)
 	a.paymentsService = services.NewPayments(a.log, a.cfg, a.paymentsRepo)
-	a.promocodesService = services.NewPromocodes(a.log, a.promocodesRepo)
-	a.subscriptionsService = services.NewSubscriptions(a.log, a.subscriptionsRepo)
+	a.promocodesService = services.NewPromocodes(a.log, a.promocodesRepo, a.usersRepo, a.paymentsRepo, a.subscriptionsRepo)
+	a.subscriptionsService = services.NewSubscriptions(a.log, a.subscriptionsRepo, a.usersRepo, a.paymentsRepo, a.cfg.Trial)
 	a.usersService = services.NewUsers(a.log, a.usersRepo)
+	a.referralsService = services.NewReferrals(a.log, a.referralsRepo, a.usersService, a.paymentsService, a.cfg.Referral.MinWithdrawal)
 	a.keysService = services.NewKeys(a.log, a.keysRepo)
 	a.serversService = services.NewServers(a.log, a.serversRepo, a.api)
-	a.checkService = services.NewCheck(a.log, a.bot, a.keysService, a.subscriptionsService, a.serversService, a.usersService, a.api, a.clientButtons)
+	a.placementService = services.NewPlacement(a.log, a.bot, a.assignmentsRepo, a.serversService, a.keysService, a.countryService, a.subscriptionsService, a.usersService, a.api)
+	a.provisioningService = services.NewProvisioning(a.log, a.jobsRepo, a.keysService, a.countryService, a.subscriptionsService, a.placementService, a.api)
+	a.serversService.OnAdd(a.provisioningService.Start)
+	a.serversService.OnAdd(a.watchServer)
+	a.reconcilerService = services.NewReconciler(a.log, a.assignmentsRepo, a.serversService, a.keysService, a.countryService, a.subscriptionsService, a.placementService, a.api)
+	a.realityService = services.NewReality(a.log, a.bot, a.countryRepo, a.countryService, a.serversService, a.keysService, a.subscriptionsService, a.usersService, a.placementService, a.api)
+	a.monitorService = services.NewMonitor(a.log, a.bot, a.serversService, a.usersService, a.api)
+	a.checkService = services.NewCheck(a.log, a.bot, a.keysService, a.subscriptionsService, a.serversService, a.usersService, a.countryService, a.api, a.clientButtons)
+}
+
+func (a *App) watchServers() {
+	servers, err := a.serversService.GetAll()
+	if err != nil {
+		a.log.Error("Failed to get all servers", err)
+		return
+	}
+
+	for _, server := range servers {
+		a.watchServer(server)
+	}
+}
+
+func (a *App) watchServer(server *models.Server) {
+	a.api.Watch(server, a.serversService.Get)
+}
+
+func (a *App) callbackSecret() string {
+	if a.cfg.CallbackSecret != "" {
+		return a.cfg.CallbackSecret
+	}
+	return a.cfg.TelegramAPI
 }
 
 func (a *App) initMiddlewares() {
@@ -191,36 +337,60 @@ func (a *App) initMiddlewares() {
 }
 
 func (a *App) initHandlers() {
+	conversations := conversation.NewMemoryStore()
+	if a.cfg.State.Backend == state.BackendRedis {
+		conversations = conversation.NewRedisStore(a.redis)
+	}
+	a.conversations = conversation.NewManager(a.log, conversations)
+	a.callbacks = callback.NewRouter(a.log, a.callbackSecret())
 	a.promocodesHandler = handlers.NewPromocodes(a.log, a.bot, a.paymentsService, a.promocodesService, a.usersService)
-	a.paymentsHandler = handlers.NewPayments(a.log, a.bot, a.cfg, a.promocodesService, a.paymentsService, a.usersService, a.promocodesHandler)
-	a.keysHandler = handlers.NewKeys(a.log, a.bot, a.keysService, a.serversService, a.subscriptionsService, a.api)
-	a.subscriptionsHandler = handlers.NewSubscriptions(a.log, a.bot, a.subscriptionsService, a.countryService, a.paymentsService, a.usersService, a.paymentsHandler, a.clientButtonsWithSub)
-	a.usersHandler = handlers.NewUsers(a.log, a.bot, a.usersService, a.subscriptionsHandler, a.paymentsHandler)
-	a.serversHandler = handlers.NewServers(a.log, a.bot, a.serversService, a.subscriptionsService, a.keysHandler, a.countryService)
-	a.baseHandler = handlers.NewBase(a.log, a.usersService)
+	a.paymentsHandler = handlers.NewPayments(a.log, a.bot, a.cfg, a.promocodesService, a.paymentsService, a.usersService, a.referralsService, a.promocodesHandler, a.conversations, a.state)
+	a.keysHandler = handlers.NewKeys(a.log, a.bot, a.callbacks, a.keysService, a.placementService, a.subscriptionsService, a.countryService, a.state)
+	a.subscriptionsHandler = handlers.NewSubscriptions(a.log, a.bot, a.callbacks, a.subscriptionsService, a.countryService, a.paymentsService, a.usersService, a.paymentsHandler, a.conversations, a.clientButtonsWithSub)
+	a.referralsHandler = handlers.NewReferrals(a.log, a.bot, a.callbacks, a.referralsService, a.usersService, a.conversations)
+	a.giftsHandler = handlers.NewGifts(a.log, a.bot, a.callbacks, a.subscriptionsService, a.paymentsService, a.usersService, a.paymentsHandler, a.conversations, a.clientButtonsWithSub)
+	a.usersHandler = handlers.NewUsers(a.log, a.bot, a.callbacks, a.usersService, a.subscriptionsHandler, a.paymentsHandler, a.referralsHandler, a.giftsHandler, a.clientButtons, a.clientButtonsWithSub)
+	a.serversHandler = handlers.NewServers(a.log, a.bot, a.callbacks, a.serversService, a.subscriptionsService, a.keysHandler, a.countryService)
+	a.baseHandler = handlers.NewBase(a.log, a.usersService, a.giftsHandler)
+	a.adminHandler = handlers.NewAdmin(a.log, a.bot, a.cfg, a.usersService, a.reconcilerService, a.realityService)
 }
 
 func (a *App) run() error {
 	a.bot.Use(a.usersMiddleware.IsUser)
 
 	a.bot.Handle("/start", a.baseHandler.StartHandler)
-	a.bot.Handle("💬 Техподдержка", func(c telebot.Context) error {
-		techBtns := services.NewButtons([]models.ButtonOption{
-			{Value: "tech_support", Display: "Техподдержка", URL: "https://t.me/nsvpn_support_bot"},
-		}, []int{1}, "inline")
-
-		return c.Send("💬 Если у вас возникли какие-либо вопросы или проблемы, обратитесь в нашу техподдержку", techBtns.AddBtns())
+	techBtns := services.NewLocalizedButtons([]models.ButtonOption{
+		{Value: "tech_support", Display: "Техподдержка", URL: "https://t.me/nsvpn_support_bot"},
+	}, []int{1}, "inline")
+	a.handleLabels("button.technical_support", func(c telebot.Context) error {
+		lang := i18n.FromContext(c)
+		return c.Send(i18n.T(lang, "base.support"), techBtns.Get(lang).AddBtns())
 	})
-	a.bot.Handle("💡 Информация", a.baseHandler.InfoHandler)
-	a.bot.Handle("👔 Профиль", a.usersHandler.ProfileHandler)
-	a.bot.Handle("🔒 Подключить VPN", a.subscriptionsHandler.ChooseDurationHandler)
-	a.bot.Handle("🌐 Список серверов", a.serversHandler.ListCountriesHandler)
-	a.bot.Handle(telebot.OnText, a.baseHandler.OnTextHandler)
+	a.handleLabels("button.info", a.baseHandler.InfoHandler)
+	a.handleLabels("button.profile", a.usersHandler.ProfileHandler)
+	a.handleLabels("button.attach_vpn", a.subscriptionsHandler.ChooseDurationHandler)
+	a.handleLabels("button.list_servers", a.serversHandler.ListCountriesHandler)
+	a.bot.Handle("/cancel", a.conversations.CancelHandler)
+	a.bot.Handle(telebot.OnText, a.conversations.OnText(a.baseHandler.OnTextHandler))
+	a.bot.Handle(telebot.OnCallback, a.callbacks.Dispatch)
 
 	a.paymentsHandler.RegisterRoutes()
+	a.subscriptionsHandler.RegisterHandlers()
+	a.usersHandler.RegisterHandlers()
+	a.referralsHandler.RegisterHandlers()
+	a.giftsHandler.RegisterHandlers()
+	a.promocodesHandler.RegisterHandlers()
+	a.usersMiddleware.RegisterHandlers()
 	a.keysHandler.RegisterHandlers()
 	a.serversHandler.RegisterHandlers()
+	a.adminHandler.RegisterHandlers()
 
 	a.bot.Start()
 	return nil
 }
+
+func (a *App) handleLabels(key string, handler telebot.HandlerFunc) {
+	for _, label := range i18n.Labels(key) {
+		a.bot.Handle(label, handler)
+	}
+}
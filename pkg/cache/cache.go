@@ -72,3 +72,35 @@ func (c *Cache) Delete(keys ...string) {
 		}
 	}
 }
+
+func (c *Cache) Flush(keepPrefixes ...string) {
+	ctx := context.Background()
+	iter := c.client.Scan(ctx, 0, "*", 1000).Iterator()
+
+	var keysToDelete []string
+	for iter.Next(ctx) {
+		key := iter.Val()
+		keep := false
+		for _, prefix := range keepPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				keep = true
+				break
+			}
+		}
+		if !keep {
+			keysToDelete = append(keysToDelete, key)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		c.log.Error("SCAN error", err)
+		return
+	}
+
+	for start := 0; start < len(keysToDelete); start += 1000 {
+		chunk := keysToDelete[start:min(start+1000, len(keysToDelete))]
+		if err := c.client.Del(ctx, chunk...).Err(); err != nil {
+			c.log.Error("Failed to delete keys", err, slog.Int("count", len(chunk)))
+		}
+	}
+	c.log.Debug("Flushed cache", slog.Int("count", len(keysToDelete)))
+}
@@ -23,10 +23,61 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Data models
+type Protocol int32
+
+const (
+	Protocol_PROTOCOL_VLESS       Protocol = 0
+	Protocol_PROTOCOL_TROJAN      Protocol = 1
+	Protocol_PROTOCOL_SHADOWSOCKS Protocol = 2
+)
+
+// Enum value maps for Protocol.
+var (
+	Protocol_name = map[int32]string{
+		0: "PROTOCOL_VLESS",
+		1: "PROTOCOL_TROJAN",
+		2: "PROTOCOL_SHADOWSOCKS",
+	}
+	Protocol_value = map[string]int32{
+		"PROTOCOL_VLESS":       0,
+		"PROTOCOL_TROJAN":      1,
+		"PROTOCOL_SHADOWSOCKS": 2,
+	}
+)
+
+func (x Protocol) Enum() *Protocol {
+	p := new(Protocol)
+	*p = x
+	return p
+}
+
+func (x Protocol) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Protocol) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_client_v1_proto_enumTypes[0].Descriptor()
+}
+
+func (Protocol) Type() protoreflect.EnumType {
+	return &file_protos_client_v1_proto_enumTypes[0]
+}
+
+func (x Protocol) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Protocol.Descriptor instead.
+func (Protocol) EnumDescriptor() ([]byte, []int) {
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{0}
+}
+
 // Requests and Responses
 type ClientExistsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Protocol      Protocol               `protobuf:"varint,2,opt,name=protocol,proto3,enum=client.v1.Protocol" json:"protocol,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -68,6 +119,13 @@ func (x *ClientExistsRequest) GetUuid() string {
 	return ""
 }
 
+func (x *ClientExistsRequest) GetProtocol() Protocol {
+	if x != nil {
+		return x.Protocol
+	}
+	return Protocol_PROTOCOL_VLESS
+}
+
 type ClientExistsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
@@ -245,10 +303,13 @@ func (x *ListClientsResponse) GetClients() []*Client {
 }
 
 type CreateClientRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
-	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
-	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Uuid      string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Email     string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Protocol  Protocol               `protobuf:"varint,4,opt,name=protocol,proto3,enum=client.v1.Protocol" json:"protocol,omitempty"`
+	// Пароль для Trojan или пользовательский PSK для Shadowsocks-2022
+	Password      string `protobuf:"bytes,5,opt,name=password,proto3" json:"password,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -304,10 +365,25 @@ func (x *CreateClientRequest) GetExpiresAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *CreateClientRequest) GetProtocol() Protocol {
+	if x != nil {
+		return x.Protocol
+	}
+	return Protocol_PROTOCOL_VLESS
+}
+
+func (x *CreateClientRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
 type UpdateClientRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
 	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Protocol      Protocol               `protobuf:"varint,3,opt,name=protocol,proto3,enum=client.v1.Protocol" json:"protocol,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -356,9 +432,17 @@ func (x *UpdateClientRequest) GetExpiresAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *UpdateClientRequest) GetProtocol() Protocol {
+	if x != nil {
+		return x.Protocol
+	}
+	return Protocol_PROTOCOL_VLESS
+}
+
 type DeleteClientRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Protocol      Protocol               `protobuf:"varint,2,opt,name=protocol,proto3,enum=client.v1.Protocol" json:"protocol,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -400,6 +484,190 @@ func (x *DeleteClientRequest) GetUuid() string {
 	return ""
 }
 
+func (x *DeleteClientRequest) GetProtocol() Protocol {
+	if x != nil {
+		return x.Protocol
+	}
+	return Protocol_PROTOCOL_VLESS
+}
+
+// Batch messages
+type BatchCreateClientsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clients       []*CreateClientRequest `protobuf:"bytes,1,rep,name=clients,proto3" json:"clients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCreateClientsRequest) Reset() {
+	*x = BatchCreateClientsRequest{}
+	mi := &file_protos_client_v1_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCreateClientsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateClientsRequest) ProtoMessage() {}
+
+func (x *BatchCreateClientsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_client_v1_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateClientsRequest.ProtoReflect.Descriptor instead.
+func (*BatchCreateClientsRequest) Descriptor() ([]byte, []int) {
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BatchCreateClientsRequest) GetClients() []*CreateClientRequest {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+type BatchUpdateClientsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clients       []*UpdateClientRequest `protobuf:"bytes,1,rep,name=clients,proto3" json:"clients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchUpdateClientsRequest) Reset() {
+	*x = BatchUpdateClientsRequest{}
+	mi := &file_protos_client_v1_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchUpdateClientsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchUpdateClientsRequest) ProtoMessage() {}
+
+func (x *BatchUpdateClientsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_client_v1_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchUpdateClientsRequest.ProtoReflect.Descriptor instead.
+func (*BatchUpdateClientsRequest) Descriptor() ([]byte, []int) {
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BatchUpdateClientsRequest) GetClients() []*UpdateClientRequest {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+type BatchDeleteClientsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clients       []*DeleteClientRequest `protobuf:"bytes,1,rep,name=clients,proto3" json:"clients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchDeleteClientsRequest) Reset() {
+	*x = BatchDeleteClientsRequest{}
+	mi := &file_protos_client_v1_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchDeleteClientsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchDeleteClientsRequest) ProtoMessage() {}
+
+func (x *BatchDeleteClientsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_client_v1_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchDeleteClientsRequest.ProtoReflect.Descriptor instead.
+func (*BatchDeleteClientsRequest) Descriptor() ([]byte, []int) {
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BatchDeleteClientsRequest) GetClients() []*DeleteClientRequest {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+type BatchClientsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*BatchClientResult   `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchClientsResponse) Reset() {
+	*x = BatchClientsResponse{}
+	mi := &file_protos_client_v1_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchClientsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchClientsResponse) ProtoMessage() {}
+
+func (x *BatchClientsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_client_v1_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchClientsResponse.ProtoReflect.Descriptor instead.
+func (*BatchClientsResponse) Descriptor() ([]byte, []int) {
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BatchClientsResponse) GetResults() []*BatchClientResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
 // Status messages
 type GetClientStatusRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -410,7 +678,7 @@ type GetClientStatusRequest struct {
 
 func (x *GetClientStatusRequest) Reset() {
 	*x = GetClientStatusRequest{}
-	mi := &file_protos_client_v1_proto_msgTypes[8]
+	mi := &file_protos_client_v1_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -422,7 +690,7 @@ func (x *GetClientStatusRequest) String() string {
 func (*GetClientStatusRequest) ProtoMessage() {}
 
 func (x *GetClientStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[8]
+	mi := &file_protos_client_v1_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -435,7 +703,7 @@ func (x *GetClientStatusRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetClientStatusRequest.ProtoReflect.Descriptor instead.
 func (*GetClientStatusRequest) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{8}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *GetClientStatusRequest) GetUuid() string {
@@ -454,7 +722,7 @@ type ClientStatusResponse struct {
 
 func (x *ClientStatusResponse) Reset() {
 	*x = ClientStatusResponse{}
-	mi := &file_protos_client_v1_proto_msgTypes[9]
+	mi := &file_protos_client_v1_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -466,7 +734,7 @@ func (x *ClientStatusResponse) String() string {
 func (*ClientStatusResponse) ProtoMessage() {}
 
 func (x *ClientStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[9]
+	mi := &file_protos_client_v1_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -479,7 +747,7 @@ func (x *ClientStatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientStatusResponse.ProtoReflect.Descriptor instead.
 func (*ClientStatusResponse) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{9}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ClientStatusResponse) GetOnline() bool {
@@ -498,7 +766,7 @@ type ListClientsStatusResponse struct {
 
 func (x *ListClientsStatusResponse) Reset() {
 	*x = ListClientsStatusResponse{}
-	mi := &file_protos_client_v1_proto_msgTypes[10]
+	mi := &file_protos_client_v1_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -510,7 +778,7 @@ func (x *ListClientsStatusResponse) String() string {
 func (*ListClientsStatusResponse) ProtoMessage() {}
 
 func (x *ListClientsStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[10]
+	mi := &file_protos_client_v1_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -523,7 +791,7 @@ func (x *ListClientsStatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListClientsStatusResponse.ProtoReflect.Descriptor instead.
 func (*ListClientsStatusResponse) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{10}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *ListClientsStatusResponse) GetStatuses() []*ClientStatus {
@@ -543,7 +811,7 @@ type GetClientTrafficRequest struct {
 
 func (x *GetClientTrafficRequest) Reset() {
 	*x = GetClientTrafficRequest{}
-	mi := &file_protos_client_v1_proto_msgTypes[11]
+	mi := &file_protos_client_v1_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -555,7 +823,7 @@ func (x *GetClientTrafficRequest) String() string {
 func (*GetClientTrafficRequest) ProtoMessage() {}
 
 func (x *GetClientTrafficRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[11]
+	mi := &file_protos_client_v1_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -568,7 +836,7 @@ func (x *GetClientTrafficRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetClientTrafficRequest.ProtoReflect.Descriptor instead.
 func (*GetClientTrafficRequest) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{11}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *GetClientTrafficRequest) GetUuid() string {
@@ -587,7 +855,7 @@ type ClientTrafficResponse struct {
 
 func (x *ClientTrafficResponse) Reset() {
 	*x = ClientTrafficResponse{}
-	mi := &file_protos_client_v1_proto_msgTypes[12]
+	mi := &file_protos_client_v1_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -599,7 +867,7 @@ func (x *ClientTrafficResponse) String() string {
 func (*ClientTrafficResponse) ProtoMessage() {}
 
 func (x *ClientTrafficResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[12]
+	mi := &file_protos_client_v1_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -612,7 +880,7 @@ func (x *ClientTrafficResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientTrafficResponse.ProtoReflect.Descriptor instead.
 func (*ClientTrafficResponse) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{12}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *ClientTrafficResponse) GetTraffic() *Traffic {
@@ -631,7 +899,7 @@ type ListClientsTrafficResponse struct {
 
 func (x *ListClientsTrafficResponse) Reset() {
 	*x = ListClientsTrafficResponse{}
-	mi := &file_protos_client_v1_proto_msgTypes[13]
+	mi := &file_protos_client_v1_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -643,7 +911,7 @@ func (x *ListClientsTrafficResponse) String() string {
 func (*ListClientsTrafficResponse) ProtoMessage() {}
 
 func (x *ListClientsTrafficResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[13]
+	mi := &file_protos_client_v1_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -656,7 +924,7 @@ func (x *ListClientsTrafficResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListClientsTrafficResponse.ProtoReflect.Descriptor instead.
 func (*ListClientsTrafficResponse) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{13}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *ListClientsTrafficResponse) GetClientTraffics() []*ClientTraffic {
@@ -666,20 +934,21 @@ func (x *ListClientsTrafficResponse) GetClientTraffics() []*ClientTraffic {
 	return nil
 }
 
-// Data models
 type Client struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
 	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
 	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
 	Id            uint64                 `protobuf:"varint,4,opt,name=id,proto3" json:"id,omitempty"`
+	Protocol      Protocol               `protobuf:"varint,5,opt,name=protocol,proto3,enum=client.v1.Protocol" json:"protocol,omitempty"`
+	Password      string                 `protobuf:"bytes,6,opt,name=password,proto3" json:"password,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Client) Reset() {
 	*x = Client{}
-	mi := &file_protos_client_v1_proto_msgTypes[14]
+	mi := &file_protos_client_v1_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -691,7 +960,7 @@ func (x *Client) String() string {
 func (*Client) ProtoMessage() {}
 
 func (x *Client) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[14]
+	mi := &file_protos_client_v1_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -704,7 +973,7 @@ func (x *Client) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Client.ProtoReflect.Descriptor instead.
 func (*Client) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{14}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *Client) GetUuid() string {
@@ -735,6 +1004,88 @@ func (x *Client) GetId() uint64 {
 	return 0
 }
 
+func (x *Client) GetProtocol() Protocol {
+	if x != nil {
+		return x.Protocol
+	}
+	return Protocol_PROTOCOL_VLESS
+}
+
+func (x *Client) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type BatchClientResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Protocol      Protocol               `protobuf:"varint,2,opt,name=protocol,proto3,enum=client.v1.Protocol" json:"protocol,omitempty"`
+	Success       bool                   `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchClientResult) Reset() {
+	*x = BatchClientResult{}
+	mi := &file_protos_client_v1_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchClientResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchClientResult) ProtoMessage() {}
+
+func (x *BatchClientResult) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_client_v1_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchClientResult.ProtoReflect.Descriptor instead.
+func (*BatchClientResult) Descriptor() ([]byte, []int) {
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *BatchClientResult) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *BatchClientResult) GetProtocol() Protocol {
+	if x != nil {
+		return x.Protocol
+	}
+	return Protocol_PROTOCOL_VLESS
+}
+
+func (x *BatchClientResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *BatchClientResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 type Traffic struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Uplink        uint64                 `protobuf:"varint,1,opt,name=uplink,proto3" json:"uplink,omitempty"`
@@ -746,7 +1097,7 @@ type Traffic struct {
 
 func (x *Traffic) Reset() {
 	*x = Traffic{}
-	mi := &file_protos_client_v1_proto_msgTypes[15]
+	mi := &file_protos_client_v1_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -758,7 +1109,7 @@ func (x *Traffic) String() string {
 func (*Traffic) ProtoMessage() {}
 
 func (x *Traffic) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[15]
+	mi := &file_protos_client_v1_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -771,7 +1122,7 @@ func (x *Traffic) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Traffic.ProtoReflect.Descriptor instead.
 func (*Traffic) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{15}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *Traffic) GetUplink() uint64 {
@@ -806,7 +1157,7 @@ type ClientTraffic struct {
 
 func (x *ClientTraffic) Reset() {
 	*x = ClientTraffic{}
-	mi := &file_protos_client_v1_proto_msgTypes[16]
+	mi := &file_protos_client_v1_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -818,7 +1169,7 @@ func (x *ClientTraffic) String() string {
 func (*ClientTraffic) ProtoMessage() {}
 
 func (x *ClientTraffic) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[16]
+	mi := &file_protos_client_v1_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -831,7 +1182,7 @@ func (x *ClientTraffic) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientTraffic.ProtoReflect.Descriptor instead.
 func (*ClientTraffic) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{16}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *ClientTraffic) GetUuid() string {
@@ -866,7 +1217,7 @@ type ClientStatus struct {
 
 func (x *ClientStatus) Reset() {
 	*x = ClientStatus{}
-	mi := &file_protos_client_v1_proto_msgTypes[17]
+	mi := &file_protos_client_v1_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -878,7 +1229,7 @@ func (x *ClientStatus) String() string {
 func (*ClientStatus) ProtoMessage() {}
 
 func (x *ClientStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_client_v1_proto_msgTypes[17]
+	mi := &file_protos_client_v1_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -891,7 +1242,7 @@ func (x *ClientStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientStatus.ProtoReflect.Descriptor instead.
 func (*ClientStatus) Descriptor() ([]byte, []int) {
-	return file_protos_client_v1_proto_rawDescGZIP(), []int{17}
+	return file_protos_client_v1_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *ClientStatus) GetUuid() string {
@@ -919,9 +1270,10 @@ var File_protos_client_v1_proto protoreflect.FileDescriptor
 
 const file_protos_client_v1_proto_rawDesc = "" +
 	"\n" +
-	"\x16protos/client_v1.proto\x12\tclient.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\")\n" +
+	"\x16protos/client_v1.proto\x12\tclient.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\"Z\n" +
 	"\x13ClientExistsRequest\x12\x12\n" +
-	"\x04uuid\x18\x01 \x01(\tR\x04uuid\".\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12/\n" +
+	"\bprotocol\x18\x02 \x01(\x0e2\x13.client.v1.ProtocolR\bprotocol\".\n" +
 	"\x14ClientExistsResponse\x12\x16\n" +
 	"\x06exists\x18\x01 \x01(\bR\x06exists\"&\n" +
 	"\x10GetClientRequest\x12\x12\n" +
@@ -929,18 +1281,30 @@ const file_protos_client_v1_proto_rawDesc = "" +
 	"\x0eClientResponse\x12)\n" +
 	"\x06client\x18\x01 \x01(\v2\x11.client.v1.ClientR\x06client\"B\n" +
 	"\x13ListClientsResponse\x12+\n" +
-	"\aclients\x18\x01 \x03(\v2\x11.client.v1.ClientR\aclients\"z\n" +
+	"\aclients\x18\x01 \x03(\v2\x11.client.v1.ClientR\aclients\"\xc7\x01\n" +
 	"\x13CreateClientRequest\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x14\n" +
 	"\x05email\x18\x02 \x01(\tR\x05email\x129\n" +
 	"\n" +
-	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"d\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12/\n" +
+	"\bprotocol\x18\x04 \x01(\x0e2\x13.client.v1.ProtocolR\bprotocol\x12\x1a\n" +
+	"\bpassword\x18\x05 \x01(\tR\bpassword\"\x95\x01\n" +
 	"\x13UpdateClientRequest\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x129\n" +
 	"\n" +
-	"expires_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\")\n" +
+	"expires_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12/\n" +
+	"\bprotocol\x18\x03 \x01(\x0e2\x13.client.v1.ProtocolR\bprotocol\"Z\n" +
 	"\x13DeleteClientRequest\x12\x12\n" +
-	"\x04uuid\x18\x01 \x01(\tR\x04uuid\",\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12/\n" +
+	"\bprotocol\x18\x02 \x01(\x0e2\x13.client.v1.ProtocolR\bprotocol\"U\n" +
+	"\x19BatchCreateClientsRequest\x128\n" +
+	"\aclients\x18\x01 \x03(\v2\x1e.client.v1.CreateClientRequestR\aclients\"U\n" +
+	"\x19BatchUpdateClientsRequest\x128\n" +
+	"\aclients\x18\x01 \x03(\v2\x1e.client.v1.UpdateClientRequestR\aclients\"U\n" +
+	"\x19BatchDeleteClientsRequest\x128\n" +
+	"\aclients\x18\x01 \x03(\v2\x1e.client.v1.DeleteClientRequestR\aclients\"N\n" +
+	"\x14BatchClientsResponse\x126\n" +
+	"\aresults\x18\x01 \x03(\v2\x1c.client.v1.BatchClientResultR\aresults\",\n" +
 	"\x16GetClientStatusRequest\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\".\n" +
 	"\x14ClientStatusResponse\x12\x16\n" +
@@ -952,13 +1316,20 @@ const file_protos_client_v1_proto_rawDesc = "" +
 	"\x15ClientTrafficResponse\x12,\n" +
 	"\atraffic\x18\x01 \x01(\v2\x12.client.v1.TrafficR\atraffic\"_\n" +
 	"\x1aListClientsTrafficResponse\x12A\n" +
-	"\x0fclient_traffics\x18\x01 \x03(\v2\x18.client.v1.ClientTrafficR\x0eclientTraffics\"}\n" +
+	"\x0fclient_traffics\x18\x01 \x03(\v2\x18.client.v1.ClientTrafficR\x0eclientTraffics\"\xca\x01\n" +
 	"\x06Client\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x14\n" +
 	"\x05email\x18\x02 \x01(\tR\x05email\x129\n" +
 	"\n" +
 	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x0e\n" +
-	"\x02id\x18\x04 \x01(\x04R\x02id\"|\n" +
+	"\x02id\x18\x04 \x01(\x04R\x02id\x12/\n" +
+	"\bprotocol\x18\x05 \x01(\x0e2\x13.client.v1.ProtocolR\bprotocol\x12\x1a\n" +
+	"\bpassword\x18\x06 \x01(\tR\bpassword\"\x88\x01\n" +
+	"\x11BatchClientResult\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12/\n" +
+	"\bprotocol\x18\x02 \x01(\x0e2\x13.client.v1.ProtocolR\bprotocol\x12\x18\n" +
+	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"|\n" +
 	"\aTraffic\x12\x16\n" +
 	"\x06uplink\x18\x01 \x01(\x04R\x06uplink\x12\x1a\n" +
 	"\bdownlink\x18\x02 \x01(\x04R\bdownlink\x12=\n" +
@@ -970,14 +1341,21 @@ const file_protos_client_v1_proto_rawDesc = "" +
 	"\fClientStatus\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\x12\x14\n" +
 	"\x05email\x18\x02 \x01(\tR\x05email\x12\x16\n" +
-	"\x06online\x18\x03 \x01(\bR\x06online2\xa3\x06\n" +
+	"\x06online\x18\x03 \x01(\bR\x06online*M\n" +
+	"\bProtocol\x12\x12\n" +
+	"\x0ePROTOCOL_VLESS\x10\x00\x12\x13\n" +
+	"\x0fPROTOCOL_TROJAN\x10\x01\x12\x18\n" +
+	"\x14PROTOCOL_SHADOWSOCKS\x10\x022\xba\b\n" +
 	"\rClientService\x12O\n" +
 	"\fClientExists\x12\x1e.client.v1.ClientExistsRequest\x1a\x1f.client.v1.ClientExistsResponse\x12C\n" +
 	"\tGetClient\x12\x1b.client.v1.GetClientRequest\x1a\x19.client.v1.ClientResponse\x12E\n" +
 	"\vListClients\x12\x16.google.protobuf.Empty\x1a\x1e.client.v1.ListClientsResponse\x12I\n" +
 	"\fCreateClient\x12\x1e.client.v1.CreateClientRequest\x1a\x19.client.v1.ClientResponse\x12I\n" +
 	"\fUpdateClient\x12\x1e.client.v1.UpdateClientRequest\x1a\x19.client.v1.ClientResponse\x12F\n" +
-	"\fDeleteClient\x12\x1e.client.v1.DeleteClientRequest\x1a\x16.google.protobuf.Empty\x12U\n" +
+	"\fDeleteClient\x12\x1e.client.v1.DeleteClientRequest\x1a\x16.google.protobuf.Empty\x12[\n" +
+	"\x12BatchCreateClients\x12$.client.v1.BatchCreateClientsRequest\x1a\x1f.client.v1.BatchClientsResponse\x12[\n" +
+	"\x12BatchUpdateClients\x12$.client.v1.BatchUpdateClientsRequest\x1a\x1f.client.v1.BatchClientsResponse\x12[\n" +
+	"\x12BatchDeleteClients\x12$.client.v1.BatchDeleteClientsRequest\x1a\x1f.client.v1.BatchClientsResponse\x12U\n" +
 	"\x0fGetClientStatus\x12!.client.v1.GetClientStatusRequest\x1a\x1f.client.v1.ClientStatusResponse\x12Q\n" +
 	"\x11ListClientsStatus\x12\x16.google.protobuf.Empty\x1a$.client.v1.ListClientsStatusResponse\x12X\n" +
 	"\x10GetClientTraffic\x12\".client.v1.GetClientTrafficRequest\x1a .client.v1.ClientTrafficResponse\x12S\n" +
@@ -995,65 +1373,88 @@ func file_protos_client_v1_proto_rawDescGZIP() []byte {
 	return file_protos_client_v1_proto_rawDescData
 }
 
-var file_protos_client_v1_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_protos_client_v1_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_protos_client_v1_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
 var file_protos_client_v1_proto_goTypes = []any{
-	(*ClientExistsRequest)(nil),        // 0: client.v1.ClientExistsRequest
-	(*ClientExistsResponse)(nil),       // 1: client.v1.ClientExistsResponse
-	(*GetClientRequest)(nil),           // 2: client.v1.GetClientRequest
-	(*ClientResponse)(nil),             // 3: client.v1.ClientResponse
-	(*ListClientsResponse)(nil),        // 4: client.v1.ListClientsResponse
-	(*CreateClientRequest)(nil),        // 5: client.v1.CreateClientRequest
-	(*UpdateClientRequest)(nil),        // 6: client.v1.UpdateClientRequest
-	(*DeleteClientRequest)(nil),        // 7: client.v1.DeleteClientRequest
-	(*GetClientStatusRequest)(nil),     // 8: client.v1.GetClientStatusRequest
-	(*ClientStatusResponse)(nil),       // 9: client.v1.ClientStatusResponse
-	(*ListClientsStatusResponse)(nil),  // 10: client.v1.ListClientsStatusResponse
-	(*GetClientTrafficRequest)(nil),    // 11: client.v1.GetClientTrafficRequest
-	(*ClientTrafficResponse)(nil),      // 12: client.v1.ClientTrafficResponse
-	(*ListClientsTrafficResponse)(nil), // 13: client.v1.ListClientsTrafficResponse
-	(*Client)(nil),                     // 14: client.v1.Client
-	(*Traffic)(nil),                    // 15: client.v1.Traffic
-	(*ClientTraffic)(nil),              // 16: client.v1.ClientTraffic
-	(*ClientStatus)(nil),               // 17: client.v1.ClientStatus
-	(*timestamppb.Timestamp)(nil),      // 18: google.protobuf.Timestamp
-	(*emptypb.Empty)(nil),              // 19: google.protobuf.Empty
+	(Protocol)(0),                      // 0: client.v1.Protocol
+	(*ClientExistsRequest)(nil),        // 1: client.v1.ClientExistsRequest
+	(*ClientExistsResponse)(nil),       // 2: client.v1.ClientExistsResponse
+	(*GetClientRequest)(nil),           // 3: client.v1.GetClientRequest
+	(*ClientResponse)(nil),             // 4: client.v1.ClientResponse
+	(*ListClientsResponse)(nil),        // 5: client.v1.ListClientsResponse
+	(*CreateClientRequest)(nil),        // 6: client.v1.CreateClientRequest
+	(*UpdateClientRequest)(nil),        // 7: client.v1.UpdateClientRequest
+	(*DeleteClientRequest)(nil),        // 8: client.v1.DeleteClientRequest
+	(*BatchCreateClientsRequest)(nil),  // 9: client.v1.BatchCreateClientsRequest
+	(*BatchUpdateClientsRequest)(nil),  // 10: client.v1.BatchUpdateClientsRequest
+	(*BatchDeleteClientsRequest)(nil),  // 11: client.v1.BatchDeleteClientsRequest
+	(*BatchClientsResponse)(nil),       // 12: client.v1.BatchClientsResponse
+	(*GetClientStatusRequest)(nil),     // 13: client.v1.GetClientStatusRequest
+	(*ClientStatusResponse)(nil),       // 14: client.v1.ClientStatusResponse
+	(*ListClientsStatusResponse)(nil),  // 15: client.v1.ListClientsStatusResponse
+	(*GetClientTrafficRequest)(nil),    // 16: client.v1.GetClientTrafficRequest
+	(*ClientTrafficResponse)(nil),      // 17: client.v1.ClientTrafficResponse
+	(*ListClientsTrafficResponse)(nil), // 18: client.v1.ListClientsTrafficResponse
+	(*Client)(nil),                     // 19: client.v1.Client
+	(*BatchClientResult)(nil),          // 20: client.v1.BatchClientResult
+	(*Traffic)(nil),                    // 21: client.v1.Traffic
+	(*ClientTraffic)(nil),              // 22: client.v1.ClientTraffic
+	(*ClientStatus)(nil),               // 23: client.v1.ClientStatus
+	(*timestamppb.Timestamp)(nil),      // 24: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),              // 25: google.protobuf.Empty
 }
 var file_protos_client_v1_proto_depIdxs = []int32{
-	14, // 0: client.v1.ClientResponse.client:type_name -> client.v1.Client
-	14, // 1: client.v1.ListClientsResponse.clients:type_name -> client.v1.Client
-	18, // 2: client.v1.CreateClientRequest.expires_at:type_name -> google.protobuf.Timestamp
-	18, // 3: client.v1.UpdateClientRequest.expires_at:type_name -> google.protobuf.Timestamp
-	17, // 4: client.v1.ListClientsStatusResponse.statuses:type_name -> client.v1.ClientStatus
-	15, // 5: client.v1.ClientTrafficResponse.traffic:type_name -> client.v1.Traffic
-	16, // 6: client.v1.ListClientsTrafficResponse.client_traffics:type_name -> client.v1.ClientTraffic
-	18, // 7: client.v1.Client.expires_at:type_name -> google.protobuf.Timestamp
-	18, // 8: client.v1.Traffic.last_updated:type_name -> google.protobuf.Timestamp
-	15, // 9: client.v1.ClientTraffic.traffic:type_name -> client.v1.Traffic
-	0,  // 10: client.v1.ClientService.ClientExists:input_type -> client.v1.ClientExistsRequest
-	2,  // 11: client.v1.ClientService.GetClient:input_type -> client.v1.GetClientRequest
-	19, // 12: client.v1.ClientService.ListClients:input_type -> google.protobuf.Empty
-	5,  // 13: client.v1.ClientService.CreateClient:input_type -> client.v1.CreateClientRequest
-	6,  // 14: client.v1.ClientService.UpdateClient:input_type -> client.v1.UpdateClientRequest
-	7,  // 15: client.v1.ClientService.DeleteClient:input_type -> client.v1.DeleteClientRequest
-	8,  // 16: client.v1.ClientService.GetClientStatus:input_type -> client.v1.GetClientStatusRequest
-	19, // 17: client.v1.ClientService.ListClientsStatus:input_type -> google.protobuf.Empty
-	11, // 18: client.v1.ClientService.GetClientTraffic:input_type -> client.v1.GetClientTrafficRequest
-	19, // 19: client.v1.ClientService.ListClientsTraffic:input_type -> google.protobuf.Empty
-	1,  // 20: client.v1.ClientService.ClientExists:output_type -> client.v1.ClientExistsResponse
-	3,  // 21: client.v1.ClientService.GetClient:output_type -> client.v1.ClientResponse
-	4,  // 22: client.v1.ClientService.ListClients:output_type -> client.v1.ListClientsResponse
-	3,  // 23: client.v1.ClientService.CreateClient:output_type -> client.v1.ClientResponse
-	3,  // 24: client.v1.ClientService.UpdateClient:output_type -> client.v1.ClientResponse
-	19, // 25: client.v1.ClientService.DeleteClient:output_type -> google.protobuf.Empty
-	9,  // 26: client.v1.ClientService.GetClientStatus:output_type -> client.v1.ClientStatusResponse
-	10, // 27: client.v1.ClientService.ListClientsStatus:output_type -> client.v1.ListClientsStatusResponse
-	12, // 28: client.v1.ClientService.GetClientTraffic:output_type -> client.v1.ClientTrafficResponse
-	13, // 29: client.v1.ClientService.ListClientsTraffic:output_type -> client.v1.ListClientsTrafficResponse
-	20, // [20:30] is the sub-list for method output_type
-	10, // [10:20] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	0,  // 0: client.v1.ClientExistsRequest.protocol:type_name -> client.v1.Protocol
+	19, // 1: client.v1.ClientResponse.client:type_name -> client.v1.Client
+	19, // 2: client.v1.ListClientsResponse.clients:type_name -> client.v1.Client
+	24, // 3: client.v1.CreateClientRequest.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 4: client.v1.CreateClientRequest.protocol:type_name -> client.v1.Protocol
+	24, // 5: client.v1.UpdateClientRequest.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 6: client.v1.UpdateClientRequest.protocol:type_name -> client.v1.Protocol
+	0,  // 7: client.v1.DeleteClientRequest.protocol:type_name -> client.v1.Protocol
+	6,  // 8: client.v1.BatchCreateClientsRequest.clients:type_name -> client.v1.CreateClientRequest
+	7,  // 9: client.v1.BatchUpdateClientsRequest.clients:type_name -> client.v1.UpdateClientRequest
+	8,  // 10: client.v1.BatchDeleteClientsRequest.clients:type_name -> client.v1.DeleteClientRequest
+	20, // 11: client.v1.BatchClientsResponse.results:type_name -> client.v1.BatchClientResult
+	23, // 12: client.v1.ListClientsStatusResponse.statuses:type_name -> client.v1.ClientStatus
+	21, // 13: client.v1.ClientTrafficResponse.traffic:type_name -> client.v1.Traffic
+	22, // 14: client.v1.ListClientsTrafficResponse.client_traffics:type_name -> client.v1.ClientTraffic
+	24, // 15: client.v1.Client.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 16: client.v1.Client.protocol:type_name -> client.v1.Protocol
+	0,  // 17: client.v1.BatchClientResult.protocol:type_name -> client.v1.Protocol
+	24, // 18: client.v1.Traffic.last_updated:type_name -> google.protobuf.Timestamp
+	21, // 19: client.v1.ClientTraffic.traffic:type_name -> client.v1.Traffic
+	1,  // 20: client.v1.ClientService.ClientExists:input_type -> client.v1.ClientExistsRequest
+	3,  // 21: client.v1.ClientService.GetClient:input_type -> client.v1.GetClientRequest
+	25, // 22: client.v1.ClientService.ListClients:input_type -> google.protobuf.Empty
+	6,  // 23: client.v1.ClientService.CreateClient:input_type -> client.v1.CreateClientRequest
+	7,  // 24: client.v1.ClientService.UpdateClient:input_type -> client.v1.UpdateClientRequest
+	8,  // 25: client.v1.ClientService.DeleteClient:input_type -> client.v1.DeleteClientRequest
+	9,  // 26: client.v1.ClientService.BatchCreateClients:input_type -> client.v1.BatchCreateClientsRequest
+	10, // 27: client.v1.ClientService.BatchUpdateClients:input_type -> client.v1.BatchUpdateClientsRequest
+	11, // 28: client.v1.ClientService.BatchDeleteClients:input_type -> client.v1.BatchDeleteClientsRequest
+	13, // 29: client.v1.ClientService.GetClientStatus:input_type -> client.v1.GetClientStatusRequest
+	25, // 30: client.v1.ClientService.ListClientsStatus:input_type -> google.protobuf.Empty
+	16, // 31: client.v1.ClientService.GetClientTraffic:input_type -> client.v1.GetClientTrafficRequest
+	25, // 32: client.v1.ClientService.ListClientsTraffic:input_type -> google.protobuf.Empty
+	2,  // 33: client.v1.ClientService.ClientExists:output_type -> client.v1.ClientExistsResponse
+	4,  // 34: client.v1.ClientService.GetClient:output_type -> client.v1.ClientResponse
+	5,  // 35: client.v1.ClientService.ListClients:output_type -> client.v1.ListClientsResponse
+	4,  // 36: client.v1.ClientService.CreateClient:output_type -> client.v1.ClientResponse
+	4,  // 37: client.v1.ClientService.UpdateClient:output_type -> client.v1.ClientResponse
+	25, // 38: client.v1.ClientService.DeleteClient:output_type -> google.protobuf.Empty
+	12, // 39: client.v1.ClientService.BatchCreateClients:output_type -> client.v1.BatchClientsResponse
+	12, // 40: client.v1.ClientService.BatchUpdateClients:output_type -> client.v1.BatchClientsResponse
+	12, // 41: client.v1.ClientService.BatchDeleteClients:output_type -> client.v1.BatchClientsResponse
+	14, // 42: client.v1.ClientService.GetClientStatus:output_type -> client.v1.ClientStatusResponse
+	15, // 43: client.v1.ClientService.ListClientsStatus:output_type -> client.v1.ListClientsStatusResponse
+	17, // 44: client.v1.ClientService.GetClientTraffic:output_type -> client.v1.ClientTrafficResponse
+	18, // 45: client.v1.ClientService.ListClientsTraffic:output_type -> client.v1.ListClientsTrafficResponse
+	33, // [33:46] is the sub-list for method output_type
+	20, // [20:33] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_protos_client_v1_proto_init() }
@@ -1066,13 +1467,14 @@ func file_protos_client_v1_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_client_v1_proto_rawDesc), len(file_protos_client_v1_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   18,
+			NumEnums:      1,
+			NumMessages:   23,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_protos_client_v1_proto_goTypes,
 		DependencyIndexes: file_protos_client_v1_proto_depIdxs,
+		EnumInfos:         file_protos_client_v1_proto_enumTypes,
 		MessageInfos:      file_protos_client_v1_proto_msgTypes,
 	}.Build()
 	File_protos_client_v1_proto = out.File
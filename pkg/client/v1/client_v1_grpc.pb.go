@@ -26,6 +26,9 @@ const (
 	ClientService_CreateClient_FullMethodName       = "/client.v1.ClientService/CreateClient"
 	ClientService_UpdateClient_FullMethodName       = "/client.v1.ClientService/UpdateClient"
 	ClientService_DeleteClient_FullMethodName       = "/client.v1.ClientService/DeleteClient"
+	ClientService_BatchCreateClients_FullMethodName = "/client.v1.ClientService/BatchCreateClients"
+	ClientService_BatchUpdateClients_FullMethodName = "/client.v1.ClientService/BatchUpdateClients"
+	ClientService_BatchDeleteClients_FullMethodName = "/client.v1.ClientService/BatchDeleteClients"
 	ClientService_GetClientStatus_FullMethodName    = "/client.v1.ClientService/GetClientStatus"
 	ClientService_ListClientsStatus_FullMethodName  = "/client.v1.ClientService/ListClientsStatus"
 	ClientService_GetClientTraffic_FullMethodName   = "/client.v1.ClientService/GetClientTraffic"
@@ -42,6 +45,9 @@ type ClientServiceClient interface {
 	CreateClient(ctx context.Context, in *CreateClientRequest, opts ...grpc.CallOption) (*ClientResponse, error)
 	UpdateClient(ctx context.Context, in *UpdateClientRequest, opts ...grpc.CallOption) (*ClientResponse, error)
 	DeleteClient(ctx context.Context, in *DeleteClientRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	BatchCreateClients(ctx context.Context, in *BatchCreateClientsRequest, opts ...grpc.CallOption) (*BatchClientsResponse, error)
+	BatchUpdateClients(ctx context.Context, in *BatchUpdateClientsRequest, opts ...grpc.CallOption) (*BatchClientsResponse, error)
+	BatchDeleteClients(ctx context.Context, in *BatchDeleteClientsRequest, opts ...grpc.CallOption) (*BatchClientsResponse, error)
 	GetClientStatus(ctx context.Context, in *GetClientStatusRequest, opts ...grpc.CallOption) (*ClientStatusResponse, error)
 	ListClientsStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListClientsStatusResponse, error)
 	GetClientTraffic(ctx context.Context, in *GetClientTrafficRequest, opts ...grpc.CallOption) (*ClientTrafficResponse, error)
@@ -116,6 +122,36 @@ func (c *clientServiceClient) DeleteClient(ctx context.Context, in *DeleteClient
 	return out, nil
 }
 
+func (c *clientServiceClient) BatchCreateClients(ctx context.Context, in *BatchCreateClientsRequest, opts ...grpc.CallOption) (*BatchClientsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchClientsResponse)
+	err := c.cc.Invoke(ctx, ClientService_BatchCreateClients_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clientServiceClient) BatchUpdateClients(ctx context.Context, in *BatchUpdateClientsRequest, opts ...grpc.CallOption) (*BatchClientsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchClientsResponse)
+	err := c.cc.Invoke(ctx, ClientService_BatchUpdateClients_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clientServiceClient) BatchDeleteClients(ctx context.Context, in *BatchDeleteClientsRequest, opts ...grpc.CallOption) (*BatchClientsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchClientsResponse)
+	err := c.cc.Invoke(ctx, ClientService_BatchDeleteClients_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *clientServiceClient) GetClientStatus(ctx context.Context, in *GetClientStatusRequest, opts ...grpc.CallOption) (*ClientStatusResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ClientStatusResponse)
@@ -166,6 +202,9 @@ type ClientServiceServer interface {
 	CreateClient(context.Context, *CreateClientRequest) (*ClientResponse, error)
 	UpdateClient(context.Context, *UpdateClientRequest) (*ClientResponse, error)
 	DeleteClient(context.Context, *DeleteClientRequest) (*emptypb.Empty, error)
+	BatchCreateClients(context.Context, *BatchCreateClientsRequest) (*BatchClientsResponse, error)
+	BatchUpdateClients(context.Context, *BatchUpdateClientsRequest) (*BatchClientsResponse, error)
+	BatchDeleteClients(context.Context, *BatchDeleteClientsRequest) (*BatchClientsResponse, error)
 	GetClientStatus(context.Context, *GetClientStatusRequest) (*ClientStatusResponse, error)
 	ListClientsStatus(context.Context, *emptypb.Empty) (*ListClientsStatusResponse, error)
 	GetClientTraffic(context.Context, *GetClientTrafficRequest) (*ClientTrafficResponse, error)
@@ -198,6 +237,15 @@ func (UnimplementedClientServiceServer) UpdateClient(context.Context, *UpdateCli
 func (UnimplementedClientServiceServer) DeleteClient(context.Context, *DeleteClientRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteClient not implemented")
 }
+func (UnimplementedClientServiceServer) BatchCreateClients(context.Context, *BatchCreateClientsRequest) (*BatchClientsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCreateClients not implemented")
+}
+func (UnimplementedClientServiceServer) BatchUpdateClients(context.Context, *BatchUpdateClientsRequest) (*BatchClientsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchUpdateClients not implemented")
+}
+func (UnimplementedClientServiceServer) BatchDeleteClients(context.Context, *BatchDeleteClientsRequest) (*BatchClientsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchDeleteClients not implemented")
+}
 func (UnimplementedClientServiceServer) GetClientStatus(context.Context, *GetClientStatusRequest) (*ClientStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetClientStatus not implemented")
 }
@@ -339,6 +387,60 @@ func _ClientService_DeleteClient_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ClientService_BatchCreateClients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCreateClientsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClientServiceServer).BatchCreateClients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClientService_BatchCreateClients_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClientServiceServer).BatchCreateClients(ctx, req.(*BatchCreateClientsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClientService_BatchUpdateClients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchUpdateClientsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClientServiceServer).BatchUpdateClients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClientService_BatchUpdateClients_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClientServiceServer).BatchUpdateClients(ctx, req.(*BatchUpdateClientsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClientService_BatchDeleteClients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchDeleteClientsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClientServiceServer).BatchDeleteClients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClientService_BatchDeleteClients_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClientServiceServer).BatchDeleteClients(ctx, req.(*BatchDeleteClientsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ClientService_GetClientStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetClientStatusRequest)
 	if err := dec(in); err != nil {
@@ -442,6 +544,18 @@ var ClientService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteClient",
 			Handler:    _ClientService_DeleteClient_Handler,
 		},
+		{
+			MethodName: "BatchCreateClients",
+			Handler:    _ClientService_BatchCreateClients_Handler,
+		},
+		{
+			MethodName: "BatchUpdateClients",
+			Handler:    _ClientService_BatchUpdateClients_Handler,
+		},
+		{
+			MethodName: "BatchDeleteClients",
+			Handler:    _ClientService_BatchDeleteClients_Handler,
+		},
 		{
 			MethodName: "GetClientStatus",
 			Handler:    _ClientService_GetClientStatus_Handler,
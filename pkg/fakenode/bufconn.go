@@ -0,0 +1,57 @@
+package fakenode
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"sync"
+)
+
+const bufSize = 1 << 20
+
+type Network struct {
+	mu        sync.RWMutex
+	listeners map[string]*bufconn.Listener
+	servers   []*grpc.Server
+}
+
+func NewNetwork() *Network {
+	return &Network{listeners: make(map[string]*bufconn.Listener)}
+}
+
+func (nw *Network) Serve(address string, node *Node, opts ...grpc.ServerOption) {
+	lis := bufconn.Listen(bufSize)
+	s := node.NewServer(opts...)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	nw.listeners[address] = lis
+	nw.servers = append(nw.servers, s)
+}
+
+func (nw *Network) Dial(ctx context.Context, address string) (net.Conn, error) {
+	nw.mu.RLock()
+	lis, ok := nw.listeners[address]
+	nw.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no fake node at %s", address)
+	}
+	return lis.DialContext(ctx)
+}
+
+func (nw *Network) Stop() {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	for _, s := range nw.servers {
+		s.Stop()
+	}
+	nw.servers = nil
+	nw.listeners = make(map[string]*bufconn.Listener)
+}
@@ -0,0 +1,199 @@
+package fakenode
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"sort"
+
+	pbClient "nsvpn/pkg/client/v1"
+)
+
+type clientService struct {
+	pbClient.UnimplementedClientServiceServer
+	node *Node
+}
+
+func (s *clientService) ClientExists(_ context.Context, req *pbClient.ClientExistsRequest) (*pbClient.ClientExistsResponse, error) {
+	_, exists := s.node.Client(req.GetProtocol(), req.GetUuid())
+	return &pbClient.ClientExistsResponse{Exists: exists}, nil
+}
+
+func (s *clientService) GetClient(_ context.Context, req *pbClient.GetClientRequest) (*pbClient.ClientResponse, error) {
+	client, err := s.findByUUID(req.GetUuid())
+	if err != nil {
+		return nil, err
+	}
+	return &pbClient.ClientResponse{Client: client}, nil
+}
+
+func (s *clientService) ListClients(_ context.Context, _ *emptypb.Empty) (*pbClient.ListClientsResponse, error) {
+	clients := s.node.Clients()
+	sort.Slice(clients, func(i, j int) bool { return clients[i].GetId() < clients[j].GetId() })
+	return &pbClient.ListClientsResponse{Clients: clients}, nil
+}
+
+func (s *clientService) CreateClient(_ context.Context, req *pbClient.CreateClientRequest) (*pbClient.ClientResponse, error) {
+	client, err := s.create(req)
+	if err != nil {
+		return nil, err
+	}
+	return &pbClient.ClientResponse{Client: client}, nil
+}
+
+func (s *clientService) UpdateClient(_ context.Context, req *pbClient.UpdateClientRequest) (*pbClient.ClientResponse, error) {
+	client, err := s.update(req)
+	if err != nil {
+		return nil, err
+	}
+	return &pbClient.ClientResponse{Client: client}, nil
+}
+
+func (s *clientService) DeleteClient(_ context.Context, req *pbClient.DeleteClientRequest) (*emptypb.Empty, error) {
+	if err := s.delete(req); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *clientService) BatchCreateClients(_ context.Context, req *pbClient.BatchCreateClientsRequest) (*pbClient.BatchClientsResponse, error) {
+	resp := &pbClient.BatchClientsResponse{}
+	for _, client := range req.GetClients() {
+		_, err := s.create(client)
+		resp.Results = append(resp.Results, result(client.GetUuid(), client.GetProtocol(), err))
+	}
+	return resp, nil
+}
+
+func (s *clientService) BatchUpdateClients(_ context.Context, req *pbClient.BatchUpdateClientsRequest) (*pbClient.BatchClientsResponse, error) {
+	resp := &pbClient.BatchClientsResponse{}
+	for _, client := range req.GetClients() {
+		_, err := s.update(client)
+		resp.Results = append(resp.Results, result(client.GetUuid(), client.GetProtocol(), err))
+	}
+	return resp, nil
+}
+
+func (s *clientService) BatchDeleteClients(_ context.Context, req *pbClient.BatchDeleteClientsRequest) (*pbClient.BatchClientsResponse, error) {
+	resp := &pbClient.BatchClientsResponse{}
+	for _, client := range req.GetClients() {
+		err := s.delete(client)
+		resp.Results = append(resp.Results, result(client.GetUuid(), client.GetProtocol(), err))
+	}
+	return resp, nil
+}
+
+func (s *clientService) GetClientStatus(_ context.Context, req *pbClient.GetClientStatusRequest) (*pbClient.ClientStatusResponse, error) {
+	if _, err := s.findByUUID(req.GetUuid()); err != nil {
+		return nil, err
+	}
+	return &pbClient.ClientStatusResponse{Online: false}, nil
+}
+
+func (s *clientService) ListClientsStatus(_ context.Context, _ *emptypb.Empty) (*pbClient.ListClientsStatusResponse, error) {
+	resp := &pbClient.ListClientsStatusResponse{}
+	for _, client := range s.node.Clients() {
+		resp.Statuses = append(resp.Statuses, &pbClient.ClientStatus{Uuid: client.GetUuid(), Email: client.GetEmail()})
+	}
+	return resp, nil
+}
+
+func (s *clientService) GetClientTraffic(_ context.Context, req *pbClient.GetClientTrafficRequest) (*pbClient.ClientTrafficResponse, error) {
+	client, err := s.findByUUID(req.GetUuid())
+	if err != nil {
+		return nil, err
+	}
+
+	traffic := s.node.Traffic(client.GetProtocol(), client.GetUuid())
+	traffic.LastUpdated = timestamppb.Now()
+	return &pbClient.ClientTrafficResponse{Traffic: traffic}, nil
+}
+
+func (s *clientService) ListClientsTraffic(_ context.Context, _ *emptypb.Empty) (*pbClient.ListClientsTrafficResponse, error) {
+	resp := &pbClient.ListClientsTrafficResponse{}
+	for _, client := range s.node.Clients() {
+		traffic := s.node.Traffic(client.GetProtocol(), client.GetUuid())
+		traffic.LastUpdated = timestamppb.Now()
+		resp.ClientTraffics = append(resp.ClientTraffics, &pbClient.ClientTraffic{
+			Uuid:    client.GetUuid(),
+			Email:   client.GetEmail(),
+			Traffic: traffic,
+		})
+	}
+	return resp, nil
+}
+
+func (s *clientService) findByUUID(uuid string) (*pbClient.Client, error) {
+	for _, client := range s.node.Clients() {
+		if client.GetUuid() == uuid {
+			return client, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "client not found")
+}
+
+func (s *clientService) create(req *pbClient.CreateClientRequest) (*pbClient.Client, error) {
+	if req.GetUuid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "uuid is required")
+	}
+
+	n := s.node
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := clientKey(req.GetProtocol(), req.GetUuid())
+	if _, exists := n.clients[key]; exists {
+		return nil, status.Error(codes.AlreadyExists, "client already exists")
+	}
+
+	n.nextID++
+	client := &pbClient.Client{
+		Id:        n.nextID,
+		Uuid:      req.GetUuid(),
+		Email:     req.GetEmail(),
+		ExpiresAt: req.GetExpiresAt(),
+		Protocol:  req.GetProtocol(),
+		Password:  req.GetPassword(),
+	}
+	n.clients[key] = client
+	return proto.Clone(client).(*pbClient.Client), nil
+}
+
+func (s *clientService) update(req *pbClient.UpdateClientRequest) (*pbClient.Client, error) {
+	n := s.node
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	client, exists := n.clients[clientKey(req.GetProtocol(), req.GetUuid())]
+	if !exists {
+		return nil, status.Error(codes.NotFound, "client not found")
+	}
+
+	client.ExpiresAt = req.GetExpiresAt()
+	return proto.Clone(client).(*pbClient.Client), nil
+}
+
+func (s *clientService) delete(req *pbClient.DeleteClientRequest) error {
+	n := s.node
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := clientKey(req.GetProtocol(), req.GetUuid())
+	if _, exists := n.clients[key]; !exists {
+		return status.Error(codes.NotFound, "client not found")
+	}
+
+	delete(n.clients, key)
+	return nil
+}
+
+func result(uuid string, protocol pbClient.Protocol, err error) *pbClient.BatchClientResult {
+	res := &pbClient.BatchClientResult{Uuid: uuid, Protocol: protocol, Success: err == nil}
+	if err != nil {
+		res.Error = status.Convert(err).Message()
+	}
+	return res
+}
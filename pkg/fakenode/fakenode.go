@@ -0,0 +1,293 @@
+package fakenode
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	pbClient "nsvpn/pkg/client/v1"
+	"nsvpn/pkg/nodeauth"
+	pbServer "nsvpn/pkg/server/v1"
+)
+
+const eventBuffer = 64
+
+type Config struct {
+	Latency     time.Duration // задержка перед каждым ответом
+	FailureRate float64       // доля запросов, завершающихся ошибкой Unavailable
+	Load        float64       // значение, возвращаемое GetLoad
+	Health      string        // значение, возвращаемое GetHealth
+}
+
+type Node struct {
+	mu       sync.RWMutex
+	cfg      Config
+	failNext int
+	nextID   uint64
+	clients  map[string]*pbClient.Client
+	traffic  map[string]*pbClient.Traffic
+	calls    map[string]int
+	reality  *pbServer.UpdateRealityConfigRequest
+	keyring  *nodeauth.Keyring
+	legacy   string
+	watchers map[chan *pbServer.Event]struct{}
+}
+
+func New(cfg Config) *Node {
+	if cfg.Health == "" {
+		cfg.Health = "ok"
+	}
+
+	return &Node{
+		cfg:      cfg,
+		clients:  make(map[string]*pbClient.Client),
+		traffic:  make(map[string]*pbClient.Traffic),
+		calls:    make(map[string]int),
+		watchers: make(map[chan *pbServer.Event]struct{}),
+	}
+}
+
+func (n *Node) EnableAuth(keyID, secret string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.keyring == nil {
+		n.keyring = nodeauth.NewKeyring()
+	}
+	n.keyring.Set(keyID, secret)
+}
+
+func (n *Node) EnableLegacyAuth(legacyKey string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.keyring == nil {
+		n.keyring = nodeauth.NewKeyring()
+	}
+	n.legacy = legacyKey
+}
+
+func (n *Node) Keyring() *nodeauth.Keyring {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.keyring
+}
+
+func (n *Node) SetLoad(load float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.cfg.Load = load
+}
+
+func (n *Node) SetHealth(health string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.cfg.Health = health
+}
+
+func (n *Node) SetLatency(latency time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.cfg.Latency = latency
+}
+
+func (n *Node) SetFailureRate(rate float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.cfg.FailureRate = rate
+}
+
+func (n *Node) FailNext(count int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.failNext = count
+}
+
+func (n *Node) Calls(method string) int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.calls[method]
+}
+
+func (n *Node) Clients() []*pbClient.Client {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	clients := make([]*pbClient.Client, 0, len(n.clients))
+	for _, client := range n.clients {
+		clients = append(clients, proto.Clone(client).(*pbClient.Client))
+	}
+	return clients
+}
+
+func (n *Node) Client(protocol pbClient.Protocol, uuid string) (*pbClient.Client, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	client, ok := n.clients[clientKey(protocol, uuid)]
+	if !ok {
+		return nil, false
+	}
+	return proto.Clone(client).(*pbClient.Client), true
+}
+
+func (n *Node) AddClient(client *pbClient.Client) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.nextID++
+	client = proto.Clone(client).(*pbClient.Client)
+	client.Id = n.nextID
+	n.clients[clientKey(client.GetProtocol(), client.GetUuid())] = client
+}
+
+func (n *Node) SetTraffic(protocol pbClient.Protocol, uuid string, uplink, downlink uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.traffic[clientKey(protocol, uuid)] = &pbClient.Traffic{Uplink: uplink, Downlink: downlink}
+}
+
+func (n *Node) Traffic(protocol pbClient.Protocol, uuid string) *pbClient.Traffic {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	traffic, ok := n.traffic[clientKey(protocol, uuid)]
+	if !ok {
+		return &pbClient.Traffic{}
+	}
+	return proto.Clone(traffic).(*pbClient.Traffic)
+}
+
+func (n *Node) Reality() *pbServer.UpdateRealityConfigRequest {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.reality == nil {
+		return nil
+	}
+	return proto.Clone(n.reality).(*pbServer.UpdateRealityConfigRequest)
+}
+
+func (n *Node) Emit(event *pbServer.Event) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	n.emitLocked(event)
+}
+
+func (n *Node) DropWatchers() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// открытые потоки событий обрываются, как при перезапуске ноды
+	for ch := range n.watchers {
+		delete(n.watchers, ch)
+		close(ch)
+	}
+}
+
+func (n *Node) emitLocked(event *pbServer.Event) {
+	for ch := range n.watchers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (n *Node) Register(s *grpc.Server) {
+	pbClient.RegisterClientServiceServer(s, &clientService{node: n})
+	pbServer.RegisterServerServiceServer(s, &serverService{node: n})
+}
+
+func (n *Node) NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{n.unaryInterceptor}
+	stream := []grpc.StreamServerInterceptor{n.streamInterceptor}
+	if keyring := n.Keyring(); keyring != nil {
+		verifier := nodeauth.NewVerifier(keyring, 0)
+		n.mu.RLock()
+		if n.legacy != "" {
+			verifier.AllowLegacy(n.legacy)
+		}
+		n.mu.RUnlock()
+		unary = append([]grpc.UnaryServerInterceptor{verifier.UnaryServerInterceptor()}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{verifier.StreamServerInterceptor()}, stream...)
+	}
+
+	opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	s := grpc.NewServer(opts...)
+	n.Register(s)
+	return s
+}
+
+func (n *Node) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := n.simulate(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (n *Node) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := n.simulate(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (n *Node) simulate(ctx context.Context, method string) error {
+	n.mu.Lock()
+	n.calls[method]++
+	latency := n.cfg.Latency
+	fail := n.failNext > 0 || (n.cfg.FailureRate > 0 && rand.Float64() < n.cfg.FailureRate)
+	if n.failNext > 0 {
+		n.failNext--
+	}
+	n.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+
+	if fail {
+		return status.Error(codes.Unavailable, "fake node failure")
+	}
+	return nil
+}
+
+func (n *Node) subscribe() (chan *pbServer.Event, func()) {
+	ch := make(chan *pbServer.Event, eventBuffer)
+
+	n.mu.Lock()
+	n.watchers[ch] = struct{}{}
+	n.mu.Unlock()
+
+	return ch, func() {
+		n.mu.Lock()
+		delete(n.watchers, ch)
+		n.mu.Unlock()
+	}
+}
+
+func clientKey(protocol pbClient.Protocol, uuid string) string {
+	return protocol.String() + ":" + uuid
+}
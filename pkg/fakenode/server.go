@@ -0,0 +1,75 @@
+package fakenode
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"nsvpn/pkg/nodeauth"
+	pbServer "nsvpn/pkg/server/v1"
+)
+
+type serverService struct {
+	pbServer.UnimplementedServerServiceServer
+	node *Node
+}
+
+func (s *serverService) GetLoad(_ context.Context, _ *pbServer.ServerRequest) (*pbServer.LoadResponse, error) {
+	s.node.mu.RLock()
+	defer s.node.mu.RUnlock()
+
+	return &pbServer.LoadResponse{LoadScore: s.node.cfg.Load}, nil
+}
+
+func (s *serverService) GetHealth(_ context.Context, _ *pbServer.ServerRequest) (*pbServer.HealthResponse, error) {
+	s.node.mu.RLock()
+	defer s.node.mu.RUnlock()
+
+	return &pbServer.HealthResponse{Status: s.node.cfg.Health}, nil
+}
+
+func (s *serverService) RotateAuthKey(ctx context.Context, req *pbServer.RotateAuthKeyRequest) (*pbServer.RotateAuthKeyResponse, error) {
+	s.node.mu.Lock()
+	if s.node.keyring == nil {
+		s.node.keyring = nodeauth.NewKeyring()
+	}
+	keyring := s.node.keyring
+	s.node.mu.Unlock()
+
+	keyring.Rotate(nodeauth.KeyIDFromContext(ctx), req.GetKeyId(), req.GetSecret(), req.GetGracePeriod().AsDuration())
+	return &pbServer.RotateAuthKeyResponse{}, nil
+}
+
+func (s *serverService) UpdateRealityConfig(_ context.Context, req *pbServer.UpdateRealityConfigRequest) (*pbServer.UpdateRealityConfigResponse, error) {
+	s.node.mu.Lock()
+	defer s.node.mu.Unlock()
+
+	s.node.reality = proto.Clone(req).(*pbServer.UpdateRealityConfigRequest)
+	s.node.emitLocked(&pbServer.Event{Type: pbServer.EventType_EVENT_TYPE_CONFIG_RELOADED, Message: "reality config updated"})
+	return &pbServer.UpdateRealityConfigResponse{}, nil
+}
+
+func (s *serverService) WatchEvents(_ *pbServer.WatchEventsRequest, stream pbServer.ServerService_WatchEventsServer) error {
+	events, unsubscribe := s.node.subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "event stream dropped")
+			}
+			if event.GetCreatedAt() == nil {
+				event = proto.Clone(event).(*pbServer.Event)
+				event.CreatedAt = timestamppb.Now()
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
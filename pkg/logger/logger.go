@@ -5,6 +5,7 @@ import (
 	"fmt"
 	multi "github.com/samber/slog-multi"
 	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
 	"log/slog"
 	"os"
 	"runtime"
@@ -76,6 +77,16 @@ func New() *Logger {
 	return l
 }
 
+func NewDiscard() *Logger {
+	l := &Logger{
+		level:      &slog.LevelVar{},
+		levelNames: map[slog.Leveler]string{},
+	}
+	l.log = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: l.level}))
+
+	return l
+}
+
 func (l *Logger) SetLogLevel(levelStr string) {
 	switch levelStr {
 	case "trace":
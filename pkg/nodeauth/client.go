@@ -0,0 +1,108 @@
+package nodeauth
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strconv"
+	"time"
+)
+
+func appendCredentials(ctx context.Context, keyID, secret, method string, req any) (context.Context, error) {
+	digest, err := Digest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	return metadata.AppendToOutgoingContext(ctx,
+		HeaderKeyID, keyID,
+		HeaderTimestamp, strconv.FormatInt(timestamp, 10),
+		HeaderNonce, nonce,
+		HeaderSignature, Sign(secret, keyID, method, timestamp, nonce, digest),
+	), nil
+}
+
+func UnaryClientInterceptor(keyID, secret string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := appendCredentials(ctx, keyID, secret, method, req)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func StreamClientInterceptor(keyID, secret string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		// заголовки уходят при открытии потока, а запрос известен только при первой отправке,
+		// поэтому поток открывается вместе с первым сообщением, и подписывается оно
+		return &signedClientStream{ctx: ctx, desc: desc, cc: cc, method: method, streamer: streamer, opts: opts, keyID: keyID, secret: secret}, nil
+	}
+}
+
+type signedClientStream struct {
+	grpc.ClientStream
+
+	ctx      context.Context
+	desc     *grpc.StreamDesc
+	cc       *grpc.ClientConn
+	method   string
+	streamer grpc.Streamer
+	opts     []grpc.CallOption
+	keyID    string
+	secret   string
+}
+
+func (s *signedClientStream) SendMsg(m any) error {
+	if s.ClientStream == nil {
+		ctx, err := appendCredentials(s.ctx, s.keyID, s.secret, s.method, m)
+		if err != nil {
+			return err
+		}
+		if s.ClientStream, err = s.streamer(ctx, s.desc, s.cc, s.method, s.opts...); err != nil {
+			return err
+		}
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *signedClientStream) RecvMsg(m any) error {
+	if s.ClientStream == nil {
+		return ErrUnsignedMessage
+	}
+	return s.ClientStream.RecvMsg(m)
+}
+
+func (s *signedClientStream) Header() (metadata.MD, error) {
+	if s.ClientStream == nil {
+		return nil, ErrUnsignedMessage
+	}
+	return s.ClientStream.Header()
+}
+
+func (s *signedClientStream) Trailer() metadata.MD {
+	if s.ClientStream == nil {
+		return nil
+	}
+	return s.ClientStream.Trailer()
+}
+
+func (s *signedClientStream) CloseSend() error {
+	if s.ClientStream == nil {
+		return ErrUnsignedMessage
+	}
+	return s.ClientStream.CloseSend()
+}
+
+func (s *signedClientStream) Context() context.Context {
+	if s.ClientStream == nil {
+		return s.ctx
+	}
+	return s.ClientStream.Context()
+}
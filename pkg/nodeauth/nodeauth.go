@@ -0,0 +1,82 @@
+package nodeauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderKeyID     = "x-auth-key-id"
+	HeaderTimestamp = "x-auth-timestamp"
+	HeaderNonce     = "x-auth-nonce"
+	HeaderSignature = "x-auth-signature"
+	HeaderLegacyKey = "x-auth-key" // статический ключ нод, ещё не получивших HMAC-ключ
+
+	DefaultMaxSkew = 5 * time.Minute
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing auth credentials")
+	ErrUnknownKey         = errors.New("unknown auth key")
+	ErrInvalidSignature   = errors.New("invalid auth signature")
+	ErrExpiredTimestamp   = errors.New("auth timestamp out of range")
+	ErrReplayedNonce      = errors.New("auth nonce already used")
+	ErrLegacyDisabled     = errors.New("legacy auth is disabled after key bootstrap")
+	ErrUnsignedMessage    = errors.New("request message can not be signed")
+)
+
+func LegacyKey(publicKey, privateKey string) string {
+	key := sha256.Sum256([]byte(publicKey + privateKey))
+	return hex.EncodeToString(key[:])
+}
+
+func Sign(secret, keyID, method string, timestamp int64, nonce, digest string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{keyID, method, strconv.FormatInt(timestamp, 10), nonce, digest}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Digest хеширует детерминированную сериализацию запроса: подпись без неё позволила бы подменить тело,
+// сохранив заголовки, а нода пересобирает ту же сериализацию из декодированного сообщения
+func Digest(msg any) (string, error) {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return "", ErrUnsignedMessage
+	}
+
+	raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func GenerateKey() (keyID, secret string, err error) {
+	id := make([]byte, 8)
+	if _, err = rand.Read(id); err != nil {
+		return "", "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err = rand.Read(raw); err != nil {
+		return "", "", err
+	}
+
+	return hex.EncodeToString(id), hex.EncodeToString(raw), nil
+}
+
+func newNonce() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,157 @@
+package nodeauth
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	testMethod = "/server.v1.ServerService/GetLoad"
+	testDigest = "digest"
+)
+
+func incoming(keyID, secret, method string, timestamp time.Time, nonce string) context.Context {
+	return incomingWithDigest(keyID, secret, method, timestamp, nonce, testDigest)
+}
+
+func incomingWithDigest(keyID, secret, method string, timestamp time.Time, nonce, digest string) context.Context {
+	ts := timestamp.Unix()
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		HeaderKeyID, keyID,
+		HeaderTimestamp, strconv.FormatInt(ts, 10),
+		HeaderNonce, nonce,
+		HeaderSignature, Sign(secret, keyID, method, ts, nonce, digest),
+	))
+}
+
+func TestVerify(t *testing.T) {
+	keyring := NewKeyring()
+	keyring.Set("key", "secret")
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+	}{
+		{"valid", incoming("key", "secret", testMethod, now, "n1"), nil},
+		{"missing credentials", context.Background(), ErrMissingCredentials},
+		{"unknown key", incoming("other", "secret", testMethod, now, "n2"), ErrUnknownKey},
+		{"wrong secret", incoming("key", "wrong", testMethod, now, "n3"), ErrInvalidSignature},
+		{"signed for another method", incoming("key", "secret", "/server.v1.ServerService/GetHealth", now, "n4"), ErrInvalidSignature},
+		{"signed for another body", incomingWithDigest("key", "secret", testMethod, now, "n6", "other"), ErrInvalidSignature},
+		{"stale timestamp", incoming("key", "secret", testMethod, now.Add(-time.Hour), "n5"), ErrExpiredTimestamp},
+		{"replayed nonce", incoming("key", "secret", testMethod, now, "n1"), ErrReplayedNonce},
+	}
+
+	v := NewVerifier(keyring, 0)
+	for _, tt := range tests {
+		ctx, err := v.Verify(tt.ctx, testMethod, testDigest)
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		if err == nil && KeyIDFromContext(ctx) != "key" {
+			t.Fatalf("%s: key id missing from context", tt.name)
+		}
+	}
+}
+
+func TestKeyringRotate(t *testing.T) {
+	keyring := NewKeyring()
+	keyring.Set("old", "old-secret")
+
+	keyring.Rotate("old", "new", "new-secret", 50*time.Millisecond)
+	if _, ok := keyring.Lookup("old"); !ok {
+		t.Fatal("old key rejected during the grace period")
+	}
+	if secret, ok := keyring.Lookup("new"); !ok || secret != "new-secret" {
+		t.Fatal("new key is not accepted")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := keyring.Lookup("old"); ok {
+		t.Fatal("old key accepted after the grace period")
+	}
+}
+
+func TestVerifyLegacyBootstrap(t *testing.T) {
+	keyring := NewKeyring()
+	v := NewVerifier(keyring, 0)
+	legacy := metadata.NewIncomingContext(context.Background(), metadata.Pairs(HeaderLegacyKey, LegacyKey("pub", "priv")))
+
+	if _, err := v.Verify(legacy, testMethod, testDigest); !errors.Is(err, ErrMissingCredentials) {
+		t.Fatalf("legacy key accepted without AllowLegacy: %v", err)
+	}
+
+	v.AllowLegacy(LegacyKey("pub", "priv"))
+	if _, err := v.Verify(legacy, testMethod, testDigest); err != nil {
+		t.Fatalf("legacy key rejected before bootstrap: %v", err)
+	}
+	wrong := metadata.NewIncomingContext(context.Background(), metadata.Pairs(HeaderLegacyKey, LegacyKey("pub", "other")))
+	if _, err := v.Verify(wrong, testMethod, testDigest); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("wrong legacy key: %v", err)
+	}
+
+	keyring.Rotate("", "key", "secret", 0)
+	if _, err := v.Verify(legacy, testMethod, testDigest); !errors.Is(err, ErrLegacyDisabled) {
+		t.Fatalf("legacy key accepted after bootstrap: %v", err)
+	}
+	if _, err := v.Verify(incoming("key", "secret", testMethod, time.Now(), "n1"), testMethod, testDigest); err != nil {
+		t.Fatalf("bootstrapped key rejected: %v", err)
+	}
+}
+
+func TestNoncesExpireByBucket(t *testing.T) {
+	v := NewVerifier(NewKeyring(), time.Minute)
+	now := time.Now()
+
+	if !v.useNonce("n1", now) || v.useNonce("n1", now.Add(time.Minute)) {
+		t.Fatal("nonce replay within the window was not detected")
+	}
+	if !v.useNonce("n2", now.Add(90*time.Second)) {
+		t.Fatal("fresh nonce rejected")
+	}
+
+	if !v.useNonce("n1", now.Add(5*time.Minute)) {
+		t.Fatal("nonce is remembered after the window")
+	}
+	if len(v.nonces) != 1 {
+		t.Fatalf("old buckets were not dropped: %d left", len(v.nonces))
+	}
+}
+
+func TestSignatureCoversRequestBody(t *testing.T) {
+	keyring := NewKeyring()
+	keyring.Set("key", "secret")
+	server := NewVerifier(keyring, 0).UnaryServerInterceptor()
+	client := UnaryClientInterceptor("key", "secret")
+	info := &grpc.UnaryServerInfo{FullMethod: testMethod}
+	handler := func(ctx context.Context, req any) (any, error) { return req, nil }
+
+	for _, tt := range []struct {
+		name     string
+		received string
+		code     codes.Code
+	}{
+		{"same body", "uuid-1", codes.OK},
+		{"replaced body", "uuid-2", codes.Unauthenticated},
+	} {
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			_, err := server(metadata.NewIncomingContext(context.Background(), md), wrapperspb.String(tt.received), info, handler)
+			return err
+		}
+		err := client(context.Background(), testMethod, wrapperspb.String("uuid-1"), nil, nil, invoker)
+		if status.Code(err) != tt.code {
+			t.Fatalf("%s: err = %v, want %v", tt.name, err, tt.code)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package nodeauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type keyEntry struct {
+	secret    string
+	expiresAt time.Time
+}
+
+type Keyring struct {
+	mu   sync.RWMutex
+	keys map[string]keyEntry
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]keyEntry)}
+}
+
+func (k *Keyring) Set(keyID, secret string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[keyID] = keyEntry{secret: secret}
+}
+
+func (k *Keyring) Remove(keyID string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.keys, keyID)
+}
+
+func (k *Keyring) Rotate(oldKeyID, newKeyID, newSecret string, gracePeriod time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[newKeyID] = keyEntry{secret: newSecret}
+	if old, ok := k.keys[oldKeyID]; ok && oldKeyID != newKeyID {
+		old.expiresAt = time.Now().Add(gracePeriod)
+		k.keys[oldKeyID] = old
+	}
+}
+
+func (k *Keyring) Len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	count := 0
+	for _, entry := range k.keys {
+		if entry.expiresAt.IsZero() || now.Before(entry.expiresAt) {
+			count++
+		}
+	}
+	return count
+}
+
+func (k *Keyring) Lookup(keyID string) (string, bool) {
+	k.mu.RLock()
+	entry, ok := k.keys[keyID]
+	k.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		k.Remove(keyID)
+		return "", false
+	}
+	return entry.secret, true
+}
+
+type Verifier struct {
+	keyring   *Keyring
+	maxSkew   time.Duration
+	legacyKey string
+
+	mu sync.Mutex
+	// одноразовые значения хранятся корзинами по времени получения: устаревшие корзины удаляются целиком
+	nonces      map[int64]map[string]struct{}
+	bucketWidth time.Duration
+}
+
+func NewVerifier(keyring *Keyring, maxSkew time.Duration) *Verifier {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+
+	return &Verifier{
+		keyring:     keyring,
+		maxSkew:     maxSkew,
+		nonces:      make(map[int64]map[string]struct{}),
+		bucketWidth: max(maxSkew/2, time.Second),
+	}
+}
+
+// AllowLegacy принимает статический ключ, пока в связке нет ни одного HMAC-ключа:
+// так панель может выдать первый ключ ноде, которая знает только старый ключ
+func (v *Verifier) AllowLegacy(legacyKey string) {
+	v.legacyKey = legacyKey
+}
+
+type keyIDContextKey struct{}
+
+func KeyIDFromContext(ctx context.Context) string {
+	keyID, _ := ctx.Value(keyIDContextKey{}).(string)
+	return keyID
+}
+
+func (v *Verifier) Verify(ctx context.Context, method, digest string) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, ErrMissingCredentials
+	}
+
+	keyID, timestampRaw, nonce, signature := first(md, HeaderKeyID), first(md, HeaderTimestamp), first(md, HeaderNonce), first(md, HeaderSignature)
+	if keyID == "" {
+		if legacyKey := first(md, HeaderLegacyKey); legacyKey != "" {
+			return v.verifyLegacy(ctx, legacyKey)
+		}
+	}
+	if keyID == "" || timestampRaw == "" || nonce == "" || signature == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	timestamp, err := strconv.ParseInt(timestampRaw, 10, 64)
+	if err != nil {
+		return nil, ErrExpiredTimestamp
+	}
+
+	now := time.Now()
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > v.maxSkew || diff < -v.maxSkew {
+		return nil, ErrExpiredTimestamp
+	}
+
+	secret, ok := v.keyring.Lookup(keyID)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, keyID, method, timestamp, nonce, digest))) {
+		return nil, ErrInvalidSignature
+	}
+
+	if !v.useNonce(keyID+":"+nonce, now) {
+		return nil, ErrReplayedNonce
+	}
+
+	return context.WithValue(ctx, keyIDContextKey{}, keyID), nil
+}
+
+func (v *Verifier) verifyLegacy(ctx context.Context, legacyKey string) (context.Context, error) {
+	if v.legacyKey == "" {
+		return nil, ErrMissingCredentials
+	}
+	if v.keyring.Len() > 0 {
+		return nil, ErrLegacyDisabled
+	}
+	if !hmac.Equal([]byte(legacyKey), []byte(v.legacyKey)) {
+		return nil, ErrInvalidSignature
+	}
+	return ctx, nil
+}
+
+func (v *Verifier) useNonce(nonce string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// подпись принимается в пределах ±maxSkew, поэтому значение достаточно помнить 2*maxSkew
+	current := now.UnixNano() / int64(v.bucketWidth)
+	oldest := current - int64(2*v.maxSkew/v.bucketWidth) - 1 // плюс неполная корзина на границе окна
+	for bucket, nonces := range v.nonces {
+		if bucket < oldest {
+			delete(v.nonces, bucket)
+			continue
+		}
+		if _, used := nonces[nonce]; used {
+			return false
+		}
+	}
+
+	if v.nonces[current] == nil {
+		v.nonces[current] = make(map[string]struct{})
+	}
+	v.nonces[current][nonce] = struct{}{}
+	return true
+}
+
+func (v *Verifier) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		digest, err := Digest(req)
+		if err == nil {
+			ctx, err = v.Verify(ctx, info.FullMethod, digest)
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (v *Verifier) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ss.Context(), verifier: v, method: info.FullMethod})
+	}
+}
+
+// serverStream проверяет подпись по первому сообщению потока: клиент подписывает именно его,
+// а до проверки поток ничего не отправляет
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	verifier *Verifier
+	method   string
+	verified bool
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil || s.verified {
+		return err
+	}
+
+	digest, err := Digest(m)
+	if err == nil {
+		s.ctx, err = s.verifier.Verify(s.ServerStream.Context(), s.method, digest)
+	}
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	s.verified = true
+	return nil
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	if !s.verified {
+		return status.Error(codes.Unauthenticated, ErrMissingCredentials.Error())
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -9,6 +9,8 @@ package v1
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,6 +23,64 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED         EventType = 0
+	EventType_EVENT_TYPE_CLIENT_CONNECTED    EventType = 1
+	EventType_EVENT_TYPE_CLIENT_DISCONNECTED EventType = 2
+	EventType_EVENT_TYPE_QUOTA_EXCEEDED      EventType = 3
+	EventType_EVENT_TYPE_LOAD_THRESHOLD      EventType = 4
+	EventType_EVENT_TYPE_CONFIG_RELOADED     EventType = 5
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_CLIENT_CONNECTED",
+		2: "EVENT_TYPE_CLIENT_DISCONNECTED",
+		3: "EVENT_TYPE_QUOTA_EXCEEDED",
+		4: "EVENT_TYPE_LOAD_THRESHOLD",
+		5: "EVENT_TYPE_CONFIG_RELOADED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED":         0,
+		"EVENT_TYPE_CLIENT_CONNECTED":    1,
+		"EVENT_TYPE_CLIENT_DISCONNECTED": 2,
+		"EVENT_TYPE_QUOTA_EXCEEDED":      3,
+		"EVENT_TYPE_LOAD_THRESHOLD":      4,
+		"EVENT_TYPE_CONFIG_RELOADED":     5,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_server_v1_proto_enumTypes[0].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_protos_server_v1_proto_enumTypes[0]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_protos_server_v1_proto_rawDescGZIP(), []int{0}
+}
+
 type ServerRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -145,20 +205,384 @@ func (x *HealthResponse) GetStatus() string {
 	return ""
 }
 
+type RotateAuthKeyRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	KeyId  string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	Secret string                 `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	// Сколько нода ещё принимает старый ключ после ротации
+	GracePeriod   *durationpb.Duration `protobuf:"bytes,3,opt,name=grace_period,json=gracePeriod,proto3" json:"grace_period,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateAuthKeyRequest) Reset() {
+	*x = RotateAuthKeyRequest{}
+	mi := &file_protos_server_v1_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateAuthKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateAuthKeyRequest) ProtoMessage() {}
+
+func (x *RotateAuthKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_server_v1_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateAuthKeyRequest.ProtoReflect.Descriptor instead.
+func (*RotateAuthKeyRequest) Descriptor() ([]byte, []int) {
+	return file_protos_server_v1_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RotateAuthKeyRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *RotateAuthKeyRequest) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+func (x *RotateAuthKeyRequest) GetGracePeriod() *durationpb.Duration {
+	if x != nil {
+		return x.GracePeriod
+	}
+	return nil
+}
+
+type RotateAuthKeyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateAuthKeyResponse) Reset() {
+	*x = RotateAuthKeyResponse{}
+	mi := &file_protos_server_v1_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateAuthKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateAuthKeyResponse) ProtoMessage() {}
+
+func (x *RotateAuthKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_server_v1_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateAuthKeyResponse.ProtoReflect.Descriptor instead.
+func (*RotateAuthKeyResponse) Descriptor() ([]byte, []int) {
+	return file_protos_server_v1_proto_rawDescGZIP(), []int{4}
+}
+
+type UpdateRealityConfigRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	PrivateKey string                 `protobuf:"bytes,1,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
+	ShortIds   []string               `protobuf:"bytes,2,rep,name=short_ids,json=shortIds,proto3" json:"short_ids,omitempty"`
+	// Старые short ID остаются валидными до old_short_ids_expire_at
+	OldShortIds         []string               `protobuf:"bytes,3,rep,name=old_short_ids,json=oldShortIds,proto3" json:"old_short_ids,omitempty"`
+	OldShortIdsExpireAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=old_short_ids_expire_at,json=oldShortIdsExpireAt,proto3" json:"old_short_ids_expire_at,omitempty"`
+	// Старый приватный ключ обслуживается вместе с новым до old_short_ids_expire_at
+	OldPrivateKey string `protobuf:"bytes,5,opt,name=old_private_key,json=oldPrivateKey,proto3" json:"old_private_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRealityConfigRequest) Reset() {
+	*x = UpdateRealityConfigRequest{}
+	mi := &file_protos_server_v1_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRealityConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRealityConfigRequest) ProtoMessage() {}
+
+func (x *UpdateRealityConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_server_v1_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRealityConfigRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRealityConfigRequest) Descriptor() ([]byte, []int) {
+	return file_protos_server_v1_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateRealityConfigRequest) GetPrivateKey() string {
+	if x != nil {
+		return x.PrivateKey
+	}
+	return ""
+}
+
+func (x *UpdateRealityConfigRequest) GetShortIds() []string {
+	if x != nil {
+		return x.ShortIds
+	}
+	return nil
+}
+
+func (x *UpdateRealityConfigRequest) GetOldShortIds() []string {
+	if x != nil {
+		return x.OldShortIds
+	}
+	return nil
+}
+
+func (x *UpdateRealityConfigRequest) GetOldShortIdsExpireAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OldShortIdsExpireAt
+	}
+	return nil
+}
+
+func (x *UpdateRealityConfigRequest) GetOldPrivateKey() string {
+	if x != nil {
+		return x.OldPrivateKey
+	}
+	return ""
+}
+
+type UpdateRealityConfigResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRealityConfigResponse) Reset() {
+	*x = UpdateRealityConfigResponse{}
+	mi := &file_protos_server_v1_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRealityConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRealityConfigResponse) ProtoMessage() {}
+
+func (x *UpdateRealityConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_server_v1_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRealityConfigResponse.ProtoReflect.Descriptor instead.
+func (*UpdateRealityConfigResponse) Descriptor() ([]byte, []int) {
+	return file_protos_server_v1_proto_rawDescGZIP(), []int{6}
+}
+
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_protos_server_v1_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_server_v1_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_protos_server_v1_proto_rawDescGZIP(), []int{7}
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          EventType              `protobuf:"varint,1,opt,name=type,proto3,enum=server.v1.EventType" json:"type,omitempty"`
+	Uuid          string                 `protobuf:"bytes,2,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Email         string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	LoadScore     float64                `protobuf:"fixed64,4,opt,name=load_score,json=loadScore,proto3" json:"load_score,omitempty"`
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_protos_server_v1_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_server_v1_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_protos_server_v1_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Event) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *Event) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Event) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *Event) GetLoadScore() float64 {
+	if x != nil {
+		return x.LoadScore
+	}
+	return 0
+}
+
+func (x *Event) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Event) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
 var File_protos_server_v1_proto protoreflect.FileDescriptor
 
 const file_protos_server_v1_proto_rawDesc = "" +
 	"\n" +
-	"\x16protos/server_v1.proto\x12\tserver.v1\"\x0f\n" +
+	"\x16protos/server_v1.proto\x12\tserver.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x0f\n" +
 	"\rServerRequest\"-\n" +
 	"\fLoadResponse\x12\x1d\n" +
 	"\n" +
 	"load_score\x18\x01 \x01(\x01R\tloadScore\"(\n" +
 	"\x0eHealthResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status2\x93\x01\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\"\x83\x01\n" +
+	"\x14RotateAuthKeyRequest\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x12\x16\n" +
+	"\x06secret\x18\x02 \x01(\tR\x06secret\x12<\n" +
+	"\fgrace_period\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\vgracePeriod\"\x17\n" +
+	"\x15RotateAuthKeyResponse\"\xf8\x01\n" +
+	"\x1aUpdateRealityConfigRequest\x12\x1f\n" +
+	"\vprivate_key\x18\x01 \x01(\tR\n" +
+	"privateKey\x12\x1b\n" +
+	"\tshort_ids\x18\x02 \x03(\tR\bshortIds\x12\"\n" +
+	"\rold_short_ids\x18\x03 \x03(\tR\voldShortIds\x12P\n" +
+	"\x17old_short_ids_expire_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x13oldShortIdsExpireAt\x12&\n" +
+	"\x0fold_private_key\x18\x05 \x01(\tR\roldPrivateKey\"\x1d\n" +
+	"\x1bUpdateRealityConfigResponse\"\x14\n" +
+	"\x12WatchEventsRequest\"\xcf\x01\n" +
+	"\x05Event\x12(\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x14.server.v1.EventTypeR\x04type\x12\x12\n" +
+	"\x04uuid\x18\x02 \x01(\tR\x04uuid\x12\x14\n" +
+	"\x05email\x18\x03 \x01(\tR\x05email\x12\x1d\n" +
+	"\n" +
+	"load_score\x18\x04 \x01(\x01R\tloadScore\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt*\xca\x01\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x1f\n" +
+	"\x1bEVENT_TYPE_CLIENT_CONNECTED\x10\x01\x12\"\n" +
+	"\x1eEVENT_TYPE_CLIENT_DISCONNECTED\x10\x02\x12\x1d\n" +
+	"\x19EVENT_TYPE_QUOTA_EXCEEDED\x10\x03\x12\x1d\n" +
+	"\x19EVENT_TYPE_LOAD_THRESHOLD\x10\x04\x12\x1e\n" +
+	"\x1aEVENT_TYPE_CONFIG_RELOADED\x10\x052\x95\x03\n" +
 	"\rServerService\x12>\n" +
 	"\aGetLoad\x12\x18.server.v1.ServerRequest\x1a\x17.server.v1.LoadResponse\"\x00\x12B\n" +
-	"\tGetHealth\x12\x18.server.v1.ServerRequest\x1a\x19.server.v1.HealthResponse\"\x00B\x0fZ\rpkg/server/v1b\x06proto3"
+	"\tGetHealth\x12\x18.server.v1.ServerRequest\x1a\x19.server.v1.HealthResponse\"\x00\x12T\n" +
+	"\rRotateAuthKey\x12\x1f.server.v1.RotateAuthKeyRequest\x1a .server.v1.RotateAuthKeyResponse\"\x00\x12B\n" +
+	"\vWatchEvents\x12\x1d.server.v1.WatchEventsRequest\x1a\x10.server.v1.Event\"\x000\x01\x12f\n" +
+	"\x13UpdateRealityConfig\x12%.server.v1.UpdateRealityConfigRequest\x1a&.server.v1.UpdateRealityConfigResponse\"\x00B\x0fZ\rpkg/server/v1b\x06proto3"
 
 var (
 	file_protos_server_v1_proto_rawDescOnce sync.Once
@@ -172,22 +596,42 @@ func file_protos_server_v1_proto_rawDescGZIP() []byte {
 	return file_protos_server_v1_proto_rawDescData
 }
 
-var file_protos_server_v1_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_protos_server_v1_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_protos_server_v1_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_protos_server_v1_proto_goTypes = []any{
-	(*ServerRequest)(nil),  // 0: server.v1.ServerRequest
-	(*LoadResponse)(nil),   // 1: server.v1.LoadResponse
-	(*HealthResponse)(nil), // 2: server.v1.HealthResponse
+	(EventType)(0),                      // 0: server.v1.EventType
+	(*ServerRequest)(nil),               // 1: server.v1.ServerRequest
+	(*LoadResponse)(nil),                // 2: server.v1.LoadResponse
+	(*HealthResponse)(nil),              // 3: server.v1.HealthResponse
+	(*RotateAuthKeyRequest)(nil),        // 4: server.v1.RotateAuthKeyRequest
+	(*RotateAuthKeyResponse)(nil),       // 5: server.v1.RotateAuthKeyResponse
+	(*UpdateRealityConfigRequest)(nil),  // 6: server.v1.UpdateRealityConfigRequest
+	(*UpdateRealityConfigResponse)(nil), // 7: server.v1.UpdateRealityConfigResponse
+	(*WatchEventsRequest)(nil),          // 8: server.v1.WatchEventsRequest
+	(*Event)(nil),                       // 9: server.v1.Event
+	(*durationpb.Duration)(nil),         // 10: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),       // 11: google.protobuf.Timestamp
 }
 var file_protos_server_v1_proto_depIdxs = []int32{
-	0, // 0: server.v1.ServerService.GetLoad:input_type -> server.v1.ServerRequest
-	0, // 1: server.v1.ServerService.GetHealth:input_type -> server.v1.ServerRequest
-	1, // 2: server.v1.ServerService.GetLoad:output_type -> server.v1.LoadResponse
-	2, // 3: server.v1.ServerService.GetHealth:output_type -> server.v1.HealthResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	10, // 0: server.v1.RotateAuthKeyRequest.grace_period:type_name -> google.protobuf.Duration
+	11, // 1: server.v1.UpdateRealityConfigRequest.old_short_ids_expire_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: server.v1.Event.type:type_name -> server.v1.EventType
+	11, // 3: server.v1.Event.created_at:type_name -> google.protobuf.Timestamp
+	1,  // 4: server.v1.ServerService.GetLoad:input_type -> server.v1.ServerRequest
+	1,  // 5: server.v1.ServerService.GetHealth:input_type -> server.v1.ServerRequest
+	4,  // 6: server.v1.ServerService.RotateAuthKey:input_type -> server.v1.RotateAuthKeyRequest
+	8,  // 7: server.v1.ServerService.WatchEvents:input_type -> server.v1.WatchEventsRequest
+	6,  // 8: server.v1.ServerService.UpdateRealityConfig:input_type -> server.v1.UpdateRealityConfigRequest
+	2,  // 9: server.v1.ServerService.GetLoad:output_type -> server.v1.LoadResponse
+	3,  // 10: server.v1.ServerService.GetHealth:output_type -> server.v1.HealthResponse
+	5,  // 11: server.v1.ServerService.RotateAuthKey:output_type -> server.v1.RotateAuthKeyResponse
+	9,  // 12: server.v1.ServerService.WatchEvents:output_type -> server.v1.Event
+	7,  // 13: server.v1.ServerService.UpdateRealityConfig:output_type -> server.v1.UpdateRealityConfigResponse
+	9,  // [9:14] is the sub-list for method output_type
+	4,  // [4:9] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_protos_server_v1_proto_init() }
@@ -200,13 +644,14 @@ func file_protos_server_v1_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protos_server_v1_proto_rawDesc), len(file_protos_server_v1_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      1,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_protos_server_v1_proto_goTypes,
 		DependencyIndexes: file_protos_server_v1_proto_depIdxs,
+		EnumInfos:         file_protos_server_v1_proto_enumTypes,
 		MessageInfos:      file_protos_server_v1_proto_msgTypes,
 	}.Build()
 	File_protos_server_v1_proto = out.File
@@ -19,8 +19,11 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ServerService_GetLoad_FullMethodName   = "/server.v1.ServerService/GetLoad"
-	ServerService_GetHealth_FullMethodName = "/server.v1.ServerService/GetHealth"
+	ServerService_GetLoad_FullMethodName             = "/server.v1.ServerService/GetLoad"
+	ServerService_GetHealth_FullMethodName           = "/server.v1.ServerService/GetHealth"
+	ServerService_RotateAuthKey_FullMethodName       = "/server.v1.ServerService/RotateAuthKey"
+	ServerService_WatchEvents_FullMethodName         = "/server.v1.ServerService/WatchEvents"
+	ServerService_UpdateRealityConfig_FullMethodName = "/server.v1.ServerService/UpdateRealityConfig"
 )
 
 // ServerServiceClient is the client API for ServerService service.
@@ -29,6 +32,9 @@ const (
 type ServerServiceClient interface {
 	GetLoad(ctx context.Context, in *ServerRequest, opts ...grpc.CallOption) (*LoadResponse, error)
 	GetHealth(ctx context.Context, in *ServerRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	RotateAuthKey(ctx context.Context, in *RotateAuthKeyRequest, opts ...grpc.CallOption) (*RotateAuthKeyResponse, error)
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	UpdateRealityConfig(ctx context.Context, in *UpdateRealityConfigRequest, opts ...grpc.CallOption) (*UpdateRealityConfigResponse, error)
 }
 
 type serverServiceClient struct {
@@ -59,12 +65,54 @@ func (c *serverServiceClient) GetHealth(ctx context.Context, in *ServerRequest,
 	return out, nil
 }
 
+func (c *serverServiceClient) RotateAuthKey(ctx context.Context, in *RotateAuthKeyRequest, opts ...grpc.CallOption) (*RotateAuthKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RotateAuthKeyResponse)
+	err := c.cc.Invoke(ctx, ServerService_RotateAuthKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serverServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ServerService_ServiceDesc.Streams[0], ServerService_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ServerService_WatchEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *serverServiceClient) UpdateRealityConfig(ctx context.Context, in *UpdateRealityConfigRequest, opts ...grpc.CallOption) (*UpdateRealityConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateRealityConfigResponse)
+	err := c.cc.Invoke(ctx, ServerService_UpdateRealityConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ServerServiceServer is the server API for ServerService service.
 // All implementations must embed UnimplementedServerServiceServer
 // for forward compatibility.
 type ServerServiceServer interface {
 	GetLoad(context.Context, *ServerRequest) (*LoadResponse, error)
 	GetHealth(context.Context, *ServerRequest) (*HealthResponse, error)
+	RotateAuthKey(context.Context, *RotateAuthKeyRequest) (*RotateAuthKeyResponse, error)
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error
+	UpdateRealityConfig(context.Context, *UpdateRealityConfigRequest) (*UpdateRealityConfigResponse, error)
 	mustEmbedUnimplementedServerServiceServer()
 }
 
@@ -81,6 +129,15 @@ func (UnimplementedServerServiceServer) GetLoad(context.Context, *ServerRequest)
 func (UnimplementedServerServiceServer) GetHealth(context.Context, *ServerRequest) (*HealthResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetHealth not implemented")
 }
+func (UnimplementedServerServiceServer) RotateAuthKey(context.Context, *RotateAuthKeyRequest) (*RotateAuthKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateAuthKey not implemented")
+}
+func (UnimplementedServerServiceServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedServerServiceServer) UpdateRealityConfig(context.Context, *UpdateRealityConfigRequest) (*UpdateRealityConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRealityConfig not implemented")
+}
 func (UnimplementedServerServiceServer) mustEmbedUnimplementedServerServiceServer() {}
 func (UnimplementedServerServiceServer) testEmbeddedByValue()                       {}
 
@@ -138,6 +195,53 @@ func _ServerService_GetHealth_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ServerService_RotateAuthKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateAuthKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerServiceServer).RotateAuthKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ServerService_RotateAuthKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerServiceServer).RotateAuthKey(ctx, req.(*RotateAuthKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ServerService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServerServiceServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ServerService_WatchEventsServer = grpc.ServerStreamingServer[Event]
+
+func _ServerService_UpdateRealityConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRealityConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServerServiceServer).UpdateRealityConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ServerService_UpdateRealityConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServerServiceServer).UpdateRealityConfig(ctx, req.(*UpdateRealityConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ServerService_ServiceDesc is the grpc.ServiceDesc for ServerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,7 +257,21 @@ var ServerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetHealth",
 			Handler:    _ServerService_GetHealth_Handler,
 		},
+		{
+			MethodName: "RotateAuthKey",
+			Handler:    _ServerService_RotateAuthKey_Handler,
+		},
+		{
+			MethodName: "UpdateRealityConfig",
+			Handler:    _ServerService_UpdateRealityConfig_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _ServerService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "protos/server_v1.proto",
 }